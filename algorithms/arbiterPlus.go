@@ -5,6 +5,7 @@ import (
 
 	glob "github.com/uccmisl/godash/global"
 	"github.com/uccmisl/godash/http"
+	abrqlog "github.com/uccmisl/godash/qlog"
 	"github.com/uccmisl/godash/utils"
 	//"math"
 )
@@ -42,13 +43,13 @@ var maximumSwitch int
 // CalculateSelectedIndexArbiter :
 /*
  * return the index of the segment which should be selected
- *
+ * estimate is the throughput estimate of an estimator, 0 to use the exponential average of the algorithm
  */
 func CalculateSelectedIndexArbiter(newThr int, lastDuration int, lastIndex int, maxBufferLevel int,
 	lastRate int, thrList *[]int, mpdDuration int, currentMPD http.MPD, currentURL string,
 	currentMPDRepAdaptSet int, segmentNumber int, baseURL string, debugLog bool, downloadTime int, bufferLevel int,
 	highestMPDrepRateIndex int, lowestMPDrepRateIndex int, bandwithList []int,
-	segmentSize int, quicBool bool, useTestbedBool bool) int {
+	segmentSize int, quicBool bool, useTestbedBool bool, estimate float64, tracer *abrqlog.StreamTracer, decision *Decision) int {
 
 	//Does not work if repRatesReversed
 	//the typical default buffer should be 60 seconds, however this is set in the config json files
@@ -78,6 +79,14 @@ func CalculateSelectedIndexArbiter(newThr int, lastDuration int, lastIndex int,
 
 	ExpAverage(*thrList, exponent, historicEstimationWindow, &exponentialAverageRate)
 
+	decision.add("buffer_fullness", bufferFullness)
+	decision.add("exponential_average_bps", exponentialAverageRate)
+
+	// use the estimator instead, if we have one
+	if estimate > 0 {
+		exponentialAverageRate = estimate
+	}
+
 	//fmt.Println(exponentialAverageRate)
 	//fmt.Println("exponentialavgrate: ", exponentialAverageRate)
 
@@ -96,6 +105,9 @@ func CalculateSelectedIndexArbiter(newThr int, lastDuration int, lastIndex int,
 	targetRate := exponentialAverageRate * bufferingFactor
 
 	targetIndex := SelectRepRateWithThroughtput(int(targetRate), bandwithList, lowestMPDrepRateIndex)
+	decision.add("buffering_factor", bufferingFactor)
+	decision.add("target_bps", int(targetRate))
+	decision.add("target_index", targetIndex)
 
 	if switchingControl {
 
@@ -112,6 +124,7 @@ func CalculateSelectedIndexArbiter(newThr int, lastDuration int, lastIndex int,
 			targetIndex = utils.Max(lastRate-maximumSwitch, 0)
 		}
 
+		decision.add("switch_control_index", targetIndex)
 	}
 	//fmt.Println("targetIndex 2: ", targetIndex)
 
@@ -126,10 +139,12 @@ func CalculateSelectedIndexArbiter(newThr int, lastDuration int, lastIndex int,
 		//fmt.Println("vidchunks", videoChunks)
 
 		videoWindow := utils.Min(videoChunks-lastIndex, predictiveEstimationWindow)
+		// the sizes of the segments of this window are checked against the target rate
+		decision.add("video_window", videoWindow)
 
 		//fmt.Println("videoWindow", videoWindow)
 		if http.SegHeadValues == nil {
-			for targetIndex < lowestMPDrepRateIndex && !SmartConvHelper(targetIndex, videoWindow, targetRate, currentMPD, currentURL, currentMPDRepAdaptSet, lastRate, segmentNumber, baseURL, debugLog, lastDuration, client) {
+			for targetIndex < lowestMPDrepRateIndex && !SmartConvHelper(targetIndex, videoWindow, targetRate, currentMPD, currentURL, currentMPDRepAdaptSet, lastRate, segmentNumber, baseURL, debugLog, lastDuration, client, tracer) {
 				targetIndex++
 			}
 		} else {
@@ -23,7 +23,7 @@ package algorithms
 
 //MeanAverageAlgo : "normal average" -> take all the throughtputs and make the average
 //call the func meanAverage with all the values of throughtput to make a "standard" average
-func MeanAverageAlgo(thrList *[]int, newThr int, repRate *int, bandwithList []int, lowestMPDrepRateIndex int) {
+func MeanAverageAlgo(thrList *[]int, newThr int, repRate *int, bandwithList []int, lowestMPDrepRateIndex int, decision *Decision) {
 
 	var average float64
 
@@ -32,6 +32,7 @@ func MeanAverageAlgo(thrList *[]int, newThr int, repRate *int, bandwithList []in
 	//if there is not enough throughtputs in the list, can't calculate the average
 	if len(*thrList) < 2 {
 		//if there is not enough throughtput, we call selectRepRate() with the newThr
+		decision.add("target_bps", newThr)
 		*repRate = SelectRepRateWithThroughtput(newThr, bandwithList, lowestMPDrepRateIndex)
 		return
 	}
@@ -42,6 +43,7 @@ func MeanAverageAlgo(thrList *[]int, newThr int, repRate *int, bandwithList []in
 	// fmt.Println("AVERAGE: ", *thrList)
 
 	//We select the reprate with the calculated throughtput
+	decision.add("average_bps", average)
 	*repRate = SelectRepRateWithThroughtput(int(average), bandwithList, lowestMPDrepRateIndex)
 }
 
@@ -1,16 +1,19 @@
 /**
 
 Cross-layer version of the MeanAverage algorithm
+The throughput of each segment is measured from the bytes received for its request on its connection
 
 */
 
 package algorithms
 
 import (
+	"math"
+
 	"github.com/uccmisl/godash/crosslayer"
 )
 
-func MeanAverageRecentXLAlgo(XLaccountant *crosslayer.CrossLayerAccountant, thrList *[]int, newThr int, repRate *int, bandwithList []int, lowestMPDrepRateIndex int) {
+func MeanAverageRecentXLAlgo(XLaccountant *crosslayer.CrossLayerAccountant, thrList *[]int, newThr int, repRate *int, bandwithList []int, lowestMPDrepRateIndex int, decision *Decision) {
 	var average float64
 
 	*thrList = append(*thrList, newThr)
@@ -18,6 +21,7 @@ func MeanAverageRecentXLAlgo(XLaccountant *crosslayer.CrossLayerAccountant, thrL
 	//if there is not enough throughtputs in the list, can't calculate the average
 	if len(*thrList) < 2 {
 		//if there is not enough throughtput, we call selectRepRate() with the newThr
+		decision.add("target_bps", newThr)
 		*repRate = SelectRepRateWithThroughtput(newThr, bandwithList, lowestMPDrepRateIndex)
 		return
 	}
@@ -34,6 +38,18 @@ func MeanAverageRecentXLAlgo(XLaccountant *crosslayer.CrossLayerAccountant, thrL
 		fmt.Println("------------------------")
 	*/
 
+	// no segment request has been measured on its connection yet, use the application throughput
+	if xlaverage <= 0 {
+		xlaverage = average
+	}
+	// we cannot download faster than the bottleneck link, a higher average is bytes counted for another request
+	if capacity := decision.capacity(); capacity > 0 {
+		decision.add("bottleneck_capacity_bps", capacity)
+		xlaverage = math.Min(xlaverage, capacity)
+	}
+
 	//We select the reprate with the calculated throughtput
+	decision.add("average_bps", average)
+	decision.add("crosslayer_average_bps", xlaverage)
 	*repRate = SelectRepRateWithThroughtput(int(xlaverage), bandwithList, lowestMPDrepRateIndex)
 }
@@ -24,6 +24,8 @@ package algorithms
 import (
 	"fmt"
 	"testing"
+
+	"github.com/uccmisl/godash/crosslayer"
 )
 
 // ------------------------------------------------------------------------------------------------
@@ -59,7 +61,7 @@ func TestMeanAverage(t *testing.T) {
 
 	//test MeanAverageAlgo(
 	//		thrList []int, newThr int, repRate int, bandwithList []int, repRatesReversed bool) (int, []int)
-	MeanAverageAlgo(&thrList, 26106059, &repRate, bandwithList, 12)
+	MeanAverageAlgo(&thrList, 26106059, &repRate, bandwithList, 12, nil)
 	if repRate != 2 {
 		t.Log("test MeanAverageAlgo")
 		t.Error("Expected repRate = 2 but got reprate choosed: ", repRate)
@@ -94,7 +96,7 @@ func TestGeomAverage(t *testing.T) {
 	}
 
 	//test func GeomAverageAlgo(thrList []int, newThr int, repRate int, bandwithList []int, repRatesReversed bool) (int, []int)
-	GeomAverageAlgo(&thrList, 26106059, &repRate, bandwithList, 12)
+	GeomAverageAlgo(&thrList, 26106059, &repRate, bandwithList, 12, nil)
 	if repRate != 2 {
 		t.Log("test MeanAverageAlgo")
 		t.Error("Expected repRate = 2 but got reprate choosed: ", repRate)
@@ -203,9 +205,63 @@ func TestLogistic(t *testing.T) {
 	//PB: we should pass the maxBufferLevel to this function
 	//test func LogisticFunction(lastRateIndex int, thrList []int, bufferLevel int, highestMPDrepRateIndex int,
 	//lowestMPDrepRateIndex int, maxBufferLevel int, bandwithList []int, repRatesReversed bool) int
-	retVal := LogisticFunction(10, thrList, 4000, 13, 3, 5000, bandwithList)
+	retVal := LogisticFunction(10, thrList, 4000, 13, 3, 5000, bandwithList, nil)
 	//t.Error(retVal)
 	fmt.Println("repRate TestLogistic: ", retVal)
 
 	//test CalculateSelectedIndex(thrList []int, newThr int, bandwithList []int, bufferLevel int) int
 }
+
+// ----------------------------- Test Decision -------------------------------------------------
+func TestDecision(t *testing.T) {
+
+	bandwithList := []int{4354160, 3894826, 3046114, 2386043}
+
+	// each decision only has the details of its own segment
+	thrList := []int{}
+	repRate := 0
+	var decisions []*Decision
+	for _, thr := range []int{3000000, 4000000} {
+		decision := &Decision{}
+		MeanAverageAlgo(&thrList, thr, &repRate, bandwithList, 3, decision)
+		decisions = append(decisions, decision)
+	}
+	for i, key := range []string{"target_bps", "average_bps"} {
+		details := decisions[i].Details()
+		if len(details) != 1 || details[0].Key != key {
+			t.Errorf("decision %d has the details %v, want only %s", i, details, key)
+		}
+	}
+
+	// a nil decision discards the details
+	var decision *Decision
+	decision.add("target_bps", 1)
+	if decision.Details() != nil {
+		t.Error("a nil decision should have no details")
+	}
+}
+
+// ----------------------------- Test MeanAverageXL --------------------------------------------
+func TestMeanAverageXLCapacity(t *testing.T) {
+
+	bandwithList := []int{4354160, 3894826, 3046114, 2386043}
+
+	// a segment request of 1 MB that ends at once, far faster than any representation
+	accountant := crosslayer.NewCrossLayerAccountant()
+	request := accountant.BeginRequest("segment.m4s")
+	accountant.SetConnection(request, "conn1")
+	accountant.ReportProgress(request, 1000000)
+	accountant.EndRequest(request)
+
+	for _, c := range []struct {
+		capacity float64
+		repRate  int
+	}{{0, 0}, {3000000, 3}} {
+		thrList := []int{2000000}
+		repRate := 3
+		MeanAverageXLAlgo(accountant, &thrList, 2000000, &repRate, bandwithList, 3, &Decision{Capacity: c.capacity})
+		if repRate != c.repRate {
+			t.Errorf("capacity %v chose rep_rate %d, want %d", c.capacity, repRate, c.repRate)
+		}
+	}
+}
@@ -14,7 +14,7 @@ import (
 * Selects the representation index according to the BBA algorithm
  */
 func BBA(bufferLevel_Milliseconds int, maxBufferLevel_Seconds int, highestMPDrepRateIndex int, lowestMPDrepRateIndex int, bandwithList []int,
-	segmentDuration int, debugLog bool, debugFile string, thrList *[]int, newThr int) int {
+	segmentDuration int, debugLog bool, debugFile string, thrList *[]int, newThr int, decision *Decision) int {
 
 	*thrList = append(*thrList, newThr)
 
@@ -29,15 +29,21 @@ func BBA(bufferLevel_Milliseconds int, maxBufferLevel_Seconds int, highestMPDrep
 		logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "The buffer is relatively small for the current segment duration")
 	}
 
+	decision.add("reservoir_lower_ms", reservoir_lower)
+	decision.add("reservoir_upper_ms", reservoir_upper)
+
 	// If we are in the lower reservoir, select the lowest bandwith
 	if reservoir_lower >= float64(bufferLevel_Milliseconds) {
 		fmt.Println("In lower reservoir")
+		decision.add("zone", "lower reservoir")
 		return lowestMPDrepRateIndex
 	} else if maxBufferLevel_Milliseconds-int(reservoir_upper) <= bufferLevel_Milliseconds {
 		// If we are in the higher reservoir, select the highest bandwixth
 		fmt.Println("In upper reservoir")
+		decision.add("zone", "upper reservoir")
 		return highestMPDrepRateIndex
 	}
+	decision.add("zone", "cushion")
 
 	// Available representation boundaries
 	R1 := LowestBitrate(bandwithList)
@@ -54,6 +60,9 @@ func BBA(bufferLevel_Milliseconds int, maxBufferLevel_Seconds int, highestMPDrep
 
 	// Map to a bitrate
 	var desiredBitrate float64 = (percentage * Rmax) + R1
+	decision.add("cushion_ms", Bm)
+	decision.add("cushion_fraction", percentage)
+	decision.add("target_bps", int(desiredBitrate))
 
 	// Choose the representation that best fits this bitrate
 	chosenRep := SelectRepRateWithThroughtput(int(desiredBitrate), bandwithList, lowestMPDrepRateIndex)
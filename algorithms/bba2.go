@@ -37,7 +37,7 @@ import (
 func CalculateSelectedIndexBba(newThr int, lastDuration int, lastIndex int, maxBufferLevel int,
 	lastRate int, thrList *[]int, mpdDuration int, currentMPD http.MPD, currentURL string,
 	currentMPDRepAdaptSet int, segmentNumber int, baseURL string, debugLog bool, downloadTime int, bufferLevel int,
-	highestMPDrepRateIndex int, lowestMPDrepRateIndex int, bandwithList []int, quicBool bool, useTestbedBool bool) int {
+	highestMPDrepRateIndex int, lowestMPDrepRateIndex int, bandwithList []int, quicBool bool, useTestbedBool bool, decision *Decision) int {
 
 	currTime := time.Now()
 
@@ -48,6 +48,7 @@ func CalculateSelectedIndexBba(newThr int, lastDuration int, lastIndex int, maxB
 
 	//average of throughtputs
 	meanAverage(*thrList, &average)
+	decision.add("average_bps", average)
 
 	//fmt.Println("average of throughtput: ", average)
 
@@ -86,6 +87,13 @@ func CalculateSelectedIndexBba(newThr int, lastDuration int, lastIndex int, maxB
 	if downloadTime > lastDuration {
 		mStaticAlgPar = 1
 	}
+	decision.add("reservoir_ms", reservoir)
+	decision.add("slow_download", mStaticAlgPar != 0)
+	if bufferLevel < reservoir {
+		decision.add("zone", "reservoir")
+	} else {
+		decision.add("zone", "cushion")
+	}
 	fmt.Println("bufferLevel: ", bufferLevel)
 
 	//fmt.Println("lastindex", lastIndex)
@@ -122,11 +130,11 @@ func CalculateSelectedIndexBba(newThr int, lastDuration int, lastIndex int, maxB
 	} else {
 		if mStaticAlgPar != 0 {
 			retVal = bba1VRAA(lastRate, *thrList, bufferLevel, highestMPDrepRateIndex,
-				lowestMPDrepRateIndex, maxBufferLevel, bandwithList, reservoir)
+				lowestMPDrepRateIndex, maxBufferLevel, bandwithList, reservoir, decision)
 			//fmt.Println("retval 5", retVal)
 		} else {
 			bba1RateIndex := bba1VRAA(lastRate, *thrList, bufferLevel, highestMPDrepRateIndex,
-				lowestMPDrepRateIndex, maxBufferLevel, bandwithList, reservoir)
+				lowestMPDrepRateIndex, maxBufferLevel, bandwithList, reservoir, decision)
 
 			///fmt.Println("bba1rateindex", bba1RateIndex)
 			if float64(downloadTime) <= 0.5*float64(lastDuration) {
@@ -147,6 +155,7 @@ func CalculateSelectedIndexBba(newThr int, lastDuration int, lastIndex int, maxB
 			*/
 			//originally here
 			retVal = utils.Min(bba1RateIndex, lowestBitrateIndex)
+			decision.add("bba1_index", bba1RateIndex)
 
 			//}
 
@@ -235,7 +244,7 @@ func bba1UpdateReservoir(lastRate int, lastRateIndex int, mpdDuration int,
 }
 
 func bba1VRAA(lastRateIndex int, thrList []int, bufferLevel int, highestMPDrepRateIndex int,
-	lowestMPDrepRateIndex int, maxBufferLevel int, bandwithList []int, reservoir int) int {
+	lowestMPDrepRateIndex int, maxBufferLevel int, bandwithList []int, reservoir int, decision *Decision) int {
 
 	var optRateIndex int
 	var rateUindex int
@@ -267,6 +276,8 @@ func bba1VRAA(lastRateIndex int, thrList []int, bufferLevel int, highestMPDrepRa
 
 		//target rate calcul
 		targetRate := (high - low) / (0.9*float64(maxBufferLevel) - (float64(reservoir) / 1000))
+		decision.add("cushion_s", 0.9*float64(maxBufferLevel))
+		decision.add("cushion_target_kbps", targetRate)
 
 		//optRateIndex = findBestRateIndex(targetRate * 1024)
 		optRateIndex = SelectRepRateWithThroughtput(int((targetRate+1)*1000), bandwithList, lowestMPDrepRateIndex)
@@ -30,7 +30,7 @@ var thr = -1
 * call the func to select the repRate from the throughtput
 * return the repRate and the list of throughtput
  */
-func Conventional(thrList *[]int, newThr int, repRate *int, bandwithList []int, lowestMPDrepRateIndex int) {
+func Conventional(thrList *[]int, newThr int, repRate *int, bandwithList []int, lowestMPDrepRateIndex int, decision *Decision) {
 
 	//if it is the first throughtput in the list, add it to the list
 	if thr == -1 {
@@ -42,6 +42,7 @@ func Conventional(thrList *[]int, newThr int, repRate *int, bandwithList []int,
 		thr = (8*thr)/10 + (2*newThr)/10
 		*thrList = append(*thrList, thr)
 	}
+	decision.add("smoothed_throughput_bps", thr)
 
 	*repRate = SelectRepRateWithThroughtput(thr, bandwithList, lowestMPDrepRateIndex)
 }
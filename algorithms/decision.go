@@ -0,0 +1,63 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package algorithms
+
+import (
+	abrqlog "github.com/uccmisl/godash/qlog"
+)
+
+// Decision :
+// * the inputs and intermediate values of the rep_rate decision of one segment
+// * the player gives a new Decision to the algorithm for every segment, and the algorithm adds to it
+// * a nil Decision discards the details
+type Decision struct {
+	// Capacity : the bottleneck link capacity of the connection of the last segment in bits/second, set by the player - 0 when it is unknown
+	// * unlike the throughput, it does not drop when the player was idle or the download was limited by flow control
+	Capacity float64
+	details  []abrqlog.DecisionDetail
+}
+
+/**
+* Returns the bottleneck link capacity in bits/second, 0 for a nil Decision or when it is unknown
+ */
+func (d *Decision) capacity() float64 {
+	if d == nil {
+		return 0
+	}
+	return d.Capacity
+}
+
+// Details : the details the algorithm added to the decision, in the order it added them
+func (d *Decision) Details() []abrqlog.DecisionDetail {
+	if d == nil {
+		return nil
+	}
+	return d.details
+}
+
+// add : add a named input or intermediate value to the decision
+func (d *Decision) add(key string, value interface{}) {
+	if d == nil {
+		return
+	}
+	d.details = append(d.details, abrqlog.DecisionDetail{Key: key, Value: value})
+}
@@ -27,7 +27,7 @@ import (
 
 // ElasticAlgo call the func harmonicAverage with the last 5 values of throughtput
 // to have a better estimate of the throughtput
-func ElasticAlgo(thrList *[]int, newThr int, delTime int, maxBuffer int, repRate *int, bandwithList []int, staticAlgParameter *float64, bufferLevel int, kP float64, kI float64, lowestMPDrepRateIndex int) {
+func ElasticAlgo(thrList *[]int, newThr int, delTime int, maxBuffer int, repRate *int, bandwithList []int, staticAlgParameter *float64, bufferLevel int, kP float64, kI float64, lowestMPDrepRateIndex int, decision *Decision) {
 
 	//number of last averages we are going to take
 
@@ -55,10 +55,31 @@ func ElasticAlgo(thrList *[]int, newThr int, delTime int, maxBuffer int, repRate
 	// fmt.Println("currbuffer", bufferLevel)
 	// harmonic average of the last throughtputs
 	harmonicAverage(harmonicAverageValue, *thrList, &averageRateEstimate)
+	decision.add("harmonic_average_bps", averageRateEstimate)
 	// fmt.Println("all", averageRateEstimate/1000)
+
+	*repRate = elasticRepRate(averageRateEstimate, delTime, maxBuffer, bandwithList, staticAlgParameter, bufferLevel, kP, kI, lowestMPDrepRateIndex, decision)
+}
+
+// ElasticEstimatorAlgo : the elastic algorithm with the estimate of a throughput estimator in place of the harmonic average
+func ElasticEstimatorAlgo(thrList *[]int, newThr int, estimate float64, delTime int, maxBuffer int, repRate *int, bandwithList []int, staticAlgParameter *float64, bufferLevel int, kP float64, kI float64, lowestMPDrepRateIndex int, decision *Decision) {
+
+	*thrList = append(*thrList, newThr)
+
+	*repRate = elasticRepRate(estimate, delTime, maxBuffer, bandwithList, staticAlgParameter, bufferLevel, kP, kI, lowestMPDrepRateIndex, decision)
+}
+
+// elasticRepRate : scale the rate estimate with the buffer level, and its integral over time, to keep the buffer at maxBuffer
+func elasticRepRate(averageRateEstimate float64, delTime int, maxBuffer int, bandwithList []int, staticAlgParameter *float64, bufferLevel int, kP float64, kI float64, lowestMPDrepRateIndex int, decision *Decision) int {
+
 	*staticAlgParameter += (float64(delTime) / glob.Conversion1000) * (float64(bufferLevel)/glob.Conversion1000 - float64(maxBuffer))
 	targetRate := averageRateEstimate / (1 - kP*float64(bufferLevel/glob.Conversion1000) - kI*float64(*staticAlgParameter))
+	// the state of the PI controller
+	decision.add("kp", kP)
+	decision.add("ki", kI)
+	decision.add("buffer_integral", *staticAlgParameter)
+	decision.add("target_bps", int(targetRate))
 	// fmt.Println("target thr: ", int(targetRate), "bandwithList: ", bandwithList)
 
-	*repRate = SelectRepRateWithThroughtput(int(targetRate), bandwithList, lowestMPDrepRateIndex)
+	return SelectRepRateWithThroughtput(int(targetRate), bandwithList, lowestMPDrepRateIndex)
 }
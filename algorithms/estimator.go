@@ -0,0 +1,34 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package algorithms
+
+// EstimatorAlgo :
+// * the rate based algorithms with a throughput estimator in place of their own estimate
+// * select the rate just below the estimate, as conventional, average, geometric and exponential do with theirs
+// * the throughput of the segment is still added to the list
+func EstimatorAlgo(thrList *[]int, newThr int, estimate float64, repRate *int, bandwithList []int, lowestMPDrepRateIndex int, decision *Decision) {
+
+	*thrList = append(*thrList, newThr)
+	decision.add("target_bps", int(estimate))
+
+	*repRate = SelectRepRateWithThroughtput(int(estimate), bandwithList, lowestMPDrepRateIndex)
+}
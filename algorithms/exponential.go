@@ -32,7 +32,7 @@ package algorithms
 call the func expAverage with all the values of the throughtput list and a window size
 to make an exponential average
 */
-func EMWAAverageAlgo(thrList *[]int, repRate *int, exponentialRatio float64, window int, newThr int, bandwithList []int, lowestMPDrepRateIndex int) {
+func EMWAAverageAlgo(thrList *[]int, repRate *int, exponentialRatio float64, window int, newThr int, bandwithList []int, lowestMPDrepRateIndex int, decision *Decision) {
 
 	var average float64
 
@@ -41,6 +41,7 @@ func EMWAAverageAlgo(thrList *[]int, repRate *int, exponentialRatio float64, win
 	//if there is not enough throughtputs in the list, can't calculate the average
 	if len(*thrList) < 2 {
 		//if there is not enough throughtput, we call selectRepRate() with the newThr
+		decision.add("target_bps", newThr)
 		*repRate = SelectRepRateWithThroughtput(newThr, bandwithList, lowestMPDrepRateIndex)
 		return
 	}
@@ -51,6 +52,7 @@ func EMWAAverageAlgo(thrList *[]int, repRate *int, exponentialRatio float64, win
 	//fmt.Println("AVERAGE: ", int(average))
 
 	//We select the reprate with the calculated throughtput
+	decision.add("exponential_average_bps", average)
 	*repRate = SelectRepRateWithThroughtput(int(average), bandwithList, lowestMPDrepRateIndex)
 }
 
@@ -28,7 +28,7 @@ import "math"
 // GeomAverageAlgo :
 // call the func geomAverage with all the values of the throughtput list
 // to make a geometric average
-func GeomAverageAlgo(thrList *[]int, newThr int, repRate *int, bandwithList []int, lowestMPDrepRateIndex int) {
+func GeomAverageAlgo(thrList *[]int, newThr int, repRate *int, bandwithList []int, lowestMPDrepRateIndex int, decision *Decision) {
 
 	var average float64
 
@@ -37,6 +37,7 @@ func GeomAverageAlgo(thrList *[]int, newThr int, repRate *int, bandwithList []in
 	//if there is not enough throughtputs in the list, can't calculate the average
 	if len(*thrList) < 2 {
 		//if there is not enough throughtput, we call selectRepRate() with the newThr
+		decision.add("target_bps", newThr)
 		*repRate = SelectRepRateWithThroughtput(newThr, bandwithList, lowestMPDrepRateIndex)
 		return
 	}
@@ -45,6 +46,7 @@ func GeomAverageAlgo(thrList *[]int, newThr int, repRate *int, bandwithList []in
 	geomAverage(*thrList, &average)
 
 	//We select the reprate with the calculated throughtput
+	decision.add("geometric_average_bps", average)
 	*repRate = SelectRepRateWithThroughtput(int(average), bandwithList, lowestMPDrepRateIndex)
 
 }
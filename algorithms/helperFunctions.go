@@ -27,6 +27,7 @@ import (
 
 	glob "github.com/uccmisl/godash/global"
 	"github.com/uccmisl/godash/http"
+	abrqlog "github.com/uccmisl/godash/qlog"
 	"github.com/uccmisl/godash/utils"
 )
 
@@ -137,13 +138,13 @@ func ThroughputSamples(window int, thrList []int) []int {
 /*
  * Checks next "videoWindow" of segments and makes sure the average rate is less than the estimated rate
  */
-func SmartConvHelper(qIndex int, videoWindow int, estRate float64, currentMPD http.MPD, currentURL string, currentMPDRepAdaptSet int, lastRate int, segmentNumber int, baseURL string, debugLog bool, lastDuration int, client *otherhttp.Client) bool {
+func SmartConvHelper(qIndex int, videoWindow int, estRate float64, currentMPD http.MPD, currentURL string, currentMPDRepAdaptSet int, lastRate int, segmentNumber int, baseURL string, debugLog bool, lastDuration int, client *otherhttp.Client, tracer *abrqlog.StreamTracer) bool {
 	var totSegSize int
 
 	for i := 0; i < videoWindow; i++ {
 
 		totSegSize += 8 * http.GetContentLengthHeader(currentMPD,
-			currentURL, currentMPDRepAdaptSet, qIndex, segmentNumber+i, baseURL, debugLog, client)
+			currentURL, currentMPDRepAdaptSet, qIndex, segmentNumber+i, baseURL, debugLog, client, tracer)
 
 	}
 	actualAvgRate := float64(float64(totSegSize) / (float64(lastDuration) / 1000 * float64(videoWindow)))
@@ -38,7 +38,7 @@ var debugLog bool
 //return the rate and throughtput list
 func Logistic(thrList *[]int, newThr int, repRate *int, bandwithList []int, bufferLevel int,
 	highestMPDrepRateIndex int, lowestMPDrepRateIndex int, debugFiles string, debugLogs bool,
-	maxBufferLevel int) {
+	maxBufferLevel int, decision *Decision) {
 
 	debugFile = debugFiles
 	debugLog = debugLogs
@@ -46,7 +46,7 @@ func Logistic(thrList *[]int, newThr int, repRate *int, bandwithList []int, buff
 	*thrList = append(*thrList, newThr)
 
 	*repRate = calculateSelectedIndex(*thrList, newThr, bandwithList, bufferLevel, *repRate, highestMPDrepRateIndex,
-		lowestMPDrepRateIndex, maxBufferLevel)
+		lowestMPDrepRateIndex, maxBufferLevel, decision)
 
 }
 
@@ -56,7 +56,7 @@ func Logistic(thrList *[]int, newThr int, repRate *int, bandwithList []int, buff
 // calculateSelectedIndex :
 //call the func LogisticFunction(lastRateIndex, thrList, bufferLevel) to calculate the rate
 func calculateSelectedIndex(thrList []int, newThr int, bandwithList []int, bufferLevel int, repRate int,
-	highestMPDrepRateIndex int, lowestMPDrepRateIndex int, maxBufferLevel int) int {
+	highestMPDrepRateIndex int, lowestMPDrepRateIndex int, maxBufferLevel int, decision *Decision) int {
 
 	//take the last rate
 	//current rep rate : repRate
@@ -65,7 +65,7 @@ func calculateSelectedIndex(thrList []int, newThr int, bandwithList []int, buffe
 	lastRateIndex := repRate
 
 	retVal := LogisticFunction(lastRateIndex, thrList, bufferLevel, highestMPDrepRateIndex, lowestMPDrepRateIndex,
-		maxBufferLevel, bandwithList)
+		maxBufferLevel, bandwithList, decision)
 	//fmt.Println(retVal)
 	return retVal
 }
@@ -76,7 +76,7 @@ func calculateSelectedIndex(thrList []int, newThr int, bandwithList []int, buffe
 // LogisticFunction :
 //calculate and return the rate index
 func LogisticFunction(lastRateIndex int, thrList []int, bufferLevel int, highestMPDrepRateIndex int,
-	lowestMPDrepRateIndex int, maxBufferLevel int, bandwithList []int) int {
+	lowestMPDrepRateIndex int, maxBufferLevel int, bandwithList []int, decision *Decision) int {
 
 	//len(tracks) = number of rates -> of representations in the MPD
 
@@ -106,7 +106,11 @@ func LogisticFunction(lastRateIndex int, thrList []int, bufferLevel int, highest
 	logging.DebugPrint(debugFile, debugLog, "\nDEBUG: ", "bufferLevel: "+strconv.Itoa(bufferLevel/100))
 	logging.DebugPrint(debugFile, debugLog, "\nDEBUG: ", "max: "+strconv.Itoa(maxBufferLevel))
 
+	decision.add("upper_index", rateUindex)
+	decision.add("lower_index", rateLindex)
+
 	if float64(bufferLevel/1000) >= float64(0.97)*float64(maxBufferLevel) {
+		decision.add("buffer_full", true)
 		optRateIndex = 0
 	} else {
 		var low float64
@@ -123,9 +127,12 @@ func LogisticFunction(lastRateIndex int, thrList []int, bufferLevel int, highest
 		logging.DebugPrint(debugFile, debugLog, "\nDEBUG: ", "Higher: "+strconv.Itoa(int(high)))
 		logging.DebugPrint(debugFile, debugLog, "\nDEBUG: ", "targetRate: "+strconv.Itoa(int(targetRate)))
 
+		decision.add("logistic_target_kbps", targetRate)
+
 		//optRateIndex = findBestRateIndex(targetRate * 1024)
 		optRateIndex = SelectRepRateWithThroughtput(int((targetRate+1)*1000), bandwithList, lowestMPDrepRateIndex)
 	}
+	decision.add("optimal_index", optRateIndex)
 
 	logging.DebugPrint(debugFile, debugLog, "\nDEBUG: ", "optRateIndex: "+strconv.Itoa(optRateIndex))
 	logging.DebugPrint(debugFile, debugLog, "\nDEBUG: ", "lastRateIndex: "+strconv.Itoa(lastRateIndex))
@@ -0,0 +1,155 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	glob "github.com/uccmisl/godash/global"
+	qlogreader "github.com/uccmisl/godash/qlog/reader"
+	"github.com/uccmisl/godash/report"
+)
+
+// analyze :
+// * the analyze command - "godash analyze [-logs folder] [-csv folder] [-qlogMerge on|off] [-report on|off] [-segments file]"
+// * read the qlog files of a run, print a summary of the session and save its time series to CSV
+// * and its HTML report with the QoE of the segment log
+func analyze(args []string) {
+
+	flags := flag.NewFlagSet(glob.AppName+" "+glob.AnalyzeCommand, flag.ExitOnError)
+	logsPtr := flags.String(glob.AnalyzeLogsName, glob.DebugFolder, "folder of the qlog files of the run")
+	csvPtr := flags.String(glob.AnalyzeCSVName, "", "folder to save the time series of the run to - defaults to the folder of the qlog files")
+	mergePtr := flags.String(glob.QlogMergeName, glob.QlogMergeOff, "also merge the qlog files of the run into "+glob.QlogMergedFile+" - \"["+glob.QlogMergeOn+"|"+glob.QlogMergeOff+"]\"")
+	reportPtr := flags.String(glob.ReportName, glob.ReportOff, "also save an HTML report of the session to the CSV folder - \"["+glob.ReportOn+"|"+glob.ReportOff+"]\"")
+	segmentsPtr := flags.String(glob.AnalyzeSegmentsName, "", "the "+glob.LogDownload+" of the run, for the QoE plots of the report")
+	flags.Parse(args)
+
+	logs, err := qlogreader.ReadFolder(*logsPtr)
+	if err != nil {
+		fmt.Println("*** could not read the qlog files in " + *logsPtr + " - " + err.Error() + " ***")
+		os.Exit(3)
+	}
+	// the logs folder also keeps the transport qlogs of earlier runs
+	logs = qlogreader.Session(logs)
+	abr := qlogreader.ABR(logs)
+	if len(abr) == 0 {
+		fmt.Println("*** there is no qlog-abr file in " + *logsPtr + " ***")
+		os.Exit(3)
+	}
+
+	for _, l := range abr {
+		printSummary(l, qlogreader.Summarise(l))
+	}
+	transport := qlogreader.Transport(logs)
+	fmt.Printf("Transport qlogs: %d\n", len(transport))
+	for _, l := range transport {
+		if l.Truncated {
+			fmt.Println("  " + l.Path + " was not closed, it is read up to its last complete event")
+		}
+	}
+
+	csvFolder := *csvPtr
+	if csvFolder == "" {
+		csvFolder = *logsPtr
+	}
+	os.MkdirAll(csvFolder, os.ModePerm)
+	paths, err := qlogreader.WriteSeries(csvFolder, logs)
+	if err != nil {
+		fmt.Println("*** could not save the time series - " + err.Error() + " ***")
+		os.Exit(3)
+	}
+	fmt.Println("Time series saved to " + strings.Join(paths, ", "))
+
+	if *reportPtr == glob.ReportOn {
+		for _, l := range abr {
+			writeReport(filepath.Join(csvFolder, filepath.Base(report.Filename(l.Path))), l, *segmentsPtr)
+		}
+	}
+
+	if *mergePtr == glob.QlogMergeOn {
+		mergeQlogs(*logsPtr, logs)
+	}
+}
+
+// mergeQlogs : write the application and transport events of a run to a single qlog in the logs folder
+func mergeQlogs(folder string, logs []*qlogreader.Log) {
+	path := filepath.Join(folder, glob.QlogMergedFile)
+	if err := qlogreader.MergeFile(path, logs); err != nil {
+		fmt.Println("*** could not merge the qlog files - " + err.Error() + " ***")
+		os.Exit(3)
+	}
+	fmt.Println("Merged qlog saved to " + path)
+}
+
+// writeReport : save the HTML report of a session, with the QoE of its segment log if it is not ""
+func writeReport(path string, l *qlogreader.Log, segmentLog string) {
+	r, err := report.New(l, segmentLog)
+	if err == nil {
+		err = r.WriteFile(path)
+	}
+	if err != nil {
+		fmt.Println("*** could not save the report - " + err.Error() + " ***")
+		return
+	}
+	fmt.Println("Report saved to " + path)
+}
+
+// printSummary : print the summary of a session to the terminal
+func printSummary(l *qlogreader.Log, s qlogreader.Summary) {
+
+	fmt.Println("Session " + l.Path)
+	if l.Truncated {
+		fmt.Println("  the qlog was not closed, the summary is up to its last complete event")
+	}
+	fmt.Printf("  startup delay:    %s\n", s.StartupDelay.Round(time.Millisecond))
+	fmt.Printf("  playback time:    %s\n", s.PlaybackTime.Round(time.Millisecond))
+	fmt.Printf("  stalls:           %d (%s)\n", s.Stalls, s.StallTime.Round(time.Millisecond))
+	for _, m := range s.MediaTypes {
+		fmt.Printf("  %s:\n", m.MediaType)
+		fmt.Printf("    switches:         %d (%d up, %d down)\n", m.Switches, m.SwitchesUp, m.SwitchesDown)
+		fmt.Printf("    average bitrate:  %s\n", kbps(m.AverageBitrate))
+		for _, rep := range m.Representations {
+			name := "representation " + rep.ID
+			if rep.ID == "" {
+				name = "unknown representation"
+			}
+			share := 0.0
+			if s.PlaybackTime > 0 {
+				share = 100 * rep.Time.Seconds() / s.PlaybackTime.Seconds()
+			}
+			fmt.Printf("    %-24s %10s %10s %5.1f%%\n", name, kbps(float64(rep.Bitrate)), rep.Time.Round(time.Millisecond), share)
+		}
+	}
+}
+
+// kbps : a bitrate of the qlog, "-" when it is not known
+func kbps(bitrate float64) string {
+	if bitrate < 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.0f kbps", bitrate)
+}
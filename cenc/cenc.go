@@ -0,0 +1,368 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+// Package cenc decrypts ISO/IEC 23001-7 (common encryption) segments.
+// Only the 'cenc' (AES-CTR) and 'cbcs' (AES-CBC with pattern) schemes are supported.
+package cenc
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/uccmisl/godash/isobmff"
+)
+
+// SchemeCENC : AES-CTR full sample and subsample encryption
+const SchemeCENC = "cenc"
+
+// SchemeCBCS : AES-CBC subsample pattern encryption
+const SchemeCBCS = "cbcs"
+
+// TrackEncryption : the encryption values of a track, read from the init segment
+type TrackEncryption struct {
+	// scheme type from the schm box
+	Scheme string
+	// sample entry type before encryption (from the frma box)
+	OriginalFormat string
+	// values from the tenc box
+	IsProtected     bool
+	PerSampleIVSize int
+	KID             []byte
+	CryptByteBlock  int
+	SkipByteBlock   int
+	ConstantIV      []byte
+	// the tracks of the init segment, media segments can take their sample sizes from the trex boxes
+	Init *isobmff.Init
+}
+
+// KIDString : the KID as a hex string, used as the key in a key map
+func (t *TrackEncryption) KIDString() string {
+	return hex.EncodeToString(t.KID)
+}
+
+// rename : change the type of a box in place, used to turn boxes into 'free' boxes
+func rename(buf []byte, b isobmff.Box, typ string) {
+	copy(buf[b.Start+4:b.Start+8], typ)
+}
+
+// protectedSampleEntries : return the encrypted sample entries from every track in the init segment
+func protectedSampleEntries(init []byte) []isobmff.Box {
+
+	var entries []isobmff.Box
+	moov, ok := isobmff.FindPath(init, "moov")
+	if !ok {
+		return nil
+	}
+	traks, _ := isobmff.ReadBoxes(init, moov.Payload, moov.End)
+	for _, trak := range traks {
+		if trak.Type != "trak" {
+			continue
+		}
+		stsd, ok := isobmff.FindBoxPath(init, trak, "mdia", "minf", "stbl", "stsd")
+		if !ok {
+			continue
+		}
+		// skip version, flags and entry_count
+		sampleEntries, _ := isobmff.ReadBoxes(init, stsd.Payload+8, stsd.End)
+		for _, entry := range sampleEntries {
+			if entry.Type == "encv" || entry.Type == "enca" {
+				entries = append(entries, entry)
+			}
+		}
+	}
+	return entries
+}
+
+// ParseInit :
+// * read the track encryption values from an init segment
+// * return nil if the init segment is not encrypted
+func ParseInit(init []byte) (*TrackEncryption, error) {
+
+	tracks, err := isobmff.ParseInit(init)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range protectedSampleEntries(init) {
+
+		sinf, ok := isobmff.FindBox(init, entry.Payload+isobmff.SampleEntryHeaderSize(entry.Type), entry.End, "sinf")
+		if !ok {
+			return nil, fmt.Errorf("%s sample entry without a sinf box", entry.Type)
+		}
+
+		te := &TrackEncryption{Init: tracks}
+		if frma, ok := isobmff.FindBox(init, sinf.Payload, sinf.End, "frma"); ok && frma.End-frma.Payload >= 4 {
+			te.OriginalFormat = string(init[frma.Payload : frma.Payload+4])
+		}
+		if schm, ok := isobmff.FindBox(init, sinf.Payload, sinf.End, "schm"); ok && schm.End-schm.Payload >= 8 {
+			// skip version and flags
+			te.Scheme = string(init[schm.Payload+4 : schm.Payload+8])
+		}
+
+		schi, ok := isobmff.FindBox(init, sinf.Payload, sinf.End, "schi")
+		if !ok {
+			return nil, errors.New("sinf box without a schi box")
+		}
+		tenc, ok := isobmff.FindBox(init, schi.Payload, schi.End, "tenc")
+		if !ok || tenc.End-tenc.Payload < 24 {
+			return nil, errors.New("schi box without a valid tenc box")
+		}
+
+		p := init[tenc.Payload:tenc.End]
+		version := p[0]
+		if version > 0 {
+			te.CryptByteBlock = int(p[5] >> 4)
+			te.SkipByteBlock = int(p[5] & 0x0f)
+		}
+		te.IsProtected = p[6] == 1
+		te.PerSampleIVSize = int(p[7])
+		te.KID = append([]byte{}, p[8:24]...)
+		if te.IsProtected && te.PerSampleIVSize == 0 && len(p) > 24 {
+			ivSize := int(p[24])
+			if len(p) < 25+ivSize {
+				return nil, errors.New("tenc box with a truncated constant IV")
+			}
+			te.ConstantIV = append([]byte{}, p[25:25+ivSize]...)
+		}
+
+		return te, nil
+	}
+
+	return nil, nil
+}
+
+// ClearInit :
+// * return a copy of the init segment that players will treat as clear content
+// * the original sample entry type is restored, sinf and pssh boxes become 'free' boxes
+func ClearInit(init []byte, te *TrackEncryption) []byte {
+
+	out := append([]byte{}, init...)
+
+	for _, entry := range protectedSampleEntries(out) {
+		if sinf, ok := isobmff.FindBox(out, entry.Payload+isobmff.SampleEntryHeaderSize(entry.Type), entry.End, "sinf"); ok {
+			rename(out, sinf, "free")
+		}
+		if te.OriginalFormat != "" {
+			rename(out, entry, te.OriginalFormat)
+		}
+	}
+
+	if moov, ok := isobmff.FindPath(out, "moov"); ok {
+		boxes, _ := isobmff.ReadBoxes(out, moov.Payload, moov.End)
+		for _, b := range boxes {
+			if b.Type == "pssh" {
+				rename(out, b, "free")
+			}
+		}
+	}
+
+	return out
+}
+
+// subsample : the clear and protected byte count of part of a sample
+type subsample struct {
+	clear     int
+	protected int
+}
+
+// sampleEncryption : the IV and subsamples of one sample, read from the senc box
+type sampleEncryption struct {
+	iv         []byte
+	subsamples []subsample
+}
+
+// parseSenc : read the per sample encryption values from a senc box
+func parseSenc(buf []byte, senc isobmff.Box, ivSize int) ([]sampleEncryption, error) {
+
+	p := buf[senc.Payload:senc.End]
+	if len(p) < 8 {
+		return nil, errors.New("truncated senc box")
+	}
+	useSubsamples := p[3]&0x02 != 0
+	count := int(binary.BigEndian.Uint32(p[4:]))
+	pos := 8
+
+	samples := make([]sampleEncryption, count)
+	for i := range samples {
+		if pos+ivSize > len(p) {
+			return nil, errors.New("truncated senc box")
+		}
+		samples[i].iv = p[pos : pos+ivSize]
+		pos += ivSize
+		if useSubsamples {
+			if pos+2 > len(p) {
+				return nil, errors.New("truncated senc box")
+			}
+			n := int(binary.BigEndian.Uint16(p[pos:]))
+			pos += 2
+			if pos+6*n > len(p) {
+				return nil, errors.New("truncated senc box")
+			}
+			for j := 0; j < n; j++ {
+				samples[i].subsamples = append(samples[i].subsamples, subsample{
+					clear:     int(binary.BigEndian.Uint16(p[pos:])),
+					protected: int(binary.BigEndian.Uint32(p[pos+2:])),
+				})
+				pos += 6
+			}
+		}
+	}
+	return samples, nil
+}
+
+// DecryptSegment :
+// * decrypt every sample of a media segment with the given key
+// * return a decrypted copy, with the senc, saiz and saio boxes turned into 'free' boxes
+func DecryptSegment(seg []byte, te *TrackEncryption, key []byte) ([]byte, error) {
+
+	if te == nil || !te.IsProtected {
+		return seg, nil
+	}
+	if te.Scheme != SchemeCENC && te.Scheme != SchemeCBCS {
+		return nil, fmt.Errorf("encryption scheme %q is not supported", te.Scheme)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	out := append([]byte{}, seg...)
+
+	topLevel, err := isobmff.ReadBoxes(out, 0, len(out))
+	if err != nil {
+		return nil, err
+	}
+	for _, moof := range topLevel {
+		if moof.Type != "moof" {
+			continue
+		}
+		trafs, _ := isobmff.ReadBoxes(out, moof.Payload, moof.End)
+		for _, traf := range trafs {
+			if traf.Type != "traf" {
+				continue
+			}
+
+			senc, ok := isobmff.FindBox(out, traf.Payload, traf.End, "senc")
+			if !ok {
+				return nil, errors.New("traf box without a senc box")
+			}
+			samples, err := parseSenc(out, senc, te.PerSampleIVSize)
+			if err != nil {
+				return nil, err
+			}
+			f, err := isobmff.ParseTraf(out, moof, traf, te.Init)
+			if err != nil {
+				return nil, err
+			}
+
+			for i, sample := range f.Samples {
+				if i >= len(samples) {
+					return nil, errors.New("more samples than senc entries")
+				}
+				// the size is in the trun, tfhd or trex box - without it we would decrypt nothing
+				if sample.Size == 0 {
+					return nil, errors.New("no sample size in the trun, tfhd or trex boxes")
+				}
+				offset, size := sample.Offset, int(sample.Size)
+				if offset+size > len(out) {
+					return nil, errors.New("sample data outside of the segment")
+				}
+				err = decryptSample(block, te, samples[i], out[offset:offset+size])
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			// the samples are clear now
+			boxes, _ := isobmff.ReadBoxes(out, traf.Payload, traf.End)
+			for _, b := range boxes {
+				if b.Type == "senc" || b.Type == "saiz" || b.Type == "saio" {
+					rename(out, b, "free")
+				}
+			}
+		}
+	}
+
+	return out, nil
+}
+
+// decryptSample : decrypt one sample in place
+func decryptSample(block cipher.Block, te *TrackEncryption, s sampleEncryption, data []byte) error {
+
+	iv := s.iv
+	if len(iv) == 0 {
+		iv = te.ConstantIV
+	}
+	// 8 byte IVs are padded with zeros
+	iv16 := make([]byte, aes.BlockSize)
+	copy(iv16, iv)
+
+	subsamples := s.subsamples
+	if len(subsamples) == 0 {
+		subsamples = []subsample{{clear: 0, protected: len(data)}}
+	}
+
+	// the CTR key stream runs across all subsamples of a sample
+	var stream cipher.Stream
+	if te.Scheme == SchemeCENC {
+		stream = cipher.NewCTR(block, iv16)
+	}
+
+	pos := 0
+	for _, sub := range subsamples {
+		pos += sub.clear
+		if pos+sub.protected > len(data) {
+			return errors.New("subsample larger than its sample")
+		}
+		protected := data[pos : pos+sub.protected]
+		if te.Scheme == SchemeCENC {
+			stream.XORKeyStream(protected, protected)
+		} else {
+			// cbcs resets the IV at the start of every subsample
+			decryptPattern(cipher.NewCBCDecrypter(block, iv16), protected, te.CryptByteBlock, te.SkipByteBlock)
+		}
+		pos += sub.protected
+	}
+	return nil
+}
+
+// decryptPattern : decrypt crypt blocks then skip blocks until the end of the data, partial blocks are clear
+func decryptPattern(mode cipher.BlockMode, data []byte, crypt int, skip int) {
+
+	// no pattern means every full block is encrypted
+	if crypt == 0 && skip == 0 {
+		crypt = 1
+	}
+
+	for pos := 0; pos+aes.BlockSize <= len(data); {
+		n := crypt * aes.BlockSize
+		if pos+n > len(data) {
+			n = (len(data) - pos) / aes.BlockSize * aes.BlockSize
+		}
+		mode.CryptBlocks(data[pos:pos+n], data[pos:pos+n])
+		pos += n + skip*aes.BlockSize
+	}
+}
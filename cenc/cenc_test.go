@@ -0,0 +1,314 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package cenc
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// ------------------------------------------------------------------------------------------------
+// helpers to build boxes
+
+func mp4Box(typ string, payload ...[]byte) []byte {
+	body := bytes.Join(payload, nil)
+	b := make([]byte, 8, 8+len(body))
+	binary.BigEndian.PutUint32(b, uint32(8+len(body)))
+	copy(b[4:], typ)
+	return append(b, body...)
+}
+
+func u32(v int) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, uint32(v))
+	return b
+}
+
+func u16(v int) []byte {
+	b := make([]byte, 2)
+	binary.BigEndian.PutUint16(b, uint16(v))
+	return b
+}
+
+var testKID = []byte{0x10, 0x00, 0x00, 0x00, 0x10, 0x00, 0x10, 0x00, 0x10, 0x00, 0x10, 0x00, 0x00, 0x00, 0x00, 0x01}
+var testKey = []byte("0123456789abcdef")
+
+// testInit : an init segment with one encrypted video track, the trex box gives the default sample size
+func testInit(scheme string, ivSize int, constantIV []byte, defaultSampleSize int) []byte {
+	tencPayload := []byte{1, 0, 0, 0, 0, 0, 1, byte(ivSize)}
+	if scheme == SchemeCBCS {
+		// 1:9 pattern
+		tencPayload[5] = 0x19
+	}
+	tencPayload = append(tencPayload, testKID...)
+	if ivSize == 0 {
+		tencPayload = append(tencPayload, byte(len(constantIV)))
+		tencPayload = append(tencPayload, constantIV...)
+	}
+	sinf := mp4Box("sinf",
+		mp4Box("frma", []byte("avc1")),
+		mp4Box("schm", u32(0), []byte(scheme), u32(0x10000)),
+		mp4Box("schi", mp4Box("tenc", tencPayload)))
+	encv := mp4Box("encv", make([]byte, 78), sinf)
+	stsd := mp4Box("stsd", u32(0), u32(1), encv)
+	// version 0 tkhd with track ID 1
+	tkhd := mp4Box("tkhd", u32(0), u32(0), u32(0), u32(1), make([]byte, 68))
+	trak := mp4Box("trak", tkhd, mp4Box("mdia", mp4Box("minf", mp4Box("stbl", stsd))))
+	trex := mp4Box("trex", u32(0), u32(1), u32(1), u32(0), u32(defaultSampleSize), u32(0))
+	return mp4Box("moov", trak, mp4Box("mvex", trex))
+}
+
+// testSegment :
+// * a media segment with the given samples, the senc box describes the encryption
+// * the sample sizes are left out of the trun box if they all have the trex default size
+func testSegment(samples [][]byte, ivs [][]byte, subsamples [][]subsample, defaultSampleSize int) []byte {
+
+	var sencEntries [][]byte
+	for i := range samples {
+		entry := append([]byte{}, ivs[i]...)
+		if subsamples != nil {
+			entry = append(entry, u16(len(subsamples[i]))...)
+			for _, s := range subsamples[i] {
+				entry = append(entry, u16(s.clear)...)
+				entry = append(entry, u32(s.protected)...)
+			}
+		}
+		sencEntries = append(sencEntries, entry)
+	}
+	sencFlags := 0
+	if subsamples != nil {
+		sencFlags = 2
+	}
+	senc := mp4Box("senc", u32(sencFlags), u32(len(samples)), bytes.Join(sencEntries, nil))
+
+	// tfhd with default-base-is-moof
+	tfhd := mp4Box("tfhd", u32(0x020000), u32(1))
+
+	buildMoof := func(dataOffset int) []byte {
+		trunFlags := 0x000001
+		for _, s := range samples {
+			if len(s) != defaultSampleSize {
+				trunFlags |= 0x000200
+			}
+		}
+		trunPayload := [][]byte{u32(trunFlags), u32(len(samples)), u32(dataOffset)}
+		for _, s := range samples {
+			if trunFlags&0x000200 != 0 {
+				trunPayload = append(trunPayload, u32(len(s)))
+			}
+		}
+		return mp4Box("moof", mp4Box("traf", tfhd, mp4Box("trun", trunPayload...), senc))
+	}
+	// the data offset points past the moof and the mdat header
+	moof := buildMoof(0)
+	moof = buildMoof(len(moof) + 8)
+
+	return append(moof, mp4Box("mdat", samples...)...)
+}
+
+// ------------------------------------------------------------------------------------------------
+// cenc
+
+func TestDecryptSegmentCENC(t *testing.T) {
+
+	te, err := ParseInit(testInit(SchemeCENC, 8, nil, 0))
+	if err != nil || te == nil {
+		t.Fatal("unable to parse the init segment: ", err)
+	}
+	if te.Scheme != SchemeCENC || te.PerSampleIVSize != 8 || !bytes.Equal(te.KID, testKID) || te.OriginalFormat != "avc1" {
+		t.Errorf("wrong track encryption values %+v", te)
+	}
+
+	clear := [][]byte{bytes.Repeat([]byte{0xaa}, 50), bytes.Repeat([]byte{0xbb}, 70)}
+	ivs := [][]byte{{1, 2, 3, 4, 5, 6, 7, 8}, {8, 7, 6, 5, 4, 3, 2, 1}}
+	subs := [][]subsample{{{clear: 10, protected: 40}}, {{clear: 5, protected: 20}, {clear: 5, protected: 40}}}
+
+	// encrypt the samples the same way a packager would
+	block, _ := aes.NewCipher(testKey)
+	var encrypted [][]byte
+	for i, sample := range clear {
+		enc := append([]byte{}, sample...)
+		iv := make([]byte, 16)
+		copy(iv, ivs[i])
+		stream := cipher.NewCTR(block, iv)
+		pos := 0
+		for _, s := range subs[i] {
+			pos += s.clear
+			stream.XORKeyStream(enc[pos:pos+s.protected], enc[pos:pos+s.protected])
+			pos += s.protected
+		}
+		encrypted = append(encrypted, enc)
+	}
+
+	seg := testSegment(encrypted, ivs, subs, 0)
+	out, err := DecryptSegment(seg, te, testKey)
+	if err != nil {
+		t.Fatal("unable to decrypt the segment: ", err)
+	}
+	if !bytes.HasSuffix(out, bytes.Join(clear, nil)) {
+		t.Error("decrypted samples do not match the clear samples")
+	}
+	if bytes.Contains(out, []byte("senc")) {
+		t.Error("senc box should have been removed")
+	}
+}
+
+func TestDecryptSegmentDefaultSampleSize(t *testing.T) {
+
+	// the samples all have the size from the trex box, so the trun box has no sizes
+	te, err := ParseInit(testInit(SchemeCENC, 8, nil, 32))
+	if err != nil || te == nil {
+		t.Fatal("unable to parse the init segment: ", err)
+	}
+
+	clear := [][]byte{bytes.Repeat([]byte{0xcc}, 32), bytes.Repeat([]byte{0xdd}, 32)}
+	ivs := [][]byte{{1, 1, 1, 1, 1, 1, 1, 1}, {2, 2, 2, 2, 2, 2, 2, 2}}
+
+	block, _ := aes.NewCipher(testKey)
+	var encrypted [][]byte
+	for i, sample := range clear {
+		enc := make([]byte, len(sample))
+		iv := make([]byte, 16)
+		copy(iv, ivs[i])
+		cipher.NewCTR(block, iv).XORKeyStream(enc, sample)
+		encrypted = append(encrypted, enc)
+	}
+
+	seg := testSegment(encrypted, ivs, nil, 32)
+	out, err := DecryptSegment(seg, te, testKey)
+	if err != nil {
+		t.Fatal("unable to decrypt the segment: ", err)
+	}
+	if !bytes.HasSuffix(out, bytes.Join(clear, nil)) {
+		t.Error("decrypted samples do not match the clear samples")
+	}
+
+	// without the trex defaults there is no sample size
+	te.Init = nil
+	if _, err := DecryptSegment(seg, te, testKey); err == nil {
+		t.Error("a segment without sample sizes should not decrypt")
+	}
+}
+
+// ------------------------------------------------------------------------------------------------
+// cbcs
+
+func TestDecryptSegmentCBCS(t *testing.T) {
+
+	constantIV := []byte("fedcba9876543210")
+	te, err := ParseInit(testInit(SchemeCBCS, 0, constantIV, 0))
+	if err != nil || te == nil {
+		t.Fatal("unable to parse the init segment: ", err)
+	}
+	if te.CryptByteBlock != 1 || te.SkipByteBlock != 9 || !bytes.Equal(te.ConstantIV, constantIV) {
+		t.Errorf("wrong track encryption values %+v", te)
+	}
+
+	// 12 protected blocks and a partial block in a 1:9 pattern - only blocks 0 and 10 are encrypted
+	sample := make([]byte, 4+16*12+5)
+	for i := range sample {
+		sample[i] = byte(i)
+	}
+	subs := [][]subsample{{{clear: 4, protected: len(sample) - 4}}}
+
+	block, _ := aes.NewCipher(testKey)
+	enc := append([]byte{}, sample...)
+	// the second crypt block continues the CBC chain of the first
+	mode := cipher.NewCBCEncrypter(block, constantIV)
+	mode.CryptBlocks(enc[4:20], sample[4:20])
+	mode.CryptBlocks(enc[4+160:4+176], sample[4+160:4+176])
+
+	seg := testSegment([][]byte{enc}, [][]byte{nil}, subs, 0)
+	out, err := DecryptSegment(seg, te, testKey)
+	if err != nil {
+		t.Fatal("unable to decrypt the segment: ", err)
+	}
+	if !bytes.HasSuffix(out, sample) {
+		t.Error("decrypted sample does not match the clear sample")
+	}
+
+	// the init should now look like a clear avc1 track
+	clearInit := ClearInit(testInit(SchemeCBCS, 0, constantIV, 0), te)
+	if !bytes.Contains(clearInit, []byte("avc1")) || bytes.Contains(clearInit, []byte("encv")) || bytes.Contains(clearInit, []byte("sinf")) {
+		t.Error("init segment was not cleared")
+	}
+	if te, _ := ParseInit(clearInit); te != nil {
+		t.Error("cleared init segment should not be encrypted")
+	}
+}
+
+// ------------------------------------------------------------------------------------------------
+// ClearKey license server
+
+func TestRequestClearKeyLicense(t *testing.T) {
+
+	// a tiny local license server
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request clearKeyRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil || r.Method != "POST" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		response := map[string]interface{}{"type": "temporary"}
+		var keys []map[string]string
+		for _, kid := range request.Kids {
+			keys = append(keys, map[string]string{"kty": "oct", "kid": kid, "k": base64.RawURLEncoding.EncodeToString(testKey)})
+		}
+		response["keys"] = keys
+		json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+
+	kid, err := ParseKID("10000000-1000-1000-1000-100000000001")
+	if err != nil || !bytes.Equal(kid, testKID) {
+		t.Fatal("unable to parse the KID: ", err)
+	}
+
+	keys, err := RequestClearKeyLicense(server.Client(), server.URL, [][]byte{kid})
+	if err != nil {
+		t.Fatal("license request failed: ", err)
+	}
+	if !bytes.Equal(keys["10000000100010001000100000000001"], testKey) {
+		t.Errorf("wrong key returned %v", keys)
+	}
+}
+
+// ------------------------------------------------------------------------------------------------
+// pssh
+
+func TestParsePSSH(t *testing.T) {
+
+	systemID := []byte{0x10, 0x77, 0xef, 0xec, 0xc0, 0xb2, 0x4d, 0x02, 0xac, 0xe3, 0x3c, 0x1e, 0x52, 0xe2, 0xfb, 0x4b}
+	pssh := mp4Box("pssh", []byte{1, 0, 0, 0}, systemID, u32(1), testKID, u32(0))
+
+	kids, err := ParsePSSH(base64.StdEncoding.EncodeToString(pssh))
+	if err != nil || len(kids) != 1 || !bytes.Equal(kids[0], testKID) {
+		t.Errorf("wrong KIDs %v from pssh: %v", kids, err)
+	}
+}
@@ -0,0 +1,191 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package cenc
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/uccmisl/godash/isobmff"
+)
+
+// MP4ProtectionScheme : ContentProtection scheme carrying the cenc:default_KID
+const MP4ProtectionScheme = "urn:mpeg:dash:mp4protection:2011"
+
+// ClearKeySchemes : ContentProtection schemes used for ClearKey (DASH-IF and W3C common system ids)
+var ClearKeySchemes = []string{
+	"urn:uuid:e2719d58-a985-b3c9-781a-b030af78d30e",
+	"urn:uuid:1077efec-c0b2-4d02-ace3-3c1e52e2fb4b",
+}
+
+// clearKeySystemIDs : the ClearKey system ids as found in a pssh box
+var clearKeySystemIDs = []string{
+	"e2719d58a985b3c9781ab030af78d30e",
+	"1077efecc0b24d02ace33c1e52e2fb4b",
+}
+
+// IsClearKeyScheme : return true if this ContentProtection schemeIdUri is a ClearKey scheme
+func IsClearKeyScheme(schemeIDURI string) bool {
+	for _, scheme := range ClearKeySchemes {
+		if strings.EqualFold(schemeIDURI, scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseKID : convert a default_KID uuid string (with or without dashes) to 16 bytes
+func ParseKID(s string) ([]byte, error) {
+	kid, err := hex.DecodeString(strings.Replace(strings.TrimSpace(s), "-", "", -1))
+	if err != nil || len(kid) != 16 {
+		return nil, fmt.Errorf("invalid KID %q", s)
+	}
+	return kid, nil
+}
+
+// ParsePSSH :
+// * read a base64 pssh box, as found in the cenc:pssh element of the MPD
+// * return the KIDs if this is a version 1 ClearKey pssh box
+func ParsePSSH(s string) ([][]byte, error) {
+
+	b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("invalid pssh: %v", err)
+	}
+	boxes, err := isobmff.ReadBoxes(b, 0, len(b))
+	if err != nil || len(boxes) == 0 || boxes[0].Type != "pssh" {
+		return nil, errors.New("invalid pssh box")
+	}
+
+	p := b[boxes[0].Payload:boxes[0].End]
+	if len(p) < 20 {
+		return nil, errors.New("truncated pssh box")
+	}
+	version := p[0]
+	systemID := hex.EncodeToString(p[4:20])
+
+	clearKey := false
+	for _, id := range clearKeySystemIDs {
+		clearKey = clearKey || systemID == id
+	}
+	// only version 1 boxes list their KIDs
+	if !clearKey || version == 0 {
+		return nil, nil
+	}
+
+	var kids [][]byte
+	if len(p) < 24 {
+		return nil, errors.New("truncated pssh box")
+	}
+	count := int(binary.BigEndian.Uint32(p[20:]))
+	if len(p) < 24+16*count {
+		return nil, errors.New("truncated pssh box")
+	}
+	for i := 0; i < count; i++ {
+		kids = append(kids, append([]byte{}, p[24+16*i:40+16*i]...))
+	}
+	return kids, nil
+}
+
+// clearKeyRequest : the W3C EME ClearKey license request
+type clearKeyRequest struct {
+	Kids []string `json:"kids"`
+	Type string   `json:"type"`
+}
+
+// clearKeyResponse : the W3C EME ClearKey license response (a JSON Web Key set)
+type clearKeyResponse struct {
+	Keys []struct {
+		Kty string `json:"kty"`
+		K   string `json:"k"`
+		Kid string `json:"kid"`
+	} `json:"keys"`
+	Type string `json:"type"`
+}
+
+// RequestClearKeyLicense :
+// * request the keys for these KIDs from a ClearKey license server
+// * return a map of hex KID to key
+func RequestClearKeyLicense(client *http.Client, licenseURL string, kids [][]byte) (map[string][]byte, error) {
+
+	request := clearKeyRequest{Type: "temporary"}
+	for _, kid := range kids {
+		request.Kids = append(request.Kids, base64.RawURLEncoding.EncodeToString(kid))
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Post(licenseURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("license request to %s failed: %v", licenseURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("license server %s returned status code %d", licenseURL, resp.StatusCode)
+	}
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	var response clearKeyResponse
+	if err := json.Unmarshal(respBody, &response); err != nil {
+		return nil, fmt.Errorf("invalid license from %s: %v", licenseURL, err)
+	}
+
+	keys := make(map[string][]byte)
+	for _, k := range response.Keys {
+		kid, err := decodeBase64URL(k.Kid)
+		if err != nil {
+			return nil, fmt.Errorf("invalid kid in license: %v", err)
+		}
+		key, err := decodeBase64URL(k.K)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key in license: %v", err)
+		}
+		keys[hex.EncodeToString(kid)] = key
+	}
+
+	for _, kid := range kids {
+		if _, ok := keys[hex.EncodeToString(kid)]; !ok {
+			return keys, fmt.Errorf("license from %s has no key for KID %x", licenseURL, kid)
+		}
+	}
+
+	return keys, nil
+}
+
+// decodeBase64URL : decode base64url, with or without padding
+func decodeBase64URL(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
@@ -0,0 +1,99 @@
+package crosslayer
+
+import (
+	"math"
+	"time"
+)
+
+// capacity estimation parameters
+const (
+	// CapacityTrainLength : the number of back-to-back packets in a train
+	CapacityTrainLength = 4
+	// capacityMinPacketSize : smaller packets are not sent back-to-back with the data, so they break a train (bytes)
+	capacityMinPacketSize = 1000
+	// capacityMaxSamples : the number of recent trains the estimate is taken from
+	capacityMaxSamples = 128
+	// capacityBinWidth : the width of the histogram bins, on a log scale (about 10%)
+	capacityBinWidth = 0.1
+)
+
+// packetArrival : the time a packet was received, relative to the start of the connection, and its length
+type packetArrival struct {
+	time   time.Duration
+	length int64
+}
+
+// capacityEstimator :
+// * estimates the capacity of the bottleneck link from the dispersion of packet trains
+// * packets that leave the bottleneck back-to-back arrive spaced by the time the bottleneck takes to send them,
+// * whatever rate we achieved overall, so each train gives a sample of the capacity
+// * trains spread by idle time or cross traffic give lower samples, and trains compressed by later queues give higher samples,
+// * so the estimate is the mode of the recent samples, not their mean
+type capacityEstimator struct {
+	train   []packetArrival
+	samples []float64 // bits / second
+	next    int
+}
+
+// observe : add a packet to the current train, and take a sample when the train is complete
+func (c *capacityEstimator) observe(arrival time.Duration, length int64) {
+
+	// a small packet, or a packet that did not arrive after the previous one, starts a new train
+	if length < capacityMinPacketSize {
+		c.train = c.train[:0]
+		return
+	}
+	if n := len(c.train); n > 0 && arrival <= c.train[n-1].time {
+		c.train = c.train[:0]
+	}
+	c.train = append(c.train, packetArrival{time: arrival, length: length})
+	if len(c.train) < CapacityTrainLength {
+		return
+	}
+
+	// the first packet only marks the start of the dispersion
+	var bytes int64
+	for _, p := range c.train[1:] {
+		bytes += p.length
+	}
+	dispersion := c.train[len(c.train)-1].time - c.train[0].time
+	c.addSample(float64(bytes*8) / dispersion.Seconds())
+
+	// the last packet of this train is the first of the next
+	c.train[0] = c.train[len(c.train)-1]
+	c.train = c.train[:1]
+}
+
+func (c *capacityEstimator) addSample(bps float64) {
+	if len(c.samples) < capacityMaxSamples {
+		c.samples = append(c.samples, bps)
+		return
+	}
+	c.samples[c.next] = bps
+	c.next = (c.next + 1) % capacityMaxSamples
+}
+
+// estimate : the mean of the samples in the fullest bin of a log scale histogram, 0 before the first train
+func (c *capacityEstimator) estimate() float64 {
+
+	bins := make(map[int][]float64)
+	best := 0
+	for _, sample := range c.samples {
+		bin := int(math.Floor(math.Log(sample) / capacityBinWidth))
+		bins[bin] = append(bins[bin], sample)
+		// on a tie, the higher bin wins - trains are spread more often than they are compressed
+		if len(bins[bin]) > len(bins[best]) || (len(bins[bin]) == len(bins[best]) && bin > best) {
+			best = bin
+		}
+	}
+
+	mode := bins[best]
+	if len(mode) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, sample := range mode {
+		sum += sample
+	}
+	return sum / float64(len(mode))
+}
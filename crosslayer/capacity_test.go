@@ -0,0 +1,46 @@
+package crosslayer
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestCapacityEstimator(t *testing.T) {
+
+	var c capacityEstimator
+	if c.estimate() != 0 {
+		t.Fatal("estimate before any packet")
+	}
+
+	// a 10 Mbit/s bottleneck, where we only achieve a third of it because of idle time between bursts
+	const packet = 1250
+	const capacity = 10e6
+	spacing := time.Duration(float64(packet*8) / capacity * float64(time.Second))
+
+	var now time.Duration
+	for burst := 0; burst < 50; burst++ {
+		for i := 0; i < 10; i++ {
+			now += spacing
+			c.observe(now, packet)
+		}
+		now += 20 * spacing
+		// an acknowledgement sized packet between bursts breaks the train
+		c.observe(now, 60)
+	}
+
+	if got := c.estimate(); math.Abs(got-capacity)/capacity > 0.05 {
+		t.Fatalf("capacity %.0f bits/s, want %.0f", got, capacity)
+	}
+}
+
+func TestCapacityEstimatorOutOfOrder(t *testing.T) {
+
+	var c capacityEstimator
+	c.observe(10*time.Millisecond, 1200)
+	c.observe(10*time.Millisecond, 1200)
+	c.observe(5*time.Millisecond, 1200)
+	if len(c.samples) != 0 || len(c.train) != 1 {
+		t.Fatalf("packets that do not arrive after the previous one must start a new train, got %d samples and a train of %d", len(c.samples), len(c.train))
+	}
+}
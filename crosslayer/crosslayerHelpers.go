@@ -4,63 +4,105 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/lucas-clemente/quic-go/qlog"
+	glob "github.com/uccmisl/godash/global"
+	"github.com/uccmisl/godash/logging"
 )
 
 type CrossLayerAccountant struct {
-	EventChannel   chan qlog.Event
+	// Intake of the transport events of every connection, first so the counters are aligned for atomics
+	accepted  uint64
+	dropped   uint64
+	processed uint64
+	events    *eventRing
+	wake      chan struct{}
+
 	throughputList []int // list of bytes
 	//relativeTimeLastEvent time.Duration
 	mu          sync.Mutex
 	trackEvents bool
 
 	// Variables used for tracking elapsed time
-	totalPassed_ms  int64
 	currentlyTiming bool // Indicates if we are currently downloading and tracking time
 	currStartTime   time.Time
 
 	// Variables for stall prediction
 	predictStall                              bool
+	stallConfig                               StallPredictorConfig
 	bufferLevel_atStartOfSegment_Milliseconds int
-	representationBitrate                     int // kbits / second
+	representationBitrate                     int // bits / second
 	segmentDuration_Milliseconds              int
-	predictionWindow                          int         // number of packets the predictor looks at
 	arrivalTimes                              []time.Time // List of the arrival times of each packet in throughputList
 	time_atStartOfSegment                     time.Time
 	m_cancel                                  context.CancelFunc // Is called when the HTTP request needs to be cancelled
-	m_aborted                                 *bool
+	m_aborted                                 *atomic.Bool
 	m_maxBuffer_ms                            int
-	m_lowestBit_kbps                          int
+	m_lowestBit_bps                           int
+	ewma                                      throughputEWMA
+	// the predictions of the current segment, and the score of the predictions of every segment
+	stallPredicted  bool
+	lastPrediction  time.Time
+	lastStall       bool
+	predictionScore StallPredictionScore
+
+	// The connections, by connection ID, and the connection of the latest request
+	conns   map[string]*connection
+	current string
+	// The requests that do not know their connection yet
+	pending []*Request
+	// The segment requests that have ended, for the cross-layer throughput averages
+	requests []*Request
 }
 
-func (a *CrossLayerAccountant) InitialisePredictor() {
-	fmt.Println("Stall prediction enabled")
-	a.predictionWindow = 20
+// NewCrossLayerAccountant : an accountant with an empty intake buffer, call Listen to start taking events
+func NewCrossLayerAccountant() *CrossLayerAccountant {
+	return &CrossLayerAccountant{
+		events: newEventRing(IntakeBufferSize),
+		wake:   make(chan struct{}, 1),
+		conns:  make(map[string]*connection),
+		stallConfig: StallPredictorConfig{
+			Model:           glob.StallModelWindow,
+			Window:          glob.StallWindowDefault,
+			BufferThreshold: glob.StallThresholdDefault,
+			Abort:           true,
+		},
+	}
+}
+
+func (a *CrossLayerAccountant) InitialisePredictor(debugFile string, debugLog bool) {
+	logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "Stall prediction enabled - "+a.stallConfig.Model+" model")
+	a.mu.Lock()
 	a.predictStall = false
+	a.mu.Unlock()
 }
 
-func (a *CrossLayerAccountant) SegmentStart_predictStall(segDuration_ms int, repLevel_kbps int, currBufferLevel int, cancel context.CancelFunc, aborted *bool, maxBuffer_ms int, lowestBit_kbps int) {
-	a.predictStall = true
+// SegmentStart_predictStall :
+// * start predicting stalls for the segment that is about to be downloaded
+// * aborted is set, and cancel called, when a stall is predicted and the predictor aborts downloads
+func (a *CrossLayerAccountant) SegmentStart_predictStall(segDuration_ms int, repLevel_bps int, currBufferLevel int, cancel context.CancelFunc, aborted *atomic.Bool, maxBuffer_ms int, lowestBit_bps int) {
+	a.StartTiming()
+
+	// The predictor runs on the goroutine of the intake, so everything it reads is set under the lock
+	a.mu.Lock()
 	a.m_cancel = cancel
 	a.m_aborted = aborted
 	a.m_maxBuffer_ms = maxBuffer_ms
-	a.m_lowestBit_kbps = lowestBit_kbps
-	a.StartTiming()
+	a.m_lowestBit_bps = lowestBit_bps
 	a.bufferLevel_atStartOfSegment_Milliseconds = currBufferLevel
 	a.time_atStartOfSegment = time.Now()
-	//fmt.Println("PREDICTORBUFFER: ", a.bufferLevel_atStartOfSegment_Milliseconds)
+	a.segmentDuration_Milliseconds = segDuration_ms
+	a.representationBitrate = repLevel_bps
 
 	// Empty the throughput and timing lists
-	a.mu.Lock()
-	//fmt.Println("NUMBEROFPACKETS: ", len(a.throughputList))
 	a.throughputList = nil
 	a.arrivalTimes = nil
+	a.ewma = throughputEWMA{last: a.time_atStartOfSegment}
+	a.stallPredicted = false
+	a.lastPrediction = time.Time{}
+	a.predictStall = true
 	a.mu.Unlock()
-
-	a.segmentDuration_Milliseconds = segDuration_ms
-	a.representationBitrate = repLevel_kbps
 }
 
 func (a *CrossLayerAccountant) SetTrackingEvents(trackEvents bool) {
@@ -68,60 +110,78 @@ func (a *CrossLayerAccountant) SetTrackingEvents(trackEvents bool) {
 }
 
 func (a *CrossLayerAccountant) Listen(trackEvents bool) {
-	a.totalPassed_ms = 0
-
 	a.SetTrackingEvents(trackEvents)
-	go a.channelListenerThread()
+	go a.listen()
 }
 
+// stallPredictor :
+// * predict if the rest of the segment will arrive before the buffer runs out, at the throughput of the model
+// * a stall is predicted when it will not, the buffer is below the threshold, and the whole segment at the lowest bitrate would arrive sooner
+// * when a stall is predicted the download is aborted, unless the predictor only scores its predictions
 func (a *CrossLayerAccountant) stallPredictor() {
+	a.mu.Lock()
+
 	// Only do predictions when we have received enough packets
-	if len(a.throughputList) > a.predictionWindow && a.segmentDuration_Milliseconds > 0 {
-		//fmt.Println("IN PREDICTION WINDOW")
-		a.mu.Lock()
+	if len(a.arrivalTimes) < a.stallConfig.Window || a.segmentDuration_Milliseconds <= 0 {
+		a.mu.Unlock()
+		return
+	}
 
-		// Calculate sum of all bits received
-		var sliceOfList []int = a.throughputList[len(a.throughputList)-a.predictionWindow:]
-		var sum int = 0
-		for _, el := range sliceOfList {
-			sum += el
-		}
-		sum_bits := sum * 8
-		predictionWindowStartTime := a.arrivalTimes[len(a.throughputList)-a.predictionWindow]
+	// Calculate sum of all bits received for this segment
+	var sum int64 = 0
+	for _, el := range a.throughputList {
+		sum += int64(el)
+	}
+	received_bits := sum * 8
+	throughput_bps := a.modelThroughput()
+
+	// Snapshot the segment being predicted, so the prediction does not race with the start of the next one
+	representationBitrate := a.representationBitrate
+	segmentDuration_ms := a.segmentDuration_Milliseconds
+	lowestBit_bps := a.m_lowestBit_bps
+	maxBuffer_ms := a.m_maxBuffer_ms
+	bufferLevel_ms := a.calculateCurrentBufferLevel()
+	cancel := a.m_cancel
+	aborted := a.m_aborted
 
-		// Calculate the average throughput of the prediction window
-		windowTotalTime_ms := time.Since(predictionWindowStartTime).Milliseconds()
+	a.mu.Unlock()
 
-		a.mu.Unlock()
+	// bits 	:=    bits / second   *   ms / 1000
+	segmentSize := int64(representationBitrate) * int64(segmentDuration_ms) / 1000
 
-		// bits 	:=    (kbps == bpms)   		 / ms
-		segmentSize := (a.representationBitrate) / a.segmentDuration_Milliseconds
-
-		// Only do predictions when we have received less bytes than we expect to receive
-		if sum_bits < segmentSize && windowTotalTime_ms > 0 {
-			bitsToDownload := segmentSize - sum_bits // Number of bytes that need to be downloaded
-			// bits / ms  := bits / ms
-			windowBitrate := sum_bits / int(windowTotalTime_ms)
-			// Time it will take in ms to download the remaining bits at this rate
-			requiredTime_ms := bitsToDownload / windowBitrate
-
-			// bits 	:=    (kbps == bpms)   		 / ms
-			segmentSizeLowestThrough := (a.m_lowestBit_kbps) / a.segmentDuration_Milliseconds
-			requiredTimeLowestThrough_ms := segmentSizeLowestThrough / windowBitrate
-
-			level := a.calculateCurrentBufferLevel()
-			if requiredTime_ms > level && float64(level) < 0.10*float64(a.m_maxBuffer_ms) && requiredTimeLowestThrough_ms < requiredTime_ms {
-				// Report stall prediction
-				fmt.Println("STALLPREDICTOR ", time.Now().UnixMilli())
-				*a.m_aborted = true
-				a.m_cancel()
-			} else {
-				fmt.Println("NO STALL", requiredTime_ms, a.calculateCurrentBufferLevel())
-			}
-		}
+	// Only do predictions when we have received less bits than we expect to receive
+	if received_bits >= segmentSize || throughput_bps <= 0 {
+		return
+	}
+
+	prediction := StallPrediction{
+		Model:         a.stallConfig.Model,
+		BufferLevel:   time.Duration(bufferLevel_ms) * time.Millisecond,
+		Threshold:     time.Duration(a.stallConfig.BufferThreshold*float64(maxBuffer_ms)) * time.Millisecond,
+		Throughput:    throughput_bps,
+		RemainingBits: segmentSize - received_bits,
+	}
+	// Time it will take to download the remaining bits at this rate
+	prediction.RequiredTime = time.Duration(float64(prediction.RemainingBits) / throughput_bps * float64(time.Second))
+	// Time it would take to download the whole segment at the lowest bitrate
+	segmentSizeLowest := int64(lowestBit_bps) * int64(segmentDuration_ms) / 1000
+	prediction.LowestTime = time.Duration(float64(segmentSizeLowest) / throughput_bps * float64(time.Second))
+
+	prediction.Stall = prediction.RequiredTime > prediction.BufferLevel && prediction.BufferLevel < prediction.Threshold && prediction.LowestTime < prediction.RequiredTime
+
+	a.recordPrediction(prediction)
+
+	if prediction.Stall && a.stallConfig.Abort {
+		// Abort the download, and stop predicting for this segment
+		a.mu.Lock()
+		a.predictStall = false
+		a.mu.Unlock()
+		aborted.Store(true)
+		cancel()
 	}
 }
 
+// calculateCurrentBufferLevel : the buffer level in ms, the caller holds a.mu
 func (a *CrossLayerAccountant) calculateCurrentBufferLevel() int {
 	passedTime := time.Since(a.time_atStartOfSegment).Milliseconds()
 	level := a.bufferLevel_atStartOfSegment_Milliseconds - int(passedTime)
@@ -134,84 +194,111 @@ func (a *CrossLayerAccountant) calculateCurrentBufferLevel() int {
 	return level
 }
 
-func (a *CrossLayerAccountant) channelListenerThread() {
-	for msg := range a.EventChannel {
-		// Only process events when this bool is set
-		if a.trackEvents {
-			//a.relativeTimeLastEvent = msg.RelativeTime
-			details := msg.GetEventDetails()
-			eventType := details.EventType()
-			if eventType == "EventPacketReceived" {
-				fmt.Println("CROSSLAYERBUFFERLEVEL", a.calculateCurrentBufferLevel(), time.Now().UnixMilli())
-				//fmt.Println(eventType)
-				packetReceivedPointer := details.(*qlog.EventPacketReceived)
-				fmt.Println("PACKETRECEIVED", int(packetReceivedPointer.Length))
-				//fmt.Println(packetReceivedPointer.Length)
-				a.mu.Lock()
-				a.throughputList = append(a.throughputList, int(packetReceivedPointer.Length))
-				a.mu.Unlock()
-
-				// If we are doing stall predictions, calculate prediction after this packet is received
-				if a.predictStall {
-					// Measure arrival time as well
-					a.mu.Lock()
-					a.arrivalTimes = append(a.arrivalTimes, time.Now())
-					a.mu.Unlock()
-
-					a.stallPredictor()
-				}
+// listen : process the events in the intake buffer, and wait for more when it is empty
+func (a *CrossLayerAccountant) listen() {
+	for {
+		for {
+			e, ok := a.events.pop()
+			if !ok {
+				break
 			}
+			a.process(e)
+			atomic.AddUint64(&a.processed, 1)
+		}
+		<-a.wake
+	}
+}
+
+// process :
+// * a packet received counts for the requests active on its connection
+// * packets received when no request is active (MPD fetches, HEAD requests) do not count towards the throughput
+// * the other events update the transport state of the connection, or forget it once it is closed
+func (a *CrossLayerAccountant) process(e intakeEvent) {
+	switch details := e.details.(type) {
+	case packetReceived:
+		// Only process events when this bool is set
+		if a.attribute(e.connID, details.length, e.time) && a.trackEvents {
+			a.received(int(details.length))
 		}
+	case connectionClosed:
+		a.CloseConnection(e.connID)
+	default:
+		a.updateTransportState(e.connID, details)
+	}
+}
+
+// ReportProgress :
+// * add the bytes of a segment read by the http client, as they arrive
+// * used when there are no QUIC packet events, so the throughput and the stall predictor also work over TCP
+func (a *CrossLayerAccountant) ReportProgress(r *Request, bytes int) {
+	a.mu.Lock()
+	r.add(int64(bytes))
+	a.mu.Unlock()
+
+	if a.trackEvents {
+		a.received(bytes)
+	}
+}
+
+// received : add the bytes of a packet (or a read) to the throughput list
+func (a *CrossLayerAccountant) received(length int) {
+	a.mu.Lock()
+	a.throughputList = append(a.throughputList, length)
+
+	// If we are doing stall predictions, calculate prediction after this packet is received
+	predictStall := a.predictStall
+	if predictStall {
+		// Measure arrival time as well
+		now := time.Now()
+		a.arrivalTimes = append(a.arrivalTimes, now)
+		a.ewma.observe(now, length*8)
+	}
+	a.mu.Unlock()
+
+	if predictStall {
+		a.stallPredictor()
 	}
 }
 
 /**
-* Returns average measured throughput in bits/second
+* Returns average measured throughput in bits/second, of the bytes received for every segment request over the time they took
  */
 func (a *CrossLayerAccountant) GetAverageThroughput() float64 {
-	// Calculate sum
-	var sum int = 0
-	for _, el := range a.throughputList {
-		sum += el
-	}
-	/*
-		fmt.Println("Sum XL: ", sum)
-			f, err := os.OpenFile("/tmp/trace.csv", os.O_CREATE|os.O_APPEND|os.O_RDWR, 0644)
-			if err != nil {
-				fmt.Println(err)
-			} else {
-				f.WriteString(strconv.FormatInt(int64(sum), 10) + "\n")
-			}*/
-	//     bits			  /  second
-	return float64(sum*8) / (float64(a.getTotalTime()) / 1000) // convert it to seconds
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return requestsThroughput(a.requests)
 }
 
+// RecentThroughputRequests : the number of segment requests of GetRecentAverageThroughput
+const RecentThroughputRequests = 3
+
 /**
-* Returns average measured throughput in bits/second of last 3000 packets
+* Returns average measured throughput in bits/second of the last RecentThroughputRequests segment requests
  */
 func (a *CrossLayerAccountant) GetRecentAverageThroughput() float64 {
-	// Calculate sum
-	var sum int = 0
-	if len(a.throughputList) > 3000 {
-		var sliceOfList []int = a.throughputList[len(a.throughputList)-3000 : len(a.throughputList)+1]
-		for _, el := range sliceOfList {
-			sum += el
-		}
-	} else {
-		for _, el := range a.throughputList {
-			sum += el
-		}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if len(a.requests) > RecentThroughputRequests {
+		return requestsThroughput(a.requests[len(a.requests)-RecentThroughputRequests:])
+	}
+	return requestsThroughput(a.requests)
+}
+
+// requestsThroughput : the bits received for the requests over the time they took, in bits/second
+func requestsThroughput(requests []*Request) float64 {
+	var bytes int64
+	var duration time.Duration
+	for _, r := range requests {
+		bytes += r.Bytes
+		duration += r.End.Sub(r.Start)
+	}
+	if duration <= 0 {
+		return 0
 	}
-	/*
-		fmt.Println("Sum XL: ", sum)
-			f, err := os.OpenFile("/tmp/trace.csv", os.O_CREATE|os.O_APPEND|os.O_RDWR, 0644)
-			if err != nil {
-				fmt.Println(err)
-			} else {
-				f.WriteString(strconv.FormatInt(int64(sum), 10) + "\n")
-			}*/
 	//     bits			  /  second
-	return float64(sum*8) / (float64(a.getTotalTime()) / 1000) // convert it to seconds
+	return float64(bytes*8) / duration.Seconds()
 }
 
 // Should be called when we start downloading a segment
@@ -222,12 +309,13 @@ func (a *CrossLayerAccountant) StartTiming() {
 
 // Should be called when we have received an entire segment
 func (a *CrossLayerAccountant) StopTiming() int {
+	a.mu.Lock()
 	a.predictStall = false
+	a.mu.Unlock()
 	if a.currentlyTiming {
 		currPassedTime := time.Since(a.currStartTime)
 		currPassedTime_ms := currPassedTime.Milliseconds()
 
-		a.totalPassed_ms += currPassedTime_ms
 		a.currentlyTiming = false
 		return int(currPassedTime_ms)
 	} else {
@@ -235,15 +323,3 @@ func (a *CrossLayerAccountant) StopTiming() int {
 		return 0
 	}
 }
-
-// Returns the total measured time in miliseconds, even when the current timer is still running
-func (a *CrossLayerAccountant) getTotalTime() int64 {
-	// If we are currently timing, calculate the current passed time and add it to the total before returning
-	if a.currentlyTiming {
-		currPassedTime := time.Since(a.currStartTime)
-		currPassedTime_ms := currPassedTime.Milliseconds()
-		return a.totalPassed_ms + currPassedTime_ms
-	} else {
-		return a.totalPassed_ms
-	}
-}
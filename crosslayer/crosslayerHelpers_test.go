@@ -0,0 +1,26 @@
+package crosslayer
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecentAverageThroughput(t *testing.T) {
+
+	// an old request of 1000 bytes in 1 s, then RecentThroughputRequests requests of 100 bytes in 100 ms
+	a := NewCrossLayerAccountant()
+	start := time.Now()
+	a.requests = append(a.requests, &Request{Bytes: 1000, Start: start, End: start.Add(time.Second)})
+	for i := 0; i < RecentThroughputRequests; i++ {
+		a.requests = append(a.requests, &Request{Bytes: 100, Start: start, End: start.Add(100 * time.Millisecond)})
+	}
+
+	// only the recent requests count, over the time they took
+	if thr := a.GetRecentAverageThroughput(); !nearly(thr, 8000) {
+		t.Errorf("recent throughput %v", thr)
+	}
+	// every request counts, weighted by its bytes
+	if thr := a.GetAverageThroughput(); !nearly(thr, float64(1000+RecentThroughputRequests*100)*8/(1+float64(RecentThroughputRequests)*0.1)) {
+		t.Errorf("average throughput %v", thr)
+	}
+}
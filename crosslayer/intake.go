@@ -0,0 +1,209 @@
+package crosslayer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+
+	"github.com/quic-go/quic-go/logging"
+	"github.com/quic-go/quic-go/qlog"
+)
+
+// IntakeBufferSize : the number of transport events waiting for the accountant, events are dropped when it is full (a power of 2)
+const IntakeBufferSize = 8192
+
+// intakeEvent : a transport event, the connection it came from and when, since the connection started
+type intakeEvent struct {
+	connID  string
+	time    time.Duration
+	details interface{}
+}
+
+// the transport events of a connection that the accountant uses
+type packetReceived struct {
+	length int64
+}
+
+type metricsUpdated struct {
+	congestionWindow int64 // bytes
+	bytesInFlight    int64
+	smoothedRTT      time.Duration
+	minRTT           time.Duration
+	latestRTT        time.Duration
+	rttVariance      time.Duration
+}
+
+type packetSent struct{}
+
+type packetLost struct{}
+
+type congestionStateUpdated struct {
+	state logging.CongestionState
+}
+
+type connectionClosed struct{}
+
+type eventSlot struct {
+	seq   uint32
+	entry intakeEvent
+}
+
+// eventRing :
+// * a bounded lock-free queue of events, for many producers (the connections) and one consumer (the accountant)
+// * the sequence numbers are 32 bits, so they are aligned for atomics on 32 bit platforms, and may wrap
+type eventRing struct {
+	head  uint32 // next slot to push
+	tail  uint32 // next slot to pop
+	mask  uint32
+	slots []eventSlot
+}
+
+// newEventRing : size must be a power of 2
+func newEventRing(size int) *eventRing {
+	r := &eventRing{mask: uint32(size - 1), slots: make([]eventSlot, size)}
+	for i := range r.slots {
+		r.slots[i].seq = uint32(i)
+	}
+	return r
+}
+
+// push : add an event, without waiting - return false if the queue is full
+func (r *eventRing) push(e intakeEvent) bool {
+	for {
+		pos := atomic.LoadUint32(&r.head)
+		slot := &r.slots[pos&r.mask]
+		seq := atomic.LoadUint32(&slot.seq)
+		diff := int32(seq - pos)
+		if diff == 0 {
+			if atomic.CompareAndSwapUint32(&r.head, pos, pos+1) {
+				slot.entry = e
+				atomic.StoreUint32(&slot.seq, pos+1)
+				return true
+			}
+		} else if diff < 0 {
+			// the consumer has not popped this slot yet
+			return false
+		}
+		// another producer took this slot, try the next one
+	}
+}
+
+// pop : take the oldest event - return false if the queue is empty
+func (r *eventRing) pop() (intakeEvent, bool) {
+	for {
+		pos := atomic.LoadUint32(&r.tail)
+		slot := &r.slots[pos&r.mask]
+		seq := atomic.LoadUint32(&slot.seq)
+		diff := int32(seq - (pos + 1))
+		if diff == 0 {
+			if atomic.CompareAndSwapUint32(&r.tail, pos, pos+1) {
+				e := slot.entry
+				slot.entry = intakeEvent{}
+				atomic.StoreUint32(&slot.seq, pos+r.mask+1)
+				return e, true
+			}
+		} else if diff < 0 {
+			return intakeEvent{}, false
+		}
+	}
+}
+
+// connectionIDKey : the context key of the *string that the tracer fills with the connection ID
+type connectionIDKey struct{}
+
+// WithConnectionID : a context for dialing a QUIC connection, the ID of the connection is written to connID when it is traced
+func WithConnectionID(ctx context.Context, connID *string) context.Context {
+	return context.WithValue(ctx, connectionIDKey{}, connID)
+}
+
+// Tracer :
+// * the quic-go tracer to use for every connection, it writes the qlog file of the connection with getLogWriter and sends its events to the accountant
+// * each connection gets its own tracer, so the accountant knows which connection each event came from
+func (a *CrossLayerAccountant) Tracer(getLogWriter func(p logging.Perspective, connectionID []byte) io.WriteCloser) func(context.Context, logging.Perspective, logging.ConnectionID) *logging.ConnectionTracer {
+	return func(ctx context.Context, p logging.Perspective, odcid logging.ConnectionID) *logging.ConnectionTracer {
+
+		connID := fmt.Sprintf("%x", odcid.Bytes())
+		if holder, ok := ctx.Value(connectionIDKey{}).(*string); ok {
+			*holder = connID
+		}
+
+		tracer := a.connectionTracer(connID)
+		if w := getLogWriter(p, odcid.Bytes()); w != nil {
+			// the qlog file is closed before the accountant forgets the connection
+			return logging.NewMultiplexedConnectionTracer(qlog.NewConnectionTracer(w, p, odcid), tracer)
+		}
+		return tracer
+	}
+}
+
+// connectionTracer :
+// * the tracer of one connection, which adds the events the accountant uses to the intake buffer
+// * the connection is forgotten once the events before its close have been processed
+func (a *CrossLayerAccountant) connectionTracer(connID string) *logging.ConnectionTracer {
+
+	start := time.Now()
+	push := func(details interface{}) bool {
+		return a.pushEvent(intakeEvent{connID: connID, time: time.Since(start), details: details})
+	}
+
+	return &logging.ConnectionTracer{
+		ReceivedLongHeaderPacket: func(_ *logging.ExtendedHeader, size logging.ByteCount, _ logging.ECN, _ []logging.Frame) {
+			push(packetReceived{length: int64(size)})
+		},
+		ReceivedShortHeaderPacket: func(_ *logging.ShortHeader, size logging.ByteCount, _ logging.ECN, _ []logging.Frame) {
+			push(packetReceived{length: int64(size)})
+		},
+		SentLongHeaderPacket: func(*logging.ExtendedHeader, logging.ByteCount, logging.ECN, *logging.AckFrame, []logging.Frame) {
+			push(packetSent{})
+		},
+		SentShortHeaderPacket: func(*logging.ShortHeader, logging.ByteCount, logging.ECN, *logging.AckFrame, []logging.Frame) {
+			push(packetSent{})
+		},
+		UpdatedMetrics: func(rttStats *logging.RTTStats, cwnd, bytesInFlight logging.ByteCount, _ int) {
+			push(metricsUpdated{
+				congestionWindow: int64(cwnd),
+				bytesInFlight:    int64(bytesInFlight),
+				smoothedRTT:      rttStats.SmoothedRTT(),
+				minRTT:           rttStats.MinRTT(),
+				latestRTT:        rttStats.LatestRTT(),
+				rttVariance:      rttStats.MeanDeviation(),
+			})
+		},
+		LostPacket: func(logging.EncryptionLevel, logging.PacketNumber, logging.PacketLossReason) {
+			push(packetLost{})
+		},
+		UpdatedCongestionState: func(state logging.CongestionState) {
+			push(congestionStateUpdated{state: state})
+		},
+		Close: func() {
+			// if the buffer is full, forget the connection now
+			if !push(connectionClosed{}) {
+				a.CloseConnection(connID)
+			}
+		},
+	}
+}
+
+// pushEvent : add an event to the intake buffer and wake the listener, or count it as dropped if the buffer is full
+func (a *CrossLayerAccountant) pushEvent(e intakeEvent) bool {
+	if !a.events.push(e) {
+		atomic.AddUint64(&a.dropped, 1)
+		return false
+	}
+	atomic.AddUint64(&a.accepted, 1)
+	// wake the listener, if it is not already awake
+	select {
+	case a.wake <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+/**
+* Returns the number of transport events taken by the accountant, and the number dropped because the intake buffer was full
+ */
+func (a *CrossLayerAccountant) IntakeStats() (accepted uint64, dropped uint64) {
+	return atomic.LoadUint64(&a.accepted), atomic.LoadUint64(&a.dropped)
+}
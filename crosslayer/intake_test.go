@@ -0,0 +1,132 @@
+package crosslayer
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/logging"
+)
+
+func TestEventRing(t *testing.T) {
+
+	r := newEventRing(4)
+	if _, ok := r.pop(); ok {
+		t.Fatal("pop from an empty ring")
+	}
+	for i := 0; i < 4; i++ {
+		if !r.push(intakeEvent{connID: strconv.Itoa(i)}) {
+			t.Fatalf("push %d to a ring with room failed", i)
+		}
+	}
+	if r.push(intakeEvent{connID: "full"}) {
+		t.Fatal("push to a full ring")
+	}
+	// the slots are reused in order, past the wrap of the sequence numbers
+	for i := 0; i < 4; i++ {
+		e, ok := r.pop()
+		if !ok || e.connID != strconv.Itoa(i) {
+			t.Fatalf("pop %d: got %q, %v", i, e.connID, ok)
+		}
+		r.push(intakeEvent{connID: strconv.Itoa(i + 4)})
+	}
+	for i := 4; i < 8; i++ {
+		if e, _ := r.pop(); e.connID != strconv.Itoa(i) {
+			t.Fatalf("pop %d: got %q", i, e.connID)
+		}
+	}
+}
+
+func TestEventRingProducers(t *testing.T) {
+
+	const producers = 8
+	const events = 10000
+
+	r := newEventRing(64)
+	counts := make(map[string]int)
+	var dropped int
+	var mu sync.Mutex
+
+	var wg sync.WaitGroup
+	for p := 0; p < producers; p++ {
+		wg.Add(1)
+		go func(connID string) {
+			defer wg.Done()
+			for i := 0; i < events; i++ {
+				if !r.push(intakeEvent{connID: connID}) {
+					mu.Lock()
+					dropped++
+					mu.Unlock()
+				}
+			}
+		}(strconv.Itoa(p))
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	for finished := false; ; {
+		select {
+		case <-done:
+			finished = true
+		default:
+		}
+		e, ok := r.pop()
+		if ok {
+			counts[e.connID]++
+		} else if finished {
+			break
+		}
+	}
+
+	total := dropped
+	for _, n := range counts {
+		total += n
+	}
+	if total != producers*events {
+		t.Fatalf("%d events taken and %d dropped, want %d in total", total-dropped, dropped, producers*events)
+	}
+}
+
+func TestConnectionTracer(t *testing.T) {
+
+	a := NewCrossLayerAccountant()
+	var connID string
+	ctx := WithConnectionID(context.Background(), &connID)
+	tracer := a.Tracer(func(logging.Perspective, []byte) io.WriteCloser { return nil })(ctx, logging.PerspectiveClient, quic.ConnectionIDFromBytes([]byte{0xab, 0x01}))
+	if connID != "ab01" {
+		t.Fatalf("connection ID %q", connID)
+	}
+
+	tracer.SentShortHeaderPacket(nil, 100, logging.ECNUnsupported, nil, nil)
+	tracer.ReceivedShortHeaderPacket(nil, 1200, logging.ECNUnsupported, nil)
+	tracer.UpdatedMetrics(&logging.RTTStats{}, 14720, 100, 1)
+	tracer.UpdatedCongestionState(logging.CongestionStateSlowStart)
+	for e, ok := a.events.pop(); ok; e, ok = a.events.pop() {
+		a.process(e)
+	}
+	transport := a.conns[connID].transport
+	if transport.packetsSent != 1 || transport.bytesReceived != 1200 || transport.congestionWindow != 14720 || transport.congestionState != "slow_start" {
+		t.Errorf("transport state %+v", transport)
+	}
+
+	// the connection is forgotten once the events before its close are processed
+	tracer.Close()
+	if _, ok := a.conns[connID]; !ok {
+		t.Error("the connection was forgotten before its last events")
+	}
+	for e, ok := a.events.pop(); ok; e, ok = a.events.pop() {
+		a.process(e)
+	}
+	if _, ok := a.conns[connID]; ok {
+		t.Error("the closed connection was not forgotten")
+	}
+	if accepted, dropped := a.IntakeStats(); accepted != 5 || dropped != 0 {
+		t.Errorf("%d events taken and %d dropped, want 5 taken", accepted, dropped)
+	}
+}
@@ -0,0 +1,159 @@
+package crosslayer
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// requestDrainTimeout : how long EndRequest waits for the events already in the intake buffer to be processed
+const requestDrainTimeout = 100 * time.Millisecond
+
+// Request :
+// * a segment request, and the bytes received for it on its connection
+// * the fields are only read once the request has ended
+type Request struct {
+	URL          string
+	ConnectionID string
+	Bytes        int64
+	Start        time.Time
+	End          time.Time
+	// another request was active on the same connection, so some of the bytes may be for that request
+	Shared bool
+	// the bytes received on each connection before the request knew its connection
+	pendingBytes map[string]int64
+}
+
+// add : count bytes for this request, a.mu must be held
+func (r *Request) add(bytes int64) {
+	r.Bytes += bytes
+}
+
+/**
+* Returns the throughput of the request in bits/second, from when it started until it ended
+ */
+func (r *Request) Throughput() float64 {
+	seconds := r.End.Sub(r.Start).Seconds()
+	if seconds <= 0 {
+		return 0
+	}
+	return float64(r.Bytes*8) / seconds
+}
+
+// BeginRequest :
+// * start counting the packets received for a request, before it is sent
+// * the request does not know its connection until the client has dialled or reused one
+// * until SetConnection, the packets of every connection are counted for it, by connection
+func (a *CrossLayerAccountant) BeginRequest(url string) *Request {
+
+	r := &Request{URL: url, Start: time.Now(), pendingBytes: make(map[string]int64)}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.pending = append(a.pending, r)
+	return r
+}
+
+// SetConnection :
+// * the connection of a request is known - keep the bytes received on it so far, and count its packets from now on
+// * the connection becomes the connection of the transport state getters
+func (a *CrossLayerAccountant) SetConnection(r *Request, connID string) {
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if !a.removePending(r) {
+		return
+	}
+	r.ConnectionID = connID
+	r.Bytes = r.pendingBytes[connID]
+	r.pendingBytes = nil
+
+	conn := a.connection(connID)
+	conn.active = append(conn.active, r)
+	// another request waiting for its connection may have received some of these bytes too
+	shared := len(conn.active) > 1
+	for _, p := range a.pending {
+		if p.pendingBytes[connID] > 0 {
+			shared = true
+		}
+	}
+	if shared {
+		for _, active := range conn.active {
+			active.Shared = true
+		}
+	}
+	a.current = connID
+}
+
+// EndRequest :
+// * stop counting packets for a request, once the events that arrived before its last byte have been processed
+// * a request that had a connection counts towards the cross-layer throughput averages
+func (a *CrossLayerAccountant) EndRequest(r *Request) {
+
+	r.End = time.Now()
+	a.drain(time.Now().Add(requestDrainTimeout))
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	// the request failed before it had a connection
+	if a.removePending(r) {
+		r.pendingBytes = nil
+		return
+	}
+
+	a.requests = append(a.requests, r)
+
+	conn, ok := a.conns[r.ConnectionID]
+	if !ok {
+		return
+	}
+	for i, active := range conn.active {
+		if active == r {
+			conn.active = append(conn.active[:i], conn.active[i+1:]...)
+			break
+		}
+	}
+}
+
+// removePending : remove a request from the requests waiting for their connection, a.mu must be held - return false if it was not waiting
+func (a *CrossLayerAccountant) removePending(r *Request) bool {
+	for i, p := range a.pending {
+		if p == r {
+			a.pending = append(a.pending[:i], a.pending[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// drain : wait until the events accepted so far have been processed, or the deadline
+func (a *CrossLayerAccountant) drain(deadline time.Time) {
+	accepted := atomic.LoadUint64(&a.accepted)
+	for atomic.LoadUint64(&a.processed) < accepted && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// attribute :
+// * count the bytes of a packet received on a connection for the requests active on it
+// * and for the requests that do not know their connection yet
+// * every packet of the connection goes to its capacity estimate, with the time it was received
+// * return false if no request is active on the connection, or waiting for its connection
+func (a *CrossLayerAccountant) attribute(connID string, length int64, arrival time.Duration) bool {
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	conn := a.connection(connID)
+	conn.transport.bytesReceived += length
+	conn.capacity.observe(arrival, length)
+	for _, r := range conn.active {
+		r.add(length)
+	}
+	for _, r := range a.pending {
+		r.pendingBytes[connID] += length
+	}
+	return len(conn.active) > 0 || len(a.pending) > 0
+}
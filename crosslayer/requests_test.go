@@ -0,0 +1,33 @@
+package crosslayer
+
+import "testing"
+
+func TestRequestBeforeConnection(t *testing.T) {
+
+	a := NewCrossLayerAccountant()
+	r := a.BeginRequest("segment.m4s")
+
+	// the response starts to arrive before the request knows its connection
+	if !a.attribute("conn1", 1000, 0) {
+		t.Error("a packet received while a request waits for its connection should count")
+	}
+	a.attribute("conn2", 500, 0)
+
+	a.SetConnection(r, "conn1")
+	a.attribute("conn1", 2000, 0)
+	a.attribute("conn2", 700, 0)
+	a.EndRequest(r)
+
+	if r.Bytes != 3000 {
+		t.Errorf("request counted %d bytes, want 3000 from its own connection", r.Bytes)
+	}
+	if r.Shared {
+		t.Error("the request was alone on its connection")
+	}
+	if a.attribute("conn1", 100, 0) {
+		t.Error("a packet received after the request ended should not count")
+	}
+	if len(a.requests) != 1 || a.requests[0] != r {
+		t.Error("the ended request should count towards the cross-layer throughput")
+	}
+}
@@ -0,0 +1,180 @@
+package crosslayer
+
+import (
+	"math"
+	"time"
+
+	glob "github.com/uccmisl/godash/global"
+)
+
+// StallPredictionInterval : how often a prediction is reported when its outcome does not change
+const StallPredictionInterval = 100 * time.Millisecond
+
+// stallEWMATimeConstant : the time over which the ewma model forgets the throughput
+const stallEWMATimeConstant = 500 * time.Millisecond
+
+// StallPredictorConfig : the settings of the stall predictor
+type StallPredictorConfig struct {
+	// predict stalls during the downloads of the segments
+	Enabled bool
+	// glob.StallModelWindow, glob.StallModelSegment or glob.StallModelEWMA
+	Model string
+	// the number of packets of a segment before the first prediction, and the window of the window model
+	Window int
+	// stalls are only predicted when the buffer is below this fraction of the maximum buffer
+	BufferThreshold float64
+	// abort the download when a stall is predicted, otherwise the predictions are only logged and scored
+	Abort bool
+	// called with the predictions to log, if set
+	Report func(StallPrediction)
+}
+
+// StallPrediction : the inputs and the outcome of one stall prediction, the same fields as the qlog stall_prediction event
+type StallPrediction struct {
+	Model string
+	// the buffer level now, and the level below which stalls are predicted
+	BufferLevel time.Duration
+	Threshold   time.Duration
+	// the throughput of the model in bits/second, and the bits of the segment still to download
+	Throughput    float64
+	RemainingBits int64
+	// the time to download the rest of the segment at the throughput, and the whole segment at the lowest bitrate
+	RequiredTime time.Duration
+	LowestTime   time.Duration
+	Stall        bool
+}
+
+// SetStallPredictor : set the model and thresholds of the stall predictor
+func (a *CrossLayerAccountant) SetStallPredictor(config StallPredictorConfig) {
+	a.stallConfig = config
+}
+
+/**
+* Returns if the stall predictor is used, with any algorithm
+ */
+func (a *CrossLayerAccountant) StallPredictorEnabled() bool {
+	return a.stallConfig.Enabled
+}
+
+// modelThroughput : the throughput of the segment in bits/second, according to the model - a.mu must be held
+func (a *CrossLayerAccountant) modelThroughput() float64 {
+
+	switch a.stallConfig.Model {
+	case glob.StallModelSegment:
+		// all bits received since the segment started
+		var sum int
+		for _, el := range a.throughputList {
+			sum += el
+		}
+		return float64(sum*8) / time.Since(a.time_atStartOfSegment).Seconds()
+	case glob.StallModelEWMA:
+		return a.ewma.rate()
+	default:
+		// the bits of the last Window packets, over the time since the first of them arrived
+		var sliceOfList []int = a.throughputList[len(a.throughputList)-a.stallConfig.Window:]
+		var sum int
+		for _, el := range sliceOfList {
+			sum += el
+		}
+		windowStartTime := a.arrivalTimes[len(a.arrivalTimes)-a.stallConfig.Window]
+		return float64(sum*8) / time.Since(windowStartTime).Seconds()
+	}
+}
+
+// throughputEWMA : a throughput that forgets the bits and the time it has seen, with the time constant stallEWMATimeConstant
+type throughputEWMA struct {
+	bits    float64
+	seconds float64
+	last    time.Time
+}
+
+func (e *throughputEWMA) observe(now time.Time, bits int) {
+	gap := now.Sub(e.last).Seconds()
+	decay := math.Exp(-gap / stallEWMATimeConstant.Seconds())
+	e.bits = e.bits*decay + float64(bits)
+	e.seconds = e.seconds*decay + gap
+	e.last = now
+}
+
+func (e *throughputEWMA) rate() float64 {
+	if e.seconds <= 0 {
+		return 0
+	}
+	return e.bits / e.seconds
+}
+
+// recordPrediction :
+// * remember if a stall was predicted during this segment
+// * report the prediction to be logged when its outcome changes, and at most every StallPredictionInterval otherwise
+func (a *CrossLayerAccountant) recordPrediction(prediction StallPrediction) {
+
+	a.mu.Lock()
+	a.stallPredicted = a.stallPredicted || prediction.Stall
+	write := a.lastPrediction.IsZero() || prediction.Stall != a.lastStall || time.Since(a.lastPrediction) >= StallPredictionInterval
+	if write {
+		a.lastPrediction = time.Now()
+		a.lastStall = prediction.Stall
+	}
+	a.mu.Unlock()
+
+	if write && a.stallConfig.Report != nil {
+		a.stallConfig.Report(prediction)
+	}
+}
+
+// StallPredictionScore : the predictions of each segment, matched with whether the segment stalled
+type StallPredictionScore struct {
+	TruePositives  int
+	FalsePositives int
+	FalseNegatives int
+	TrueNegatives  int
+}
+
+/**
+* Returns the fraction of the segments with a predicted stall that stalled, 0 if no stall was predicted
+ */
+func (s StallPredictionScore) Precision() float64 {
+	if s.TruePositives+s.FalsePositives == 0 {
+		return 0
+	}
+	return float64(s.TruePositives) / float64(s.TruePositives+s.FalsePositives)
+}
+
+/**
+* Returns the fraction of the segments that stalled for which a stall was predicted, 0 if no segment stalled
+ */
+func (s StallPredictionScore) Recall() float64 {
+	if s.TruePositives+s.FalseNegatives == 0 {
+		return 0
+	}
+	return float64(s.TruePositives) / float64(s.TruePositives+s.FalseNegatives)
+}
+
+// ScoreStallPrediction :
+// * match the predictions of the segment that has just been played with whether it stalled
+// * with Abort set, a predicted stall is avoided when the lower bitrate arrives in time, so precision is only a lower bound
+func (a *CrossLayerAccountant) ScoreStallPrediction(stalled bool) {
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	switch {
+	case a.stallPredicted && stalled:
+		a.predictionScore.TruePositives++
+	case a.stallPredicted:
+		a.predictionScore.FalsePositives++
+	case stalled:
+		a.predictionScore.FalseNegatives++
+	default:
+		a.predictionScore.TrueNegatives++
+	}
+}
+
+/**
+* Returns the score of the stall predictions of every segment so far
+ */
+func (a *CrossLayerAccountant) StallPredictionScore() StallPredictionScore {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.predictionScore
+}
@@ -0,0 +1,131 @@
+package crosslayer
+
+import (
+	"math"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	glob "github.com/uccmisl/godash/global"
+)
+
+func TestStallPredictionScore(t *testing.T) {
+
+	a := NewCrossLayerAccountant()
+	if s := a.StallPredictionScore(); s.Precision() != 0 || s.Recall() != 0 {
+		t.Fatal("precision and recall before any segment")
+	}
+
+	// predicted and stalled, predicted twice without a stall, a stall that was not predicted, and a quiet segment
+	for _, segment := range []struct{ predicted, stalled bool }{
+		{true, true}, {true, false}, {true, false}, {false, true}, {false, false},
+	} {
+		a.stallPredicted = segment.predicted
+		a.ScoreStallPrediction(segment.stalled)
+	}
+
+	s := a.StallPredictionScore()
+	if s != (StallPredictionScore{TruePositives: 1, FalsePositives: 2, FalseNegatives: 1, TrueNegatives: 1}) {
+		t.Fatalf("score %+v", s)
+	}
+	if s.Precision() != 1.0/3 || s.Recall() != 0.5 {
+		t.Fatalf("precision %v, recall %v", s.Precision(), s.Recall())
+	}
+}
+
+// predictSyntheticSegment :
+// * a segment of 450 kbit at 1 s, started 1 s ago with 2 s of buffer, so 1 s is left
+// * 5 packets of 40 kbit then 5 of 10 kbit, one every 100 ms - 250 kbit received, the throughput is falling
+// * with aborted set the predictor aborts the download when it predicts a stall
+// * returns the prediction made after the last packet
+func predictSyntheticSegment(t *testing.T, model string, aborted *atomic.Bool) StallPrediction {
+
+	var predictions []StallPrediction
+	a := NewCrossLayerAccountant()
+	a.SetStallPredictor(StallPredictorConfig{
+		Enabled:         true,
+		Model:           model,
+		Window:          4,
+		BufferThreshold: 0.5,
+		Abort:           aborted != nil,
+		Report:          func(p StallPrediction) { predictions = append(predictions, p) },
+	})
+	a.m_aborted = aborted
+	a.m_cancel = func() {}
+	a.predictStall = true
+
+	now := time.Now()
+	a.time_atStartOfSegment = now.Add(-time.Second)
+	a.bufferLevel_atStartOfSegment_Milliseconds = 2000
+	a.m_maxBuffer_ms = 10000
+	a.segmentDuration_Milliseconds = 1000
+	a.representationBitrate = 450000
+	a.m_lowestBit_bps = 100000
+	a.ewma = throughputEWMA{last: a.time_atStartOfSegment}
+	for i := 1; i <= 10; i++ {
+		length := 5000
+		if i > 5 {
+			length = 1250
+		}
+		arrival := a.time_atStartOfSegment.Add(time.Duration(i) * 100 * time.Millisecond)
+		a.throughputList = append(a.throughputList, length)
+		a.arrivalTimes = append(a.arrivalTimes, arrival)
+		a.ewma.observe(arrival, length*8)
+	}
+	a.stallPredictor()
+
+	if len(predictions) != 1 {
+		t.Fatalf("%d predictions", len(predictions))
+	}
+	p := predictions[0]
+	if p.Model != model || p.RemainingBits != 200000 {
+		t.Errorf("prediction %+v", p)
+	}
+	return p
+}
+
+// nearly : within 1% of the expected throughput, the test runs after the last packet
+func nearly(throughput, expected float64) bool {
+	return math.Abs(throughput-expected) < expected/100
+}
+
+func TestStallModelWindow(t *testing.T) {
+	// the last 4 packets, 40 kbit since the first of them 300 ms ago
+	p := predictSyntheticSegment(t, glob.StallModelWindow, nil)
+	if !nearly(p.Throughput, 40000/0.3) || !p.Stall {
+		t.Errorf("prediction %+v", p)
+	}
+}
+
+func TestStallPredictorAbort(t *testing.T) {
+	var aborted atomic.Bool
+	if p := predictSyntheticSegment(t, glob.StallModelWindow, &aborted); !p.Stall || !aborted.Load() {
+		t.Errorf("prediction %+v, aborted %v", p, aborted.Load())
+	}
+}
+
+func TestStallModelSegment(t *testing.T) {
+	// 250 kbit in 1 s, the last 200 kbit arrive in 800 ms, before the buffer runs out
+	p := predictSyntheticSegment(t, glob.StallModelSegment, nil)
+	if !nearly(p.Throughput, 250000) || p.Stall {
+		t.Errorf("prediction %+v", p)
+	}
+}
+
+func TestStallModelEWMA(t *testing.T) {
+	// each packet and gap weighs exp(-age/500ms), the recent small packets weigh more than the large ones
+	var bits, seconds float64
+	for i := 1; i <= 10; i++ {
+		weight := math.Exp(-float64(10-i) * 0.1 / 0.5)
+		packet := 40000.0
+		if i > 5 {
+			packet = 10000
+		}
+		bits += packet * weight
+		seconds += 0.1 * weight
+	}
+	p := predictSyntheticSegment(t, glob.StallModelEWMA, nil)
+	if !nearly(p.Throughput, bits/seconds) || p.Throughput <= 40000/0.3 || p.Throughput >= 250000 || !p.Stall {
+		t.Errorf("prediction %+v, expected a throughput of %v", p, bits/seconds)
+	}
+}
@@ -0,0 +1,238 @@
+package crosslayer
+
+import (
+	"time"
+
+	"github.com/quic-go/quic-go/logging"
+)
+
+// transportState :
+// * the state of a transport connection
+// * over QUIC, kept up to date from the events of the quic-go connection tracer
+// * over TCP, kept up to date from the TCP_INFO samples of the http client
+type transportState struct {
+	// from metrics_updated
+	congestionWindow int64 // bytes
+	bytesInFlight    int64
+	smoothedRTT      time.Duration
+	minRTT           time.Duration
+	latestRTT        time.Duration
+	rttVariance      time.Duration
+	// from congestion_state_updated
+	congestionState string
+	// from packet_sent and packet_lost
+	packetsSent int
+	packetsLost int
+	// from packet_received, for every request on the connection
+	bytesReceived int64
+	// only reported by TCP
+	deliveryRate int64 // bytes / second
+}
+
+// TransportSample :
+// * one reading of the state of a TCP connection, from TCP_INFO
+// * the counters are totals for the connection
+type TransportSample struct {
+	RTT              time.Duration
+	RTTVariance      time.Duration
+	MinRTT           time.Duration
+	CongestionWindow int64 // bytes
+	SlowStart        bool
+	Recovery         bool
+	DeliveryRate     int64 // bytes / second
+	SegmentsSent     int
+	Retransmits      int
+	BytesReceived    int64
+}
+
+// connection : a QUIC or TCP connection, its transport state, the capacity of its path and the requests active on it
+type connection struct {
+	transport transportState
+	capacity  capacityEstimator
+	active    []*Request
+}
+
+// connection : the connection with this ID, a.mu must be held
+func (a *CrossLayerAccountant) connection(connID string) *connection {
+	conn, ok := a.conns[connID]
+	if !ok {
+		conn = &connection{}
+		a.conns[connID] = conn
+	}
+	return conn
+}
+
+// CloseConnection : forget a connection that has been closed
+func (a *CrossLayerAccountant) CloseConnection(connID string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.conns, connID)
+}
+
+// currentTransport : the transport state of the connection of the latest request
+func (a *CrossLayerAccountant) currentTransport() transportState {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if conn, ok := a.conns[a.current]; ok {
+		return conn.transport
+	}
+	return transportState{}
+}
+
+// updateTransportState : update the transport state of a connection from a metrics_updated, packet_sent, packet_lost or congestion_state_updated event, other events are ignored
+func (a *CrossLayerAccountant) updateTransportState(connID string, details interface{}) {
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	transport := &a.connection(connID).transport
+	switch event := details.(type) {
+	case metricsUpdated:
+		transport.congestionWindow = event.congestionWindow
+		transport.bytesInFlight = event.bytesInFlight
+		transport.smoothedRTT = event.smoothedRTT
+		transport.minRTT = event.minRTT
+		transport.latestRTT = event.latestRTT
+		transport.rttVariance = event.rttVariance
+	case packetSent:
+		transport.packetsSent++
+	case packetLost:
+		transport.packetsLost++
+	case congestionStateUpdated:
+		transport.congestionState = congestionStateString(event.state)
+	}
+}
+
+// ReportTransportSample :
+// * replace the transport state with a TCP_INFO sample
+// * TCP has no packet events, so the retransmitted segments count as the lost packets
+func (a *CrossLayerAccountant) ReportTransportSample(connID string, sample TransportSample) {
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	transport := &a.connection(connID).transport
+
+	transport.congestionWindow = sample.CongestionWindow
+	transport.smoothedRTT = sample.RTT
+	transport.latestRTT = sample.RTT
+	transport.minRTT = sample.MinRTT
+	transport.rttVariance = sample.RTTVariance
+	transport.packetsSent = sample.SegmentsSent
+	transport.packetsLost = sample.Retransmits
+	transport.bytesReceived = sample.BytesReceived
+	transport.deliveryRate = sample.DeliveryRate
+
+	switch {
+	case sample.Recovery:
+		transport.congestionState = "recovery"
+	case sample.SlowStart:
+		transport.congestionState = "slow_start"
+	default:
+		transport.congestionState = "congestion_avoidance"
+	}
+}
+
+// congestionStateString : the qlog name of a congestion state
+func congestionStateString(state logging.CongestionState) string {
+	switch state {
+	case logging.CongestionStateSlowStart:
+		return "slow_start"
+	case logging.CongestionStateCongestionAvoidance:
+		return "congestion_avoidance"
+	case logging.CongestionStateRecovery:
+		return "recovery"
+	case logging.CongestionStateApplicationLimited:
+		return "application_limited"
+	default:
+		return "unknown"
+	}
+}
+
+/**
+* Returns the congestion window of the connection of the latest request in bytes
+ */
+func (a *CrossLayerAccountant) CongestionWindow() int64 {
+	return a.currentTransport().congestionWindow
+}
+
+/**
+* Returns the bytes sent on the connection of the latest request that have not been acknowledged or lost, 0 over TCP
+ */
+func (a *CrossLayerAccountant) BytesInFlight() int64 {
+	return a.currentTransport().bytesInFlight
+}
+
+/**
+* Returns the smoothed RTT of the connection of the latest request
+ */
+func (a *CrossLayerAccountant) SRTT() time.Duration {
+	return a.currentTransport().smoothedRTT
+}
+
+/**
+* Returns the minimum RTT of the connection of the latest request
+ */
+func (a *CrossLayerAccountant) MinRTT() time.Duration {
+	return a.currentTransport().minRTT
+}
+
+/**
+* Returns the latest RTT sample of the connection of the latest request
+ */
+func (a *CrossLayerAccountant) LatestRTT() time.Duration {
+	return a.currentTransport().latestRTT
+}
+
+/**
+* Returns the RTT variance of the connection of the latest request
+ */
+func (a *CrossLayerAccountant) RTTVariance() time.Duration {
+	return a.currentTransport().rttVariance
+}
+
+/**
+* Returns the fraction of packets sent that were declared lost, 0 before any packet is sent
+ */
+func (a *CrossLayerAccountant) LossRate() float64 {
+	transport := a.currentTransport()
+	if transport.packetsSent == 0 {
+		return 0
+	}
+	return float64(transport.packetsLost) / float64(transport.packetsSent)
+}
+
+/**
+* Returns the state of the congestion controller - "slow_start", "congestion_avoidance", "recovery" or "application_limited"
+* empty before the first congestion_state_updated event
+ */
+func (a *CrossLayerAccountant) CongestionState() string {
+	return a.currentTransport().congestionState
+}
+
+/**
+* Returns the bytes received on the connection of the latest request
+ */
+func (a *CrossLayerAccountant) BytesReceived() int64 {
+	return a.currentTransport().bytesReceived
+}
+
+/**
+* Returns the delivery rate of the TCP connection of the latest request in bits/second, 0 over QUIC
+ */
+func (a *CrossLayerAccountant) DeliveryRate() float64 {
+	return float64(a.currentTransport().deliveryRate * 8)
+}
+
+/**
+* Returns the estimated capacity of the bottleneck link of the connection of the latest request in bits/second
+* unlike the throughput, this does not drop when we are idle or limited by flow control - 0 over TCP, or before enough packets have arrived
+ */
+func (a *CrossLayerAccountant) BottleneckCapacity() float64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if conn, ok := a.conns[a.current]; ok {
+		return conn.capacity.estimate()
+	}
+	return 0
+}
@@ -0,0 +1,90 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+// Package estimators estimates the throughput of the next segment from the segments downloaded so far.
+// Every estimator takes the same samples, so any adaptation algorithm that selects on a throughput estimate can use any of them.
+package estimators
+
+import (
+	"time"
+
+	glob "github.com/uccmisl/godash/global"
+)
+
+// Sample : the download of a segment
+type Sample struct {
+	Bytes    int
+	Duration time.Duration
+}
+
+// Throughput : the throughput of the download in bits/second, 0 without a duration
+func (s Sample) Throughput() float64 {
+	if s.Duration <= 0 {
+		return 0
+	}
+	return float64(s.Bytes*8) / s.Duration.Seconds()
+}
+
+// Estimator : a throughput estimator
+type Estimator interface {
+	// Observe : add the download of a segment, samples without bytes or a duration are ignored
+	Observe(sample Sample)
+	// Estimate : the throughput expected for the next segment in bits/second, 0 before the first sample
+	Estimate() float64
+}
+
+// New :
+// * the estimator with this name - one of the glob.Estimator constants
+// * window is the number of samples of the window and harmonic estimators, season the number of segments in a season of holtwinters
+// * returns nil for glob.EstimatorOff, the algorithms then use their own estimate
+func New(name string, window int, season int) Estimator {
+	switch name {
+	case glob.EstimatorSlidingWindow:
+		return NewSlidingWindow(window)
+	case glob.EstimatorDualEWMA:
+		return NewDualEWMA()
+	case glob.EstimatorHarmonic:
+		return NewHarmonic(window)
+	case glob.EstimatorKalman:
+		return NewKalman()
+	case glob.EstimatorHoltWinters:
+		return NewHoltWinters(season)
+	}
+	return nil
+}
+
+// usable : a sample with a throughput
+func usable(sample Sample) bool {
+	return sample.Bytes > 0 && sample.Duration > 0
+}
+
+// window : the throughput of the last size samples
+type window struct {
+	size    int
+	samples []float64
+}
+
+func (w *window) add(throughput float64) {
+	w.samples = append(w.samples, throughput)
+	if len(w.samples) > w.size {
+		w.samples = w.samples[len(w.samples)-w.size:]
+	}
+}
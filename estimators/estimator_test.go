@@ -0,0 +1,151 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package estimators
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	glob "github.com/uccmisl/godash/global"
+)
+
+// sample : a one second download at this throughput in bits/second
+func sample(throughput float64) Sample {
+	return Sample{Bytes: int(throughput / 8), Duration: time.Second}
+}
+
+func near(got, want, tolerance float64) bool {
+	return math.Abs(got-want) <= tolerance*want
+}
+
+func TestNew(t *testing.T) {
+	if New(glob.EstimatorOff, 5, 0) != nil {
+		t.Error("an estimator for off")
+	}
+	for _, name := range []string{glob.EstimatorSlidingWindow, glob.EstimatorDualEWMA, glob.EstimatorHarmonic, glob.EstimatorKalman, glob.EstimatorHoltWinters} {
+		e := New(name, 5, 0)
+		if e == nil {
+			t.Fatalf("no estimator for %s", name)
+		}
+		if e.Estimate() != 0 {
+			t.Errorf("%s estimates %v before any sample", name, e.Estimate())
+		}
+		// samples without a throughput are ignored
+		e.Observe(Sample{Bytes: 100000})
+		e.Observe(Sample{Duration: time.Second})
+		if e.Estimate() != 0 {
+			t.Errorf("%s estimates %v from samples without a throughput", name, e.Estimate())
+		}
+		// a constant throughput is estimated as it is
+		for i := 0; i < 20; i++ {
+			e.Observe(sample(4e6))
+		}
+		if !near(e.Estimate(), 4e6, 0.01) {
+			t.Errorf("%s estimates %v from a constant 4 Mbit/s", name, e.Estimate())
+		}
+	}
+}
+
+func TestSlidingWindowAndHarmonic(t *testing.T) {
+	s := NewSlidingWindow(3)
+	h := NewHarmonic(3)
+	// the first sample falls out of the window
+	for _, throughput := range []float64{100e6, 1e6, 2e6, 4e6} {
+		s.Observe(sample(throughput))
+		h.Observe(sample(throughput))
+	}
+	if !near(s.Estimate(), 7e6/3, 1e-9) {
+		t.Errorf("sliding window %v", s.Estimate())
+	}
+	if !near(h.Estimate(), 3/(1/1e6+1/2e6+1/4e6), 1e-9) {
+		t.Errorf("harmonic %v", h.Estimate())
+	}
+}
+
+func TestDualEWMA(t *testing.T) {
+	d := NewDualEWMA()
+	for i := 0; i < 30; i++ {
+		d.Observe(sample(10e6))
+	}
+	// small downloads are ignored
+	d.Observe(Sample{Bytes: DualEWMAMinBytes - 1, Duration: time.Millisecond})
+	if !near(d.Estimate(), 10e6, 0.001) {
+		t.Fatalf("estimate %v after a constant 10 Mbit/s", d.Estimate())
+	}
+
+	// after a drop the estimate follows the fast average down, after a peak the slow average holds it back
+	d.Observe(sample(2e6))
+	fast := 2e6 + (10e6-2e6)*math.Pow(0.5, 1/DualEWMAFastHalfLife.Seconds())
+	if !near(d.Estimate(), fast, 0.01) {
+		t.Errorf("estimate %v after a drop, want the fast average %v", d.Estimate(), fast)
+	}
+	d = NewDualEWMA()
+	for i := 0; i < 30; i++ {
+		d.Observe(sample(10e6))
+	}
+	d.Observe(sample(50e6))
+	slow := 50e6 + (10e6-50e6)*math.Pow(0.5, 1/DualEWMASlowHalfLife.Seconds())
+	if !near(d.Estimate(), slow, 0.01) {
+		t.Errorf("estimate %v after a peak, want the slow average %v", d.Estimate(), slow)
+	}
+}
+
+func TestKalman(t *testing.T) {
+	k := NewKalman()
+	k.Observe(sample(1e6))
+	if !near(k.Estimate(), 1e6, 1e-9) {
+		t.Fatalf("first estimate %v", k.Estimate())
+	}
+	// a step is followed, but not in one segment
+	k.Observe(sample(8e6))
+	if k.Estimate() <= 1e6 || k.Estimate() >= 8e6 {
+		t.Errorf("estimate %v after a step from 1 to 8 Mbit/s", k.Estimate())
+	}
+	for i := 0; i < 50; i++ {
+		k.Observe(sample(8e6))
+	}
+	if !near(k.Estimate(), 8e6, 0.01) {
+		t.Errorf("estimate %v after a constant 8 Mbit/s", k.Estimate())
+	}
+}
+
+func TestHoltWinters(t *testing.T) {
+	// a steady rise is forecast to go on
+	h := NewHoltWinters(0)
+	for i := 1; i <= 20; i++ {
+		h.Observe(sample(float64(i) * 1e6))
+	}
+	if !near(h.Estimate(), 21e6, 0.02) {
+		t.Errorf("forecast %v after a rise of 1 Mbit/s a segment, want 21 Mbit/s", h.Estimate())
+	}
+
+	// a throughput that repeats every 4 segments is forecast from the season
+	pattern := []float64{8e6, 2e6, 2e6, 4e6}
+	h = NewHoltWinters(len(pattern))
+	for i := 0; i < 40; i++ {
+		h.Observe(sample(pattern[i%len(pattern)]))
+	}
+	if !near(h.Estimate(), pattern[0], 0.05) {
+		t.Errorf("forecast %v for a season of %v, want %v", h.Estimate(), pattern, pattern[0])
+	}
+}
@@ -0,0 +1,143 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package estimators
+
+import (
+	"math"
+	"time"
+
+	glob "github.com/uccmisl/godash/global"
+)
+
+// Registered : every estimator, in the order they are evaluated
+var Registered = []string{glob.EstimatorSlidingWindow, glob.EstimatorDualEWMA, glob.EstimatorHarmonic, glob.EstimatorKalman, glob.EstimatorHoltWinters}
+
+// EvaluationHorizons : how many samples ahead the estimators predict in the offline evaluation
+var EvaluationHorizons = []int{1, 2, 5, 10}
+
+// PacketInterval : the packets of a transport qlog are evaluated as the throughput of each interval they arrive in
+const PacketInterval = 100 * time.Millisecond
+
+// Evaluation : how well an estimator predicted the throughput of a series, horizon samples ahead
+type Evaluation struct {
+	Series      string `json:"series"`
+	Estimator   string `json:"estimator"`
+	Horizon     int    `json:"horizon"`
+	Predictions int    `json:"predictions"`
+	// the mean absolute and root mean square error in bits/second, and the mean absolute percentage error
+	MAE  float64 `json:"mae_bps"`
+	RMSE float64 `json:"rmse_bps"`
+	MAPE float64 `json:"mape_percent"`
+	// the fraction of the predictions above, and below, the throughput
+	Over  float64 `json:"over_ratio"`
+	Under float64 `json:"under_ratio"`
+}
+
+// Evaluate :
+// * replay the samples of a series through a new estimator of each name, with the window and season
+// * after each sample, the estimate is the prediction of the throughput of the sample horizon samples later
+// * an estimator that has no estimate yet makes no prediction
+func Evaluate(series string, samples []Sample, names []string, horizons []int, window int, season int) []Evaluation {
+
+	var throughputs []float64
+	var usableSamples []Sample
+	for _, sample := range samples {
+		if usable(sample) {
+			usableSamples = append(usableSamples, sample)
+			throughputs = append(throughputs, sample.Throughput())
+		}
+	}
+
+	var evaluations []Evaluation
+	for _, name := range names {
+		estimator := New(name, window, season)
+		if estimator == nil {
+			continue
+		}
+		estimates := make([]float64, len(usableSamples))
+		for i, sample := range usableSamples {
+			estimator.Observe(sample)
+			estimates[i] = estimator.Estimate()
+		}
+		for _, horizon := range horizons {
+			evaluations = append(evaluations, score(series, name, horizon, estimates, throughputs))
+		}
+	}
+	return evaluations
+}
+
+// score : compare the estimate after each sample with the throughput horizon samples later
+func score(series string, name string, horizon int, estimates []float64, throughputs []float64) Evaluation {
+
+	e := Evaluation{Series: series, Estimator: name, Horizon: horizon}
+	var absolute, squared, percentage float64
+	var over, under int
+	for i := 0; i+horizon < len(throughputs); i++ {
+		if estimates[i] <= 0 {
+			continue
+		}
+		actual := throughputs[i+horizon]
+		err := estimates[i] - actual
+		absolute += math.Abs(err)
+		squared += err * err
+		percentage += math.Abs(err) / actual
+		if err > 0 {
+			over++
+		} else if err < 0 {
+			under++
+		}
+		e.Predictions++
+	}
+	if e.Predictions == 0 {
+		return e
+	}
+	n := float64(e.Predictions)
+	e.MAE = absolute / n
+	e.RMSE = math.Sqrt(squared / n)
+	e.MAPE = 100 * percentage / n
+	e.Over = float64(over) / n
+	e.Under = float64(under) / n
+	return e
+}
+
+// Packet : a packet received, at its time from the start of the connection
+type Packet struct {
+	Time  time.Duration
+	Bytes int
+}
+
+// PacketSamples :
+// * the bytes of the packets received in each interval, as a sample of the interval
+// * an interval without packets is idle time between requests, not a throughput of 0, so it gives no sample
+func PacketSamples(packets []Packet, interval time.Duration) []Sample {
+
+	var samples []Sample
+	for i := 0; i < len(packets); {
+		bin := packets[i].Time / interval
+		sample := Sample{Duration: interval}
+		for ; i < len(packets) && packets[i].Time/interval == bin; i++ {
+			sample.Bytes += packets[i].Bytes
+		}
+		samples = append(samples, sample)
+	}
+	return samples
+}
@@ -0,0 +1,121 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package estimators
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	glob "github.com/uccmisl/godash/global"
+)
+
+func TestEvaluate(t *testing.T) {
+
+	// a constant throughput is predicted without error by every estimator
+	var samples []Sample
+	for i := 0; i < 20; i++ {
+		samples = append(samples, sample(4e6))
+	}
+	evaluations := Evaluate("constant", samples, Registered, EvaluationHorizons, 5, 0)
+	if len(evaluations) != len(Registered)*len(EvaluationHorizons) {
+		t.Fatalf("%d evaluations, want %d", len(evaluations), len(Registered)*len(EvaluationHorizons))
+	}
+	for _, e := range evaluations {
+		if e.Predictions == 0 || e.MAE > 1 || e.MAPE > 0.01 {
+			t.Errorf("%s at horizon %d: %d predictions, MAE %v, MAPE %v", e.Estimator, e.Horizon, e.Predictions, e.MAE, e.MAPE)
+		}
+	}
+
+	// a window of one always predicts the last sample, which is double or half the next one
+	samples = nil
+	for i := 0; i < 10; i++ {
+		samples = append(samples, sample(2e6), sample(4e6))
+	}
+	e := Evaluate("alternating", samples, []string{glob.EstimatorSlidingWindow}, []int{1}, 1, 0)[0]
+	if e.Predictions != 19 || !near(e.MAE, 2e6, 1e-9) || !near(e.RMSE, 2e6, 1e-9) || !near(e.MAPE, 1400.0/19, 1e-9) {
+		t.Errorf("%d predictions, MAE %v, RMSE %v, MAPE %v", e.Predictions, e.MAE, e.RMSE, e.MAPE)
+	}
+	if !near(e.Over, 9.0/19, 1e-9) || !near(e.Under, 10.0/19, 1e-9) {
+		t.Errorf("over %v, under %v", e.Over, e.Under)
+	}
+}
+
+func TestPacketSamples(t *testing.T) {
+
+	packets := []Packet{{10 * time.Millisecond, 1000}, {90 * time.Millisecond, 500}, {350 * time.Millisecond, 1200}}
+	samples := PacketSamples(packets, PacketInterval)
+	if len(samples) != 2 || samples[0].Bytes != 1500 || samples[1].Bytes != 1200 || samples[0].Duration != PacketInterval {
+		t.Errorf("samples %v", samples)
+	}
+}
+
+func TestReadRun(t *testing.T) {
+
+	folder := t.TempDir()
+	log := filepath.Join(folder, glob.LogDownload)
+	os.WriteFile(log, []byte(
+		"     Seg_#     Arr_time     Del_Time    Stall_Dur    Rep_Level     Del_Rate     Act_Rate    Byte_Size   Buff_Level      Algorithm   Seg_Dur   Codec\n"+
+			"         1         1000         1000            0          254         2000          254       250000         2000   conventional         2     H264\n"+
+			"         1         1000          500            0           64          800           64        50000         2000   conventional         2     AAC\n"+
+			"         2         2000         2000            0          254         1000          254       250000         3000   conventional         2     H264\n"), 0644)
+	codecs, series, err := ReadSegmentLog(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codecs) != 2 || codecs[0] != "H264" || len(series["H264"]) != 2 || len(series["AAC"]) != 1 {
+		t.Fatalf("codecs %v, series %v", codecs, series)
+	}
+	if got := series["H264"][1].Throughput(); got != 1e6 {
+		t.Errorf("throughput %v, want 1e6", got)
+	}
+
+	// a transport qlog of a client that was stopped, so the events are not closed
+	qlog := filepath.Join(folder, "client_01.qlog")
+	os.WriteFile(qlog, []byte(`{"qlog_version":"draft-02","traces":[{"common_fields":{},"events":[
+{"time":1.5,"name":"transport:packet_received","data":{"header":{"packet_type":"1RTT"},"raw":{"length":1252}}},
+{"time":2,"name":"transport:packet_sent","data":{"raw":{"length":40}}},
+{"time":2.5,"name":"transport:packet_received","data":{"header":{"packet_size":1200}}},
+{"time":3,"name":"transport:packet_rec`), 0644)
+	packets, err := ReadTransportQlog(qlog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(packets) != 2 || packets[0].Bytes != 1252 || packets[1].Bytes != 1200 || packets[0].Time != 1500*time.Microsecond {
+		t.Fatalf("packets %v", packets)
+	}
+
+	evaluations, err := EvaluateRun([]string{log, qlog}, 5, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	csvPath, jsonPath, err := WriteEvaluations(folder, evaluations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{csvPath, jsonPath} {
+		if info, err := os.Stat(path); err != nil || info.Size() == 0 {
+			t.Errorf("%s not written", path)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package estimators
+
+import (
+	"math"
+	"time"
+)
+
+// dual EWMA parameters, the defaults of the Shaka player
+const (
+	// DualEWMAFastHalfLife : the half-life of the fast average, which follows drops
+	DualEWMAFastHalfLife = 2 * time.Second
+	// DualEWMASlowHalfLife : the half-life of the slow average, which smooths out peaks
+	DualEWMASlowHalfLife = 5 * time.Second
+	// DualEWMAMinBytes : smaller downloads are dominated by the round trip time, not the throughput
+	DualEWMAMinBytes = 16000
+)
+
+// DualEWMA :
+// * a fast and a slow exponentially weighted moving average of the throughput, weighted by the download time of each segment
+// * the estimate is the lower of the two, so it drops as fast as the fast average and rises as slowly as the slow one
+type DualEWMA struct {
+	fast ewma
+	slow ewma
+}
+
+// NewDualEWMA : a dual EWMA with the half-lives of the Shaka player
+func NewDualEWMA() *DualEWMA {
+	return &DualEWMA{fast: newEWMA(DualEWMAFastHalfLife), slow: newEWMA(DualEWMASlowHalfLife)}
+}
+
+// Observe : add the throughput of the segment to both averages, weighted by its download time in seconds
+func (d *DualEWMA) Observe(sample Sample) {
+	if !usable(sample) || sample.Bytes < DualEWMAMinBytes {
+		return
+	}
+	d.fast.sample(sample.Duration.Seconds(), sample.Throughput())
+	d.slow.sample(sample.Duration.Seconds(), sample.Throughput())
+}
+
+// Estimate : the lower of the fast and the slow average
+func (d *DualEWMA) Estimate() float64 {
+	return math.Min(d.fast.value(), d.slow.value())
+}
+
+// ewma : an exponentially weighted moving average that halves the weight of a sample every half-life
+type ewma struct {
+	alpha       float64
+	estimate    float64
+	totalWeight float64
+}
+
+func newEWMA(halfLife time.Duration) ewma {
+	return ewma{alpha: math.Exp(math.Log(0.5) / halfLife.Seconds())}
+}
+
+func (e *ewma) sample(weight float64, value float64) {
+	adjAlpha := math.Pow(e.alpha, weight)
+	e.estimate = value*(1-adjAlpha) + adjAlpha*e.estimate
+	e.totalWeight += weight
+}
+
+// value : the average, corrected for the zero it starts from
+func (e *ewma) value() float64 {
+	zeroFactor := 1 - math.Pow(e.alpha, e.totalWeight)
+	if zeroFactor == 0 {
+		return 0
+	}
+	return e.estimate / zeroFactor
+}
@@ -0,0 +1,181 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package estimators
+
+import (
+	"math"
+
+	"gonum.org/v1/gonum/optimize"
+)
+
+// Holt-Winters parameters
+const (
+	// HoltWintersHistory : the number of segments the smoothing parameters are fitted to
+	HoltWintersHistory = 50
+	// holtWintersEvaluations : the number of forecasts of the history the fit may run for each segment
+	holtWintersEvaluations = 200
+)
+
+// HoltWinters :
+// * additive Holt-Winters smoothing of the throughput - a level, a trend and, with a season, the throughput of each segment of the season
+// * after each segment the smoothing parameters are fitted to the history with Nelder-Mead, minimising the error of the forecasts of each segment
+// * without a season, or until the history holds two seasons, it is Holt's linear method - a level and a trend
+type HoltWinters struct {
+	season  int
+	history []float64
+	// the fitted smoothing parameters, before the logistic function maps them to (0, 1)
+	params   []float64
+	forecast float64
+}
+
+// NewHoltWinters : Holt-Winters smoothing with a season of season segments, 0 for no season
+func NewHoltWinters(season int) *HoltWinters {
+	if season < 0 {
+		season = 0
+	}
+	return &HoltWinters{season: season}
+}
+
+// Observe : add the throughput of the segment to the history, refit the smoothing parameters and forecast the next segment
+func (h *HoltWinters) Observe(sample Sample) {
+	if !usable(sample) {
+		return
+	}
+	h.history = append(h.history, sample.Throughput())
+	if len(h.history) > HoltWintersHistory {
+		h.history = h.history[len(h.history)-HoltWintersHistory:]
+	}
+
+	// a trend needs two samples, and a fit a third to test the forecast on
+	if len(h.history) < 3 {
+		h.forecast = h.history[len(h.history)-1]
+		return
+	}
+
+	// scale the history to a mean of 1, so the fit does not depend on the bitrate
+	var mean float64
+	for _, throughput := range h.history {
+		mean += throughput
+	}
+	mean /= float64(len(h.history))
+	scaled := make([]float64, len(h.history))
+	for i, throughput := range h.history {
+		scaled[i] = throughput / mean
+	}
+
+	season := h.season
+	if len(scaled) < 2*season {
+		season = 0
+	}
+	// alpha and beta, and gamma with a season - starting from a level that follows the throughput closely and a slow trend
+	dims := 2
+	if season > 0 {
+		dims = 3
+	}
+	if len(h.params) != dims {
+		h.params = []float64{0, logit(0.1), logit(0.1)}[:dims]
+	}
+
+	problem := optimize.Problem{
+		Func: func(x []float64) float64 {
+			sse, _ := holtWinters(scaled, season, x)
+			return sse
+		},
+	}
+	result, err := optimize.Minimize(problem, h.params, &optimize.Settings{FuncEvaluations: holtWintersEvaluations}, &optimize.NelderMead{})
+	if err == nil && result != nil {
+		h.params = result.X
+	}
+
+	_, forecast := holtWinters(scaled, season, h.params)
+	// a falling trend can forecast below zero
+	h.forecast = math.Max(forecast*mean, 0)
+}
+
+// Estimate : the forecast of the throughput of the next segment
+func (h *HoltWinters) Estimate() float64 {
+	return h.forecast
+}
+
+// smoothing : alpha, beta and gamma in (0, 1) from the parameters of the fit, gamma is 0 without a season
+func smoothing(x []float64) (alpha, beta, gamma float64) {
+	alpha = logistic(x[0])
+	beta = logistic(x[1])
+	if len(x) > 2 {
+		gamma = logistic(x[2])
+	}
+	return
+}
+
+// holtWinters :
+// * run the additive Holt-Winters recursions over the samples, with the smoothing of the parameters of the fit
+// * return the sum of the squared errors of the forecast of each sample, and the forecast of the sample after the last
+func holtWinters(samples []float64, season int, params []float64) (sse float64, forecast float64) {
+
+	alpha, beta, gamma := smoothing(params)
+
+	if season == 0 {
+		level := samples[0]
+		trend := samples[1] - samples[0]
+		for _, y := range samples[1:] {
+			err := y - (level + trend)
+			sse += err * err
+			newLevel := alpha*y + (1-alpha)*(level+trend)
+			trend = beta*(newLevel-level) + (1-beta)*trend
+			level = newLevel
+		}
+		return sse, level + trend
+	}
+
+	// the level of the first season, the trend from the first to the second, and each segment against the first level
+	var first, second float64
+	for i := 0; i < season; i++ {
+		first += samples[i]
+		second += samples[season+i]
+	}
+	level := first / float64(season)
+	trend := (second - first) / float64(season*season)
+	seasonal := make([]float64, season)
+	for i := range seasonal {
+		seasonal[i] = samples[i] - level
+	}
+
+	for t := season; t < len(samples); t++ {
+		y := samples[t]
+		s := seasonal[t%season]
+		err := y - (level + trend + s)
+		sse += err * err
+		newLevel := alpha*(y-s) + (1-alpha)*(level+trend)
+		trend = beta*(newLevel-level) + (1-beta)*trend
+		seasonal[t%season] = gamma*(y-newLevel) + (1-gamma)*s
+		level = newLevel
+	}
+	return sse, level + trend + seasonal[len(samples)%season]
+}
+
+func logistic(x float64) float64 {
+	return 1 / (1 + math.Exp(-x))
+}
+
+func logit(p float64) float64 {
+	return math.Log(p / (1 - p))
+}
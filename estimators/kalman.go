@@ -0,0 +1,73 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package estimators
+
+import "math"
+
+// Kalman filter parameters, the variances of the log2 of the throughput
+const (
+	// KalmanProcessNoise : how much the throughput of the path changes from one segment to the next
+	KalmanProcessNoise = 0.05
+	// KalmanMeasurementNoise : how much the throughput of a segment differs from the throughput of the path
+	KalmanMeasurementNoise = 0.2
+)
+
+// Kalman :
+// * a Kalman filter of the throughput, modelled as a random walk that each segment measures with noise
+// * the filter runs on the log2 of the throughput, so the same noise fits any bitrate and a halving weighs as much as a doubling
+type Kalman struct {
+	// the estimate of log2 of the throughput, and its variance
+	x       float64
+	p       float64
+	started bool
+}
+
+// NewKalman : a Kalman filter with no sample yet
+func NewKalman() *Kalman {
+	return &Kalman{}
+}
+
+// Observe : predict the throughput of the segment from the last estimate, and correct it with the throughput measured
+func (k *Kalman) Observe(sample Sample) {
+	if !usable(sample) {
+		return
+	}
+	z := math.Log2(sample.Throughput())
+	if !k.started {
+		k.x = z
+		k.p = KalmanMeasurementNoise
+		k.started = true
+		return
+	}
+	k.p += KalmanProcessNoise
+	gain := k.p / (k.p + KalmanMeasurementNoise)
+	k.x += gain * (z - k.x)
+	k.p *= 1 - gain
+}
+
+// Estimate : the throughput of the filtered estimate
+func (k *Kalman) Estimate() float64 {
+	if !k.started {
+		return 0
+	}
+	return math.Exp2(k.x)
+}
@@ -0,0 +1,179 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package estimators
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	glob "github.com/uccmisl/godash/global"
+	qlogreader "github.com/uccmisl/godash/qlog/reader"
+)
+
+// ReadSegmentLog :
+// * the delivery time and the size of each segment in a logDownload.txt, as a sample of the segment
+// * audio and video are logged in the same file, so there is a series for each codec when the log has the codec column
+func ReadSegmentLog(path string) (codecs []string, series map[string][]Sample, err error) {
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	series = make(map[string][]Sample)
+	delTime, byteSize, codec := -1, -1, -1
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		// the header line gives the columns
+		if fields[0] == glob.SegNum {
+			for i, field := range fields {
+				switch field {
+				case glob.DelTime:
+					delTime = i
+				case glob.ByteSize:
+					byteSize = i
+				case glob.CodecHeader:
+					codec = i
+				}
+			}
+			continue
+		}
+		if delTime < 0 || byteSize < 0 || delTime >= len(fields) || byteSize >= len(fields) {
+			continue
+		}
+		deliveryTime, err1 := strconv.Atoi(fields[delTime])
+		size, err2 := strconv.Atoi(fields[byteSize])
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		name := ""
+		if codec >= 0 && codec < len(fields) {
+			name = fields[codec]
+		}
+		if _, ok := series[name]; !ok {
+			codecs = append(codecs, name)
+		}
+		series[name] = append(series[name], Sample{Bytes: size, Duration: time.Duration(deliveryTime) * time.Millisecond})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	if delTime < 0 || byteSize < 0 {
+		return nil, nil, fmt.Errorf("%s has no %s and %s columns", path, glob.DelTime, glob.ByteSize)
+	}
+	return codecs, series, nil
+}
+
+// ReadTransportQlog :
+// * the packets received in a transport qlog of a connection, at their time from the start of the connection
+// * the packets of a qlog that was not closed (the client was stopped) are still read
+func ReadTransportQlog(path string) ([]Packet, error) {
+
+	l, err := qlogreader.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var packets []Packet
+	for _, event := range l.Events {
+		if packet, ok := event.Details.(qlogreader.Packet); ok && event.Name == "packet_received" {
+			packets = append(packets, Packet{Time: event.Time, Bytes: packet.Length})
+		}
+	}
+	return packets, nil
+}
+
+// EvaluateRun :
+// * evaluate every registered estimator on the files of a run, at every horizon
+// * a .qlog file is a transport qlog, its packets are evaluated in intervals of PacketInterval
+// * any other file is a logDownload.txt, its segments are evaluated for each codec
+func EvaluateRun(paths []string, window int, season int) ([]Evaluation, error) {
+
+	var evaluations []Evaluation
+	for _, path := range paths {
+		name := filepath.Base(path)
+		if strings.HasSuffix(path, ".qlog") {
+			packets, err := ReadTransportQlog(path)
+			if err != nil {
+				return nil, err
+			}
+			evaluations = append(evaluations, Evaluate(name, PacketSamples(packets, PacketInterval), Registered, EvaluationHorizons, window, season)...)
+			continue
+		}
+		codecs, series, err := ReadSegmentLog(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, codec := range codecs {
+			seriesName := name
+			if codec != "" {
+				seriesName += ":" + codec
+			}
+			evaluations = append(evaluations, Evaluate(seriesName, series[codec], Registered, EvaluationHorizons, window, season)...)
+		}
+	}
+	return evaluations, nil
+}
+
+// WriteEvaluations : write the evaluations to a CSV and a JSON file in the folder, and return their paths
+func WriteEvaluations(folder string, evaluations []Evaluation) (csvPath string, jsonPath string, err error) {
+
+	csvPath = filepath.Join(folder, glob.EstimatorEvaluationFile+".csv")
+	jsonPath = filepath.Join(folder, glob.EstimatorEvaluationFile+".json")
+
+	f, err := os.Create(csvPath)
+	if err != nil {
+		return "", "", err
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	w.Write([]string{"series", "estimator", "horizon", "predictions", "mae_bps", "rmse_bps", "mape_percent", "over_ratio", "under_ratio"})
+	for _, e := range evaluations {
+		w.Write([]string{e.Series, e.Estimator, strconv.Itoa(e.Horizon), strconv.Itoa(e.Predictions),
+			strconv.FormatFloat(e.MAE, 'f', 0, 64), strconv.FormatFloat(e.RMSE, 'f', 0, 64), strconv.FormatFloat(e.MAPE, 'f', 2, 64),
+			strconv.FormatFloat(e.Over, 'f', 3, 64), strconv.FormatFloat(e.Under, 'f', 3, 64)})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return "", "", err
+	}
+
+	data, err := json.MarshalIndent(evaluations, "", "  ")
+	if err != nil {
+		return "", "", err
+	}
+	if err := os.WriteFile(jsonPath, data, 0644); err != nil {
+		return "", "", err
+	}
+	return csvPath, jsonPath, nil
+}
@@ -0,0 +1,88 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package estimators
+
+// SlidingWindow : the mean throughput of the last window segments
+type SlidingWindow struct {
+	window window
+}
+
+// NewSlidingWindow : a sliding window of window segments, at least 1
+func NewSlidingWindow(size int) *SlidingWindow {
+	if size < 1 {
+		size = 1
+	}
+	return &SlidingWindow{window: window{size: size}}
+}
+
+// Observe : add the throughput of the segment to the window
+func (s *SlidingWindow) Observe(sample Sample) {
+	if usable(sample) {
+		s.window.add(sample.Throughput())
+	}
+}
+
+// Estimate : the mean of the window
+func (s *SlidingWindow) Estimate() float64 {
+	if len(s.window.samples) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, throughput := range s.window.samples {
+		sum += throughput
+	}
+	return sum / float64(len(s.window.samples))
+}
+
+// Harmonic :
+// * the harmonic mean throughput of the last window segments, as the elastic algorithm takes it
+// * a segment with a low throughput weighs more than one with a high throughput, so the estimate is slow to rise after a drop
+type Harmonic struct {
+	window window
+}
+
+// NewHarmonic : a harmonic mean of window segments, at least 1
+func NewHarmonic(size int) *Harmonic {
+	if size < 1 {
+		size = 1
+	}
+	return &Harmonic{window: window{size: size}}
+}
+
+// Observe : add the throughput of the segment to the window
+func (h *Harmonic) Observe(sample Sample) {
+	if usable(sample) {
+		h.window.add(sample.Throughput())
+	}
+}
+
+// Estimate : the harmonic mean of the window
+func (h *Harmonic) Estimate() float64 {
+	if len(h.window.samples) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, throughput := range h.window.samples {
+		sum += 1 / throughput
+	}
+	return float64(len(h.window.samples)) / sum
+}
@@ -48,6 +48,18 @@ var DownloadFileStoreName = "./files/"
 // LogDownload : where to save the log download text
 const LogDownload = "logDownload.txt"
 
+// LogDownloadCSV : where to save every field of the log download, as CSV
+const LogDownloadCSV = "logDownload.csv"
+
+// LogDownloadJSONL : where to save every field of the log download, as JSON Lines
+const LogDownloadJSONL = "logDownload.jsonl"
+
+// LogDownloadSchema : where to save the schema version, fields and units of the log download
+const LogDownloadSchema = "logDownload.schema.json"
+
+// SummaryFile : where to save the QoE KPIs of the session
+const SummaryFile = "summary.json"
+
 // RepRateCodecAVC : AVC constants for our encoder
 const RepRateCodecAVC = "h264"
 
@@ -178,6 +190,9 @@ const StoreFilesOff = "off"
 // StoreFilesOn : constants for storing files
 const StoreFilesOn = "on"
 
+// StoreFilesConcat : constants for storing files
+const StoreFilesConcat = "concat"
+
 // TerminalPrintName : parameter variables
 const TerminalPrintName = "terminalPrint"
 
@@ -238,6 +253,183 @@ const UseTestBedOff = "off"
 // UseTestBedOn : constants for useTest
 const UseTestBedOn = "on"
 
+// HeadersName : parameter variables
+const HeadersName = "headers"
+
+// CookiesName : parameter variables
+const CookiesName = "cookies"
+
+// CookieJarName : parameter variables
+const CookieJarName = "cookieJar"
+
+// CookieJarOff : constants for cookieJar
+const CookieJarOff = "off"
+
+// CookieJarOn : constants for cookieJar
+const CookieJarOn = "on"
+
+// ProxyName : parameter variables
+const ProxyName = "proxy"
+
+// TokenScriptName : parameter variables
+const TokenScriptName = "tokenScript"
+
+// TokenURLName : parameter variables
+const TokenURLName = "tokenURL"
+
+// TokenRefreshName : parameter variables
+const TokenRefreshName = "tokenRefresh"
+
+// LicenseURLName : parameter variables
+const LicenseURLName = "licenseURL"
+
+// StallModelName : parameter variables
+const StallModelName = "stallModel"
+
+// StallWindowName : parameter variables
+const StallWindowName = "stallWindow"
+
+// StallThresholdName : parameter variables
+const StallThresholdName = "stallThreshold"
+
+// StallAbortName : parameter variables
+const StallAbortName = "stallAbort"
+
+// StallModelWindow : constants for the stall predictor model - throughput of the last stallWindow packets
+const StallModelWindow = "window"
+
+// StallModelSegment : constants for the stall predictor model - throughput since the segment started
+const StallModelSegment = "segment"
+
+// StallModelEWMA : constants for the stall predictor model - exponentially weighted throughput
+const StallModelEWMA = "ewma"
+
+// StallWindowDefault : the default number of packets for the stall predictor
+const StallWindowDefault = 20
+
+// StallThresholdDefault : the default fraction of the maximum buffer below which stalls are predicted
+const StallThresholdDefault = 0.1
+
+// StallAbortOn : constants for stallAbort
+const StallAbortOn = "on"
+
+// StallAbortOff : constants for stallAbort
+const StallAbortOff = "off"
+
+// EstimatorName : parameter variables
+const EstimatorName = "estimator"
+
+// EstimatorWindowName : parameter variables
+const EstimatorWindowName = "estimatorWindow"
+
+// EstimatorSeasonName : parameter variables
+const EstimatorSeasonName = "estimatorSeason"
+
+// EstimatorOff : constants for the throughput estimator - the algorithm uses its own estimate
+const EstimatorOff = "off"
+
+// EstimatorSlidingWindow : constants for the throughput estimator - mean of the last estimatorWindow segments
+const EstimatorSlidingWindow = "window"
+
+// EstimatorDualEWMA : constants for the throughput estimator - lower of a fast and a slow moving average
+const EstimatorDualEWMA = "ewma"
+
+// EstimatorHarmonic : constants for the throughput estimator - harmonic mean of the last estimatorWindow segments
+const EstimatorHarmonic = "harmonic"
+
+// EstimatorKalman : constants for the throughput estimator - Kalman filter
+const EstimatorKalman = "kalman"
+
+// EstimatorHoltWinters : constants for the throughput estimator - Holt-Winters smoothing
+const EstimatorHoltWinters = "holtwinters"
+
+// EstimatorWindowDefault : the default number of segments of the window and harmonic estimators
+const EstimatorWindowDefault = 5
+
+// EstimatorSeasonDefault : the default number of segments in a season of the holtwinters estimator - no season
+const EstimatorSeasonDefault = 0
+
+// EvaluateEstimatorsName : parameter variables
+const EvaluateEstimatorsName = "evaluateEstimators"
+
+// AnalyzeCommand : the command that analyzes the qlog files of a run - "godash analyze"
+const AnalyzeCommand = "analyze"
+
+// AnalyzeLogsName : parameter variables
+const AnalyzeLogsName = "logs"
+
+// AnalyzeCSVName : parameter variables
+const AnalyzeCSVName = "csv"
+
+// QlogMergeName : parameter variables
+const QlogMergeName = "qlogMerge"
+
+// QlogMergeOn : constants for qlogMerge
+const QlogMergeOn = "on"
+
+// QlogMergeOff : constants for qlogMerge
+const QlogMergeOff = "off"
+
+// QlogFormatName : parameter variables
+const QlogFormatName = "qlogFormat"
+
+// QlogFormatJSON : constants for qlogFormat - a single JSON document, for qvis
+const QlogFormatJSON = "json"
+
+// QlogFormatSeq : constants for qlogFormat - JSON Text Sequences
+const QlogFormatSeq = "sqlog"
+
+// QlogFormatNDJSON : constants for qlogFormat - a JSON record per line
+const QlogFormatNDJSON = "ndjson"
+
+// QlogPlayheadIntervalName : parameter variables
+const QlogPlayheadIntervalName = "qlogPlayheadInterval"
+
+// QlogPlayheadIntervalDefault : the default interval of the playhead progress events of the qlog-abr file, in milliseconds
+const QlogPlayheadIntervalDefault = 1000
+
+// QlogDirName : parameter variables
+const QlogDirName = "qlogDir"
+
+// QlogPatternName : parameter variables
+const QlogPatternName = "qlogPattern"
+
+// QlogGzipName : parameter variables
+const QlogGzipName = "qlogGzip"
+
+// QlogGzipOn : constants for qlogGzip
+const QlogGzipOn = "on"
+
+// QlogGzipOff : constants for qlogGzip
+const QlogGzipOff = "off"
+
+// QlogCategoriesName : parameter variables
+const QlogCategoriesName = "qlogCategories"
+
+// LiveAddrName : parameter variables
+const LiveAddrName = "liveAddr"
+
+// MetricsAddrName : parameter variables
+const MetricsAddrName = "metricsAddr"
+
+// ReportName : parameter variables
+const ReportName = "report"
+
+// ReportOn : constants for report
+const ReportOn = "on"
+
+// ReportOff : constants for report
+const ReportOff = "off"
+
+// AnalyzeSegmentsName : parameter variables
+const AnalyzeSegmentsName = "segments"
+
+// QlogMergedFile : the qlog of the application and transport events of a run, in the logs folder
+const QlogMergedFile = "client_merged.qlog"
+
+// EstimatorEvaluationFile : the name of the CSV and JSON files of the estimator evaluation
+const EstimatorEvaluationFile = "estimatorEvaluation"
+
 // HTTPcertLocation : location of the http cert
 const HTTPcertLocation = "http/certs/cert.pem"
 
@@ -262,6 +454,15 @@ const P1203maxHeight = 1080
 // P1203exec : executable for P1203
 const P1203exec = "p1203-standalone"
 
+// P1203PythonName : parameter variables
+const P1203PythonName = "p1203Python"
+
+// P1203PythonOn : constants for p1203Python
+const P1203PythonOn = "on"
+
+// P1203PythonOff : constants for p1203Python
+const P1203PythonOff = "off"
+
 // InsecureSSL :  "Accept/Ignore all server SSL certificates"
 const InsecureSSL = true
 
@@ -330,6 +531,27 @@ const SegReplaceHeader = "Seg_Repl"
 // HTTPProtocolHeader : header for
 const HTTPProtocolHeader = "Protocol"
 
+// DNSHeader : header for
+const DNSHeader = "DNS"
+
+// ConnectHeader : header for
+const ConnectHeader = "Connect"
+
+// TLSHeader : header for
+const TLSHeader = "TLS"
+
+// TTFBHeader : header for
+const TTFBHeader = "TTFB"
+
+// TTLBHeader : header for
+const TTLBHeader = "TTLB"
+
+// ReusedHeader : header for
+const ReusedHeader = "Reused"
+
+// EstimateHeader : header for
+const EstimateHeader = "Estimate"
+
 // QOE
 
 // P1203Header : header for
@@ -1,17 +1,24 @@
 module github.com/uccmisl/godash
 
-go 1.16
+go 1.22
 
 require (
 	github.com/cavaliercoder/grab v2.0.1-0.20200331080741-9f014744ee41+incompatible
-	github.com/cheekybits/genny v1.0.1-0.20190611084615-df3d48aa411e // indirect
 	github.com/francoispqt/gojay v1.2.13
 	github.com/golang/protobuf v1.5.2
 	github.com/hashicorp/consul/api v1.4.0
-	github.com/lucas-clemente/quic-go v0.23.0
-	golang.org/x/net v0.0.0-20210428140749-89ef3d95e781
+	github.com/quic-go/quic-go v0.48.2
+	golang.org/x/net v0.28.0
 	gonum.org/v1/gonum v0.7.0
 	google.golang.org/grpc v1.19.0
 )
 
-replace github.com/lucas-clemente/quic-go => ../quic-go
+require (
+	github.com/cheekybits/genny v1.0.1-0.20190611084615-df3d48aa411e // indirect
+	github.com/quic-go/qpack v0.5.1 // indirect
+	golang.org/x/crypto v0.26.0 // indirect
+	golang.org/x/exp v0.0.0-20240506185415-9bf2ced13842 // indirect
+	golang.org/x/sys v0.23.0 // indirect
+	golang.org/x/text v0.17.0 // indirect
+	golang.org/x/tools v0.21.1-0.20240508182429-e35e4ccd0d2d // indirect
+)
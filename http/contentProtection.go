@@ -0,0 +1,195 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package http
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/uccmisl/godash/cenc"
+	"github.com/uccmisl/godash/logging"
+	"github.com/uccmisl/godash/utils"
+
+	abrqlog "github.com/uccmisl/godash/qlog"
+)
+
+// license url passed in by the user, used instead of the Laurl in the MPD
+var licenseURLOverride string
+
+// map of hex KID to key, from all licenses we have received
+var contentKeys = make(map[string][]byte)
+
+// encryption values from the init segment of each media type
+var trackEncryptions = make(map[abrqlog.MediaType]*cenc.TrackEncryption)
+
+// SetLicenseURL : set the ClearKey license url used for all encrypted content
+func SetLicenseURL(licenseURL string) {
+	licenseURLOverride = licenseURL
+}
+
+// GetContentProtection :
+// * get the KIDs and ClearKey license url of an adaptation set (and its representations)
+// * protected is true if the adaptation set has any ContentProtection element
+func GetContentProtection(mpd MPD, currentMPDRepAdaptSet int) (kids [][]byte, licenseURL string, protected bool) {
+
+	adaptationSet := mpd.Periods[0].AdaptationSet[currentMPDRepAdaptSet]
+	contentProtections := adaptationSet.ContentProtection
+	for _, rep := range adaptationSet.Representation {
+		contentProtections = append(contentProtections, rep.ContentProtection...)
+	}
+
+	seen := make(map[string]bool)
+	addKID := func(kid []byte) {
+		if !seen[hex.EncodeToString(kid)] {
+			seen[hex.EncodeToString(kid)] = true
+			kids = append(kids, kid)
+		}
+	}
+
+	for _, cp := range contentProtections {
+		protected = true
+
+		if cp.DefaultKID != "" {
+			if kid, err := cenc.ParseKID(cp.DefaultKID); err == nil {
+				addKID(kid)
+			}
+		}
+
+		if cenc.IsClearKeyScheme(cp.SchemeIDURI) {
+			if cp.Pssh != "" {
+				psshKIDs, _ := cenc.ParsePSSH(cp.Pssh)
+				for _, kid := range psshKIDs {
+					addKID(kid)
+				}
+			}
+			if licenseURL == "" {
+				licenseURL = strings.TrimSpace(cp.Laurl + cp.LaurlLower)
+			}
+		}
+	}
+
+	if licenseURLOverride != "" {
+		licenseURL = licenseURLOverride
+	}
+
+	return
+}
+
+// GetLicense :
+// * request the keys for an encrypted adaptation set from its ClearKey license server
+// * return the time taken to get the license (0 if the content is clear)
+func GetLicense(mpd MPD, currentMPDRepAdaptSet int, quicBool bool, debugFile string, debugLog bool, useTestbedBool bool) time.Duration {
+
+	kids, licenseURL, protected := GetContentProtection(mpd, currentMPDRepAdaptSet)
+	if !protected {
+		return 0
+	}
+	logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "ContentProtection found for adaptation set "+fmt.Sprint(currentMPDRepAdaptSet)+" with "+fmt.Sprint(len(kids))+" KID(s)")
+
+	if len(kids) == 0 || licenseURL == "" {
+		logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "no KID or ClearKey license url for this adaptation set, segments will not be decrypted")
+		return 0
+	}
+
+	// only ask for keys we do not already have
+	var missingKIDs [][]byte
+	for _, kid := range kids {
+		if _, ok := contentKeys[hex.EncodeToString(kid)]; !ok {
+			missingKIDs = append(missingKIDs, kid)
+		}
+	}
+	if len(missingKIDs) == 0 {
+		return 0
+	}
+
+	_, client, _ := GetHTTPClient(quicBool, debugFile, debugLog, useTestbedBool)
+
+	start := time.Now()
+	keys, err := cenc.RequestClearKeyLicense(client, licenseURL, missingKIDs)
+	licenseTime := time.Since(start)
+	if err != nil {
+		fmt.Println(err)
+		fmt.Println("*** unable to get the keys from the license server " + licenseURL + " ***")
+		// stop the app
+		utils.StopApp()
+	}
+
+	for kid, key := range keys {
+		contentKeys[kid] = key
+	}
+	logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "license from "+licenseURL+" received in "+fmt.Sprint(licenseTime.Milliseconds())+"ms")
+
+	return licenseTime
+}
+
+// canDecrypt : true if we have the key for the media segments of this media type
+func canDecrypt(mediaType abrqlog.MediaType) bool {
+	te, ok := trackEncryptions[mediaType]
+	if !ok {
+		return false
+	}
+	_, ok = contentKeys[te.KIDString()]
+	return ok
+}
+
+// decryptFile :
+// * read the track encryption values if this is an init segment, or decrypt the samples if this is a media segment
+// * return the content unchanged if it is clear or we do not have the key - init segments included
+func decryptFile(content []byte, mediaType abrqlog.MediaType, debugFile string, debugLog bool) []byte {
+
+	// init segments carry the tenc box
+	te, err := cenc.ParseInit(content)
+	if err != nil {
+		logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "unable to read the encryption values of the init segment: "+err.Error())
+		return content
+	}
+	if te != nil {
+		trackEncryptions[mediaType] = te
+		logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "init segment is encrypted with "+te.Scheme+" and KID "+te.KIDString())
+		// keep the sinf and tenc boxes if we cannot decrypt the media segments
+		if !canDecrypt(mediaType) {
+			logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "no key for KID "+te.KIDString()+", init segment saved encrypted")
+			return content
+		}
+		return cenc.ClearInit(content, te)
+	}
+
+	// media segments need the values from their init segment
+	te, ok := trackEncryptions[mediaType]
+	if !ok {
+		return content
+	}
+	key, ok := contentKeys[te.KIDString()]
+	if !ok {
+		logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "no key for KID "+te.KIDString()+", segment saved encrypted")
+		return content
+	}
+
+	decrypted, err := cenc.DecryptSegment(content, te, key)
+	if err != nil {
+		logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "unable to decrypt segment: "+err.Error())
+		return content
+	}
+	return decrypted
+}
@@ -0,0 +1,142 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package http
+
+import (
+	"context"
+	"crypto/sha256"
+	"hash"
+	"io"
+	"sync/atomic"
+	"time"
+
+	xlayer "github.com/uccmisl/godash/crosslayer"
+	"github.com/uccmisl/godash/isobmff"
+
+	abrqlog "github.com/uccmisl/godash/qlog"
+)
+
+// ProgressUpdateInterval : how often the qlog request_update event is written while a segment downloads
+const ProgressUpdateInterval = 100 * time.Millisecond
+
+// StatusIncomplete : the status GetFile returns when the segment body could not be read, or its download was abandoned
+// * nothing of the segment is stored, counted or shared, so the player can request it again
+const StatusIncomplete = -1
+
+// DownloadProgress :
+// * the bytes of a segment received so far, and the time since its request was sent
+// * the player puts it in the context of the download with WithDownloadProgress, so it can read it while it decides to abandon the download, and after
+type DownloadProgress struct {
+	start    time.Time
+	received atomic.Int64
+}
+
+type downloadProgressKey struct{}
+
+// NewDownloadProgress : the progress of a download that starts now
+func NewDownloadProgress() *DownloadProgress {
+	return &DownloadProgress{start: time.Now()}
+}
+
+// WithDownloadProgress : a context whose segment download reports its progress to p
+func WithDownloadProgress(ctx context.Context, p *DownloadProgress) context.Context {
+	return context.WithValue(ctx, downloadProgressKey{}, p)
+}
+
+// downloadProgress : the progress of the context, nil if it has none
+func downloadProgress(ctx context.Context) *DownloadProgress {
+	p, _ := ctx.Value(downloadProgressKey{}).(*DownloadProgress)
+	return p
+}
+
+/**
+* Returns the bytes of the segment body received so far
+ */
+func (p *DownloadProgress) Received() int64 {
+	return p.received.Load()
+}
+
+/**
+* Returns the time since the download started
+ */
+func (p *DownloadProgress) Elapsed() time.Duration {
+	return time.Since(p.start)
+}
+
+// progressReader :
+// * counts the bytes read from a segment body, and reports them to the DownloadProgress of the player if it has one
+// * over TCP, reports each read for its request to the cross layer accountant - over QUIC it gets the packets from the qlog tracer
+// * reports the bytes so far to the qlog request_update event of the tracer every ProgressUpdateInterval
+type progressReader struct {
+	r          io.Reader
+	url        string
+	quicBool   bool
+	request    *xlayer.Request
+	progress   *DownloadProgress
+	tracer     *abrqlog.StreamTracer
+	received   int64
+	lastUpdate time.Time
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	if n > 0 {
+		p.received += int64(n)
+		if p.progress != nil {
+			p.progress.received.Store(p.received)
+		}
+		if p.request != nil && !p.quicBool {
+			globAccountant.ReportProgress(p.request, n)
+		}
+		if time.Since(p.lastUpdate) >= ProgressUpdateInterval {
+			p.tracer.RequestUpdate(p.url, p.received)
+			p.lastUpdate = time.Now()
+		}
+	}
+	return n, err
+}
+
+// readSegment :
+// * read a segment body as it arrives, without keeping the body in memory
+// * the boxes are read by the scanner, and if out is set, the body is hashed and written to out
+// * over TCP, the bytes read count for the request in the cross layer accountant
+// * the bytes read are reported to progress, if it is not nil
+// * return the scanner, the sha256 of the body (nil if out is not set) and any read or write error
+func readSegment(body io.Reader, url string, quicBool bool, request *xlayer.Request, progress *DownloadProgress, out io.Writer, tracer *abrqlog.StreamTracer) (*isobmff.Scanner, []byte, error) {
+
+	scanner := isobmff.NewScanner()
+	writers := []io.Writer{scanner}
+
+	var sum hash.Hash
+	if out != nil {
+		sum = sha256.New()
+		writers = append(writers, sum, out)
+	}
+
+	reader := &progressReader{r: body, url: url, quicBool: quicBool, request: request, progress: progress, tracer: tracer, lastUpdate: time.Now()}
+	_, err := io.Copy(io.MultiWriter(writers...), reader)
+
+	if sum == nil {
+		return scanner, nil, err
+	}
+	return scanner, sum.Sum(nil), err
+}
@@ -0,0 +1,33 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestReadSegmentProgress(t *testing.T) {
+
+	progress := NewDownloadProgress()
+	if downloadProgress(WithDownloadProgress(context.Background(), progress)) != progress || downloadProgress(context.Background()) != nil {
+		t.Fatal("the progress of the context")
+	}
+
+	// the connection breaks after 500 bytes of the body
+	broken := errors.New("connection reset")
+	body := io.MultiReader(bytes.NewReader(make([]byte, 500)), &failingReader{broken})
+	_, _, err := readSegment(body, "seg1.m4s", false, nil, progress, nil, nil)
+
+	if !errors.Is(err, broken) {
+		t.Errorf("read error %v, want %v", err, broken)
+	}
+	if progress.Received() != 500 || progress.Elapsed() <= 0 {
+		t.Errorf("progress %d bytes in %v", progress.Received(), progress.Elapsed())
+	}
+}
+
+type failingReader struct{ err error }
+
+func (f *failingReader) Read([]byte) (int, error) { return 0, f.err }
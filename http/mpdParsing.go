@@ -78,6 +78,7 @@ type StreamStruct struct {
 	RepRate               int
 	BandwithList          []int
 	Profile               string
+	SegmentInits          *SegmentInits
 }
 
 // MPD structure
@@ -138,6 +139,7 @@ type AdaptationSet struct {
 	SubsegmentStartsWithSAP   int                       `xml:"subsegmentStartsWithSAP"`
 	AudioChannelConfiguration AudioChannelConfiguration `xml:"AudioChannelConfiguration"`
 	Role                      Role                      `xml:"Role"`
+	ContentProtection         []ContentProtection       `xml:"ContentProtection"`
 	ContentType               string                    `xml:"contentType,attr"`
 	MimeType                  string                    `xml:"mimeType,attr"`
 	StartWithSAP              int                       `xml:"startWithSAP,attr"`
@@ -166,6 +168,20 @@ type Representation struct {
 	SegmentBase               SegmentBase               `xml:"SegmentBase"`
 	AudioSamplingRate         int                       `xml:"audioSamplingRate,attr"`
 	AudioChannelConfiguration AudioChannelConfiguration `xml:"AudioChannelConfiguration"`
+	ContentProtection         []ContentProtection       `xml:"ContentProtection"`
+}
+
+// ContentProtection in MPD
+// default_KID, pssh and Laurl are namespaced (cenc:, clearkey: or dashif:), so match on the local name
+type ContentProtection struct {
+	XMLName     xml.Name `xml:"ContentProtection"`
+	SchemeIDURI string   `xml:"schemeIdUri,attr"`
+	Value       string   `xml:"value,attr"`
+	DefaultKID  string   `xml:"default_KID,attr"`
+	Pssh        string   `xml:"pssh"`
+	Laurl       string   `xml:"Laurl"`
+	// DASH-IF IOP 5 spelling
+	LaurlLower string `xml:"laurl"`
 }
 
 // SegmentTemplate in MPD
@@ -235,12 +251,12 @@ var SegHeadValues map[int]map[int][]int
 // * For each URL, call the method GET and parse the result with the function fileParser()
 // * If the URL doesn't match, displays an error, then continue with the other strings
 // * Add each structure
-func getStructList(requestedURLs []string, debugFile string, debugLog bool, useTestbedBool bool, quicbool bool) (mpds []MPD) {
+func getStructList(requestedURLs []string, debugFile string, debugLog bool, useTestbedBool bool, quicbool bool, tracer *abrqlog.StreamTracer) (mpds []MPD) {
 
 	// for each of the requested URLs
 	for i := 0; i < len(requestedURLs); i++ {
 
-		urls, _, _ := GetURL(requestedURLs[i], false, 0, 0, quicbool, debugFile, debugLog, useTestbedBool)
+		urls, _, _ := GetURL(requestedURLs[i], false, 0, 0, quicbool, debugFile, debugLog, useTestbedBool, tracer)
 
 		// Call the fileParser in parser.go
 		mpd := fileParser(urls)
@@ -367,7 +383,7 @@ func GetAllSegmentHeaders(mpdList []MPD, codecIndexList [][]int,
 	segmentNumber int, streamDuration int,
 	isByteRangeMPD bool,
 	maxBuffer int,
-	headerURL string, codec string, urlInput []string, debugLog bool, printToFile bool, client *http.Client) map[int]map[int][]int {
+	headerURL string, codec string, urlInput []string, debugLog bool, printToFile bool, client *http.Client, tracer *abrqlog.StreamTracer) map[int]map[int][]int {
 
 	// store the seg header maps
 	var segHeadValues map[int]map[int][]int
@@ -395,7 +411,7 @@ func GetAllSegmentHeaders(mpdList []MPD, codecIndexList [][]int,
 		currentURL := strings.TrimSpace(urlInput[mpdListIndex])
 
 		// get the segment headers for this MPD url
-		segHeadValues[mpdListIndex] = getSegmentHeaders(mpdList, mpdListIndex, currentMPDRepAdaptSet, maxHeight, segmentNumber, streamDuration, isByteRangeMPD, maxBuffer, currentURL, headerURL, debugLog, printToFile, client)
+		segHeadValues[mpdListIndex] = getSegmentHeaders(mpdList, mpdListIndex, currentMPDRepAdaptSet, maxHeight, segmentNumber, streamDuration, isByteRangeMPD, maxBuffer, currentURL, headerURL, debugLog, printToFile, client, tracer)
 	}
 	return segHeadValues
 }
@@ -407,7 +423,7 @@ func GetNSegmentHeaders(mpdList []MPD, codecIndexList [][]int,
 	segmentNumber int, streamDuration int,
 	isByteRangeMPD bool,
 	maxBuffer int,
-	headerURL string, codec string, urlInput []string, debugLog bool, useHeaderFile bool, client *http.Client) map[int]map[int][]int {
+	headerURL string, codec string, urlInput []string, debugLog bool, useHeaderFile bool, client *http.Client, tracer *abrqlog.StreamTracer) map[int]map[int][]int {
 
 	// store the seg header maps
 	var segHeadValues map[int]map[int][]int
@@ -439,7 +455,7 @@ func GetNSegmentHeaders(mpdList []MPD, codecIndexList [][]int,
 		if useHeaderFile {
 			segHeadValues[mpdListIndex] = getNSegmentHeadersFromFile(mpdList, mpdListIndex, currentMPDRepAdaptSet, maxHeight, segmentNumber, streamDuration, isByteRangeMPD, maxBuffer, currentURL, headerURL, debugLog)
 		} else {
-			segHeadValues[mpdListIndex] = getSegmentHeaders(mpdList, mpdListIndex, currentMPDRepAdaptSet, maxHeight, segmentNumber, streamDuration, isByteRangeMPD, maxBuffer, currentURL, headerURL, debugLog, useHeaderFile, client)
+			segHeadValues[mpdListIndex] = getSegmentHeaders(mpdList, mpdListIndex, currentMPDRepAdaptSet, maxHeight, segmentNumber, streamDuration, isByteRangeMPD, maxBuffer, currentURL, headerURL, debugLog, useHeaderFile, client, tracer)
 		}
 	}
 	SegHeadValues = segHeadValues
@@ -571,7 +587,7 @@ func getNSegmentHeadersFromFile(mpdList []MPD, mpdListIndex int, currentMPDRepAd
 
 // GetContentLengthHeader :
 // get the header of the next segment to have the informations about it
-func GetContentLengthHeader(currentMPD MPD, currentURL string, currentMPDRepAdaptSet int, repRate int, segmentNumber int, adaptationSetBaseURL string, debugLog bool, client *http.Client) int {
+func GetContentLengthHeader(currentMPD MPD, currentURL string, currentMPDRepAdaptSet int, repRate int, segmentNumber int, adaptationSetBaseURL string, debugLog bool, client *http.Client, tracer *abrqlog.StreamTracer) int {
 
 	// get the base url
 	baseURL := GetNextSegment(currentMPD, segmentNumber, repRate, currentMPDRepAdaptSet)
@@ -590,7 +606,7 @@ func GetContentLengthHeader(currentMPD MPD, currentURL string, currentMPDRepAdap
 	// or just add a description of the request:
 	// body, header, ...
 	// possibly needs a custom media type as well? or just the media type of the body?
-	abrqlog.MainTracer.Request(abrqlog.MediaTypeOther, url, "")
+	tracer.Request(abrqlog.MediaTypeOther, url, "")
 
 	//Get the header of the url
 	resp, err := client.Head(url)
@@ -600,7 +616,7 @@ func GetContentLengthHeader(currentMPD MPD, currentURL string, currentMPDRepAdap
 	defer resp.Body.Close()
 
 	//TODO get size of header
-	//abrqlog.MainTracer.RequestUpdate(url, len(resp.Header))
+	//tracer.RequestUpdate(url, len(resp.Header))
 
 	contentLen, err := strconv.Atoi(resp.Header.Get("Content-Length"))
 	if err != nil {
@@ -625,7 +641,7 @@ func getSegmentHeaders(mpdList []MPD, mpdListIndex int, currentMPDRepAdaptSet in
 	segmentNumber int, streamDuration int,
 	isByteRangeMPD bool,
 	maxBuffer int, currentURL string,
-	headerURL string, debugLog bool, printToFile bool, client *http.Client) map[int][]int {
+	headerURL string, debugLog bool, printToFile bool, client *http.Client, tracer *abrqlog.StreamTracer) map[int][]int {
 
 	var fileName string
 
@@ -778,7 +794,7 @@ func getSegmentHeaders(mpdList []MPD, mpdListIndex int, currentMPDRepAdaptSet in
 			*/
 			for j := highestMPDrepRateIndex; j <= lowestMPDrepRateIndex; j++ {
 				// get the content length of the next segment that will be downloaded
-				contentLength := GetContentLengthHeader(mpdList[mpdListIndex], currentURL, currentMPDRepAdaptSet, j, i, baseURL, debugLog, client)
+				contentLength := GetContentLengthHeader(mpdList[mpdListIndex], currentURL, currentMPDRepAdaptSet, j, i, baseURL, debugLog, client, tracer)
 				// save this value in a dictionary
 				contentLengthDictionary[j] = append(contentLengthDictionary[j], contentLength)
 				if printToFile {
@@ -1185,7 +1201,7 @@ func URLList(urlString string) []string {
 * call getStructList with the list to have the MPDs
 * return a struct of MPDs
  */
-func ReadURLArray(args string, debugLog bool, useTestbedBool bool, quicbool bool) (structList []MPD) {
+func ReadURLArray(args string, debugLog bool, useTestbedBool bool, quicbool bool, tracer *abrqlog.StreamTracer) (structList []MPD) {
 
 	var requestedURLs []string
 
@@ -1216,7 +1232,7 @@ func ReadURLArray(args string, debugLog bool, useTestbedBool bool, quicbool bool
 	}
 	if len(requestedURLs) > 0 {
 		// get the []struct of MPDs
-		structList = getStructList(requestedURLs, glob.DebugFile, debugLog, useTestbedBool, quicbool)
+		structList = getStructList(requestedURLs, glob.DebugFile, debugLog, useTestbedBool, quicbool, tracer)
 	}
 
 	return structList
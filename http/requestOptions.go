@@ -0,0 +1,241 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package http
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/uccmisl/godash/logging"
+)
+
+// TokenURLPlaceholder : replaced by the (query escaped) unsigned url in the token url template
+const TokenURLPlaceholder = "$URL$"
+
+// requestOptions : the user defined additions to every request made by our http client
+type requestOptions struct {
+	// static headers added to every request
+	headers map[string]string
+	// static cookies added to every request
+	cookies []*http.Cookie
+	// cookie jar used to store cookies set by the server
+	jar http.CookieJar
+	// proxy used by the TCP transport
+	proxyURL *url.URL
+	// script used to re-sign a url - called with the unsigned url, prints the signed url
+	tokenScript string
+	// url template used to re-sign a url - the response body is the signed url
+	tokenURL string
+	// how long a signed url stays valid for (0 means re-sign on every request)
+	tokenRefresh time.Duration
+
+	debugFile string
+	debugLog  bool
+}
+
+// signedURL : a signed url and the time it needs to be re-signed
+type signedURL struct {
+	url     string
+	expires time.Time
+}
+
+var reqOptions requestOptions
+
+// map of unsigned urls to signed urls
+var signedURLs = make(map[string]signedURL)
+var signedURLsMutex sync.Mutex
+
+// SetRequestOptions :
+// * set the headers, cookies, proxy and token hook used for every request made through GetHTTPClient
+// * must be called before the first call to GetHTTPClient
+func SetRequestOptions(headers map[string]string, cookies string, useCookieJar bool, proxy string, tokenScript string, tokenURL string, tokenRefresh int, debugFile string, debugLog bool) {
+
+	reqOptions = requestOptions{
+		headers:      headers,
+		tokenScript:  tokenScript,
+		tokenURL:     tokenURL,
+		tokenRefresh: time.Duration(tokenRefresh) * time.Second,
+		debugFile:    debugFile,
+		debugLog:     debugLog,
+	}
+
+	// parse the cookies in the same format as the "Cookie" request header
+	if cookies != "" {
+		reqOptions.cookies = (&http.Request{Header: http.Header{"Cookie": {cookies}}}).Cookies()
+	}
+
+	// the cookie jar will keep any cookies the server sets on us
+	if useCookieJar {
+		reqOptions.jar, _ = cookiejar.New(nil)
+	}
+
+	// main.go has already checked this url
+	if proxy != "" {
+		reqOptions.proxyURL, _ = url.Parse(proxy)
+	}
+}
+
+// getProxy : return the proxy function for our TCP transport, nil if no proxy is set
+func getProxy() func(*http.Request) (*url.URL, error) {
+	if reqOptions.proxyURL == nil {
+		return nil
+	}
+	return http.ProxyURL(reqOptions.proxyURL)
+}
+
+// decoratedTransport : adds our request options to every request before passing it on
+type decoratedTransport struct {
+	base http.RoundTripper
+}
+
+// RoundTrip : implement http.RoundTripper
+func (d *decoratedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+
+	// a RoundTripper should not modify the request it was given
+	req = req.Clone(req.Context())
+
+	err := decorateRequest(req, d.base)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.base.RoundTrip(req)
+}
+
+// decorateRequest :
+// * re-sign the url, if we have a token hook
+// * add the static headers and cookies
+func decorateRequest(req *http.Request, rt http.RoundTripper) error {
+
+	if reqOptions.tokenScript != "" || reqOptions.tokenURL != "" {
+		signed, err := signURL(req.URL.String(), rt)
+		if err != nil {
+			return err
+		}
+		req.URL, err = url.Parse(signed)
+		if err != nil {
+			return fmt.Errorf("token hook returned an invalid url %q: %v", signed, err)
+		}
+		req.Host = req.URL.Host
+	}
+
+	for name, value := range reqOptions.headers {
+		// the host header is not read from req.Header
+		if http.CanonicalHeaderKey(name) == "Host" {
+			req.Host = value
+			continue
+		}
+		req.Header.Set(name, value)
+	}
+
+	for _, cookie := range reqOptions.cookies {
+		// do not overwrite a fresh cookie from the jar
+		if _, err := req.Cookie(cookie.Name); err != nil {
+			req.AddCookie(cookie)
+		}
+	}
+
+	return nil
+}
+
+// signURL :
+// * return the signed version of this url
+// * signed urls are kept until they are due to be refreshed
+func signURL(unsigned string, rt http.RoundTripper) (string, error) {
+
+	signedURLsMutex.Lock()
+	defer signedURLsMutex.Unlock()
+
+	if s, ok := signedURLs[unsigned]; ok && time.Now().Before(s.expires) {
+		return s.url, nil
+	}
+
+	var signed string
+	var err error
+	if reqOptions.tokenScript != "" {
+		signed, err = signURLWithScript(unsigned)
+	} else {
+		signed, err = signURLWithTemplate(unsigned, rt)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	logging.DebugPrint(reqOptions.debugFile, reqOptions.debugLog, "DEBUG: ", "signed url "+unsigned+" as "+signed)
+
+	if reqOptions.tokenRefresh > 0 {
+		signedURLs[unsigned] = signedURL{url: signed, expires: time.Now().Add(reqOptions.tokenRefresh)}
+	}
+
+	return signed, nil
+}
+
+// signURLWithScript : call the token script with the unsigned url and read the signed url from stdout
+func signURLWithScript(unsigned string) (string, error) {
+
+	out, err := exec.Command(reqOptions.tokenScript, unsigned).Output()
+	if err != nil {
+		return "", fmt.Errorf("token script %s failed for %s: %v", reqOptions.tokenScript, unsigned, err)
+	}
+
+	return strings.TrimSpace(string(out)), nil
+}
+
+// signURLWithTemplate : request the token url template with the unsigned url and read the signed url from the body
+func signURLWithTemplate(unsigned string, rt http.RoundTripper) (string, error) {
+
+	tokenURL := strings.Replace(reqOptions.tokenURL, TokenURLPlaceholder, url.QueryEscape(unsigned), -1)
+
+	req, err := http.NewRequest("GET", tokenURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("invalid token url %s: %v", tokenURL, err)
+	}
+	// the token service gets our static headers, so it can authenticate us
+	for name, value := range reqOptions.headers {
+		req.Header.Set(name, value)
+	}
+
+	// use the undecorated transport, otherwise we would try to sign the token url
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		return "", fmt.Errorf("token url %s failed: %v", tokenURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token url %s returned status code %d", tokenURL, resp.StatusCode)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("unable to read token url %s: %v", tokenURL, err)
+	}
+
+	return strings.TrimSpace(string(body)), nil
+}
@@ -0,0 +1,82 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDecorateRequest(t *testing.T) {
+
+	defer func() { reqOptions = requestOptions{} }()
+	SetRequestOptions(map[string]string{"X-Session": "abc", "host": "cdn.example.com"}, "token=1; user=test", false, "", "", "", 0, "", false)
+
+	req, _ := http.NewRequest("GET", "http://origin.example.com/seg1.m4s", nil)
+	// a cookie from the jar is newer than our static cookie
+	req.AddCookie(&http.Cookie{Name: "user", Value: "fresh"})
+
+	if err := decorateRequest(req, nil); err != nil {
+		t.Fatal(err)
+	}
+	if req.Header.Get("X-Session") != "abc" {
+		t.Errorf("header X-Session is %q, want abc", req.Header.Get("X-Session"))
+	}
+	if req.Host != "cdn.example.com" {
+		t.Errorf("host is %q, want cdn.example.com", req.Host)
+	}
+	if c, err := req.Cookie("token"); err != nil || c.Value != "1" {
+		t.Errorf("static cookie token not added: %v", err)
+	}
+	if c, _ := req.Cookie("user"); c.Value != "fresh" || len(req.Cookies()) != 2 {
+		t.Errorf("cookies %v, the jar cookie should not be overwritten", req.Cookies())
+	}
+}
+
+func TestSignURL(t *testing.T) {
+
+	// the token service signs the url it is given
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprintf(w, "%s?sig=%d\n", r.URL.Query().Get("url"), requests)
+	}))
+	defer server.Close()
+
+	defer func() {
+		reqOptions = requestOptions{}
+		signedURLs = make(map[string]signedURL)
+	}()
+	SetRequestOptions(nil, "", false, "", "", server.URL+"/sign?url="+TokenURLPlaceholder, 60, "", false)
+
+	unsigned := "http://origin.example.com/seg1.m4s"
+	req, _ := http.NewRequest("GET", unsigned, nil)
+	if err := decorateRequest(req, http.DefaultTransport); err != nil {
+		t.Fatal(err)
+	}
+	if req.URL.String() != unsigned+"?sig=1" {
+		t.Errorf("signed url is %s, want %s?sig=1", req.URL, unsigned)
+	}
+
+	// the signed url is kept until it is due to be refreshed
+	if signed, err := signURL(unsigned, http.DefaultTransport); err != nil || signed != unsigned+"?sig=1" || requests != 1 {
+		t.Errorf("signed url is %s after %d requests, want the kept url", signed, requests)
+	}
+	signedURLs[unsigned] = signedURL{url: unsigned + "?sig=1", expires: time.Now().Add(-time.Second)}
+	if signed, _ := signURL(unsigned, http.DefaultTransport); signed != unsigned+"?sig=2" {
+		t.Errorf("signed url is %s, want it signed again once it expired", signed)
+	}
+}
+
+func TestGrabClientShared(t *testing.T) {
+
+	first, httpClient := getGrabClient(false, "", false, false)
+	second, _ := getGrabClient(false, "", false, false)
+	if first != second {
+		t.Error("the progressive downloads built a second grab client")
+	}
+	if first.HTTPClient != httpClient {
+		t.Error("the grab client does not use our http client")
+	}
+}
@@ -0,0 +1,234 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"github.com/quic-go/quic-go"
+
+	xlayer "github.com/uccmisl/godash/crosslayer"
+	abrqlog "github.com/uccmisl/godash/qlog"
+)
+
+// RequestTiming :
+// * the phases of one request
+// * DNS, Connect and TLS are 0 when the request reused a connection
+// * over QUIC the transport and TLS handshakes are one handshake, which is given as TLS (Connect is 0)
+type RequestTiming struct {
+	DNS     time.Duration
+	Connect time.Duration
+	TLS     time.Duration
+	// from the start of the request to the first byte of the response, and to the last byte of the body
+	TTFB time.Duration
+	TTLB time.Duration
+	// the request used a connection that was already open
+	Reused bool
+	// the connection, its local address over TCP and its connection ID over QUIC
+	connID string
+
+	// the trace hooks can be called from the dialing goroutines, so every field is guarded by mu
+	// the caller reads the fields from the snapshot returned by done
+	mu           *sync.Mutex
+	start        time.Time
+	dnsStart     time.Time
+	connectStart time.Time
+	tlsStart     time.Time
+}
+
+// newRequestTiming : start timing a request now
+func newRequestTiming() *RequestTiming {
+	return &RequestTiming{mu: &sync.Mutex{}, start: time.Now()}
+}
+
+// withClientTrace :
+// * add a httptrace.ClientTrace to the request that fills in the timing of each phase
+// * the http3 client does not call these hooks, see quicDial for QUIC
+func (t *RequestTiming) withClientTrace(ctx context.Context, req *http.Request) *http.Request {
+
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { t.update(func() { t.dnsStart = time.Now() }) },
+		DNSDone:  func(httptrace.DNSDoneInfo) { t.update(func() { t.DNS = time.Since(t.dnsStart) }) },
+		ConnectStart: func(string, string) {
+			t.update(func() {
+				// with happy eyeballs there can be more than one connection attempt, time the first
+				if t.connectStart.IsZero() {
+					t.connectStart = time.Now()
+				}
+			})
+		},
+		ConnectDone: func(_ string, _ string, err error) {
+			t.update(func() {
+				if err == nil && t.Connect == 0 {
+					t.Connect = time.Since(t.connectStart)
+				}
+			})
+		},
+		TLSHandshakeStart: func() { t.update(func() { t.tlsStart = time.Now() }) },
+		TLSHandshakeDone:  func(tls.ConnectionState, error) { t.update(func() { t.TLS = time.Since(t.tlsStart) }) },
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.update(func() {
+				t.Reused = info.Reused
+				t.connID = info.Conn.LocalAddr().String()
+			})
+		},
+		GotFirstResponseByte: func() {
+			t.update(func() { t.TTFB = time.Since(t.start) })
+		},
+	}
+	return req.WithContext(httptrace.WithClientTrace(ctx, trace))
+}
+
+// update : change the fields of the timing while holding its lock
+func (t *RequestTiming) update(f func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	f()
+}
+
+// connection : the connection of the request, once the client has dialled or reused one
+func (t *RequestTiming) connection() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.connID
+}
+
+// responseStarted : if the trace hooks did not see the first byte of the response, it arrived now
+func (t *RequestTiming) responseStarted() {
+	t.update(func() {
+		if t.TTFB == 0 {
+			t.TTFB = time.Since(t.start)
+		}
+	})
+}
+
+// quicDialTiming : the DNS and handshake times of a new QUIC connection
+type quicDialTiming struct {
+	dns       time.Duration
+	handshake time.Duration
+}
+
+// new QUIC connections, by address, waiting for the request that opened them
+var quicDials = make(map[string]quicDialTiming)
+
+// the ID of the latest QUIC connection to each address, the http3 client keeps one connection per address
+var quicConnections = make(map[string]string)
+var quicDialsMutex sync.Mutex
+
+// quicDial :
+// * the http3.RoundTripper dial function - time the DNS lookup and the handshake of each new connection
+// * the request that opened the connection takes these times in addQuicDial
+func quicDial(ctx context.Context, addr string, tlsCfg *tls.Config, cfg *quic.Config) (quic.EarlyConnection, error) {
+
+	var timing quicDialTiming
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	dialAddr := addr
+	if net.ParseIP(host) == nil {
+		dnsStart := time.Now()
+		ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		timing.dns = time.Since(dnsStart)
+		dialAddr = net.JoinHostPort(ips[0].String(), port)
+	}
+
+	// the certificate is still checked against the host name
+	if tlsCfg == nil {
+		tlsCfg = &tls.Config{}
+	}
+	if tlsCfg.ServerName == "" {
+		tlsCfg = tlsCfg.Clone()
+		tlsCfg.ServerName = host
+	}
+
+	// the tracer gives us the ID of the new connection
+	var connID string
+	ctx = xlayer.WithConnectionID(ctx, &connID)
+
+	handshakeStart := time.Now()
+	session, err := quic.DialAddrEarly(ctx, dialAddr, tlsCfg, cfg)
+	if err != nil {
+		return nil, err
+	}
+	<-session.HandshakeComplete()
+	timing.handshake = time.Since(handshakeStart)
+
+	quicDialsMutex.Lock()
+	quicDials[addr] = timing
+	quicConnections[addr] = connID
+	quicDialsMutex.Unlock()
+
+	return session, nil
+}
+
+// addQuicDial : if this request opened a new QUIC connection, add its DNS and handshake times, otherwise the connection was reused - and add the connection ID
+func (t *RequestTiming) addQuicDial(req *http.Request) {
+
+	addr := req.URL.Host
+	if req.URL.Port() == "" {
+		addr = net.JoinHostPort(req.URL.Hostname(), "443")
+	}
+
+	quicDialsMutex.Lock()
+	timing, ok := quicDials[addr]
+	delete(quicDials, addr)
+	connID := quicConnections[addr]
+	quicDialsMutex.Unlock()
+
+	t.update(func() {
+		t.connID = connID
+		t.Reused = !ok
+		t.DNS = timing.dns
+		t.TLS = timing.handshake
+	})
+}
+
+// done : the last byte of the body has arrived - return a snapshot of the timing, which later trace hooks do not change
+func (t *RequestTiming) done() RequestTiming {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.TTLB = time.Since(t.start)
+	return *t
+}
+
+// qlog : the timing for the qlog request_update event
+func (t *RequestTiming) qlog() abrqlog.RequestTiming {
+	stats := abrqlog.NewRequestTiming()
+	stats.DNS = t.DNS
+	stats.Connect = t.Connect
+	stats.TLS = t.TLS
+	stats.TTFB = t.TTFB
+	stats.TTLB = t.TTLB
+	stats.Reused = t.Reused
+	return stats
+}
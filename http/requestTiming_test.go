@@ -0,0 +1,38 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"testing"
+)
+
+func TestRequestTimingHooks(t *testing.T) {
+
+	timing := newRequestTiming()
+	req, _ := http.NewRequest("GET", "http://origin.example.com/seg1.m4s", nil)
+	trace := httptrace.ContextClientTrace(timing.withClientTrace(context.Background(), req).Context())
+
+	// with happy eyeballs the hooks of each connection attempt are called from its own goroutine
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			trace.ConnectStart("tcp", "192.0.2.1:80")
+			trace.ConnectDone("tcp", "192.0.2.1:80", nil)
+		}()
+	}
+	trace.GotFirstResponseByte()
+	timing.connection()
+	snapshot := timing.done()
+	wg.Wait()
+
+	if snapshot.TTFB == 0 || snapshot.TTLB < snapshot.TTFB {
+		t.Errorf("TTFB %v and TTLB %v, want the last byte after the first", snapshot.TTFB, snapshot.TTLB)
+	}
+	if timing.done().Connect == 0 {
+		t.Error("the connect time of the first attempt was not kept")
+	}
+}
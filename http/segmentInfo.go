@@ -0,0 +1,159 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/uccmisl/godash/isobmff"
+	"github.com/uccmisl/godash/logging"
+
+	abrqlog "github.com/uccmisl/godash/qlog"
+)
+
+// RepresentationKey : a representation of a stream - the index of its MPD, adaptation set and representation
+type RepresentationKey struct {
+	MPD            int
+	AdaptationSet  int
+	Representation int
+}
+
+// SegmentInits :
+// * the tracks from the init segment of each representation of a stream, they give the timescale and default sample values of its media segments
+// * each stream keeps its own in its StreamStruct
+type SegmentInits struct {
+	inits map[RepresentationKey]*isobmff.Init
+	// the latest init segment of each adaptation set, for the representations we have no init segment for
+	latest map[RepresentationKey]*isobmff.Init
+}
+
+// NewSegmentInits : the init segments of a stream, before any are downloaded
+func NewSegmentInits() *SegmentInits {
+	return &SegmentInits{inits: make(map[RepresentationKey]*isobmff.Init), latest: make(map[RepresentationKey]*isobmff.Init)}
+}
+
+/**
+* Returns if we have the init segment of the representation
+ */
+func (s *SegmentInits) Has(rep RepresentationKey) bool {
+	_, ok := s.inits[rep]
+	return ok
+}
+
+// add : keep the init segment of a representation
+func (s *SegmentInits) add(rep RepresentationKey, init *isobmff.Init) {
+	s.inits[rep] = init
+	s.latest[RepresentationKey{MPD: rep.MPD, AdaptationSet: rep.AdaptationSet}] = init
+}
+
+// get : the init segment of a representation, or the latest of its adaptation set if we do not have it
+func (s *SegmentInits) get(rep RepresentationKey) *isobmff.Init {
+	if init, ok := s.inits[rep]; ok {
+		return init
+	}
+	return s.latest[RepresentationKey{MPD: rep.MPD, AdaptationSet: rep.AdaptationSet}]
+}
+
+// Fetch :
+// * get and read the init segment of a representation, before the first media segment of the representation is read
+// * only MPDs with a SegmentTemplate have an init segment for each representation
+// * if the request fails, the media segments are read with the latest init segment of the adaptation set
+func (s *SegmentInits) Fetch(mpd MPD, currentURL string, rep RepresentationKey, quicBool bool, debugFile string, debugLog bool, useTestbedBool bool, tracer *abrqlog.StreamTracer) {
+
+	initURL := representationInitURL(mpd, currentURL, rep.AdaptationSet, rep.Representation, debugLog)
+	if initURL == "" {
+		return
+	}
+
+	logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "getting the init segment "+initURL+" of rep_rate "+fmt.Sprint(rep.Representation))
+	content, status, err := getURLContent(initURL, quicBool, debugFile, debugLog, useTestbedBool, tracer)
+	if err != nil || status != http.StatusOK {
+		logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "unable to get the init segment "+initURL+": "+fmt.Sprint(status, err))
+		return
+	}
+	init, err := isobmff.ParseInit(content)
+	if err != nil || init == nil {
+		logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "unable to read the init segment boxes of "+initURL+": "+fmt.Sprint(err))
+		return
+	}
+	s.add(rep, init)
+}
+
+// representationInitURL : the url of the init segment of a representation, "" if the MPD has no SegmentTemplate init segment
+func representationInitURL(mpd MPD, currentURL string, adaptIndex int, repIndex int, debugLog bool) string {
+
+	adaptationSet := mpd.Periods[0].AdaptationSet[adaptIndex]
+	if len(adaptationSet.SegmentTemplate) == 0 || adaptationSet.SegmentTemplate[0].Initialization == "" {
+		return ""
+	}
+	rep := adaptationSet.Representation[repIndex]
+
+	initURL := GetFullStreamHeader(mpd, false, adaptIndex, false, repIndex)
+	initURL = strings.Replace(initURL, "$Bandwidth$", strconv.Itoa(rep.BandWidth), -1)
+	initURL = strings.Replace(initURL, "$RepresentationID$", rep.ID, -1)
+	return JoinURL(currentURL, adaptationSet.BaseURL+initURL, debugLog)
+}
+
+// inspectSegment :
+// * read the boxes of a downloaded file, from the scanner it was written to
+// * init segments are kept for their representation, media segments are read with the init segment of their representation
+// * return the parsed media segment, nil if this is an init segment or not a fragmented MP4 segment
+// * and if this is an init segment
+func inspectSegment(scanner *isobmff.Scanner, inits *SegmentInits, rep RepresentationKey, debugFile string, debugLog bool) (*isobmff.Segment, bool) {
+
+	init, err := scanner.Init()
+	if err != nil {
+		logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "unable to read the init segment boxes: "+err.Error())
+		return nil, false
+	}
+	if init != nil {
+		inits.add(rep, init)
+		for _, t := range init.Tracks {
+			logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "init segment track "+fmt.Sprint(t.ID)+": "+t.Handler+" "+t.SampleEntry+
+				", timescale "+fmt.Sprint(t.Timescale)+", "+fmt.Sprint(t.Width)+"x"+fmt.Sprint(t.Height))
+		}
+		return nil, true
+	}
+
+	segment, err := scanner.Segment(inits.get(rep))
+	if err != nil {
+		logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "unable to read the segment boxes: "+err.Error())
+		return nil, false
+	}
+	if segment == nil {
+		return nil, false
+	}
+
+	logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "segment payload is "+fmt.Sprint(segment.PayloadBytes())+" bytes, "+
+		fmt.Sprint(segment.SampleCount())+" samples at "+fmt.Sprintf("%.3f", segment.FrameRate())+" fps, keyframes at "+fmt.Sprint(segment.KeyframePositions()))
+	if times := segment.DecodeTimes(); len(times) > 0 {
+		logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "segment decode time starts at "+times[0].String()+", duration "+segment.Duration().String())
+	}
+	for _, emsg := range segment.Emsgs {
+		logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "emsg "+emsg.SchemeIDURI+" value "+emsg.Value+" id "+fmt.Sprint(emsg.ID))
+	}
+
+	return segment, false
+}
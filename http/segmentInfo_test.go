@@ -0,0 +1,31 @@
+package http
+
+import (
+	"testing"
+
+	"github.com/uccmisl/godash/isobmff"
+)
+
+func TestSegmentInitsByRepresentation(t *testing.T) {
+
+	inits := NewSegmentInits()
+	low := RepresentationKey{MPD: 0, AdaptationSet: 1, Representation: 3}
+	high := RepresentationKey{MPD: 0, AdaptationSet: 1, Representation: 0}
+	audio := RepresentationKey{MPD: 0, AdaptationSet: 2, Representation: 0}
+	lowInit := &isobmff.Init{Tracks: []isobmff.Track{{ID: 1, Timescale: 90000}}}
+	highInit := &isobmff.Init{Tracks: []isobmff.Track{{ID: 1, Timescale: 12800}}}
+
+	inits.add(low, lowInit)
+	if !inits.Has(low) || inits.Has(high) {
+		t.Fatal("only the init segment of the lowest representation was added")
+	}
+	// until we have its own, a representation is read with the latest init segment of its adaptation set
+	if inits.get(high) != lowInit || inits.get(audio) != nil {
+		t.Error("a representation without an init segment")
+	}
+
+	inits.add(high, highInit)
+	if inits.get(high) != highInit || inits.get(low) != lowInit {
+		t.Error("each representation is read with its own init segment")
+	}
+}
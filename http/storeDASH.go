@@ -0,0 +1,431 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package http
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/uccmisl/godash/logging"
+	"github.com/uccmisl/godash/utils"
+
+	abrqlog "github.com/uccmisl/godash/qlog"
+)
+
+// StoredMPDName : the MPD written by storeDASH, it plays back the segments the player fetched
+const StoredMPDName = "storeDASH.mpd"
+
+// concatenate each track into a single fragmented MP4 at the end of the session
+var storeConcat bool
+
+// the init segment stored for each representation, by file prefix
+var storedInits = make(map[string]string)
+
+// the number of versions of each segment stored in this session, and the sha256 of each stored file
+var storedNames = make(map[string]int)
+var storedSums = make(map[string][]byte)
+
+// SetStoreDASH : if concat is true, every track is also written as a single fragmented MP4
+func SetStoreDASH(concat bool) {
+	storeConcat = concat
+}
+
+// storedFilePrefix :
+// * the start of the name of every file stored for this representation
+// * names do not depend on the segment url, so every representation gets its own files
+func storedFilePrefix(fileLocation string, segmentDuration int, profile string, mediaType abrqlog.MediaType, repRate int) string {
+	return filepath.Join(fileLocation, strconv.Itoa(segmentDuration)+"sec_"+profile+"_"+mediaType.String()+"_rep"+strconv.Itoa(repRate))
+}
+
+// storedFileName :
+// * the name to store an init or media segment as
+// * a segment that is downloaded again (replaced) is stored next to the first version, with a "_v2" suffix
+// * unless it has the same sha256 as a version we already stored, then exists is true
+func storedFileName(prefix string, segmentNumber int, isInit bool, sum []byte) (createFile string, exists bool) {
+
+	if isInit {
+		return prefix + "_init.mp4", false
+	}
+
+	base := prefix + "_" + strconv.Itoa(segmentNumber)
+	versionName := func(version int) string {
+		if version == 1 {
+			return base + ".m4s"
+		}
+		return base + "_v" + strconv.Itoa(version) + ".m4s"
+	}
+
+	for version := 1; version <= storedNames[base]; version++ {
+		if sum != nil && bytes.Equal(storedSums[versionName(version)], sum) {
+			return versionName(version), true
+		}
+	}
+	storedNames[base]++
+	createFile = versionName(storedNames[base])
+	if sum != nil {
+		storedSums[createFile] = sum
+	}
+	return createFile, false
+}
+
+// storeDownload :
+// * move a downloaded segment from its temporary file to its stored name, and return that name
+// * encrypted content is decrypted, if we have the key - this needs the whole segment in memory
+func storeDownload(tmpFile string, sum []byte, prefix string, segmentNumber int, isInit bool, mediaType abrqlog.MediaType, debugFile string, debugLog bool) string {
+
+	createFile, exists := storedFileName(prefix, segmentNumber, isInit, sum)
+	if exists {
+		logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "segment is the same as the stored "+createFile)
+		os.Remove(tmpFile)
+		return createFile
+	}
+
+	var err error
+	if isInit || canDecrypt(mediaType) {
+		var content []byte
+		if content, err = ioutil.ReadFile(tmpFile); err == nil {
+			err = ioutil.WriteFile(tmpFile, decryptFile(content, mediaType, debugFile, debugLog), 0644)
+		}
+	}
+	if err == nil {
+		err = os.Chmod(tmpFile, 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmpFile, createFile)
+	}
+	if err != nil {
+		fmt.Println("*** " + createFile + " cannot be saved ***")
+		// stop the app
+		utils.StopApp()
+	}
+
+	if isInit {
+		storedInits[prefix] = createFile
+	}
+	if sum != nil {
+		logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "segment stored as "+createFile+", sha256 "+hex.EncodeToString(sum))
+	}
+	return createFile
+}
+
+// storeFile : save an init or media segment we have in memory and return the file name
+func storeFile(content []byte, prefix string, segmentNumber int, isInit bool) string {
+
+	createFile, _ := storedFileName(prefix, segmentNumber, isInit, nil)
+	if err := ioutil.WriteFile(createFile, content, 0644); err != nil {
+		fmt.Println("*** " + createFile + " cannot be saved ***")
+		// stop the app
+		utils.StopApp()
+	}
+
+	if isInit {
+		storedInits[prefix] = createFile
+	}
+
+	return createFile
+}
+
+// shareFile :
+// * collaborative clients find each others files by the base name of the url
+// * so link the stored file to that name as well
+func shareFile(storedFile string, sharedFile string, debugFile string, debugLog bool) {
+	os.Remove(sharedFile)
+	if err := os.Link(storedFile, sharedFile); err != nil {
+		content, err := ioutil.ReadFile(storedFile)
+		if err == nil {
+			err = ioutil.WriteFile(sharedFile, content, 0644)
+		}
+		if err != nil {
+			logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "unable to share "+storedFile+" as "+sharedFile+": "+err.Error())
+		}
+	}
+}
+
+// the MPD written by storeDASH - one Period for each run of segments from the same representations
+type storedMPD struct {
+	XMLName                   xml.Name       `xml:"MPD"`
+	Xmlns                     string         `xml:"xmlns,attr"`
+	Profiles                  string         `xml:"profiles,attr"`
+	Type                      string         `xml:"type,attr"`
+	MinBufferTime             string         `xml:"minBufferTime,attr"`
+	MediaPresentationDuration string         `xml:"mediaPresentationDuration,attr"`
+	Periods                   []storedPeriod `xml:"Period"`
+}
+
+type storedPeriod struct {
+	ID             string                `xml:"id,attr"`
+	Start          string                `xml:"start,attr"`
+	Duration       string                `xml:"duration,attr"`
+	AdaptationSets []storedAdaptationSet `xml:"AdaptationSet"`
+}
+
+type storedAdaptationSet struct {
+	ID             int                  `xml:"id,attr"`
+	ContentType    string               `xml:"contentType,attr,omitempty"`
+	Lang           string               `xml:"lang,attr,omitempty"`
+	Representation storedRepresentation `xml:"Representation"`
+}
+
+type storedRepresentation struct {
+	ID                string            `xml:"id,attr"`
+	MimeType          string            `xml:"mimeType,attr"`
+	Codecs            string            `xml:"codecs,attr,omitempty"`
+	Bandwidth         int               `xml:"bandwidth,attr"`
+	Width             int               `xml:"width,attr,omitempty"`
+	Height            int               `xml:"height,attr,omitempty"`
+	FrameRate         int               `xml:"frameRate,attr,omitempty"`
+	AudioSamplingRate int               `xml:"audioSamplingRate,attr,omitempty"`
+	SegmentList       storedSegmentList `xml:"SegmentList"`
+}
+
+type storedSegmentList struct {
+	Timescale              int                `xml:"timescale,attr"`
+	Duration               int                `xml:"duration,attr"`
+	PresentationTimeOffset int                `xml:"presentationTimeOffset,attr,omitempty"`
+	Initialization         *storedURL         `xml:"Initialization,omitempty"`
+	SegmentURLs            []storedSegmentURL `xml:"SegmentURL"`
+}
+
+type storedURL struct {
+	SourceURL string `xml:"sourceURL,attr"`
+}
+
+type storedSegmentURL struct {
+	Media string `xml:"media,attr"`
+}
+
+// WriteStoredSession :
+// * write a MPD that references exactly the segments the player fetched, segment by segment
+// * a new Period starts every time a track changes representation (or MPD)
+// * fetch the init segment of any representation we did not get the init segment for
+// * if set, write each track as a single fragmented MP4
+func WriteStoredSession(mpdList []MPD, logs []map[int]logging.SegPrintLogInformation, mediaTypes []abrqlog.MediaType, urlInput []string,
+	isByteRangeMPD bool, fileLocation string, quicBool bool, debugFile string, debugLog bool, useTestbedBool bool, tracer *abrqlog.StreamTracer) {
+
+	// the init segment for each track and segment
+	initFile := func(track int, seg logging.SegPrintLogInformation) string {
+		prefix := storedFilePrefix(fileLocation, seg.SegmentDuration, seg.Profile, mediaTypes[track], seg.RepIndex)
+		if _, ok := storedInits[prefix]; !ok && !isByteRangeMPD {
+			fetchStoredInit(mpdList[seg.MpdIndex], urlInput[seg.MpdIndex], seg, mediaTypes[track], prefix, quicBool, debugFile, debugLog, useTestbedBool, tracer)
+		}
+		return storedInits[prefix]
+	}
+
+	// the number of segments played - segment maps start at 1
+	numSegments := 0
+	for _, log := range logs {
+		numSegments = utils.Max(numSegments, len(log))
+	}
+
+	mpd := storedMPD{
+		Xmlns:    "urn:mpeg:dash:schema:mpd:2011",
+		Profiles: "urn:mpeg:dash:profile:full:2011",
+		Type:     "static",
+	}
+
+	// the representation (and MPD) of each track for a segment
+	repKey := func(segmentNumber int) string {
+		var key []string
+		for _, log := range logs {
+			if seg, ok := log[segmentNumber]; ok && seg.SegmentFileName != "" {
+				key = append(key, strconv.Itoa(seg.MpdIndex)+":"+strconv.Itoa(seg.RepIndex))
+			} else {
+				key = append(key, "-")
+			}
+		}
+		return strings.Join(key, ",")
+	}
+
+	startMs := 0
+	maxSegmentMs := 0
+	for segmentNumber := 1; segmentNumber <= numSegments; {
+
+		// find the run of segments that use the same representations
+		key := repKey(segmentNumber)
+		last := segmentNumber
+		for last+1 <= numSegments && repKey(last+1) == key {
+			last++
+		}
+
+		period := storedPeriod{ID: strconv.Itoa(len(mpd.Periods) + 1), Start: msToDuration(startMs)}
+		periodMs := 0
+
+		for track, log := range logs {
+			first, ok := log[segmentNumber]
+			if !ok || first.SegmentFileName == "" {
+				continue
+			}
+			adaptationSet := mpdList[first.MpdIndex].Periods[0].AdaptationSet[first.AdaptIndex]
+			rep := adaptationSet.Representation[first.RepIndex]
+
+			mimeType := rep.MimeType
+			if mimeType == "" {
+				mimeType = adaptationSet.MimeType
+			}
+			repID := rep.ID
+			if repID == "" {
+				repID = strconv.Itoa(first.RepIndex)
+			}
+
+			segmentList := storedSegmentList{
+				Timescale:              1000,
+				Duration:               first.SegmentDuration * 1000,
+				PresentationTimeOffset: first.DecodeTime,
+			}
+			if init := initFile(track, first); init != "" {
+				segmentList.Initialization = &storedURL{SourceURL: filepath.Base(init)}
+			}
+
+			trackMs := 0
+			for n := segmentNumber; n <= last; n++ {
+				segmentList.SegmentURLs = append(segmentList.SegmentURLs, storedSegmentURL{Media: filepath.Base(log[n].SegmentFileName)})
+				trackMs += log[n].SegmentDuration * 1000
+				maxSegmentMs = utils.Max(maxSegmentMs, log[n].SegmentDuration*1000)
+			}
+			periodMs = utils.Max(periodMs, trackMs)
+
+			period.AdaptationSets = append(period.AdaptationSets, storedAdaptationSet{
+				ID:          track,
+				ContentType: mediaTypes[track].String(),
+				Lang:        adaptationSet.Lang,
+				Representation: storedRepresentation{
+					ID:                repID,
+					MimeType:          mimeType,
+					Codecs:            rep.Codecs,
+					Bandwidth:         rep.BandWidth,
+					Width:             rep.Width,
+					Height:            rep.Height,
+					FrameRate:         rep.FrameRate,
+					AudioSamplingRate: rep.AudioSamplingRate,
+					SegmentList:       segmentList,
+				},
+			})
+		}
+
+		period.Duration = msToDuration(periodMs)
+		mpd.Periods = append(mpd.Periods, period)
+		startMs += periodMs
+		segmentNumber = last + 1
+	}
+
+	mpd.MediaPresentationDuration = msToDuration(startMs)
+	mpd.MinBufferTime = msToDuration(maxSegmentMs)
+
+	out, err := xml.MarshalIndent(mpd, "", "  ")
+	if err != nil {
+		fmt.Println("*** unable to create the " + StoredMPDName + " file ***")
+		return
+	}
+	mpdFile := filepath.Join(fileLocation, StoredMPDName)
+	if err := ioutil.WriteFile(mpdFile, append([]byte(xml.Header), out...), 0644); err != nil {
+		fmt.Println("*** " + mpdFile + " cannot be saved ***")
+		return
+	}
+	logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "storeDASH MPD written to "+mpdFile+" with "+strconv.Itoa(len(mpd.Periods))+" period(s)")
+
+	if storeConcat {
+		for track, log := range logs {
+			concatenateTrack(log, initFile, track, filepath.Join(fileLocation, mediaTypes[track].String()+".mp4"), debugFile, debugLog)
+		}
+	}
+}
+
+// fetchStoredInit :
+// * download and store the init segment of a representation the player switched to
+// * it is decrypted like the init segments the player downloaded, and not stored if the request fails
+func fetchStoredInit(mpd MPD, currentURL string, seg logging.SegPrintLogInformation, mediaType abrqlog.MediaType, prefix string, quicBool bool, debugFile string, debugLog bool, useTestbedBool bool, tracer *abrqlog.StreamTracer) {
+
+	initURL := representationInitURL(mpd, currentURL, seg.AdaptIndex, seg.RepIndex, debugLog)
+	if initURL == "" {
+		return
+	}
+
+	logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "storeDASH getting the init segment "+initURL)
+	content, status, err := getURLContent(initURL, quicBool, debugFile, debugLog, useTestbedBool, tracer)
+	if err != nil {
+		logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "storeDASH unable to get the init segment "+initURL+": "+err.Error())
+		return
+	}
+	if status != http.StatusOK {
+		logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "storeDASH init segment "+initURL+" returned the status code "+strconv.Itoa(status))
+		return
+	}
+
+	// the key for this representation is the same as for the init segment of the first representation
+	storeFile(decryptFile(content, mediaType, debugFile, debugLog), prefix, 0, true)
+}
+
+// concatenateTrack :
+// * write the init segment of the first representation, then every media segment, as one file
+// * decoders that take the parameter sets from the samples (avc3, hev1) can also follow representation switches
+func concatenateTrack(log map[int]logging.SegPrintLogInformation, initFile func(int, logging.SegPrintLogInformation) string, track int, createFile string, debugFile string, debugLog bool) {
+
+	if len(log) == 0 {
+		return
+	}
+
+	out, err := os.Create(createFile)
+	if err != nil {
+		fmt.Println("*** " + createFile + " cannot be created ***")
+		return
+	}
+	defer out.Close()
+
+	var files []string
+	if init := initFile(track, log[1]); init != "" {
+		files = append(files, init)
+	}
+	for n := 1; n <= len(log); n++ {
+		if log[n].SegmentFileName != "" {
+			files = append(files, log[n].SegmentFileName)
+		}
+	}
+
+	for _, file := range files {
+		in, err := os.Open(file)
+		if err != nil {
+			logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "storeDASH cannot read "+file+": "+err.Error())
+			continue
+		}
+		_, err = io.Copy(out, in)
+		in.Close()
+		if err != nil {
+			fmt.Println("*** " + createFile + " cannot be saved ***")
+			return
+		}
+	}
+	logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "storeDASH track written to "+createFile)
+}
+
+// msToDuration : an xs:duration for a number of milliseconds
+func msToDuration(ms int) string {
+	return "PT" + strconv.FormatFloat(float64(ms)/1000, 'f', -1, 64) + "S"
+}
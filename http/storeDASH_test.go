@@ -0,0 +1,39 @@
+package http
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	abrqlog "github.com/uccmisl/godash/qlog"
+)
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+// the init segments fetched when the session is stored are not stored if the server does not return them
+func TestGetURLContentStatus(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/init.mp4" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("ftyp"))
+	}))
+	defer server.Close()
+	tracer := abrqlog.NewStreamTracer(nopWriteCloser{io.Discard}, abrqlog.PerspectiveClient, "")
+
+	content, status, err := getURLContent(server.URL+"/init.mp4", false, "", false, false, tracer)
+	if err != nil || status != http.StatusOK || string(content) != "ftyp" {
+		t.Errorf("init segment %q, status %d, error %v", content, status, err)
+	}
+	if _, status, err = getURLContent(server.URL+"/missing.mp4", false, "", false, false, tracer); err != nil || status != http.StatusNotFound {
+		t.Errorf("missing init segment status %d, error %v", status, err)
+	}
+	if _, _, err = getURLContent("http://127.0.0.1:1/init.mp4", false, "", false, false, tracer); err == nil {
+		t.Error("a request that fails should return its error")
+	}
+}
@@ -0,0 +1,142 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package http
+
+import (
+	"context"
+	"net"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// TCPInfoInterval : how often TCP_INFO is read from the connection while a segment downloads
+const TCPInfoInterval = 50 * time.Millisecond
+
+// tcpConn : a connection opened by our http client, which removes itself from tcpConns and the accountant when closed
+type tcpConn struct {
+	net.Conn
+	raw syscall.RawConn
+}
+
+func (c *tcpConn) Close() error {
+	tcpConnsMutex.Lock()
+	delete(tcpConns, c.LocalAddr().String())
+	tcpConnsMutex.Unlock()
+	if globAccountant != nil {
+		globAccountant.CloseConnection(c.LocalAddr().String())
+	}
+	return c.Conn.Close()
+}
+
+// the open TCP connections, by local address
+var tcpConns = make(map[string]*tcpConn)
+var tcpConnsMutex sync.Mutex
+
+// dialTCP :
+// * the http.Transport dial function - keep each new connection, so we can read its TCP_INFO
+// * the request finds its connection by the local address it gets in the GotConn trace hook
+func dialTCP(ctx context.Context, network, addr string) (net.Conn, error) {
+
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	conn, err := dialer.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+	tcp, ok := conn.(*net.TCPConn)
+	if !ok {
+		return conn, nil
+	}
+	raw, err := tcp.SyscallConn()
+	if err != nil {
+		return conn, nil
+	}
+
+	c := &tcpConn{Conn: conn, raw: raw}
+	tcpConnsMutex.Lock()
+	tcpConns[conn.LocalAddr().String()] = c
+	tcpConnsMutex.Unlock()
+	return c, nil
+}
+
+// tcpSampler : reads the TCP_INFO of one connection every TCPInfoInterval, and reports it to the cross layer accountant
+type tcpSampler struct {
+	connID string
+	conn   *tcpConn
+	quit   chan struct{}
+	done   chan struct{}
+}
+
+// startTCPSampler :
+// * start reading the TCP_INFO of the connection with this local address
+// * return nil if we did not dial the connection (QUIC) or the platform has no TCP_INFO
+// * through a proxy, this is the connection to the proxy
+func startTCPSampler(localAddr string) *tcpSampler {
+
+	if globAccountant == nil || !tcpInfoSupported {
+		return nil
+	}
+	tcpConnsMutex.Lock()
+	conn := tcpConns[localAddr]
+	tcpConnsMutex.Unlock()
+	if conn == nil {
+		return nil
+	}
+
+	s := &tcpSampler{connID: localAddr, conn: conn, quit: make(chan struct{}), done: make(chan struct{})}
+	go s.run()
+	return s
+}
+
+func (s *tcpSampler) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(TCPInfoInterval)
+	defer ticker.Stop()
+
+	s.sample()
+	for {
+		select {
+		case <-s.quit:
+			return
+		case <-ticker.C:
+			s.sample()
+		}
+	}
+}
+
+// sample : read TCP_INFO once and report it
+func (s *tcpSampler) sample() {
+	if sample, ok := readTCPInfo(s.conn.raw); ok {
+		globAccountant.ReportTransportSample(s.connID, sample)
+	}
+}
+
+// stop : stop sampling, after one last sample at the end of the download
+func (s *tcpSampler) stop() {
+	if s == nil {
+		return
+	}
+	close(s.quit)
+	<-s.done
+	s.sample()
+}
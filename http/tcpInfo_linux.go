@@ -0,0 +1,120 @@
+//go:build linux && !386
+// +build linux,!386
+
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package http
+
+import (
+	"syscall"
+	"time"
+	"unsafe"
+
+	xlayer "github.com/uccmisl/godash/crosslayer"
+)
+
+// tcpInfoSupported : this platform can read TCP_INFO - not linux/386, where getsockopt goes through socketcall
+const tcpInfoSupported = true
+
+// linux congestion avoidance states, from tcpi_ca_state
+const (
+	tcpCARecovery = 3
+	tcpCALoss     = 4
+)
+
+// tcpInfo : struct tcp_info from linux/tcp.h, up to tcpi_delivery_rate (linux 4.9)
+type tcpInfo struct {
+	State         uint8
+	CAState       uint8
+	Retransmits   uint8
+	Probes        uint8
+	Backoff       uint8
+	Options       uint8
+	WScale        uint8
+	Flags         uint8
+	RTO           uint32
+	ATO           uint32
+	SndMSS        uint32
+	RcvMSS        uint32
+	Unacked       uint32
+	Sacked        uint32
+	Lost          uint32
+	Retrans       uint32
+	Fackets       uint32
+	LastDataSent  uint32
+	LastAckSent   uint32
+	LastDataRecv  uint32
+	LastAckRecv   uint32
+	PMTU          uint32
+	RcvSsthresh   uint32
+	RTT           uint32 // microseconds
+	RTTVar        uint32 // microseconds
+	SndSsthresh   uint32
+	SndCwnd       uint32 // segments
+	AdvMSS        uint32
+	Reordering    uint32
+	RcvRTT        uint32
+	RcvSpace      uint32
+	TotalRetrans  uint32
+	PacingRate    uint64
+	MaxPacingRate uint64
+	BytesAcked    uint64
+	BytesReceived uint64
+	SegsOut       uint32
+	SegsIn        uint32
+	NotsentBytes  uint32
+	MinRTT        uint32 // microseconds
+	DataSegsIn    uint32
+	DataSegsOut   uint32
+	DeliveryRate  uint64 // bytes / second
+}
+
+// readTCPInfo :
+// * read TCP_INFO from the socket
+// * older kernels return a shorter struct, the fields they do not fill are left at 0
+func readTCPInfo(raw syscall.RawConn) (xlayer.TransportSample, bool) {
+
+	var info tcpInfo
+	size := uint32(unsafe.Sizeof(info))
+	var errno syscall.Errno
+
+	err := raw.Control(func(fd uintptr) {
+		_, _, errno = syscall.Syscall6(syscall.SYS_GETSOCKOPT, fd, syscall.IPPROTO_TCP, syscall.TCP_INFO,
+			uintptr(unsafe.Pointer(&info)), uintptr(unsafe.Pointer(&size)), 0)
+	})
+	if err != nil || errno != 0 {
+		return xlayer.TransportSample{}, false
+	}
+
+	return xlayer.TransportSample{
+		RTT:              time.Duration(info.RTT) * time.Microsecond,
+		RTTVariance:      time.Duration(info.RTTVar) * time.Microsecond,
+		MinRTT:           time.Duration(info.MinRTT) * time.Microsecond,
+		CongestionWindow: int64(info.SndCwnd) * int64(info.SndMSS),
+		SlowStart:        info.SndCwnd < info.SndSsthresh,
+		Recovery:         info.CAState == tcpCARecovery || info.CAState == tcpCALoss,
+		DeliveryRate:     int64(info.DeliveryRate),
+		SegmentsSent:     int(info.SegsOut),
+		Retransmits:      int(info.TotalRetrans),
+		BytesReceived:    int64(info.BytesReceived),
+	}, true
+}
@@ -0,0 +1,39 @@
+//go:build !linux || 386
+// +build !linux 386
+
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package http
+
+import (
+	"syscall"
+
+	xlayer "github.com/uccmisl/godash/crosslayer"
+)
+
+// tcpInfoSupported : this platform can read TCP_INFO
+const tcpInfoSupported = false
+
+// readTCPInfo : TCP_INFO is only read on linux
+func readTCPInfo(raw syscall.RawConn) (xlayer.TransportSample, bool) {
+	return xlayer.TransportSample{}, false
+}
@@ -23,15 +23,13 @@ package http
 
 import (
 	"bufio"
-	"bytes"
 	"context"
 	"crypto/tls"
 	"crypto/x509"
-	"encoding/hex"
 	"fmt"
 	"path/filepath"
 
-	"github.com/francoispqt/gojay"
+	"github.com/uccmisl/godash/isobmff"
 	"github.com/uccmisl/godash/logging"
 	"github.com/uccmisl/godash/utils"
 
@@ -50,16 +48,15 @@ import (
 	glob "github.com/uccmisl/godash/global"
 
 	"github.com/cavaliercoder/grab"
-	"github.com/lucas-clemente/quic-go"
-	"github.com/lucas-clemente/quic-go/http3"
-	quiclogging "github.com/lucas-clemente/quic-go/logging"
-	"github.com/lucas-clemente/quic-go/qlog"
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+	quiclogging "github.com/quic-go/quic-go/logging"
 
 	xlayer "github.com/uccmisl/godash/crosslayer"
 	abrqlog "github.com/uccmisl/godash/qlog"
 )
 
-// *** Copied from "github.com/lucas-clemente/quic-go/internal/utils"
+// *** Copied from "github.com/quic-go/quic-go/internal/utils"
 
 type bufferedWriteCloser struct {
 	*bufio.Writer
@@ -95,6 +92,9 @@ var client *http.Client = nil
 var tr *http.Transport
 var trQuic *http3.RoundTripper
 
+// grabClient : the client of the progressive downloads, built once on our http client
+var grabClient *grab.Client
+
 var globAccountant *xlayer.CrossLayerAccountant = nil
 
 // Sets the globAccountant to the given accountant object
@@ -147,14 +147,12 @@ func GetHTTPClient(quicBool bool, debugFile string, debugLog bool, useTestbedBoo
 		}
 	}
 
-	// TODO: remove, we now receive this channel from upper calls
-	//qlogEventChan := make(chan qlog.Event)
-
 	// if we want to use quic
 	if quicBool {
 		qconf := quic.Config{}
 		//qconf.KeepAlive = true
-		qconf.Tracer = qlog.NewTracer(func(_ quiclogging.Perspective, connID []byte) io.WriteCloser {
+		// each connection gets its own qlog file, and the accountant gets its events by connection ID
+		qconf.Tracer = globAccountant.Tracer(func(_ quiclogging.Perspective, connID []byte) io.WriteCloser {
 			filename := fmt.Sprintf("logs/client_%x.qlog", connID)
 			//filename := "logs/client.qlog"
 			f, err := os.Create(filename)
@@ -164,12 +162,7 @@ func GetHTTPClient(quicBool bool, debugFile string, debugLog bool, useTestbedBoo
 			}
 			log.Printf("Creating qlog file %s.\n", filename)
 			return NewBufferedWriteCloser(bufio.NewWriter(f), f)
-		},
-			globAccountant.EventChannel,
-		)
-		//go printQlogEvents(qlogEventChan)
-		//accountant := xlayer.CrossLayerAccountant{EventChannel: qlogEventChan}
-		//accountant.Listen()
+		})
 
 		// if we are not using the terstbed
 		if !useTestbedBool {
@@ -178,7 +171,9 @@ func GetHTTPClient(quicBool bool, debugFile string, debugLog bool, useTestbedBoo
 					RootCAs:            caCertPool,
 					InsecureSkipVerify: glob.InsecureSSL,
 				},
-				QuicConfig: &qconf,
+				QUICConfig: &qconf,
+				// time the DNS lookup and handshake of each connection
+				Dial: quicDial,
 			}
 			defer trQuic.Close()
 			client = &http.Client{
@@ -199,7 +194,7 @@ func GetHTTPClient(quicBool bool, debugFile string, debugLog bool, useTestbedBoo
 			// set up our http transport
 			logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "creating our http transport using our tls config for quic")
 
-			trQuic = &http3.RoundTripper{TLSClientConfig: quicConfig, QuicConfig: &qconf, DisableCompression: true}
+			trQuic = &http3.RoundTripper{TLSClientConfig: quicConfig, QUICConfig: &qconf, DisableCompression: true, Dial: quicDial}
 			// set up the client
 			logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "creating our client using our http transport and our tls config for quic")
 			client = &http.Client{Transport: trQuic}
@@ -219,7 +214,7 @@ func GetHTTPClient(quicBool bool, debugFile string, debugLog bool, useTestbedBoo
 			}
 			// set up our http transport
 			logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "creating our http transport using our tls config")
-			tr = &http.Transport{TLSClientConfig: config}
+			tr = &http.Transport{TLSClientConfig: config, Proxy: getProxy(), DialContext: dialTCP}
 			// set up the client
 			logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "creating our client using our http transport and our tls config")
 			client = &http.Client{Transport: tr}
@@ -231,20 +226,45 @@ func GetHTTPClient(quicBool bool, debugFile string, debugLog bool, useTestbedBoo
 				// this is set statically in the globalVar.go file (set to true if needed)
 				InsecureSkipVerify: glob.InsecureSSL,
 			}
-			tr = &http.Transport{TLSClientConfig: config}
+			tr = &http.Transport{TLSClientConfig: config, Proxy: getProxy(), DialContext: dialTCP}
 			client = &http.Client{Transport: tr}
 		}
 	}
 
+	// add our headers, cookies and signed urls to every request made by this client
+	// this covers the MPD, segment, progressive, HEAD and arbiter requests
+	client.Transport = &decoratedTransport{base: client.Transport}
+	if reqOptions.jar != nil {
+		logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "using a cookie jar for our client")
+		client.Jar = reqOptions.jar
+	}
+	if reqOptions.proxyURL != nil {
+		logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "using proxy "+reqOptions.proxyURL.Redacted())
+	}
+
 	return tr, client, trQuic
 
 }
 
+// getGrabClient :
+// * return the grab client of the progressive downloads
+// * it is built once, on our http client, so it has our headers, cookies, proxy and signed urls
+func getGrabClient(quicBool bool, debugFile string, debugLog bool, useTestbedBool bool) (*grab.Client, *http.Client) {
+
+	_, httpClient, _ := GetHTTPClient(quicBool, debugFile, debugLog, useTestbedBool)
+	if grabClient == nil {
+		grabClient = grab.NewClient()
+		grabClient.HTTPClient = httpClient
+	}
+
+	return grabClient, httpClient
+}
+
 // getURLBody :
 // * get the response body of the url
 // * calculate the rtt
 // * return the response body and the rtt
-func getURLBody(url string, isByteRangeMPD bool, startRange int, endRange int, quicBool bool, debugFile string, debugLog bool, useTestbedBool bool, returnContentLengthOnly bool, ctx context.Context) (io.ReadCloser, time.Duration, string, int, int) {
+func getURLBody(url string, isByteRangeMPD bool, startRange int, endRange int, quicBool bool, debugFile string, debugLog bool, useTestbedBool bool, returnContentLengthOnly bool, ctx context.Context, tracer *abrqlog.StreamTracer) (io.ReadCloser, time.Duration, string, int, int, *RequestTiming) {
 
 	var client *http.Client
 	var err error
@@ -262,7 +282,7 @@ func getURLBody(url string, isByteRangeMPD bool, startRange int, endRange int, q
 	if err != nil {
 		fmt.Println(err)
 		fmt.Println("the URL " + url + " doesn't match with anything")
-		abrqlog.MainTracer.AbortRequest(url)
+		tracer.AbortRequest(url)
 		// stop the app
 		utils.StopApp()
 	}
@@ -352,27 +372,37 @@ func getURLBody(url string, isByteRangeMPD bool, startRange int, endRange int, q
 
 	var resp *http.Response
 
-	// if we want to use quic
+	// time each phase of the request - DNS, connect, TLS, first and last byte
+	timing := newRequestTiming()
+	req = timing.withClientTrace(ctx, req)
+
 	// determine the rtt for this segment
 	start := time.Now()
-	if quicBool {
-		req = req.WithContext(ctx)
-		resp, err = client.Do(req)
-	} else {
-		//request the URL using the client
-		resp, err = client.Do(req)
-	}
+	//request the URL using the client
+	resp, err = client.Do(req)
 	// get rtt
 	end := time.Now()
 	rtt := end.Sub(start)
 
-	abrqlog.MainTracer.RTT.UpdateRTT(rtt, end)
-	abrqlog.MainTracer.UpdatedMetrics(abrqlog.MainTracer.RTT)
+	// the http3 client does not call the trace hooks, so use the times of its dial and the time the response arrived
+	if quicBool {
+		timing.addQuicDial(req)
+	}
+	timing.responseStarted()
 
+	tracer.RTT.UpdateRTT(rtt, end)
+	tracer.UpdatedMetrics(tracer.RTT)
+
+	// the player abandoned the download before the response arrived
+	if err != nil && ctx.Err() != nil {
+		logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "request for "+url+" abandoned: "+err.Error())
+		tracer.AbortRequest(url)
+		return nil, rtt, "", contentLen, StatusIncomplete, timing
+	}
 	if err != nil {
 		fmt.Println(err)
 		fmt.Println("the URL " + url + " doesn't match with anything")
-		abrqlog.MainTracer.AbortRequest(url)
+		tracer.AbortRequest(url)
 		// stop the app
 		utils.StopApp()
 	}
@@ -388,14 +418,14 @@ func getURLBody(url string, isByteRangeMPD bool, startRange int, endRange int, q
 	if resp.StatusCode != http.StatusOK && !isByteRangeMPD {
 		// add this to the debug log
 		logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "The URL returned a non status okay error code: "+strconv.Itoa(resp.StatusCode))
-		abrqlog.MainTracer.AbortRequest(url)
+		tracer.AbortRequest(url)
 		// stop the app
 		utils.StopApp()
 	}
 	//fmt.Println("len : ", resp.ContentLength)
 
 	// return the response body
-	return resp.Body, rtt, protocol, contentLen, status
+	return resp.Body, rtt, protocol, contentLen, status, timing
 
 }
 
@@ -403,28 +433,30 @@ func getURLBody(url string, isByteRangeMPD bool, startRange int, endRange int, q
 // * get the response body of the url
 // * calculate the rtt and throughtput for the download per second
 // * return the rtt
-func getURLProgressively(url string, isByteRangeMPD bool, startRange int, endRange int, fileLocation string) time.Duration {
+func getURLProgressively(url string, isByteRangeMPD bool, startRange int, endRange int, fileLocation string, quicBool bool, debugLog bool, useTestbedBool bool, tracer *abrqlog.StreamTracer) time.Duration {
 
 	var thrPerSecond []int64
 
-	// set up a http client
-	client := grab.NewClient()
+	// get the grab client on our http client
+	client, httpClient := getGrabClient(quicBool, glob.DebugFile, debugLog, useTestbedBool)
 	// request the url and save to a file location
 	req, err := grab.NewRequest(fileLocation, url)
 	// if there is an error, stop the app
 	if err != nil {
 		fmt.Println(err)
 		fmt.Println("the URL " + url + " doesn't match with anything")
-		abrqlog.MainTracer.AbortRequest(url)
+		tracer.AbortRequest(url)
 		// stop the app
 		utils.StopApp()
 	}
 
 	// determine the rtt for this segment
 	start := time.Now()
-	if _, err := http.DefaultTransport.RoundTrip(req.HTTPRequest); err != nil {
+	rttResp, err := httpClient.Do(req.HTTPRequest)
+	if err != nil {
 		log.Fatal(err)
 	}
+	rttResp.Body.Close()
 	// get rtt
 	rtt := time.Since(start)
 	//fmt.Printf("grab RTT in %dms for %s\n", rtt, url)
@@ -470,7 +502,7 @@ func getURLProgressively(url string, isByteRangeMPD bool, startRange int, endRan
 	// check for errors
 	if err := resp.Err(); err != nil {
 		fmt.Fprintf(os.Stderr, "Download failed: %v\n", err)
-		abrqlog.MainTracer.AbortRequest(url)
+		tracer.AbortRequest(url)
 		// stop the app
 		utils.StopApp()
 	}
@@ -493,25 +525,27 @@ func getURLProgressively(url string, isByteRangeMPD bool, startRange int, endRan
 // GetURLByteRangeBody :
 // * get the response body of the url and return an io.ReadCloser
 // * based on byte-ranges
-func GetURLByteRangeBody(url string, startRange int, endRange int) (io.ReadCloser, time.Duration) {
+func GetURLByteRangeBody(url string, startRange int, endRange int, tracer *abrqlog.StreamTracer) (io.ReadCloser, time.Duration) {
 
-	// set up a http client
-	client := &http.Client{}
+	// get our http client, the player has already set it up
+	_, httpClient, _ := GetHTTPClient(false, glob.DebugFile, false, false)
 	// request the url
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		fmt.Println(err)
 		fmt.Println("the URL " + url + " doesn't match with anything")
-		abrqlog.MainTracer.AbortRequest(url)
+		tracer.AbortRequest(url)
 		// stop the app
 		utils.StopApp()
 	}
 
 	//req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
 	start := time.Now()
-	if _, err := http.DefaultTransport.RoundTrip(req); err != nil {
+	rttResp, err := httpClient.Do(req)
+	if err != nil {
 		log.Fatal(err)
 	}
+	rttResp.Body.Close()
 	// get rtt
 	rtt := time.Since(start)
 
@@ -520,11 +554,11 @@ func GetURLByteRangeBody(url string, startRange int, endRange int) (io.ReadClose
 	req.Header.Add("Range", byteRange)
 
 	//request the URL using the client
-	resp, err := client.Do(req)
+	resp, err := httpClient.Do(req)
 	if err != nil {
 		fmt.Println(err)
 		fmt.Println("the URL " + url + " doesn't match with anything")
-		abrqlog.MainTracer.AbortRequest(url)
+		tracer.AbortRequest(url)
 		// stop the app
 		utils.StopApp()
 	}
@@ -533,7 +567,7 @@ func GetURLByteRangeBody(url string, startRange int, endRange int) (io.ReadClose
 	if resp.StatusCode != http.StatusOK {
 		// add this to the debug log
 		fmt.Println("The URL returned a non status okay error code: " + strconv.Itoa(resp.StatusCode))
-		abrqlog.MainTracer.AbortRequest(url)
+		tracer.AbortRequest(url)
 		// stop the app
 		utils.StopApp()
 	}
@@ -546,18 +580,18 @@ func GetURLByteRangeBody(url string, startRange int, endRange int) (io.ReadClose
 
 // GetURL :
 // * return the content of the body of the url
-func GetURL(url string, isByteRangeMPD bool, startRange int, endRange int, quicBool bool, debugFile string, debugLog bool, useTestbedBool bool) ([]byte, time.Duration, string) {
+func GetURL(url string, isByteRangeMPD bool, startRange int, endRange int, quicBool bool, debugFile string, debugLog bool, useTestbedBool bool, tracer *abrqlog.StreamTracer) ([]byte, time.Duration, string) {
 
 	byteRangeString := ""
 	if startRange != endRange {
 		byteRangeString = fmt.Sprint(startRange) + "-" + fmt.Sprint(endRange)
 	}
-	abrqlog.MainTracer.Request(abrqlog.MediaTypeOther, url, byteRangeString)
+	tracer.Request(abrqlog.MediaTypeOther, url, byteRangeString)
 
 	ctx2 := context.Background()
 
 	// get the response body and rtt for this url
-	responseBody, rtt, protocol, _, status := getURLBody(url, isByteRangeMPD, startRange, endRange, quicBool, debugFile, debugLog, useTestbedBool, false, ctx2)
+	responseBody, rtt, protocol, _, status, timing := getURLBody(url, isByteRangeMPD, startRange, endRange, quicBool, debugFile, debugLog, useTestbedBool, false, ctx2, tracer)
 
 	// Lets read from the http stream and not create a file to store the body
 	body, err := ioutil.ReadAll(responseBody)
@@ -565,12 +599,13 @@ func GetURL(url string, isByteRangeMPD bool, startRange int, endRange int, quicB
 	if err != nil {
 		fmt.Println("Unable to read from url")
 		fmt.Println("Statuscode:", status)
-		abrqlog.MainTracer.AbortRequest(url)
+		tracer.AbortRequest(url)
 		// stop the app
 		utils.StopApp()
 	}
 
-	abrqlog.MainTracer.RequestUpdate(url, int64(len(body)))
+	requestTiming := timing.done()
+	tracer.RequestComplete(url, int64(len(body)), requestTiming.qlog())
 
 	// close the responseBody
 	responseBody.Close()
@@ -579,6 +614,41 @@ func GetURL(url string, isByteRangeMPD bool, startRange int, endRange int, quicB
 	return body, rtt, protocol
 }
 
+// getURLContent :
+// * get the body of the url with the shared client, like GetURL, but without stopping the app if the request fails
+// * return the body and the status code, or the error of the request or of reading the body
+func getURLContent(url string, quicBool bool, debugFile string, debugLog bool, useTestbedBool bool, tracer *abrqlog.StreamTracer) ([]byte, int, error) {
+
+	tracer.Request(abrqlog.MediaTypeOther, url, "")
+
+	_, client, _ := GetHTTPClient(quicBool, debugFile, debugLog, useTestbedBool)
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		tracer.AbortRequest(url)
+		return nil, 0, err
+	}
+	timing := newRequestTiming()
+	req = timing.withClientTrace(context.Background(), req)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		tracer.AbortRequest(url)
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	timing.responseStarted()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		tracer.AbortRequest(url)
+		return nil, resp.StatusCode, err
+	}
+
+	requestTiming := timing.done()
+	tracer.RequestComplete(url, int64(len(body)), requestTiming.qlog())
+	return body, resp.StatusCode, nil
+}
+
 // GetRepresentationBaseURL :
 // * get BaseURL for byte-range MPD
 func GetRepresentationBaseURL(mpd MPD, currentMPDRepAdaptSet int) string {
@@ -617,11 +687,12 @@ func JoinURL(baseURL string, append string, debugLog bool) string {
 /*
  * Function getFile :
  * get the provided file from the online HTTP server and save to folder
+ * the boxes are read with the init segment of rep, from the init segments of the stream
  */
 func GetFile(currentURL string, fileBaseURL string, fileLocation string, isByteRangeMPD bool, startRange int, endRange int,
 	segmentNumber int, segmentDuration int, addSegDuration bool, quicBool bool, debugFile string, debugLog bool,
 	useTestbedBool bool, repRate int, saveFilesBool bool, AudioByteRange bool, profile string, mediaType abrqlog.MediaType,
-	ctx context.Context) (time.Duration, int, string, string, float64, int) {
+	ctx context.Context, inits *SegmentInits, rep RepresentationKey, tracer *abrqlog.StreamTracer) (time.Duration, int, string, string, float64, int, *isobmff.Segment, RequestTiming) {
 
 	// create the string where we want to save this file
 	var createFile string
@@ -658,46 +729,84 @@ func GetFile(currentURL string, fileBaseURL string, fileLocation string, isByteR
 	if startRange != endRange {
 		byteRangeString = fmt.Sprint(startRange) + "-" + fmt.Sprint(endRange)
 	}
-	abrqlog.MainTracer.Request(mediaType, urlHeaderString, byteRangeString)
+	tracer.Request(mediaType, urlHeaderString, byteRangeString)
+
+	// count the bytes received for this request from before it is sent, the response can arrive before getURLBody returns
+	var request *xlayer.Request
+	if globAccountant != nil {
+		request = globAccountant.BeginRequest(urlHeaderString)
+	}
 
 	//request the URL with GET
-	body, rtt, protocol, _, status := getURLBody(urlHeaderString, isByteRangeMPD, startRange, endRange, quicBool, debugFile, debugLog, useTestbedBool, false, ctx)
+	body, rtt, protocol, _, status, timing := getURLBody(urlHeaderString, isByteRangeMPD, startRange, endRange, quicBool, debugFile, debugLog, useTestbedBool, false, ctx, tracer)
+	if status == StatusIncomplete {
+		if request != nil {
+			globAccountant.EndRequest(request)
+		}
+		return rtt, 0, protocol, "", 0, status, nil, timing.done()
+	}
 
-	// read from the buffer
-	var buf bytes.Buffer
-	// duplicate the buffer incase I need it later
-	tee := io.TeeReader(body, &buf)
-	myBytes, _ := ioutil.ReadAll(tee)
-	// get the size of this segment
-	size := strconv.FormatInt(int64(len(myBytes)), 10)
-	segSize, err := strconv.Atoi(size)
-	if err != nil {
-		logging.DebugPrint(debugFile, debugLog, "Error : ", "Cannot convert the size to an int when getting a file")
-		abrqlog.MainTracer.AbortRequest(urlHeaderString)
-		utils.StopApp()
+	// save the body to a temporary file as it arrives, we only know its name once we have read its boxes
+	var out *os.File
+	var saveTo io.Writer
+	if saveFilesBool {
+		var err error
+		out, err = ioutil.TempFile(fileLocation, ".download-*")
+		if err != nil {
+			fmt.Println("*** " + fileLocation + " cannot be written to ***")
+			tracer.AbortRequest(urlHeaderString)
+			// stop the app
+			utils.StopApp()
+		}
+		saveTo = out
 	}
 
-	abrqlog.MainTracer.RequestUpdate(urlHeaderString, int64(segSize))
+	// from now on only count the bytes received on the connection of this request
+	if request != nil {
+		globAccountant.SetConnection(request, timing.connection())
+	}
+	// read the transport state of the TCP connection while the segment downloads, over QUIC the accountant gets it from the qlog events
+	sampler := startTCPSampler(timing.connection())
 
-	// get the P.1203 segSize (less the header)
-	withoutHeaderVal := int64(segSize)
+	// read the body as it arrives - count the bytes, read the boxes, and write and hash it if we are saving it
+	scanner, sum, err := readSegment(body, urlHeaderString, quicBool, request, downloadProgress(ctx), saveTo, tracer)
+	sampler.stop()
+	if request != nil {
+		globAccountant.EndRequest(request)
+		accepted, dropped := globAccountant.IntakeStats()
+		logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "request throughput "+strconv.FormatFloat(request.Throughput(), 'f', 0, 64)+" bits/s, bottleneck capacity "+
+			strconv.FormatFloat(globAccountant.BottleneckCapacity(), 'f', 0, 64)+" bits/s on connection "+request.ConnectionID+
+			", shared connection "+strconv.FormatBool(request.Shared)+", qlog events taken "+strconv.FormatUint(accepted, 10)+", dropped "+strconv.FormatUint(dropped, 10))
+	}
+	if out != nil {
+		out.Close()
+	}
+	requestTiming := timing.done()
 
-	// lets see if we can find this {0x00, 0x00, 0x00, 0x04, 0x68, 0xEF, 0xBC, 0x80}
-	// in our segment
-	src := []byte("0000000468EFBC80")
-	dst := make([]byte, hex.DecodedLen(len(src)))
-	n, err := hex.Decode(dst, src)
+	// an incomplete segment is not stored, counted or shared - the player requests it again
 	if err != nil {
-		log.Fatal(err)
+		logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "segment download stopped after "+strconv.FormatInt(scanner.Size(), 10)+" bytes: "+err.Error())
+		tracer.AbortRequest(urlHeaderString)
+		if out != nil {
+			os.Remove(out.Name())
+		}
+		body.Close()
+		return rtt, 0, protocol, "", 0, StatusIncomplete, nil, requestTiming
 	}
-	// see if this value is in myBytes
-	if bytes.Contains(myBytes, dst[:n]) {
-		// get the index for our dst value
-		mdatValueInt := bytes.Index(myBytes, dst[:n])
-		// add 8 bits for header
-		mdatValueInt += 8
-		// get the file byte size less the header
-		withoutHeaderVal = int64(segSize) - int64(mdatValueInt)
+	// get the size of this segment
+	segSize := int(scanner.Size())
+
+	tracer.RequestComplete(urlHeaderString, int64(segSize), requestTiming.qlog())
+	logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "request timing - DNS "+requestTiming.DNS.String()+", connect "+requestTiming.Connect.String()+", TLS "+requestTiming.TLS.String()+
+		", TTFB "+requestTiming.TTFB.String()+", TTLB "+requestTiming.TTLB.String()+", reused connection "+strconv.FormatBool(requestTiming.Reused))
+
+	// read the boxes of this segment - the media payload, samples, frame rate and keyframes
+	segment, isInit := inspectSegment(scanner, inits, rep, debugFile, debugLog)
+
+	// get the P.1203 segSize (the media payload, less the box headers)
+	withoutHeaderVal := int64(segSize)
+	if segment != nil && segment.PayloadBytes() > 0 {
+		withoutHeaderVal = segment.PayloadBytes()
 	}
 	// determine the bitrate based on segment duration - multiply by 8 and divide by segment duration
 	kbpsInt := ((withoutHeaderVal * 8) / int64(segmentDuration))
@@ -709,36 +818,18 @@ func GetFile(currentURL string, fileBaseURL string, fileLocation string, isByteR
 	logging.DebugPrint(glob.DebugFile, debugLog, "DEBUG: ", "HTTP body size is "+kbpsFloatStringVal)
 
 	// if we want to save the streamed files
-	// NOTICE (Arno Verstraete): this check was disabled for testing
-	// if saveFilesBool
-	if false {
+	if out != nil {
 
-		// Restore the io.ReadCloser to it's original state, if needed
-		body = ioutil.NopCloser(bytes.NewBuffer(myBytes))
+		// encrypted content is saved decrypted, if we have the key
+		// each representation gets its own init segment and segment names
+		prefix := storedFilePrefix(fileLocation, segmentDuration, profile, mediaType, repRate)
+		storedFile := storeDownload(out.Name(), sum, prefix, segmentNumber, isInit, mediaType, debugFile, debugLog)
 
-		// save the file to the provided file location
-		// write if not existing, append if existing
-		out, err := os.OpenFile(createFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-		if err != nil {
-			fmt.Println("*** " + createFile + " cannot be downloaded and written/append to file ***")
-			utils.StopApp()
-		}
-		// save the file to the provided file location
-		// out, err := os.Create(createFile)
-		// if err != nil {
-		// 	fmt.Println("*** " + createFile + " cannot be downloaded and written to file ***")
-		// 	// stop the app
-		// 	utils.StopApp()
-		// }
-		// defer out.Close()
-
-		// Write the body to file
-		_, err = io.Copy(out, body)
-		if err != nil {
-			fmt.Println("*** " + createFile + " cannot be saved ***")
-			// stop the app
-			utils.StopApp()
+		// collaborative clients look for the file by the base name of the url
+		if Noden.ClientName != "off" && Noden.ClientName != "" {
+			shareFile(storedFile, createFile, debugFile, debugLog)
 		}
+		createFile = storedFile
 	}
 
 	logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "Before consul update")
@@ -752,7 +843,7 @@ func GetFile(currentURL string, fileBaseURL string, fileLocation string, isByteR
 	// close the body connection
 	body.Close()
 
-	return rtt, segSize, protocol, createFile, kbpsFloat, status
+	return rtt, segSize, protocol, createFile, kbpsFloat, status, segment, requestTiming
 }
 
 // GetFileProgressively :
@@ -760,7 +851,7 @@ func GetFile(currentURL string, fileBaseURL string, fileLocation string, isByteR
  * get the provided file from the online HTTP server and save to folder
  * get a 1-second piece of each file
  */
-func GetFileProgressively(currentURL string, fileBaseURL string, fileLocation string, isByteRangeMPD bool, startRange int, endRange int, segmentNumber int, segmentDuration int, addSegDuration bool, debugLog bool, AudioByteRange bool, profile string) (time.Duration, int) {
+func GetFileProgressively(currentURL string, fileBaseURL string, fileLocation string, isByteRangeMPD bool, startRange int, endRange int, segmentNumber int, segmentDuration int, addSegDuration bool, quicBool bool, debugLog bool, useTestbedBool bool, AudioByteRange bool, profile string, tracer *abrqlog.StreamTracer) (time.Duration, int) {
 
 	// create the string where we want to save this file
 	var createFile string
@@ -801,7 +892,7 @@ func GetFileProgressively(currentURL string, fileBaseURL string, fileLocation st
 	defer out.Close()
 
 	//request the URL with GET
-	rtt := getURLProgressively(urlHeaderString, isByteRangeMPD, startRange, endRange, createFile)
+	rtt := getURLProgressively(urlHeaderString, isByteRangeMPD, startRange, endRange, createFile, quicBool, debugLog, useTestbedBool, tracer)
 
 	fi, err := os.Stat(createFile)
 	if err != nil {
@@ -817,15 +908,3 @@ func GetFileProgressively(currentURL string, fileBaseURL string, fileLocation st
 
 	return rtt, segSize
 }
-
-func printQlogEvents(c chan qlog.Event) {
-	for msg := range c {
-		start := time.Now()
-		fmt.Println("Received transport layer event")
-		fmt.Println(msg.RelativeTime)
-		msgJSON, _ := gojay.Marshal(msg)
-		duration := time.Since(start)
-		fmt.Println(string(msgJSON))
-		fmt.Println("Time to decode qlog JSON: ", duration.Microseconds(), "µs")
-	}
-}
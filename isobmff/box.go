@@ -0,0 +1,217 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+// Package isobmff reads the boxes of ISO/IEC 14496-12 (fragmented MP4) init and media segments.
+// Only the boxes needed to describe the media of a segment are parsed:
+// moov/trak, mvex/trex, sidx, emsg, moof/traf (tfhd, tfdt, trun) and mdat.
+package isobmff
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// errTruncated : returned when a box is shorter than its fields
+var errTruncated = errors.New("truncated box")
+
+// Box : the position of a box in a byte slice
+type Box struct {
+	Type string
+	// start of the box header
+	Start int
+	// start of the box payload
+	Payload int
+	// end of the box
+	End int
+}
+
+// Size : the size of the box, including its header
+func (b Box) Size() int {
+	return b.End - b.Start
+}
+
+// PayloadSize : the size of the box, less its header
+func (b Box) PayloadSize() int {
+	return b.End - b.Payload
+}
+
+// ReadBoxes : return the boxes found between start and end
+func ReadBoxes(buf []byte, start int, end int) ([]Box, error) {
+
+	var boxes []Box
+	for pos := start; pos+8 <= end; {
+		size := int(binary.BigEndian.Uint32(buf[pos:]))
+		b := Box{Type: string(buf[pos+4 : pos+8]), Start: pos, Payload: pos + 8}
+		switch size {
+		case 0:
+			// box extends to the end of the container
+			size = end - pos
+		case 1:
+			// 64 bit largesize
+			if pos+16 > end {
+				return boxes, fmt.Errorf("truncated %s box at %d", b.Type, pos)
+			}
+			size = int(binary.BigEndian.Uint64(buf[pos+8:]))
+			b.Payload = pos + 16
+		}
+		if size < b.Payload-pos || pos+size > end {
+			return boxes, fmt.Errorf("invalid %s box size %d at %d", b.Type, size, pos)
+		}
+		b.End = pos + size
+		boxes = append(boxes, b)
+		pos += size
+	}
+	return boxes, nil
+}
+
+// FindBox : return the first box of this type found between start and end
+func FindBox(buf []byte, start int, end int, typ string) (Box, bool) {
+	boxes, _ := ReadBoxes(buf, start, end)
+	for _, b := range boxes {
+		if b.Type == typ {
+			return b, true
+		}
+	}
+	return Box{}, false
+}
+
+// FindPath : follow a path of box types down from the top level
+func FindPath(buf []byte, path ...string) (Box, bool) {
+	return FindBoxPath(buf, Box{Payload: 0, End: len(buf)}, path...)
+}
+
+// FindBoxPath : follow a path of box types down from the given box
+func FindBoxPath(buf []byte, b Box, path ...string) (Box, bool) {
+	for _, typ := range path {
+		var ok bool
+		if b, ok = FindBox(buf, b.Payload, b.End, typ); !ok {
+			return Box{}, false
+		}
+	}
+	return b, true
+}
+
+// SampleEntryHeaderSize :
+// * the size of the fields before the child boxes of a sample entry
+// * 0 if this is not a sample entry we know about
+func SampleEntryHeaderSize(typ string) int {
+	switch typ {
+	// visual sample entries
+	case "encv", "avc1", "avc3", "hvc1", "hev1", "vp08", "vp09", "av01", "dvh1", "dvhe":
+		return 78
+	// audio sample entries
+	case "enca", "mp4a", "ac-3", "ec-3", "ac-4", "Opus", "fLaC":
+		return 28
+	}
+	return 0
+}
+
+// reader : read big endian fields from a box payload, any read past the end sets err
+type reader struct {
+	p   []byte
+	pos int
+	err error
+}
+
+// newReader : create a reader for the payload of this box
+func newReader(buf []byte, b Box) *reader {
+	return &reader{p: buf[b.Payload:b.End]}
+}
+
+// next : return the next n bytes, or nil if there are not enough bytes left
+func (r *reader) next(n int) []byte {
+	if r.err != nil || n < 0 || r.pos+n > len(r.p) {
+		r.err = errTruncated
+		return nil
+	}
+	b := r.p[r.pos : r.pos+n]
+	r.pos += n
+	return b
+}
+
+func (r *reader) u8() uint8 {
+	if b := r.next(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *reader) u16() uint16 {
+	if b := r.next(2); b != nil {
+		return binary.BigEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (r *reader) u32() uint32 {
+	if b := r.next(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (r *reader) u64() uint64 {
+	if b := r.next(8); b != nil {
+		return binary.BigEndian.Uint64(b)
+	}
+	return 0
+}
+
+// uint : read a 64 bit field for version 1 boxes, otherwise a 32 bit field
+func (r *reader) uint(version uint8) uint64 {
+	if version == 1 {
+		return r.u64()
+	}
+	return uint64(r.u32())
+}
+
+// fullBox : read the version and flags of a full box
+func (r *reader) fullBox() (version uint8, flags uint32) {
+	v := r.u32()
+	return uint8(v >> 24), v & 0xffffff
+}
+
+// cstring : read a null terminated string
+func (r *reader) cstring() string {
+	if r.err != nil {
+		return ""
+	}
+	for i := r.pos; i < len(r.p); i++ {
+		if r.p[i] == 0 {
+			s := string(r.p[r.pos:i])
+			r.pos = i + 1
+			return s
+		}
+	}
+	r.err = errTruncated
+	return ""
+}
+
+// rest : the bytes left in the payload
+func (r *reader) rest() []byte {
+	if r.err != nil {
+		return nil
+	}
+	b := r.p[r.pos:]
+	r.pos = len(r.p)
+	return b
+}
@@ -0,0 +1,189 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package isobmff
+
+import (
+	"fmt"
+)
+
+// HandlerVideo : hdlr handler type of a video track
+const HandlerVideo = "vide"
+
+// HandlerAudio : hdlr handler type of an audio track
+const HandlerAudio = "soun"
+
+// Track : the values of one trak box, and its trex box, from an init segment
+type Track struct {
+	// from tkhd
+	ID     uint32
+	Width  int
+	Height int
+	// from mdhd
+	Timescale uint32
+	Duration  uint64
+	// from hdlr - "vide", "soun", ...
+	Handler string
+	// the sample entry type from stsd - "avc1", "hvc1", "mp4a", ...
+	// for encrypted content this is the type before encryption (from the frma box)
+	SampleEntry string
+	// defaults from trex, used when a fragment does not set its own
+	DefaultSampleDuration uint32
+	DefaultSampleSize     uint32
+	DefaultSampleFlags    uint32
+}
+
+// Init : the tracks of an init segment
+type Init struct {
+	Tracks []Track
+}
+
+// Track : return the track with this ID, nil if there is no such track
+func (i *Init) Track(id uint32) *Track {
+	if i == nil {
+		return nil
+	}
+	for k := range i.Tracks {
+		if i.Tracks[k].ID == id {
+			return &i.Tracks[k]
+		}
+	}
+	return nil
+}
+
+// ParseInit :
+// * read the tracks from the moov box of an init segment
+// * return nil if there is no moov box
+func ParseInit(buf []byte) (*Init, error) {
+
+	moov, ok := FindPath(buf, "moov")
+	if !ok {
+		return nil, nil
+	}
+
+	init := &Init{}
+	boxes, err := ReadBoxes(buf, moov.Payload, moov.End)
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range boxes {
+		if b.Type != "trak" {
+			continue
+		}
+		track, err := parseTrak(buf, b)
+		if err != nil {
+			return nil, err
+		}
+		init.Tracks = append(init.Tracks, track)
+	}
+
+	// the trex boxes hold the default sample values for each track
+	if mvex, ok := FindBox(buf, moov.Payload, moov.End, "mvex"); ok {
+		boxes, _ := ReadBoxes(buf, mvex.Payload, mvex.End)
+		for _, b := range boxes {
+			if b.Type != "trex" {
+				continue
+			}
+			r := newReader(buf, b)
+			r.fullBox()
+			id := r.u32()
+			// skip default_sample_description_index
+			r.u32()
+			duration, size, flags := r.u32(), r.u32(), r.u32()
+			if r.err != nil {
+				return nil, fmt.Errorf("trex: %v", r.err)
+			}
+			if t := init.Track(id); t != nil {
+				t.DefaultSampleDuration, t.DefaultSampleSize, t.DefaultSampleFlags = duration, size, flags
+			}
+		}
+	}
+
+	return init, nil
+}
+
+// parseTrak : read the values of one trak box
+func parseTrak(buf []byte, trak Box) (Track, error) {
+
+	var t Track
+
+	if tkhd, ok := FindBox(buf, trak.Payload, trak.End, "tkhd"); ok {
+		r := newReader(buf, tkhd)
+		version, _ := r.fullBox()
+		// creation_time and modification_time
+		r.uint(version)
+		r.uint(version)
+		t.ID = r.u32()
+		// reserved, duration, reserved, layer, alternate_group, volume, reserved and matrix
+		r.u32()
+		r.uint(version)
+		r.next(8 + 2 + 2 + 2 + 2 + 36)
+		// 16.16 fixed point
+		t.Width = int(r.u32() >> 16)
+		t.Height = int(r.u32() >> 16)
+		if r.err != nil {
+			return t, fmt.Errorf("tkhd: %v", r.err)
+		}
+	}
+
+	mdia, ok := FindBox(buf, trak.Payload, trak.End, "mdia")
+	if !ok {
+		return t, nil
+	}
+
+	if mdhd, ok := FindBox(buf, mdia.Payload, mdia.End, "mdhd"); ok {
+		r := newReader(buf, mdhd)
+		version, _ := r.fullBox()
+		// creation_time and modification_time
+		r.uint(version)
+		r.uint(version)
+		t.Timescale = r.u32()
+		t.Duration = r.uint(version)
+		if r.err != nil {
+			return t, fmt.Errorf("mdhd: %v", r.err)
+		}
+	}
+
+	if hdlr, ok := FindBox(buf, mdia.Payload, mdia.End, "hdlr"); ok {
+		r := newReader(buf, hdlr)
+		r.fullBox()
+		// pre_defined
+		r.u32()
+		t.Handler = string(r.next(4))
+	}
+
+	if stsd, ok := FindBoxPath(buf, mdia, "minf", "stbl", "stsd"); ok {
+		// skip version, flags and entry_count
+		entries, _ := ReadBoxes(buf, stsd.Payload+8, stsd.End)
+		if len(entries) > 0 {
+			entry := entries[0]
+			t.SampleEntry = entry.Type
+			// encrypted sample entries keep their original type in the frma box
+			if entry.Type == "encv" || entry.Type == "enca" {
+				if frma, ok := FindBoxPath(buf, Box{Payload: entry.Payload + SampleEntryHeaderSize(entry.Type), End: entry.End}, "sinf", "frma"); ok && frma.PayloadSize() >= 4 {
+					t.SampleEntry = string(buf[frma.Payload : frma.Payload+4])
+				}
+			}
+		}
+	}
+
+	return t, nil
+}
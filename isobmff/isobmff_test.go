@@ -0,0 +1,350 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package isobmff
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// ------------------------------------------------------------------------------------------------
+// helpers to build boxes
+
+func mp4Box(typ string, payload ...[]byte) []byte {
+	body := bytes.Join(payload, nil)
+	b := make([]byte, 8, 8+len(body))
+	binary.BigEndian.PutUint32(b, uint32(8+len(body)))
+	copy(b[4:], typ)
+	return append(b, body...)
+}
+
+func u16(v int) []byte {
+	b := make([]byte, 2)
+	binary.BigEndian.PutUint16(b, uint16(v))
+	return b
+}
+
+func u32(v int) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, uint32(v))
+	return b
+}
+
+func u64(v int) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(v))
+	return b
+}
+
+// testInit : an init segment with a 1280x720 video track (ID 1, timescale 12800)
+// and an audio track (ID 2, timescale 48000)
+func testInit() []byte {
+
+	trak := func(id int, width int, height int, timescale int, handler string, entry []byte) []byte {
+		tkhd := mp4Box("tkhd", u32(0), u32(0), u32(0), u32(id), u32(0), u32(0), make([]byte, 8+2+2+2+2+36), u32(width<<16), u32(height<<16))
+		mdhd := mp4Box("mdhd", u32(0), u32(0), u32(0), u32(timescale), u32(0), u32(0))
+		hdlr := mp4Box("hdlr", u32(0), u32(0), []byte(handler), make([]byte, 12), []byte{0})
+		stsd := mp4Box("stsd", u32(0), u32(1), entry)
+		return mp4Box("trak", tkhd, mp4Box("mdia", mdhd, hdlr, mp4Box("minf", mp4Box("stbl", stsd))))
+	}
+
+	// the video track is encrypted, so the original format comes from the frma box
+	sinf := mp4Box("sinf", mp4Box("frma", []byte("avc1")))
+	video := trak(1, 1280, 720, 12800, HandlerVideo, mp4Box("encv", make([]byte, 78), sinf))
+	audio := trak(2, 0, 0, 48000, HandlerAudio, mp4Box("mp4a", make([]byte, 28)))
+
+	// the audio track has a default sample duration of 1024 in the trex box
+	mvex := mp4Box("mvex",
+		mp4Box("trex", u32(0), u32(1), u32(1), u32(0), u32(0), u32(0)),
+		mp4Box("trex", u32(0), u32(2), u32(1), u32(1024), u32(0), u32(0)))
+
+	return mp4Box("moov", mp4Box("mvhd", make([]byte, 100)), audio, video, mvex)
+}
+
+// testFragment : a moof and mdat pair for one track - durations and flags can be nil
+func testFragment(sequence int, trackID int, decodeTime int, sizes []int, durations []int, flags []int) []byte {
+
+	trunFlags := 0x000001 | 0x000200
+	if durations != nil {
+		trunFlags |= 0x000100
+	}
+	if flags != nil {
+		trunFlags |= 0x000400
+	}
+	buildMoof := func(dataOffset int) []byte {
+		trun := [][]byte{u32(trunFlags), u32(len(sizes)), u32(dataOffset)}
+		for i := range sizes {
+			if durations != nil {
+				trun = append(trun, u32(durations[i]))
+			}
+			trun = append(trun, u32(sizes[i]))
+			if flags != nil {
+				trun = append(trun, u32(flags[i]))
+			}
+		}
+		traf := [][]byte{mp4Box("tfhd", u32(0x020000), u32(trackID))}
+		if decodeTime >= 0 {
+			traf = append(traf, mp4Box("tfdt", u32(1<<24), u64(decodeTime)))
+		}
+		traf = append(traf, mp4Box("trun", trun...))
+		return mp4Box("moof", mp4Box("mfhd", u32(0), u32(sequence)), mp4Box("traf", traf...))
+	}
+	moof := buildMoof(0)
+	moof = buildMoof(len(moof) + 8)
+
+	var data [][]byte
+	for _, size := range sizes {
+		data = append(data, bytes.Repeat([]byte{byte(size)}, size))
+	}
+	return append(moof, mp4Box("mdat", data...)...)
+}
+
+// ------------------------------------------------------------------------------------------------
+// init segment
+
+func TestParseInit(t *testing.T) {
+
+	init, err := ParseInit(testInit())
+	if err != nil {
+		t.Fatal("unable to parse the init segment: ", err)
+	}
+	if len(init.Tracks) != 2 {
+		t.Fatalf("expected 2 tracks, found %d", len(init.Tracks))
+	}
+
+	video := init.Track(1)
+	if video == nil || video.Width != 1280 || video.Height != 720 || video.Timescale != 12800 || video.Handler != HandlerVideo || video.SampleEntry != "avc1" {
+		t.Errorf("wrong video track %+v", video)
+	}
+	audio := init.Track(2)
+	if audio == nil || audio.Timescale != 48000 || audio.Handler != HandlerAudio || audio.SampleEntry != "mp4a" || audio.DefaultSampleDuration != 1024 {
+		t.Errorf("wrong audio track %+v", audio)
+	}
+
+	if init, err := ParseInit(testFragment(1, 1, 0, []int{10}, nil, nil)); init != nil || err != nil {
+		t.Error("a media segment should not be parsed as an init segment")
+	}
+}
+
+// ------------------------------------------------------------------------------------------------
+// media segment
+
+func TestParseSegment(t *testing.T) {
+
+	init, _ := ParseInit(testInit())
+
+	// 2 seconds of 25 fps video in two fragments, the second without a tfdt box
+	sizes := []int{300, 20, 25, 30, 35}
+	durations := []int{512, 512, 512, 512, 512}
+	flags := []int{0x02000000, 0x01010000, 0x01010000, 0x01010000, 0x01010000}
+	var moreSizes, moreDurations, moreFlags []int
+	for i := 0; i < 45; i++ {
+		moreSizes = append(moreSizes, 40)
+		moreDurations = append(moreDurations, 512)
+		moreFlags = append(moreFlags, 0x01010000)
+	}
+	// a keyframe at the start of the second second
+	moreFlags[20] = 0x02000000
+
+	emsg0 := mp4Box("emsg", u32(0), []byte("urn:scte:scte35:2013:bin\x00"), []byte("1\x00"), u32(90000), u32(900), u32(1800), u32(7), []byte{0xde, 0xad})
+	emsg1 := mp4Box("emsg", u32(1<<24), u32(1000), u64(51000), u32(500), u32(8), []byte("urn:mpeg:dash:event:2012\x00"), []byte("\x00"))
+	sidx := mp4Box("sidx", u32(0), u32(1), u32(12800), u32(12800*50), u32(0), u16(0), u16(1), u32(5000), u32(25600), u32(0x90000000))
+
+	seg := bytes.Join([][]byte{
+		mp4Box("styp", []byte("msdh")),
+		sidx,
+		emsg0,
+		emsg1,
+		testFragment(10, 1, 12800*50, sizes, durations, flags),
+		testFragment(11, 1, -1, moreSizes, moreDurations, moreFlags),
+	}, nil)
+
+	s, err := ParseSegment(seg, init)
+	if err != nil || s == nil {
+		t.Fatal("unable to parse the segment: ", err)
+	}
+
+	payload := int64(300 + 20 + 25 + 30 + 35 + 45*40)
+	if s.PayloadBytes() != payload {
+		t.Errorf("payload is %d bytes, expected %d", s.PayloadBytes(), payload)
+	}
+	if s.SampleCount() != 50 {
+		t.Errorf("found %d samples, expected 50", s.SampleCount())
+	}
+	if s.Duration() != 2*time.Second {
+		t.Errorf("duration is %v, expected 2s", s.Duration())
+	}
+	if s.FrameRate() != 25 {
+		t.Errorf("frame rate is %f, expected 25", s.FrameRate())
+	}
+	if !reflect.DeepEqual(s.KeyframePositions(), []int{0, 25}) {
+		t.Errorf("keyframes at %v, expected [0 25]", s.KeyframePositions())
+	}
+
+	times := s.DecodeTimes()
+	if len(times) != 50 || times[0] != 50*time.Second || times[25] != 51*time.Second || times[49] != 51*time.Second+24*40*time.Millisecond {
+		t.Errorf("wrong decode times %v", times)
+	}
+	if s.Fragments[1].SequenceNumber != 11 || s.Fragments[1].BaseMediaDecodeTime != 12800*50+5*512 {
+		t.Errorf("wrong second fragment %+v", s.Fragments[1])
+	}
+
+	// each sample offset points at its own data
+	samples := s.Samples()
+	if seg[samples[0].Offset] != 44 || seg[samples[1].Offset] != 20 || seg[samples[5].Offset] != 40 {
+		t.Error("sample offsets do not point at the sample data")
+	}
+
+	if len(s.Sidx) != 1 || s.Sidx[0].Timescale != 12800 || len(s.Sidx[0].References) != 1 {
+		t.Fatalf("wrong sidx %+v", s.Sidx)
+	}
+	ref := s.Sidx[0].References[0]
+	if ref.ReferenceType != 0 || ref.ReferencedSize != 5000 || ref.SubsegmentDuration != 25600 || !ref.StartsWithSAP || ref.SAPType != 1 {
+		t.Errorf("wrong sidx reference %+v", ref)
+	}
+
+	if len(s.Emsgs) != 2 {
+		t.Fatalf("found %d emsg boxes, expected 2", len(s.Emsgs))
+	}
+	e := s.Emsgs[0]
+	if e.SchemeIDURI != "urn:scte:scte35:2013:bin" || e.Value != "1" || e.Timescale != 90000 || e.PresentationTime != 900 || e.EventDuration != 1800 || e.ID != 7 || !bytes.Equal(e.MessageData, []byte{0xde, 0xad}) {
+		t.Errorf("wrong version 0 emsg %+v", e)
+	}
+	e = s.Emsgs[1]
+	if e.SchemeIDURI != "urn:mpeg:dash:event:2012" || e.Value != "" || e.PresentationTime != 51000 || e.ID != 8 || len(e.MessageData) != 0 {
+		t.Errorf("wrong version 1 emsg %+v", e)
+	}
+}
+
+func TestParseSegmentDefaults(t *testing.T) {
+
+	init, _ := ParseInit(testInit())
+
+	// audio durations come from the trex box, 47 samples of 1024 at 48kHz
+	var sizes []int
+	for i := 0; i < 47; i++ {
+		sizes = append(sizes, 10)
+	}
+	seg := testFragment(1, 2, 0, sizes, nil, nil)
+
+	s, err := ParseSegment(seg, init)
+	if err != nil || s == nil {
+		t.Fatal("unable to parse the segment: ", err)
+	}
+	if s.TrackID != 2 || s.Timescale() != 48000 || s.Duration() != time.Duration(47*1024)*time.Second/48000 {
+		t.Errorf("wrong audio segment values - track %d, timescale %d, duration %v", s.TrackID, s.Timescale(), s.Duration())
+	}
+
+	// without the init segment we do not know the timescale
+	s, _ = ParseSegment(seg, nil)
+	if s.PayloadBytes() != 470 || s.SampleCount() != 47 || s.FrameRate() != 0 || s.DecodeTimes() != nil {
+		t.Error("wrong values for a segment without an init segment")
+	}
+
+	// not a fragmented segment
+	if s, err := ParseSegment(testInit(), init); s != nil || err != nil {
+		t.Error("an init segment should not be parsed as a media segment")
+	}
+	// a truncated segment
+	if _, err := ParseSegment(seg[:len(seg)-5], init); err == nil {
+		t.Error("a truncated segment should return an error")
+	}
+}
+
+// ------------------------------------------------------------------------------------------------
+// scanner
+
+// scan : write the file to a scanner a few bytes at a time
+func scan(buf []byte, chunk int) *Scanner {
+	s := NewScanner()
+	for len(buf) > 0 {
+		n := chunk
+		if n > len(buf) {
+			n = len(buf)
+		}
+		s.Write(buf[:n])
+		buf = buf[n:]
+	}
+	return s
+}
+
+func TestScanner(t *testing.T) {
+
+	init, _ := ParseInit(testInit())
+
+	// a large mdat, and a second fragment using a 64 bit box size for its mdat
+	var sizes []int
+	for i := 0; i < 25; i++ {
+		sizes = append(sizes, 4000)
+	}
+	second := testFragment(2, 1, 12800, []int{10, 20}, []int{512, 512}, nil)
+	moof, _ := FindPath(second, "moof")
+	largeMdat := append(append(u32(1), []byte("mdat")...), u64(16+30)...)
+	largeMdat = append(largeMdat, second[moof.End+8:]...)
+	// the data offset of the trun box now starts 8 bytes later
+	trun, _ := FindBoxPath(second, moof, "traf", "trun")
+	binary.BigEndian.PutUint32(second[trun.Payload+8:], uint32(moof.Size()+16))
+
+	seg := bytes.Join([][]byte{
+		mp4Box("styp", []byte("msdh")),
+		testFragment(1, 1, 0, sizes, nil, nil),
+		second[:moof.End],
+		largeMdat,
+	}, nil)
+
+	want, err := ParseSegment(seg, init)
+	if err != nil {
+		t.Fatal("unable to parse the segment: ", err)
+	}
+
+	for _, chunk := range []int{1, 7, 4096, len(seg)} {
+		s := scan(seg, chunk)
+		got, err := s.Segment(init)
+		if err != nil {
+			t.Fatalf("chunk %d: unable to scan the segment: %v", chunk, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("chunk %d: scanned segment %+v, expected %+v", chunk, got, want)
+		}
+		if s.Size() != int64(len(seg)) || len(s.Bytes()) >= 1000 {
+			t.Errorf("chunk %d: scanned %d bytes and kept %d", chunk, s.Size(), len(s.Bytes()))
+		}
+	}
+
+	// init segments are kept whole
+	got, err := scan(testInit(), 5).Init()
+	if err != nil || !reflect.DeepEqual(got, init) {
+		t.Errorf("scanned init segment %+v, expected %+v", got, init)
+	}
+
+	// a truncated mdat is not counted as a full segment
+	if _, err := scan(seg[:len(seg)-5], 100).Segment(init); err == nil {
+		t.Error("a truncated segment should return an error")
+	}
+	// an invalid box size
+	if _, err := scan(append(u32(4), []byte("moof")...), 3).Segment(init); err == nil {
+		t.Error("an invalid box size should return an error")
+	}
+}
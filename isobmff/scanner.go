@@ -0,0 +1,206 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package isobmff
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// MaxRetainedBoxSize : top level boxes larger than this are counted, but not kept, by the Scanner
+const MaxRetainedBoxSize = 16 << 20
+
+// Scanner :
+// * an io.Writer that reads the top level boxes of a file as it is downloaded
+// * every box except mdat is kept, the mdat payload is only counted
+// * so memory use depends on the size of the moov/moof boxes and not on the size of the segment
+type Scanner struct {
+	// the header of the box we are reading
+	header []byte
+	// bytes left in the box we are reading, -1 if it runs to the end of the file
+	remaining int64
+	// keep the box we are reading
+	keep bool
+	// the box we are reading is a mdat box
+	inMdat bool
+	// the kept boxes, and where each one started in the file
+	retained []byte
+	starts   []int64
+	// bytes written so far
+	written   int64
+	mdatBytes int64
+	foundMdat bool
+	err       error
+}
+
+// NewScanner : return a scanner for a new file
+func NewScanner() *Scanner {
+	return &Scanner{}
+}
+
+// Write : read the next bytes of the file - this never returns an error, see Err
+func (s *Scanner) Write(p []byte) (int, error) {
+
+	n := len(p)
+	for len(p) > 0 && s.err == nil {
+
+		// read the next box header
+		if s.remaining == 0 {
+			need := 8
+			if len(s.header) >= 8 && binary.BigEndian.Uint32(s.header) == 1 {
+				need = 16
+			}
+			take := need - len(s.header)
+			if take > len(p) {
+				take = len(p)
+			}
+			s.header = append(s.header, p[:take]...)
+			s.written += int64(take)
+			p = p[take:]
+			if len(s.header) == need && (need == 16 || binary.BigEndian.Uint32(s.header) != 1) {
+				s.startBox()
+			}
+			continue
+		}
+
+		take := int64(len(p))
+		if s.remaining > 0 && take > s.remaining {
+			take = s.remaining
+		}
+		if s.keep {
+			s.retained = append(s.retained, p[:take]...)
+		}
+		if s.inMdat {
+			s.mdatBytes += take
+		}
+		if s.remaining > 0 {
+			s.remaining -= take
+		}
+		s.written += take
+		p = p[take:]
+	}
+
+	// after an error we only count the bytes
+	s.written += int64(len(p))
+	return n, nil
+}
+
+// startBox : we have the full header of the next box
+func (s *Scanner) startBox() {
+
+	typ := string(s.header[4:8])
+	size := int64(binary.BigEndian.Uint32(s.header))
+	if size == 1 {
+		size = int64(binary.BigEndian.Uint64(s.header[8:]))
+	}
+	// the start of this box in the file
+	start := s.written - int64(len(s.header))
+
+	switch {
+	case size == 0:
+		s.remaining = -1
+	case size < int64(len(s.header)):
+		s.err = fmt.Errorf("invalid %s box size %d at %d", typ, size, start)
+		return
+	default:
+		s.remaining = size - int64(len(s.header))
+	}
+
+	s.inMdat = typ == "mdat"
+	s.foundMdat = s.foundMdat || s.inMdat
+	s.keep = !s.inMdat && size <= MaxRetainedBoxSize
+	if s.keep {
+		s.starts = append(s.starts, start)
+		s.retained = append(s.retained, s.header...)
+	}
+	s.header = s.header[:0]
+}
+
+// Err : the error that stopped the scanner reading the boxes, or an error if the last box is not complete
+func (s *Scanner) Err() error {
+	if s.err == nil && (s.remaining > 0 || len(s.header) > 0) {
+		return fmt.Errorf("truncated box at the end of %d bytes", s.written)
+	}
+	return s.err
+}
+
+// Size : the number of bytes written to the scanner
+func (s *Scanner) Size() int64 {
+	return s.written
+}
+
+// Bytes : the kept boxes, one after the other
+func (s *Scanner) Bytes() []byte {
+	return s.retained
+}
+
+// Init : read the tracks of an init segment, nil if there is no moov box
+func (s *Scanner) Init() (*Init, error) {
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return ParseInit(s.retained)
+}
+
+// Segment :
+// * read the boxes of a media segment, as ParseSegment does
+// * the payload is the size of the mdat boxes we counted, and sample offsets are positions in the scanned file
+// * return nil if there is no moof or mdat box
+func (s *Scanner) Segment(init *Init) (*Segment, error) {
+
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	seg, err := ParseSegment(s.retained, init)
+	if err != nil {
+		return nil, err
+	}
+	if seg == nil {
+		if !s.foundMdat {
+			return nil, nil
+		}
+		seg = &Segment{}
+	}
+	seg.MdatBytes = s.mdatBytes
+
+	// move the samples of each moof box from the kept boxes back to their place in the file
+	boxes, _ := ReadBoxes(s.retained, 0, len(s.retained))
+	fragment := 0
+	for i, b := range boxes {
+		if b.Type != "moof" || i >= len(s.starts) {
+			continue
+		}
+		shift := int(s.starts[i]) - b.Start
+		trafs, _ := ReadBoxes(s.retained, b.Payload, b.End)
+		for _, traf := range trafs {
+			if traf.Type != "traf" || fragment >= len(seg.Fragments) {
+				continue
+			}
+			for k := range seg.Fragments[fragment].Samples {
+				seg.Fragments[fragment].Samples[k].Offset += shift
+			}
+			fragment++
+		}
+	}
+
+	return seg, nil
+}
@@ -0,0 +1,473 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package isobmff
+
+import (
+	"fmt"
+	"time"
+)
+
+// sample flags - sample_is_non_sync_sample
+const sampleIsNonSyncSample = 0x00010000
+
+// Sample : one sample of a track fragment
+type Sample struct {
+	// position of the sample data in the segment
+	Offset int
+	Size   uint32
+	// duration and decode timestamp in the track timescale
+	Duration   uint32
+	DecodeTime uint64
+	// composition time offset in the track timescale
+	CompositionTimeOffset int32
+	Flags                 uint32
+}
+
+// IsKeyframe : true if this is a sync sample
+func (s Sample) IsKeyframe() bool {
+	return s.Flags&sampleIsNonSyncSample == 0
+}
+
+// Fragment : the samples of one traf box
+type Fragment struct {
+	// from mfhd
+	SequenceNumber uint32
+	// from tfhd
+	TrackID uint32
+	// timescale of the track, from the init segment or the sidx box (0 if unknown)
+	Timescale uint32
+	// from tfdt - the decode timestamp of the first sample
+	BaseMediaDecodeTime uint64
+	Samples             []Sample
+}
+
+// SidxReference : one reference of a sidx box
+type SidxReference struct {
+	// 1 if this reference points to another sidx box
+	ReferenceType      uint8
+	ReferencedSize     uint32
+	SubsegmentDuration uint32
+	StartsWithSAP      bool
+	SAPType            uint8
+	SAPDeltaTime       uint32
+}
+
+// Sidx : a segment index box
+type Sidx struct {
+	ReferenceID              uint32
+	Timescale                uint32
+	EarliestPresentationTime uint64
+	FirstOffset              uint64
+	References               []SidxReference
+}
+
+// Emsg : an event message box
+type Emsg struct {
+	Version     uint8
+	SchemeIDURI string
+	Value       string
+	Timescale   uint32
+	// version 0 - the delta from the earliest presentation time of the segment
+	// version 1 - the presentation time on the media timeline
+	PresentationTime uint64
+	EventDuration    uint32
+	ID               uint32
+	MessageData      []byte
+}
+
+// Segment : the boxes of a media segment
+type Segment struct {
+	Sidx      []Sidx
+	Emsgs     []Emsg
+	Fragments []Fragment
+	// the number of bytes in the mdat payloads - the media payload of the segment
+	MdatBytes int64
+	// the track the sample values are reported for - the video track, if there is one
+	TrackID uint32
+}
+
+// ParseSegment :
+// * read the sidx, emsg, moof and mdat boxes of a media segment
+// * the init segment of the track gives the timescale and default sample values (it can be nil)
+// * return nil if there is no moof or mdat box
+func ParseSegment(buf []byte, init *Init) (*Segment, error) {
+
+	boxes, err := ReadBoxes(buf, 0, len(buf))
+	if err != nil {
+		return nil, err
+	}
+
+	seg := &Segment{}
+	found := false
+	// where the next fragment of each track starts on the decode timeline
+	nextDecodeTime := make(map[uint32]uint64)
+
+	for _, b := range boxes {
+		switch b.Type {
+		case "sidx":
+			sidx, err := parseSidx(buf, b)
+			if err != nil {
+				return nil, err
+			}
+			seg.Sidx = append(seg.Sidx, sidx)
+		case "emsg":
+			emsg, err := parseEmsg(buf, b)
+			if err != nil {
+				return nil, err
+			}
+			seg.Emsgs = append(seg.Emsgs, emsg)
+		case "mdat":
+			found = true
+			seg.MdatBytes += int64(b.PayloadSize())
+		case "moof":
+			found = true
+			var sequenceNumber uint32
+			if mfhd, ok := FindBox(buf, b.Payload, b.End, "mfhd"); ok {
+				r := newReader(buf, mfhd)
+				r.fullBox()
+				sequenceNumber = r.u32()
+			}
+			trafs, _ := ReadBoxes(buf, b.Payload, b.End)
+			for _, traf := range trafs {
+				if traf.Type != "traf" {
+					continue
+				}
+				f, hasDecodeTime, err := parseTraf(buf, b, traf, init)
+				if err != nil {
+					return nil, err
+				}
+				// without a tfdt box the fragment follows on from the previous one
+				if !hasDecodeTime {
+					f.setBaseMediaDecodeTime(nextDecodeTime[f.TrackID])
+				}
+				if n := len(f.Samples); n > 0 {
+					nextDecodeTime[f.TrackID] = f.Samples[n-1].DecodeTime + uint64(f.Samples[n-1].Duration)
+				}
+				f.SequenceNumber = sequenceNumber
+				seg.Fragments = append(seg.Fragments, f)
+			}
+		}
+	}
+
+	if !found {
+		return nil, nil
+	}
+
+	// use the sidx timescale if we do not have the init segment
+	for k := range seg.Fragments {
+		if seg.Fragments[k].Timescale != 0 {
+			continue
+		}
+		for _, sidx := range seg.Sidx {
+			if sidx.ReferenceID == seg.Fragments[k].TrackID || len(seg.Sidx) == 1 {
+				seg.Fragments[k].Timescale = sidx.Timescale
+				break
+			}
+		}
+	}
+
+	// report the values for the video track, otherwise the first track
+	if len(seg.Fragments) > 0 {
+		seg.TrackID = seg.Fragments[0].TrackID
+	}
+	if init != nil {
+		for _, f := range seg.Fragments {
+			if t := init.Track(f.TrackID); t != nil && t.Handler == HandlerVideo {
+				seg.TrackID = f.TrackID
+				break
+			}
+		}
+	}
+
+	return seg, nil
+}
+
+// ParseTraf :
+// * read the samples of one traf box from its tfhd, tfdt and trun boxes
+// * the init segment gives the default sample values from its trex boxes (it can be nil)
+func ParseTraf(buf []byte, moof Box, traf Box, init *Init) (Fragment, error) {
+	f, _, err := parseTraf(buf, moof, traf, init)
+	return f, err
+}
+
+// parseTraf : read the samples of one traf box, hasDecodeTime is false if there is no tfdt box
+func parseTraf(buf []byte, moof Box, traf Box, init *Init) (f Fragment, hasDecodeTime bool, err error) {
+
+	tfhd, ok := FindBox(buf, traf.Payload, traf.End, "tfhd")
+	if !ok {
+		return f, false, fmt.Errorf("traf box without a tfhd box")
+	}
+	r := newReader(buf, tfhd)
+	_, flags := r.fullBox()
+	f.TrackID = r.u32()
+
+	// defaults from the trex box, then the tfhd box
+	var defaultDuration, defaultSize, defaultFlags uint32
+	if t := init.Track(f.TrackID); t != nil {
+		f.Timescale = t.Timescale
+		defaultDuration, defaultSize, defaultFlags = t.DefaultSampleDuration, t.DefaultSampleSize, t.DefaultSampleFlags
+	}
+
+	// the default base is the start of the moof box
+	base := moof.Start
+	if flags&0x01 != 0 {
+		base = int(r.u64())
+	}
+	if flags&0x02 != 0 {
+		// sample_description_index
+		r.u32()
+	}
+	if flags&0x08 != 0 {
+		defaultDuration = r.u32()
+	}
+	if flags&0x10 != 0 {
+		defaultSize = r.u32()
+	}
+	if flags&0x20 != 0 {
+		defaultFlags = r.u32()
+	}
+	if r.err != nil {
+		return f, false, fmt.Errorf("tfhd: %v", r.err)
+	}
+
+	if tfdt, ok := FindBox(buf, traf.Payload, traf.End, "tfdt"); ok {
+		r := newReader(buf, tfdt)
+		version, _ := r.fullBox()
+		f.BaseMediaDecodeTime = r.uint(version)
+		if r.err != nil {
+			return f, false, fmt.Errorf("tfdt: %v", r.err)
+		}
+		hasDecodeTime = true
+	}
+
+	boxes, _ := ReadBoxes(buf, traf.Payload, traf.End)
+	next := base
+	decodeTime := f.BaseMediaDecodeTime
+	for _, trun := range boxes {
+		if trun.Type != "trun" {
+			continue
+		}
+		r := newReader(buf, trun)
+		_, flags := r.fullBox()
+		count := int(r.u32())
+		offset := next
+		if flags&0x01 != 0 {
+			offset = base + int(int32(r.u32()))
+		}
+		firstFlags, hasFirstFlags := uint32(0), flags&0x04 != 0
+		if hasFirstFlags {
+			firstFlags = r.u32()
+		}
+		// do not trust the count further than the box can hold
+		perSample := 0
+		for _, flag := range []uint32{0x100, 0x200, 0x400, 0x800} {
+			if flags&flag != 0 {
+				perSample += 4
+			}
+		}
+		if r.err != nil || count*perSample > len(r.p)-r.pos {
+			return f, false, fmt.Errorf("trun: %v", errTruncated)
+		}
+		for i := 0; i < count; i++ {
+			s := Sample{Offset: offset, Size: defaultSize, Duration: defaultDuration, Flags: defaultFlags, DecodeTime: decodeTime}
+			if flags&0x100 != 0 {
+				s.Duration = r.u32()
+			}
+			if flags&0x200 != 0 {
+				s.Size = r.u32()
+			}
+			if flags&0x400 != 0 {
+				s.Flags = r.u32()
+			} else if i == 0 && hasFirstFlags {
+				s.Flags = firstFlags
+			}
+			if flags&0x800 != 0 {
+				s.CompositionTimeOffset = int32(r.u32())
+			}
+			if r.err != nil {
+				return f, false, fmt.Errorf("trun: %v", r.err)
+			}
+			offset += int(s.Size)
+			decodeTime += uint64(s.Duration)
+			f.Samples = append(f.Samples, s)
+		}
+		next = offset
+	}
+
+	return f, hasDecodeTime, nil
+}
+
+// setBaseMediaDecodeTime : move the decode timestamps of every sample to start at this time
+func (f *Fragment) setBaseMediaDecodeTime(t uint64) {
+	for k := range f.Samples {
+		f.Samples[k].DecodeTime = f.Samples[k].DecodeTime - f.BaseMediaDecodeTime + t
+	}
+	f.BaseMediaDecodeTime = t
+}
+
+// parseSidx : read a sidx box
+func parseSidx(buf []byte, b Box) (Sidx, error) {
+
+	var sidx Sidx
+	r := newReader(buf, b)
+	version, _ := r.fullBox()
+	sidx.ReferenceID = r.u32()
+	sidx.Timescale = r.u32()
+	sidx.EarliestPresentationTime = r.uint(version)
+	sidx.FirstOffset = r.uint(version)
+	// reserved
+	r.u16()
+	count := int(r.u16())
+	for i := 0; i < count && r.err == nil; i++ {
+		typeSize := r.u32()
+		duration := r.u32()
+		sap := r.u32()
+		sidx.References = append(sidx.References, SidxReference{
+			ReferenceType:      uint8(typeSize >> 31),
+			ReferencedSize:     typeSize & 0x7fffffff,
+			SubsegmentDuration: duration,
+			StartsWithSAP:      sap>>31 == 1,
+			SAPType:            uint8(sap>>28) & 0x07,
+			SAPDeltaTime:       sap & 0x0fffffff,
+		})
+	}
+	if r.err != nil {
+		return sidx, fmt.Errorf("sidx: %v", r.err)
+	}
+	return sidx, nil
+}
+
+// parseEmsg : read an emsg box
+func parseEmsg(buf []byte, b Box) (Emsg, error) {
+
+	var emsg Emsg
+	r := newReader(buf, b)
+	emsg.Version, _ = r.fullBox()
+	switch emsg.Version {
+	case 0:
+		emsg.SchemeIDURI = r.cstring()
+		emsg.Value = r.cstring()
+		emsg.Timescale = r.u32()
+		emsg.PresentationTime = uint64(r.u32())
+		emsg.EventDuration = r.u32()
+		emsg.ID = r.u32()
+	case 1:
+		emsg.Timescale = r.u32()
+		emsg.PresentationTime = r.u64()
+		emsg.EventDuration = r.u32()
+		emsg.ID = r.u32()
+		emsg.SchemeIDURI = r.cstring()
+		emsg.Value = r.cstring()
+	default:
+		return emsg, fmt.Errorf("emsg version %d is not supported", emsg.Version)
+	}
+	emsg.MessageData = append([]byte{}, r.rest()...)
+	if r.err != nil {
+		return emsg, fmt.Errorf("emsg: %v", r.err)
+	}
+	return emsg, nil
+}
+
+// Samples : the samples of the reported track, in decode order
+func (s *Segment) Samples() []Sample {
+	var samples []Sample
+	for _, f := range s.Fragments {
+		if f.TrackID == s.TrackID {
+			samples = append(samples, f.Samples...)
+		}
+	}
+	return samples
+}
+
+// Timescale : the timescale of the reported track (0 if unknown)
+func (s *Segment) Timescale() uint32 {
+	for _, f := range s.Fragments {
+		if f.TrackID == s.TrackID {
+			return f.Timescale
+		}
+	}
+	return 0
+}
+
+// PayloadBytes : the media payload of the segment, without any of the box headers
+func (s *Segment) PayloadBytes() int64 {
+	return s.MdatBytes
+}
+
+// SampleCount : the number of samples (frames for video) of the reported track
+func (s *Segment) SampleCount() int {
+	return len(s.Samples())
+}
+
+// Duration : the sum of the sample durations of the reported track (0 if the timescale is unknown)
+func (s *Segment) Duration() time.Duration {
+	timescale := s.Timescale()
+	if timescale == 0 {
+		return 0
+	}
+	var ticks uint64
+	for _, sample := range s.Samples() {
+		ticks += uint64(sample.Duration)
+	}
+	return ticksToDuration(ticks, timescale)
+}
+
+// FrameRate : samples per second of the reported track (0 if the duration is unknown)
+func (s *Segment) FrameRate() float64 {
+	d := s.Duration()
+	if d <= 0 {
+		return 0
+	}
+	return float64(s.SampleCount()) / d.Seconds()
+}
+
+// KeyframePositions : the index of each sync sample of the reported track
+func (s *Segment) KeyframePositions() []int {
+	var positions []int
+	for k, sample := range s.Samples() {
+		if sample.IsKeyframe() {
+			positions = append(positions, k)
+		}
+	}
+	return positions
+}
+
+// DecodeTimes : the decode timestamp of each sample of the reported track (nil if the timescale is unknown)
+func (s *Segment) DecodeTimes() []time.Duration {
+	timescale := s.Timescale()
+	if timescale == 0 {
+		return nil
+	}
+	samples := s.Samples()
+	times := make([]time.Duration, len(samples))
+	for k, sample := range samples {
+		times[k] = ticksToDuration(sample.DecodeTime, timescale)
+	}
+	return times
+}
+
+// ticksToDuration : convert a time in timescale units to a duration
+func ticksToDuration(ticks uint64, timescale uint32) time.Duration {
+	seconds := ticks / uint64(timescale)
+	remainder := ticks % uint64(timescale)
+	return time.Duration(seconds)*time.Second + time.Duration(remainder*uint64(time.Second)/uint64(timescale))
+}
@@ -0,0 +1,226 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+// Package live serves what a running player is doing to local clients over Server-Sent Events:
+// the qlog-abr events of the StreamTracer and the log record of each segment, as they happen.
+// Publishing never blocks the player, the events of a client that does not keep up are dropped.
+package live
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/uccmisl/godash/logging"
+)
+
+// EventQlog : the SSE event of a qlog-abr event, its data is the qlog event
+const EventQlog = "qlog"
+
+// EventSegment : the SSE event of the log record of a segment, its data is the SegPrintLogInformation
+const EventSegment = "segment"
+
+// clientBuffer : the number of events a client can fall behind before its events are dropped
+const clientBuffer = 256
+
+// message : an event of the stream
+type message struct {
+	event string
+	data  []byte
+}
+
+// Server : the live view of a player
+type Server struct {
+	mutex    sync.Mutex
+	clients  map[chan message]struct{}
+	dropped  uint64
+	server   *http.Server
+	listener net.Listener
+}
+
+// NewServer : the live view, on a local address such as "localhost:8080"
+func NewServer(addr string) *Server {
+	s := &Server{clients: make(map[chan message]struct{})}
+	s.server = &http.Server{Addr: addr, Handler: s.Handler()}
+	return s
+}
+
+// Handler : the page of the live view at "/", and its events at "/events"
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/events", s.serveEvents)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, page)
+	})
+	return mux
+}
+
+// Start : listen on the address of the server, and serve the clients in the background
+func (s *Server) Start() error {
+	listener, err := net.Listen("tcp", s.server.Addr)
+	if err != nil {
+		return err
+	}
+	s.listener = listener
+	go s.server.Serve(listener)
+	return nil
+}
+
+// Addr : the address the server listens on, once it is started
+func (s *Server) Addr() string {
+	if s.listener == nil {
+		return s.server.Addr
+	}
+	return s.listener.Addr().String()
+}
+
+// Close : stop the server and disconnect its clients
+func (s *Server) Close() error {
+	if s == nil {
+		return nil
+	}
+	return s.server.Close()
+}
+
+// Publish : send an event to every connected client, without waiting for any of them.
+// A nil server publishes nothing, so the player can publish without checking for one.
+func (s *Server) Publish(event string, data []byte) {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if len(s.clients) == 0 {
+		return
+	}
+	// the data of a qlog event is reused by the tracer once it returns
+	m := message{event: event, data: append([]byte(nil), data...)}
+	for messages := range s.clients {
+		select {
+		case messages <- m:
+		default:
+			s.dropped++
+		}
+	}
+}
+
+// QlogEvent : publish a qlog-abr event, it is a sink of the StreamTracer
+func (s *Server) QlogEvent(record []byte) {
+	s.Publish(EventQlog, record)
+}
+
+// Segment : publish the log record of a segment
+func (s *Server) Segment(information logging.SegPrintLogInformation) {
+	if s == nil {
+		return
+	}
+	data, err := json.Marshal(information)
+	if err != nil {
+		// a record with a value JSON can not hold (NaN) is not published
+		return
+	}
+	s.Publish(EventSegment, data)
+}
+
+// Dropped : the number of events dropped for the clients that did not keep up
+func (s *Server) Dropped() uint64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.dropped
+}
+
+func (s *Server) subscribe() chan message {
+	messages := make(chan message, clientBuffer)
+	s.mutex.Lock()
+	s.clients[messages] = struct{}{}
+	s.mutex.Unlock()
+	return messages
+}
+
+func (s *Server) unsubscribe(messages chan message) {
+	s.mutex.Lock()
+	delete(s.clients, messages)
+	s.mutex.Unlock()
+}
+
+// serveEvents : stream the events to a client until it disconnects
+func (s *Server) serveEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	messages := s.subscribe()
+	defer s.unsubscribe(messages)
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case m := <-messages:
+			// the data is a single line of JSON
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", m.event, m.data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// page : the live view in a browser, the latest segment and the latest events
+const page = `<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>goDASH live</title>
+<style>body{font-family:monospace;margin:1em}pre{background:#f4f4f4;padding:.5em;max-height:40em;overflow:auto}</style>
+</head>
+<body>
+<h3>goDASH live</h3>
+<p id="segment">waiting for the first segment</p>
+<pre id="events"></pre>
+<script>
+const events = document.getElementById("events");
+const source = new EventSource("events");
+source.addEventListener("segment", e => {
+  const s = JSON.parse(e.data);
+  document.getElementById("segment").textContent = "segment " + s.SegmentIndex + " (" + s.MimeType + "): " +
+    Math.round(s.Bandwidth / 1000) + " kbps, buffer " + s.BufferLevel + " ms, stall " + s.StallTime + " ms";
+});
+source.addEventListener("qlog", e => {
+  const ev = JSON.parse(e.data);
+  events.textContent = (ev.time.toFixed(0) + " " + ev.name + " " + JSON.stringify(ev.data) + "\n" + events.textContent).slice(0, 20000);
+});
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,96 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package live
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/uccmisl/godash/logging"
+)
+
+func TestPublishSlowClient(t *testing.T) {
+	var none *Server
+	none.Publish(EventQlog, []byte("{}"))
+
+	s := NewServer("localhost:0")
+	// without a client, nothing is kept
+	s.Publish(EventQlog, []byte("{}"))
+	messages := s.subscribe()
+	for i := 0; i < clientBuffer+5; i++ {
+		s.Publish(EventQlog, []byte("{}"))
+	}
+	if len(messages) != clientBuffer || s.Dropped() != 5 {
+		t.Errorf("%d events queued, %d dropped", len(messages), s.Dropped())
+	}
+}
+
+func TestServeEvents(t *testing.T) {
+	s := NewServer("localhost:0")
+	server := httptest.NewServer(s.Handler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("content type %s", resp.Header.Get("Content-Type"))
+	}
+	// the client is subscribed once the headers are sent
+	for i := 0; i < 100; i++ {
+		s.mutex.Lock()
+		subscribed := len(s.clients) == 1
+		s.mutex.Unlock()
+		if subscribed {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	record := []byte(`{"time":1,"name":"abr:switch","data":{}}`)
+	s.QlogEvent(record)
+	// the tracer reuses the record
+	copy(record, "xxxxxxxx")
+	s.Segment(logging.SegPrintLogInformation{SegmentIndex: 3, Bandwidth: 1000000})
+
+	r := bufio.NewReader(resp.Body)
+	var lines []string
+	for len(lines) < 6 {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, strings.TrimSuffix(line, "\n"))
+	}
+	if lines[0] != "event: qlog" || lines[1] != `data: {"time":1,"name":"abr:switch","data":{}}` || lines[2] != "" {
+		t.Errorf("qlog event %q", lines[:3])
+	}
+	if lines[3] != "event: segment" || !strings.Contains(lines[4], `"SegmentIndex":3`) || !strings.Contains(lines[4], `"Bandwidth":1000000`) {
+		t.Errorf("segment event %q", lines[3:5])
+	}
+}
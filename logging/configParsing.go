@@ -37,31 +37,59 @@ import (
 
 // Config : Struct for reading content from the config file in json
 type Config struct {
-	URL            string  `json:"url"`
-	Adapt          string  `json:"adapt"`
-	Codec          string  `json:"codec"`
-	Debug          string  `json:"debug"`
-	InitBuffer     int     `json:"initBuffer"`
-	MaxBuffer      int     `json:"maxBuffer"`
-	MaxHeight      int     `json:"maxHeight"`
-	StreamDuration int     `json:"streamDuration"`
-	StreamSpeed    float64 `json:"streamSpeed"`
-	OutputFolder   string  `json:"outputFolder"`
-	StoreDash      string  `json:"storeDash"`
-	TerminalPrint  string  `json:"terminalPrint"`
-	HLS            string  `json:"hls"`
-	GetHeaders     string  `json:"getHeaders"`
-	ExpRatio       float64 `json:"expRatio"`
-	Quic           string  `json:"quic"`
-	PrintHeader    string  `json:"printHeader"`
-	UseTestbed     string  `json:"useTestbed"`
-	QoE            string  `json:"QoE"`
-	LogFile        string  `json:"logFile"`
-	CollabPrint    string  `json:"serveraddr"`
+	URL                  string  `json:"url"`
+	Adapt                string  `json:"adapt"`
+	Codec                string  `json:"codec"`
+	Debug                string  `json:"debug"`
+	InitBuffer           int     `json:"initBuffer"`
+	MaxBuffer            int     `json:"maxBuffer"`
+	MaxHeight            int     `json:"maxHeight"`
+	StreamDuration       int     `json:"streamDuration"`
+	StreamSpeed          float64 `json:"streamSpeed"`
+	OutputFolder         string  `json:"outputFolder"`
+	StoreDash            string  `json:"storeDash"`
+	TerminalPrint        string  `json:"terminalPrint"`
+	HLS                  string  `json:"hls"`
+	GetHeaders           string  `json:"getHeaders"`
+	ExpRatio             float64 `json:"expRatio"`
+	Quic                 string  `json:"quic"`
+	PrintHeader          string  `json:"printHeader"`
+	UseTestbed           string  `json:"useTestbed"`
+	QoE                  string  `json:"QoE"`
+	P1203Python          string  `json:"p1203Python"`
+	LogFile              string  `json:"logFile"`
+	CollabPrint          string  `json:"serveraddr"`
+	Headers              string  `json:"headers"`
+	Cookies              string  `json:"cookies"`
+	CookieJar            string  `json:"cookieJar"`
+	Proxy                string  `json:"proxy"`
+	TokenScript          string  `json:"tokenScript"`
+	TokenURL             string  `json:"tokenURL"`
+	TokenRefresh         int     `json:"tokenRefresh"`
+	LicenseURL           string  `json:"licenseURL"`
+	StallModel           string  `json:"stallModel"`
+	StallWindow          int     `json:"stallWindow"`
+	StallThreshold       float64 `json:"stallThreshold"`
+	StallAbort           string  `json:"stallAbort"`
+	Estimator            string  `json:"estimator"`
+	EstimatorWindow      int     `json:"estimatorWindow"`
+	EstimatorSeason      int     `json:"estimatorSeason"`
+	QlogMerge            string  `json:"qlogMerge"`
+	QlogFormat           string  `json:"qlogFormat"`
+	QlogPlayheadInterval int     `json:"qlogPlayheadInterval"`
+	QlogDir              string  `json:"qlogDir"`
+	QlogPattern          string  `json:"qlogPattern"`
+	QlogGzip             string  `json:"qlogGzip"`
+	QlogCategories       string  `json:"qlogCategories"`
+	LiveAddr             string  `json:"liveAddr"`
+	MetricsAddr          string  `json:"metricsAddr"`
+	Report               string  `json:"report"`
 }
 
-// Configure : extract all parameter values from the input config file
-func Configure(file string, debugFile string, debugLog bool) (urls string, adapt string, codec string, maxHeight int, streamDuration int, streamSpeed float64, maxBuffer int, initBuffer int, hLS string, outputFolder string, storeDash string, getHeader string, debug string, terminalPrint string, quic string, expRatio float64, printHeader string, useTestbed string, qoe string, configLogFile string, collabPrint string) {
+// Configure :
+// * extract all parameter values from the input config file
+// * the URL of the returned config is the comma separated list of its urls
+func Configure(file string, debugFile string, debugLog bool) Config {
 
 	// unmarshal the json file
 	config := recupStructWithConfigFile(file, debugFile, debugLog)
@@ -69,43 +97,11 @@ func Configure(file string, debugFile string, debugLog bool) (urls string, adapt
 	// get all of the URLs from the config file
 	requestedURLs := recupURLsFromConfig(config)
 
-	// get all of the variables from the config file
-	adapt, codec, maxHeight, streamDuration, streamSpeed, maxBuffer, initBuffer, hLS, outputFolder, storeDash, getHeader, debug, terminalPrint, quic, expRatio, printHeader, useTestbed, qoe, configLogFile, collabPrint = recupParameters(config)
-
 	// get list of urls
-	urls = string(strings.Join(requestedURLs, ","))
-
-	return
-}
-
-// RecupParameters : extract all of the values from the config struct (excluding url)
-func recupParameters(config Config) (adapt string, codec string, maxHeight int, streamDuration int, streamSpeed float64, maxBuffer int, initBuffer int, hLS string, outputFolder string, storeDash string, getHeaders string, debug string, terminalPrint string, quic string, expRatio float64, printHeader string, useTestbed string, qoe string, configLogFile string, collab string) {
-
-	// there is no need to test conmpatibility for any of these parameters as main.go tests will check for this
-
-	// get all passed in parameters and return them
-	adapt = config.Adapt
-	codec = config.Codec
-	maxHeight = config.MaxHeight
-	streamDuration = config.StreamDuration
-	streamSpeed = config.StreamSpeed
-	maxBuffer = config.MaxBuffer
-	initBuffer = config.InitBuffer
-	outputFolder = config.OutputFolder
-	storeDash = config.StoreDash
-	hLS = config.HLS
-	getHeaders = config.GetHeaders
-	debug = config.Debug
-	terminalPrint = config.TerminalPrint
-	quic = config.Quic
-	expRatio = config.ExpRatio
-	printHeader = config.PrintHeader
-	useTestbed = config.UseTestbed
-	qoe = config.QoE
-	configLogFile = config.LogFile
-	collab = config.CollabPrint
+	// there is no need to test conmpatibility for any of the other parameters as main.go tests will check for this
+	config.URL = strings.Join(requestedURLs, ",")
 
-	return
+	return config
 }
 
 // RecupStructWithConfigFile : take the input file and generate a config struct
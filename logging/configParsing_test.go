@@ -0,0 +1,48 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package logging
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfigure(t *testing.T) {
+
+	file := filepath.Join(t.TempDir(), "configure")
+	content := `{"url": "[http://a.example.com/a.mpd, http://b.example.com/b.mpd]", "adapt": "bba", "maxBuffer": 60, "stallThreshold": 0.5, "report": "on"}`
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config := Configure(file, "", false)
+	if config.URL != "http://a.example.com/a.mpd,http://b.example.com/b.mpd" {
+		t.Errorf("urls are %q", config.URL)
+	}
+	if config.Adapt != "bba" || config.MaxBuffer != 60 || config.StallThreshold != 0.5 || config.Report != "on" {
+		t.Errorf("parameters not read from the config file: %+v", config)
+	}
+	if config.Codec != "" || config.StreamDuration != 0 {
+		t.Errorf("parameters not in the config file should be empty: %+v", config)
+	}
+}
@@ -81,6 +81,26 @@ type SegPrintLogInformation struct {
 	RateChange     []float64
 	MimeType       string
 	Profile        string
+	// time taken to get the license for encrypted content (first segment only)
+	LicenseTime int
+	// values read from the boxes of the segment (0 if the segment could not be read)
+	PayloadSize       int
+	SampleCount       int
+	FrameRate         float64
+	KeyframePositions []int
+	// decode timestamp of the first sample in milliseconds
+	DecodeTime int
+	// the timing of each phase of the request in milliseconds (0 on a reused connection)
+	DNSTime     float64
+	ConnectTime float64
+	TLSTime     float64
+	TTFB        float64
+	TTLB        float64
+	ConnReused  bool
+	// the throughput estimate of the estimator after this segment in bits/second (0 without an estimator)
+	Estimate int
+	// the bottleneck link capacity of the connection of this segment in bits/second (0 over TCP, or before enough packets have arrived)
+	Capacity int
 }
 
 // headers for the print log
@@ -103,6 +123,13 @@ const playHeader = glob.PlayHeader
 const rttHeader = glob.RttHeader
 const segReplaceHeader = glob.SegReplaceHeader
 const httpProtocolHeader = glob.HTTPProtocolHeader
+const dnsHeader = glob.DNSHeader
+const connectHeader = glob.ConnectHeader
+const tlsHeader = glob.TLSHeader
+const ttfbHeader = glob.TTFBHeader
+const ttlbHeader = glob.TTLBHeader
+const reusedHeader = glob.ReusedHeader
+const estimateHeader = glob.EstimateHeader
 
 // QOE
 const p1203Header = glob.P1203Header
@@ -206,12 +233,12 @@ func PrintsegInformationLogMap(debugFile string, debugLog bool, mapSegments map[
 
 	// print map header
 	mainPrintString := "%7s  %10s  %8s  %12s  %8s  %12s  %8s  %8s  %10s"
-	extendPrintString := "  %12s%s%s%s%s%s%s%s%s%s%s%s%s%s%s\n"
-	PrintToFile("seg_Num", "size", "downTime", "thr", "duration", "playbackTime", "repIndex", "MPDIndex", "adaptIndex", "bandwith", "", true, "", "", "", "", "", "", mainPrintString, extendPrintString, debugFile, "", "", "", "", "", "", "")
+	extendPrintString := "  %12s%s%s%s%s%s%s%s%s%s%s%s%s%s%s%s%s%s%s%s%s%s\n"
+	PrintToFile("seg_Num", "size", "downTime", "thr", "duration", "playbackTime", "repIndex", "MPDIndex", "adaptIndex", "bandwith", "", true, "", "", "", "", "", "", mainPrintString, extendPrintString, debugFile, "", "", "", "", "", "", "", "", "", "", "", "", "", "")
 
 	for k := 1; k <= len(mapSegments); k++ {
 		// print out each segment map
-		PrintToFile(strconv.Itoa(k), strconv.Itoa(mapSegments[k].SegSize), strconv.Itoa(mapSegments[k].DeliveryTime), strconv.Itoa(mapSegments[k].DelRate), strconv.Itoa(mapSegments[k].SegmentDuration*glob.Conversion1000), strconv.Itoa(mapSegments[k].PlaybackTime), strconv.Itoa(mapSegments[k].RepIndex), strconv.Itoa(mapSegments[k].MpdIndex), strconv.Itoa(mapSegments[k].AdaptIndex), strconv.Itoa(mapSegments[k].Bandwidth), "", true, "", "", "", "", "", "", mainPrintString, extendPrintString, debugFile, "", "", "", "", "", "", "")
+		PrintToFile(strconv.Itoa(k), strconv.Itoa(mapSegments[k].SegSize), strconv.Itoa(mapSegments[k].DeliveryTime), strconv.Itoa(mapSegments[k].DelRate), strconv.Itoa(mapSegments[k].SegmentDuration*glob.Conversion1000), strconv.Itoa(mapSegments[k].PlaybackTime), strconv.Itoa(mapSegments[k].RepIndex), strconv.Itoa(mapSegments[k].MpdIndex), strconv.Itoa(mapSegments[k].AdaptIndex), strconv.Itoa(mapSegments[k].Bandwidth), "", true, "", "", "", "", "", "", mainPrintString, extendPrintString, debugFile, "", "", "", "", "", "", "", "", "", "", "", "", "", "")
 	}
 	// }
 }
@@ -220,7 +247,8 @@ func PrintsegInformationLogMap(debugFile string, debugLog bool, mapSegments map[
 // * print a line to the file logDownload
 func PrintToFile(segNum string, arrTime string, delTime string, stallDur string,
 	repLevel string, delRate string, actRate string, byteSize string,
-	buffLevel string, algo string, segDuration string, extendPrintLog bool, codec string, width string, height string, fps string, playHeader string, rttHeader string, mainPrintString string, extendPrintString string, fileLocation string, segReplace string, httpProtocol string, p1203 string, clae string, duanmu string, yin string, yu string) {
+	buffLevel string, algo string, segDuration string, extendPrintLog bool, codec string, width string, height string, fps string, playHeader string, rttHeader string, mainPrintString string, extendPrintString string, fileLocation string, segReplace string, httpProtocol string, p1203 string, clae string, duanmu string, yin string, yu string,
+	dns string, connect string, tlsTime string, ttfb string, ttlb string, reused string, estimate string) {
 
 	// open the logfile and print to it
 	f, err := os.OpenFile(fileLocation, os.O_APPEND|os.O_WRONLY, 0644)
@@ -236,7 +264,7 @@ func PrintToFile(segNum string, arrTime string, delTime string, stallDur string,
 
 	if extendPrintLog {
 		//fmt.Fprint(f, algo+"\t"+segDuration+"\t"+codec+"\t"+height+"\t"+width+"\t"+fps+"\t"+playHeader+"\t"+rttHeader+"\t\n")
-		fmt.Fprintf(f, extendPrintString, algo, segDuration, codec, width, height, fps, playHeader, rttHeader, segReplace, httpProtocol, p1203, clae, duanmu, yin, yu)
+		fmt.Fprintf(f, extendPrintString, algo, segDuration, codec, width, height, fps, playHeader, rttHeader, segReplace, httpProtocol, p1203, clae, duanmu, yin, yu, dns, connect, tlsTime, ttfb, ttlb, reused, estimate)
 	} else {
 		fmt.Fprint(f, "\n")
 	}
@@ -258,17 +286,22 @@ func PrintHeaders(extendPrintLog bool, fileLocation string, logDownload string,
 
 	// print a line of the log file to terminal
 	PrintLog(segNum, arrTime, delTime, stallDur, repLevel, delRate, actRate,
-		byteSize, buffLevel, algoHeader, segDurHeader, extendPrintLog, codecHeader, heightHeader, widthHeader, fpsHeader, playHeader, rttHeader, fileLocation, logDownload, printLog, printHeadersData, segReplaceHeader, httpProtocolHeader, p1203Header, claeHeader, duanmuHeader, yinHeader, yuHeader)
+		byteSize, buffLevel, algoHeader, segDurHeader, extendPrintLog, codecHeader, heightHeader, widthHeader, fpsHeader, playHeader, rttHeader, fileLocation, logDownload, printLog, printHeadersData, segReplaceHeader, httpProtocolHeader, p1203Header, claeHeader, duanmuHeader, yinHeader, yuHeader,
+		dnsHeader, connectHeader, tlsHeader, ttfbHeader, ttlbHeader, reusedHeader, estimateHeader)
+
+	// every field of each segment is also saved as CSV and JSON Lines
+	createSegmentLog(fileLocation, debugFile, debugLog)
 }
 
 // PrintLog :
 // * print a line to the output log
 func PrintLog(segNum string, arrTime string, delTime string, stallDur string,
 	repLevel string, delRate string, actRate string, byteSize string,
-	buffLevel string, algoIn string, segDurationIn string, extendPrintLog bool, codecIn string, widthIn string, heightIn string, fpsIn string, playIn string, rttIn string, fileLocation string, logDownload string, printLog bool, printHeadersData map[string]string, segReplaceIn string, httpProtocolIn string, p1203In string, claeIn string, duanmuIn string, yinIn string, yuIn string) {
+	buffLevel string, algoIn string, segDurationIn string, extendPrintLog bool, codecIn string, widthIn string, heightIn string, fpsIn string, playIn string, rttIn string, fileLocation string, logDownload string, printLog bool, printHeadersData map[string]string, segReplaceIn string, httpProtocolIn string, p1203In string, claeIn string, duanmuIn string, yinIn string, yuIn string,
+	dnsIn string, connectIn string, tlsIn string, ttfbIn string, ttlbIn string, reusedIn string, estimateIn string) {
 
 	const mainPrintString = "%10s   %10s   %10s   %10s   %10s   %10s   %10s   %10s   %10s"
-	const fileExtendPrintString = "   %12s   %7s   %5s   %5s   %6s   %5s   %8s   %8s   %s   %8s   %8s   %8s   %8s   %12s   %12s\n"
+	const fileExtendPrintString = "   %12s   %7s   %5s   %5s   %6s   %5s   %8s   %8s   %s   %8s   %8s   %8s   %8s   %12s   %12s   %8s   %8s   %8s   %8s   %8s   %6s   %12s\n"
 	var extendPrintString = ""
 	const fiveString = "   %5s"
 	const eightString = "   %8s"
@@ -288,6 +321,13 @@ func PrintLog(segNum string, arrTime string, delTime string, stallDur string,
 	var duanmu = ""
 	var yin = ""
 	var yu = ""
+	var dns = ""
+	var connect = ""
+	var tlsTime = ""
+	var ttfb = ""
+	var ttlb = ""
+	var reused = ""
+	var estimate = ""
 
 	//"   %12s   %7s   %5s   %5s   %6s   %5s   %8s   %8s\n"
 	//"Algorithm\":\"off\",\"Seg_Dur\":\"on\",\"Codec\":\"on\",\"Width\":\"on\",\"Height\":\"on\",\"FPS\":\"on\",\"Play_Pos\":\"on\",\"RTT\"
@@ -313,10 +353,17 @@ func PrintLog(segNum string, arrTime string, delTime string, stallDur string,
 			checkInputHeader(printHeadersData, duanmuHeader, &extendPrintString, twelveString, &duanmu, duanmuIn)
 			checkInputHeader(printHeadersData, yinHeader, &extendPrintString, twelveString, &yin, yinIn)
 			checkInputHeader(printHeadersData, yuHeader, &extendPrintString, twelveString, &yu, yuIn)
+			checkInputHeader(printHeadersData, dnsHeader, &extendPrintString, eightString, &dns, dnsIn)
+			checkInputHeader(printHeadersData, connectHeader, &extendPrintString, eightString, &connect, connectIn)
+			checkInputHeader(printHeadersData, tlsHeader, &extendPrintString, eightString, &tlsTime, tlsIn)
+			checkInputHeader(printHeadersData, ttfbHeader, &extendPrintString, eightString, &ttfb, ttfbIn)
+			checkInputHeader(printHeadersData, ttlbHeader, &extendPrintString, eightString, &ttlb, ttlbIn)
+			checkInputHeader(printHeadersData, reusedHeader, &extendPrintString, "   %6s", &reused, reusedIn)
+			checkInputHeader(printHeadersData, estimateHeader, &extendPrintString, twelveString, &estimate, estimateIn)
 
 			// one of these has to be true, so print a new line at the end
 			extendPrintString += "\n"
-			fmt.Printf(extendPrintString, algo, segDuration, codec, width, height, fps, play, rtt, segReplace, httpProtocol, p1203, clae, duanmu, yin, yu)
+			fmt.Printf(extendPrintString, algo, segDuration, codec, width, height, fps, play, rtt, segReplace, httpProtocol, p1203, clae, duanmu, yin, yu, dns, connect, tlsTime, ttfb, ttlb, reused, estimate)
 		} else {
 			fmt.Printf("\n")
 		}
@@ -324,7 +371,8 @@ func PrintLog(segNum string, arrTime string, delTime string, stallDur string,
 
 	printLocal := fileLocation + "/" + logDownload
 
-	PrintToFile(segNum, arrTime, delTime, stallDur, repLevel, delRate, actRate, byteSize, buffLevel, algoIn, segDurationIn, extendPrintLog, codecIn, widthIn, heightIn, fpsIn, playIn, rttIn, mainPrintString, fileExtendPrintString, printLocal, segReplaceIn, httpProtocolIn, p1203In, claeIn, duanmuIn, yinIn, yuIn)
+	PrintToFile(segNum, arrTime, delTime, stallDur, repLevel, delRate, actRate, byteSize, buffLevel, algoIn, segDurationIn, extendPrintLog, codecIn, widthIn, heightIn, fpsIn, playIn, rttIn, mainPrintString, fileExtendPrintString, printLocal, segReplaceIn, httpProtocolIn, p1203In, claeIn, duanmuIn, yinIn, yuIn,
+		dnsIn, connectIn, tlsIn, ttfbIn, ttlbIn, reusedIn, estimateIn)
 }
 
 //
@@ -353,6 +401,15 @@ func checkInputHeader(printHeadersData map[string]string, key string, extendPrin
 	}
 }
 
+// segmentFps :
+// * return the frame rate read from the segment, or the MPD frame rate if we could not read the segment
+func segmentFps(segment SegPrintLogInformation) string {
+	if segment.FrameRate > 0 {
+		return strconv.FormatFloat(segment.FrameRate, 'f', -1, 64)
+	}
+	return strconv.Itoa(segment.RepFps)
+}
+
 // PrintPlayOutLog :
 // * print the play_out logs only when the current time is >= play_out time
 func PrintPlayOutLog(currentTime int, initBuffer int, mapSegments []map[int]SegPrintLogInformation, logDownload string, printLog bool, printHeadersData map[string]string) {
@@ -379,7 +436,7 @@ func PrintPlayOutLog(currentTime int, initBuffer int, mapSegments []map[int]SegP
 					mapSegments[logIndex][playoutSegmentNumber].RepCodec,
 					strconv.Itoa(mapSegments[logIndex][playoutSegmentNumber].RepWidth),
 					strconv.Itoa(mapSegments[logIndex][playoutSegmentNumber].RepHeight),
-					strconv.Itoa(mapSegments[logIndex][playoutSegmentNumber].RepFps),
+					segmentFps(mapSegments[logIndex][playoutSegmentNumber]),
 					// print out the value of the comulative segment size less the segment size of the first segment
 					strconv.Itoa(mapSegments[logIndex][playoutSegmentNumber-1].PlayStartPosition),
 					fmt.Sprintf("%.3f", mapSegments[logIndex][playoutSegmentNumber].Rtt),
@@ -394,12 +451,24 @@ func PrintPlayOutLog(currentTime int, initBuffer int, mapSegments []map[int]SegP
 					fmt.Sprintf("%.3f", mapSegments[logIndex][playoutSegmentNumber].Clae),
 					fmt.Sprintf("%.3f", mapSegments[logIndex][playoutSegmentNumber].Duanmu),
 					fmt.Sprintf("%.3f", mapSegments[logIndex][playoutSegmentNumber].Yin),
-					fmt.Sprintf("%.3f", mapSegments[logIndex][playoutSegmentNumber].Yu))
+					fmt.Sprintf("%.3f", mapSegments[logIndex][playoutSegmentNumber].Yu),
+					// add the request timing
+					fmt.Sprintf("%.3f", mapSegments[logIndex][playoutSegmentNumber].DNSTime),
+					fmt.Sprintf("%.3f", mapSegments[logIndex][playoutSegmentNumber].ConnectTime),
+					fmt.Sprintf("%.3f", mapSegments[logIndex][playoutSegmentNumber].TLSTime),
+					fmt.Sprintf("%.3f", mapSegments[logIndex][playoutSegmentNumber].TTFB),
+					fmt.Sprintf("%.3f", mapSegments[logIndex][playoutSegmentNumber].TTLB),
+					strconv.FormatBool(mapSegments[logIndex][playoutSegmentNumber].ConnReused),
+					// add the throughput estimate
+					strconv.Itoa(mapSegments[logIndex][playoutSegmentNumber].Estimate))
 
 				// update the played boolean to true
 				localMap := mapSegments[logIndex][playoutSegmentNumber]
 				localMap.Played = true
 				mapSegments[logIndex][playoutSegmentNumber] = localMap
+
+				// and every field of the segment
+				printSegmentLog(localMap.FileDownloadLocation, playoutSegmentNumber, localMap)
 			}
 		}
 	}
@@ -0,0 +1,206 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package logging
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"strconv"
+	"strings"
+
+	glob "github.com/uccmisl/godash/global"
+)
+
+// SegmentLogSchemaVersion : the version of the fields of logDownload.csv and logDownload.jsonl,
+// increased when a field is renamed or removed or when its unit changes - new fields are added at the end
+const SegmentLogSchemaVersion = 1
+
+// segmentField : a field of the segment log, with its unit ("" if it has none)
+type segmentField struct {
+	name  string
+	unit  string
+	value func(segment int, s SegPrintLogInformation) interface{}
+}
+
+// segmentFields : the fields of the segment log, in the order of the columns.
+// SegmentRates and RateChange are left out, they are the history of bitrate and rate_difference
+var segmentFields = []segmentField{
+	{"segment", "", func(segment int, s SegPrintLogInformation) interface{} { return segment }},
+	{"arrival_time", "ms", func(_ int, s SegPrintLogInformation) interface{} { return s.ArrivalTime }},
+	{"delivery_time", "ms", func(_ int, s SegPrintLogInformation) interface{} { return s.DeliveryTime }},
+	{"stall_time", "ms", func(_ int, s SegPrintLogInformation) interface{} { return s.StallTime }},
+	{"bitrate", "bps", func(_ int, s SegPrintLogInformation) interface{} { return s.Bandwidth }},
+	{"delivery_rate", "bps", func(_ int, s SegPrintLogInformation) interface{} { return s.DelRate }},
+	{"actual_rate", "kbps", func(_ int, s SegPrintLogInformation) interface{} { return s.ActRate }},
+	{"segment_size", "bytes", func(_ int, s SegPrintLogInformation) interface{} { return s.SegSize }},
+	{"p1203_header_rate", "kbps", func(_ int, s SegPrintLogInformation) interface{} { return s.P1203HeaderSize }},
+	{"buffer_level", "ms", func(_ int, s SegPrintLogInformation) interface{} { return s.BufferLevel }},
+	{"algorithm", "", func(_ int, s SegPrintLogInformation) interface{} { return s.Adapt }},
+	{"segment_duration", "s", func(_ int, s SegPrintLogInformation) interface{} { return s.SegmentDuration }},
+	{"extend_print_log", "", func(_ int, s SegPrintLogInformation) interface{} { return s.ExtendPrintLog }},
+	{"codec", "", func(_ int, s SegPrintLogInformation) interface{} { return s.RepCodec }},
+	{"width", "px", func(_ int, s SegPrintLogInformation) interface{} { return s.RepWidth }},
+	{"height", "px", func(_ int, s SegPrintLogInformation) interface{} { return s.RepHeight }},
+	{"fps", "fps", func(_ int, s SegPrintLogInformation) interface{} { return s.RepFps }},
+	{"play_start_position", "ms", func(_ int, s SegPrintLogInformation) interface{} { return s.PlayStartPosition }},
+	{"playback_time", "ms", func(_ int, s SegPrintLogInformation) interface{} { return s.PlaybackTime }},
+	{"rtt", "ms", func(_ int, s SegPrintLogInformation) interface{} { return s.Rtt }},
+	{"file_download_location", "", func(_ int, s SegPrintLogInformation) interface{} { return s.FileDownloadLocation }},
+	{"rep_index", "", func(_ int, s SegPrintLogInformation) interface{} { return s.RepIndex }},
+	{"mpd_index", "", func(_ int, s SegPrintLogInformation) interface{} { return s.MpdIndex }},
+	{"adapt_index", "", func(_ int, s SegPrintLogInformation) interface{} { return s.AdaptIndex }},
+	{"segment_index", "", func(_ int, s SegPrintLogInformation) interface{} { return s.SegmentIndex }},
+	{"played", "", func(_ int, s SegPrintLogInformation) interface{} { return s.Played }},
+	{"segment_replaced", "", func(_ int, s SegPrintLogInformation) interface{} { return s.SegReplace }},
+	{"p1203", "mos", func(_ int, s SegPrintLogInformation) interface{} { return s.P1203 }},
+	{"http_protocol", "", func(_ int, s SegPrintLogInformation) interface{} { return s.HTTPprotocol }},
+	{"clae", "", func(_ int, s SegPrintLogInformation) interface{} { return s.Clae }},
+	{"duanmu", "", func(_ int, s SegPrintLogInformation) interface{} { return s.Duanmu }},
+	{"yin", "", func(_ int, s SegPrintLogInformation) interface{} { return s.Yin }},
+	{"yu", "", func(_ int, s SegPrintLogInformation) interface{} { return s.Yu }},
+	{"p1203_rate", "kbps", func(_ int, s SegPrintLogInformation) interface{} { return s.P1203Kbps }},
+	{"segment_file_name", "", func(_ int, s SegPrintLogInformation) interface{} { return s.SegmentFileName }},
+	{"sum_bitrate", "bps", func(_ int, s SegPrintLogInformation) interface{} { return s.SumSegRate }},
+	{"total_stall_duration", "ms", func(_ int, s SegPrintLogInformation) interface{} { return s.TotalStallDur }},
+	{"stalls", "", func(_ int, s SegPrintLogInformation) interface{} { return s.NumStalls }},
+	{"switches", "", func(_ int, s SegPrintLogInformation) interface{} { return s.NumSwitches }},
+	{"rate_difference", "bps", func(_ int, s SegPrintLogInformation) interface{} { return s.RateDifference }},
+	{"sum_rate_change", "bps", func(_ int, s SegPrintLogInformation) interface{} { return s.SumRateChange }},
+	{"mime_type", "", func(_ int, s SegPrintLogInformation) interface{} { return s.MimeType }},
+	{"profile", "", func(_ int, s SegPrintLogInformation) interface{} { return s.Profile }},
+	{"license_time", "ms", func(_ int, s SegPrintLogInformation) interface{} { return s.LicenseTime }},
+	{"payload_size", "bytes", func(_ int, s SegPrintLogInformation) interface{} { return s.PayloadSize }},
+	{"sample_count", "", func(_ int, s SegPrintLogInformation) interface{} { return s.SampleCount }},
+	{"frame_rate", "fps", func(_ int, s SegPrintLogInformation) interface{} { return s.FrameRate }},
+	{"keyframe_positions", "", func(_ int, s SegPrintLogInformation) interface{} { return s.KeyframePositions }},
+	{"decode_time", "ms", func(_ int, s SegPrintLogInformation) interface{} { return s.DecodeTime }},
+	{"dns_time", "ms", func(_ int, s SegPrintLogInformation) interface{} { return s.DNSTime }},
+	{"connect_time", "ms", func(_ int, s SegPrintLogInformation) interface{} { return s.ConnectTime }},
+	{"tls_time", "ms", func(_ int, s SegPrintLogInformation) interface{} { return s.TLSTime }},
+	{"ttfb", "ms", func(_ int, s SegPrintLogInformation) interface{} { return s.TTFB }},
+	{"ttlb", "ms", func(_ int, s SegPrintLogInformation) interface{} { return s.TTLB }},
+	{"connection_reused", "", func(_ int, s SegPrintLogInformation) interface{} { return s.ConnReused }},
+	{"estimate", "bps", func(_ int, s SegPrintLogInformation) interface{} { return s.Estimate }},
+	{"bottleneck_capacity", "bps", func(_ int, s SegPrintLogInformation) interface{} { return s.Capacity }},
+}
+
+// segmentLogUnits : the unit of each field that has one
+func segmentLogUnits() map[string]string {
+	units := make(map[string]string)
+	for _, f := range segmentFields {
+		if f.unit != "" {
+			units[f.name] = f.unit
+		}
+	}
+	return units
+}
+
+// createSegmentLog :
+// * create logDownload.csv and logDownload.jsonl next to logDownload.txt
+// * the first CSV row is the names of the columns, so any CSV reader can load it
+// * the schema version and units are saved to logDownload.schema.json
+// * the first JSON line is the schema version and units, then one object per segment
+func createSegmentLog(fileLocation string, debugFile string, debugLog bool) {
+
+	names := make([]string, len(segmentFields))
+	for i, f := range segmentFields {
+		names[i] = f.name
+	}
+	csvHeader := &bytes.Buffer{}
+	w := csv.NewWriter(csvHeader)
+	w.Write(names)
+	w.Flush()
+
+	schema, _ := json.Marshal(struct {
+		SchemaVersion int               `json:"schema_version"`
+		Fields        []string          `json:"fields"`
+		Units         map[string]string `json:"units"`
+	}{SegmentLogSchemaVersion, names, segmentLogUnits()})
+	schema = append(schema, '\n')
+
+	for file, header := range map[string][]byte{glob.LogDownloadCSV: csvHeader.Bytes(), glob.LogDownloadJSONL: schema, glob.LogDownloadSchema: schema} {
+		if err := os.WriteFile(fileLocation+"/"+file, header, 0644); err != nil {
+			DebugPrint(debugFile, debugLog, "DEBUG: ", "can't create the file "+file+" in files")
+		}
+	}
+}
+
+// printSegmentLog :
+// * add a segment to logDownload.csv and logDownload.jsonl
+func printSegmentLog(fileLocation string, segment int, s SegPrintLogInformation) {
+
+	record := make([]string, len(segmentFields))
+	line := &bytes.Buffer{}
+	line.WriteByte('{')
+	for i, f := range segmentFields {
+		value := f.value(segment, s)
+		switch v := value.(type) {
+		case string:
+			record[i] = v
+		case int:
+			record[i] = strconv.Itoa(v)
+		case float64:
+			record[i] = strconv.FormatFloat(v, 'f', -1, 64)
+		case bool:
+			record[i] = strconv.FormatBool(v)
+		case []int:
+			// the positions are separated by spaces in a single column
+			positions := make([]string, len(v))
+			for j, position := range v {
+				positions[j] = strconv.Itoa(position)
+			}
+			record[i] = strings.Join(positions, " ")
+			if v == nil {
+				value = []int{}
+			}
+		}
+		if i > 0 {
+			line.WriteByte(',')
+		}
+		name, _ := json.Marshal(f.name)
+		data, err := json.Marshal(value)
+		if err != nil {
+			// NaN and infinite values have no JSON number
+			data = []byte("null")
+		}
+		line.Write(name)
+		line.WriteByte(':')
+		line.Write(data)
+	}
+	line.WriteString("}\n")
+
+	csvLine := &bytes.Buffer{}
+	w := csv.NewWriter(csvLine)
+	w.Write(record)
+	w.Flush()
+
+	for file, data := range map[string][]byte{glob.LogDownloadCSV: csvLine.Bytes(), glob.LogDownloadJSONL: line.Bytes()} {
+		f, err := os.OpenFile(fileLocation+"/"+file, os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			continue
+		}
+		f.Write(data)
+		f.Close()
+	}
+}
@@ -0,0 +1,119 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package logging
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	glob "github.com/uccmisl/godash/global"
+)
+
+func TestSegmentLog(t *testing.T) {
+	dir := t.TempDir()
+	createSegmentLog(dir, "", false)
+	printSegmentLog(dir, 1, SegPrintLogInformation{ArrivalTime: 1200, Bandwidth: 2500000, Adapt: "bba", RepIndex: 3, AdaptIndex: 1,
+		MimeType: "video/mp4", Profile: "urn:mpeg:dash:profile:isoff-live:2011", SegmentFileName: "seg,1.m4s", P1203Kbps: 2400.5,
+		KeyframePositions: []int{0, 48}, Played: true})
+	printSegmentLog(dir, 2, SegPrintLogInformation{Rtt: math.NaN()})
+
+	f, err := os.Open(filepath.Join(dir, glob.LogDownloadCSV))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	records, err := r.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 || len(records[0]) != len(segmentFields) {
+		t.Fatalf("records %v", records)
+	}
+	row := make(map[string]string)
+	for i, name := range records[0] {
+		row[name] = records[1][i]
+	}
+	if row["segment"] != "1" || row["bitrate"] != "2500000" || row["rep_index"] != "3" || row["mime_type"] != "video/mp4" ||
+		row["segment_file_name"] != "seg,1.m4s" || row["p1203_rate"] != "2400.5" || row["keyframe_positions"] != "0 48" || row["played"] != "true" {
+		t.Errorf("row %v", row)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, glob.LogDownloadJSONL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("lines %q", lines)
+	}
+	var header struct {
+		SchemaVersion int               `json:"schema_version"`
+		Fields        []string          `json:"fields"`
+		Units         map[string]string `json:"units"`
+	}
+	if err := json.Unmarshal([]byte(lines[0]), &header); err != nil {
+		t.Fatal(err)
+	}
+	if header.SchemaVersion != SegmentLogSchemaVersion || !reflect.DeepEqual(header.Fields, records[0]) || header.Units["buffer_level"] != "ms" {
+		t.Errorf("header %+v", header)
+	}
+	var segment map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[1]), &segment); err != nil {
+		t.Fatal(err)
+	}
+	if segment["arrival_time"] != 1200.0 || segment["algorithm"] != "bba" || segment["adapt_index"] != 1.0 ||
+		!reflect.DeepEqual(segment["keyframe_positions"], []interface{}{0.0, 48.0}) {
+		t.Errorf("segment %v", segment)
+	}
+	if err := json.Unmarshal([]byte(lines[2]), &segment); err != nil || segment["rtt"] != nil || segment["keyframe_positions"] == nil {
+		t.Errorf("segment %v %v", segment, err)
+	}
+
+	// the schema is in its own file, the same as the JSON header
+	schema, err := os.ReadFile(filepath.Join(dir, glob.LogDownloadSchema))
+	if err != nil || string(schema) != lines[0]+"\n" {
+		t.Errorf("schema %q %v", schema, err)
+	}
+
+	// the names of the columns are the first line of the CSV
+	f.Seek(0, 0)
+	first, _ := bufio.NewReader(f).ReadString('\n')
+	if !strings.HasPrefix(first, "segment,") {
+		t.Errorf("first line %q", first)
+	}
+}
+
+// every field of SegPrintLogInformation is in the segment log, apart from the history of the rates
+func TestSegmentLogFields(t *testing.T) {
+	fields := reflect.TypeOf(SegPrintLogInformation{}).NumField()
+	if len(segmentFields) != fields-2+1 {
+		t.Errorf("%d fields in the segment log for %d fields", len(segmentFields), fields)
+	}
+}
@@ -29,6 +29,7 @@ import (
 	"log"
 	"math"
 	"math/rand"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -36,16 +37,21 @@ import (
 	"strings"
 	"sync"
 
-	"github.com/lucas-clemente/quic-go/qlog"
 	"github.com/uccmisl/godash/P2Pconsul"
+	"github.com/uccmisl/godash/estimators"
 	glob "github.com/uccmisl/godash/global"
 	"github.com/uccmisl/godash/http"
+	"github.com/uccmisl/godash/live"
 	"github.com/uccmisl/godash/logging"
+	"github.com/uccmisl/godash/metrics"
 	"github.com/uccmisl/godash/player"
+	"github.com/uccmisl/godash/qoe"
+	"github.com/uccmisl/godash/report"
 	"github.com/uccmisl/godash/utils"
 
 	xlayer "github.com/uccmisl/godash/crosslayer"
 	abrqlog "github.com/uccmisl/godash/qlog"
+	qlogreader "github.com/uccmisl/godash/qlog/reader"
 )
 
 // variable to determine if debug log string will print
@@ -76,6 +82,9 @@ var getHeaderReadFromFile string
 // variable to determine if we are using the config file
 var configSet = false
 
+// variable to determine if the stall predictor is configured, so it runs with any algorithm
+var stallPredictorSet = false
+
 // variable to determine if QoE is on
 var getQoEBool = false
 
@@ -96,7 +105,19 @@ var Noden = P2Pconsul.NodeUrl{}
 var codecSlice = []string{glob.RepRateCodecAVC, glob.RepRateCodecHEVC, glob.RepRateCodecVP9, glob.RepRateCodecAV1}
 var algorithmSlice = []string{glob.ConventionalAlg, glob.ElasticAlg, glob.LogisticAlg, glob.TestAlg, glob.ProgressiveAlg, glob.MeanAverageAlg, glob.GeomAverageAlg, glob.EMWAAverageAlg, glob.ArbiterAlg, glob.BBAAlg, glob.MeanAverageXLAlg, glob.MeanAverageRecentXLAlg, glob.BB1AAlg_AV, glob.BB1AAlg_AVXL}
 var hlsSlice = []string{glob.HlsOff, glob.HlsOn}
-var storeFilesSlice = []string{glob.StoreFilesOff, glob.StoreFilesOn}
+var storeFilesSlice = []string{glob.StoreFilesOff, glob.StoreFilesOn, glob.StoreFilesConcat}
+var stallModelSlice = []string{glob.StallModelWindow, glob.StallModelSegment, glob.StallModelEWMA}
+var stallAbortSlice = []string{glob.StallAbortOn, glob.StallAbortOff}
+var qlogMergeSlice = []string{glob.QlogMergeOn, glob.QlogMergeOff}
+var qlogFormats = map[string]abrqlog.Format{glob.QlogFormatJSON: abrqlog.FormatJSON, glob.QlogFormatSeq: abrqlog.FormatJSONSeq, glob.QlogFormatNDJSON: abrqlog.FormatNDJSON}
+var qlogFormatSlice = []string{glob.QlogFormatJSON, glob.QlogFormatSeq, glob.QlogFormatNDJSON}
+var qlogGzipSlice = []string{glob.QlogGzipOn, glob.QlogGzipOff}
+var reportSlice = []string{glob.ReportOn, glob.ReportOff}
+var p1203PythonSlice = []string{glob.P1203PythonOn, glob.P1203PythonOff}
+var estimatorSlice = []string{glob.EstimatorOff, glob.EstimatorSlidingWindow, glob.EstimatorDualEWMA, glob.EstimatorHarmonic, glob.EstimatorKalman, glob.EstimatorHoltWinters}
+
+// the algorithms that choose from the buffer level, and have no throughput estimate to replace
+var bufferBasedAlgorithmSlice = []string{glob.LogisticAlg, glob.BBAAlg, glob.BB1AAlg_AV, glob.BB1AAlg_AVXL}
 
 // default value for the exponential ratio
 var exponentialRatio = 0.0
@@ -122,6 +143,12 @@ func main() {
 
 	os.Setenv("VERSION", "2.0")
 
+	// the analyze command reads the logs of a previous run, instead of streaming
+	if len(os.Args) > 1 && os.Args[1] == glob.AnalyzeCommand {
+		analyze(os.Args[2:])
+		return
+	}
+
 	var structList []http.MPD
 
 	// creating the flag structure of the help output
@@ -136,7 +163,7 @@ func main() {
 	maxBufferPtr := flag.Int(glob.MaxBufferName, 30, "maximum stream buffer in seconds")
 	initBufferPtr := flag.Int(glob.InitBufferName, 2, "initial number of segments to download before stream starts")
 	adaptPtr := flag.String(glob.AdaptName, glob.ConventionalAlg, "DASH algorithms - \""+glob.ConventionalAlg+"|"+glob.ElasticAlg+"|"+glob.ProgressiveAlg+"|"+glob.LogisticAlg+"|"+glob.MeanAverageAlg+"|"+glob.GeomAverageAlg+"|"+glob.EMWAAverageAlg+"|"+glob.ArbiterAlg+"|"+glob.BBAAlg+"\"")
-	storeFilesPtr := flag.String(glob.StoreFiles, glob.StoreFilesOff, "store the streamed DASH files, and associated files - \"["+glob.StoreFilesOn+"|"+glob.StoreFilesOff+"]\"")
+	storeFilesPtr := flag.String(glob.StoreFiles, glob.StoreFilesOff, "store the streamed DASH files, and associated files - \"["+glob.StoreFilesOn+"|"+glob.StoreFilesOff+"|"+glob.StoreFilesConcat+"]\"")
 	fileStoreNamePtr := flag.String(glob.FileStoreName, "", "folder location within "+fileDownloadLocation+" to store the streamed DASH files - if no folder is passed, output defaults to \"../files\" folder")
 	terminalPrintPtr := flag.String(glob.TerminalPrintName, glob.TerminalPrintOff, "extend the output logs to provide additional information - \"["+glob.TerminalPrintOn+"|"+glob.TerminalPrintOff+"]\"")
 	hlsPtr := flag.String(glob.HlsName, glob.HlsOff, "HLS setting - used for redownloading chunks at a higher quality rep_rate - \""+glob.HlsOff+"|"+glob.HlsOn+"\"")
@@ -146,9 +173,41 @@ func main() {
 	printHeaderPtr := flag.String(glob.PrintHeaderName, "", "print columns based on selected print headers:")
 	useTestbedPtr := flag.String(glob.UseTestBedName, glob.UseTestBedOff, "setup https certs and use goDASHbed testbed - \"["+glob.UseTestBedOn+"|"+glob.UseTestBedOff+"]\"")
 	QoEPtr := flag.String(glob.QoEName, glob.QoEOff, "print per segment QoE values (P1203 mode 0 and Claye) - \"["+glob.QoEOn+"|"+glob.QoEOff+"]\"")
+	p1203PythonPtr := flag.String(glob.P1203PythonName, glob.P1203PythonOff, "cross-check the native P1203 value of each segment with the itu_p1203 Python module, in the debug log - \"["+glob.P1203PythonOn+"|"+glob.P1203PythonOff+"]\"")
 	LogFilePtr := flag.String(glob.DebugFileName, glob.DebugFile, "Location to store the debug logs")
 	// collaborative players
 	collabPrintPtr := flag.String(glob.CollabPrintName, glob.CollabPrintOff, "implement Collaborative framework for streaming clients - \"["+glob.CollabPrintOn+"|"+glob.CollabPrintOff+"]\"")
+	// request options
+	headersPtr := flag.String(glob.HeadersName, "", "static headers added to every request - '{\"<name>\":\"<value>\"}'")
+	cookiesPtr := flag.String(glob.CookiesName, "", "static cookies added to every request - \"<name>=<value>; <name>=<value>\"")
+	cookieJarPtr := flag.String(glob.CookieJarName, glob.CookieJarOff, "keep the cookies set by the server and return them on later requests - \"["+glob.CookieJarOn+"|"+glob.CookieJarOff+"]\"")
+	proxyPtr := flag.String(glob.ProxyName, "", "proxy for all requests (not available with -"+glob.QuicName+") - \"[http|https|socks5]://<host>:<port>\"")
+	tokenScriptPtr := flag.String(glob.TokenScriptName, "", "script used to re-sign urls - called with the unsigned url, must print the signed url")
+	tokenURLPtr := flag.String(glob.TokenURLName, "", "url template used to re-sign urls - "+http.TokenURLPlaceholder+" is replaced by the unsigned url, the response body must be the signed url")
+	tokenRefreshPtr := flag.Int(glob.TokenRefreshName, 0, "number of seconds a signed url is reused before it is re-signed - defaults to re-signing on every request")
+	// encrypted content
+	licenseURLPtr := flag.String(glob.LicenseURLName, "", "ClearKey license server url for encrypted content - defaults to the Laurl in the MPD ContentProtection")
+	// stall prediction - used by the bba1XL algorithm
+	stallModelPtr := flag.String(glob.StallModelName, glob.StallModelWindow, "throughput model of the stall predictor - \"["+glob.StallModelWindow+"|"+glob.StallModelSegment+"|"+glob.StallModelEWMA+"]\"")
+	stallWindowPtr := flag.Int(glob.StallWindowName, glob.StallWindowDefault, "number of packets the stall predictor waits for, and the window of the "+glob.StallModelWindow+" model")
+	stallThresholdPtr := flag.Float64(glob.StallThresholdName, glob.StallThresholdDefault, "fraction of the maximum buffer below which the stall predictor predicts stalls")
+	stallAbortPtr := flag.String(glob.StallAbortName, glob.StallAbortOn, "abort the download when a stall is predicted, off only logs and scores the predictions - \"["+glob.StallAbortOn+"|"+glob.StallAbortOff+"]\"")
+	// throughput estimation - used by the rate based algorithms
+	estimatorPtr := flag.String(glob.EstimatorName, glob.EstimatorOff, "throughput estimator used in place of the estimate of the algorithm - \"["+strings.Join(estimatorSlice, "|")+"]\"")
+	estimatorWindowPtr := flag.Int(glob.EstimatorWindowName, glob.EstimatorWindowDefault, "number of segments of the "+glob.EstimatorSlidingWindow+" and "+glob.EstimatorHarmonic+" estimators")
+	estimatorSeasonPtr := flag.Int(glob.EstimatorSeasonName, glob.EstimatorSeasonDefault, "number of segments in a season of the "+glob.EstimatorHoltWinters+" estimator - 0 for no season")
+	// qlog
+	qlogFormatPtr := flag.String(glob.QlogFormatName, glob.QlogFormatJSON, "format of the qlog-abr file - \"["+glob.QlogFormatJSON+"|"+glob.QlogFormatSeq+"|"+glob.QlogFormatNDJSON+"]\" - the streamed formats stay valid if the run is stopped")
+	qlogPlayheadIntervalPtr := flag.Int(glob.QlogPlayheadIntervalName, glob.QlogPlayheadIntervalDefault, "interval in milliseconds of the playhead progress and buffer occupancy events of the qlog-abr file - 0 to only log them when a segment arrives")
+	qlogMergePtr := flag.String(glob.QlogMergeName, glob.QlogMergeOff, "merge the application and transport qlog files of the run into "+glob.QlogMergedFile+" - \"["+glob.QlogMergeOn+"|"+glob.QlogMergeOff+"]\"")
+	qlogDirPtr := flag.String(glob.QlogDirName, glob.DebugFolder, "folder of the qlog-abr file, it is created if it does not exist")
+	qlogPatternPtr := flag.String(glob.QlogPatternName, abrqlog.DefaultPattern, "name of the qlog-abr file, without its extension - {perspective}, {run}, {algorithm} and {mpd} are replaced by their values")
+	qlogGzipPtr := flag.String(glob.QlogGzipName, glob.QlogGzipOff, "compress the qlog-abr file with gzip - \"["+glob.QlogGzipOn+"|"+glob.QlogGzipOff+"]\"")
+	liveAddrPtr := flag.String(glob.LiveAddrName, "", "local address, such as \"localhost:8080\", to stream the qlog-abr events and the log of each segment to over Server-Sent Events - off if it is empty")
+	metricsAddrPtr := flag.String(glob.MetricsAddrName, "", "local address, such as \"localhost:9100\", to serve the Prometheus metrics of the player at /metrics - off if it is empty")
+	reportPtr := flag.String(glob.ReportName, glob.ReportOff, "save an HTML report of the run, with plots of its bitrate, buffer level, throughput, stalls and QoE, next to the qlog-abr file - \"["+glob.ReportOn+"|"+glob.ReportOff+"]\"")
+	qlogCategoriesPtr := flag.String(glob.QlogCategoriesName, "", "only log the events of these categories to the qlog-abr file - \"[category,category]\" of "+strings.Join(abrqlog.Categories, ", ")+" - every category if it is empty")
+	evaluateEstimatorsPtr := flag.String(glob.EvaluateEstimatorsName, "", "evaluate every estimator offline on the logDownload.txt or the transport qlog files of a run - \"[file,file]\"")
 
 	// nicer print out for flags details
 	flag.Usage = func() {
@@ -170,14 +229,10 @@ func main() {
 	}
 
 	// Create accountant for cross-layer events
-	qlogEventChan := make(chan qlog.Event)
-	accountant := &xlayer.CrossLayerAccountant{EventChannel: qlogEventChan}
+	accountant := xlayer.NewCrossLayerAccountant()
 	accountant.Listen(true)
 	http.SetAccountant(accountant)
 
-	abrqlog.MainTracer.InitialiseStream(true)
-	//abrqlog.MainTracer.ChangeReadyState(abrqlog.ReadyStateHaveNothing)	//NOTE: not applicable for a headless client
-
 	// check config is first - check the config arguement
 	if utils.IsFlagSet(glob.ConfigName) {
 
@@ -196,35 +251,62 @@ func main() {
 				}
 
 				// get some new values from the config file
-				configURLPtr, configAdaptPtr, configCodecPtr, configMaxHeightPtr, configStreamDurationPtr, configStreamSpeedPtr, configMaxBufferPtr, configInitBufferPtr, configHlsPtr, configFileStoreNamePtr, configStoreFilesPtr, configGetHeaderPtr, configDebugPtr, configTerminalPrintPtr, configQuicPtr, configExpRatioPtr, configPrintHeaderPtr, configUseTestbedPtr, configQoEPtr, configLogFilePtr, configCollabPrintPtr := logging.Configure(*configPtr, glob.DebugFile, debugLog)
+				config := logging.Configure(*configPtr, glob.DebugFile, debugLog)
 
-				if configURLPtr == "" {
+				if config.URL == "" {
 					log.Fatal("There is an issue with the URL parameter - this could be a malformed configuration file, please double check")
 					os.Exit(3)
 				}
 
 				// check for variables with no value assigned in the config file
-				utils.CheckStringVal(&configURLPtr, urlPtr)
-				utils.CheckStringVal(&configAdaptPtr, adaptPtr)
-				utils.CheckStringVal(&configCodecPtr, codecPtr)
-				utils.CheckIntVal(&configMaxHeightPtr, maxHeightPtr)
-				utils.CheckIntVal(&configStreamDurationPtr, streamDurationPtr)
-				utils.CheckFloatVal(&configStreamSpeedPtr, streamSpeedPtr)
-				utils.CheckIntVal(&configMaxBufferPtr, maxBufferPtr)
-				utils.CheckIntVal(&configInitBufferPtr, initBufferPtr)
-				utils.CheckStringVal(&configHlsPtr, hlsPtr)
-				utils.CheckStringVal(&configFileStoreNamePtr, fileStoreNamePtr)
-				utils.CheckStringVal(&configStoreFilesPtr, storeFilesPtr)
-				utils.CheckStringVal(&configGetHeaderPtr, getHeaderPtr)
-				utils.CheckStringVal(&configDebugPtr, debugPtr)
-				utils.CheckStringVal(&configTerminalPrintPtr, terminalPrintPtr)
-				utils.CheckStringVal(&configQuicPtr, quicPtr)
-				utils.CheckFloatVal(&configExpRatioPtr, expRatioPtr)
-				utils.CheckStringVal(&configPrintHeaderPtr, printHeaderPtr)
-				utils.CheckStringVal(&configUseTestbedPtr, useTestbedPtr)
-				utils.CheckStringVal(&configQoEPtr, QoEPtr)
-				utils.CheckStringVal(&configLogFilePtr, LogFilePtr)
-				utils.CheckStringVal(&configCollabPrintPtr, collabPrintPtr)
+				utils.CheckStringVal(&config.URL, urlPtr)
+				utils.CheckStringVal(&config.Adapt, adaptPtr)
+				utils.CheckStringVal(&config.Codec, codecPtr)
+				utils.CheckIntVal(&config.MaxHeight, maxHeightPtr)
+				utils.CheckIntVal(&config.StreamDuration, streamDurationPtr)
+				utils.CheckFloatVal(&config.StreamSpeed, streamSpeedPtr)
+				utils.CheckIntVal(&config.MaxBuffer, maxBufferPtr)
+				utils.CheckIntVal(&config.InitBuffer, initBufferPtr)
+				utils.CheckStringVal(&config.HLS, hlsPtr)
+				utils.CheckStringVal(&config.OutputFolder, fileStoreNamePtr)
+				utils.CheckStringVal(&config.StoreDash, storeFilesPtr)
+				utils.CheckStringVal(&config.GetHeaders, getHeaderPtr)
+				utils.CheckStringVal(&config.Debug, debugPtr)
+				utils.CheckStringVal(&config.TerminalPrint, terminalPrintPtr)
+				utils.CheckStringVal(&config.Quic, quicPtr)
+				utils.CheckFloatVal(&config.ExpRatio, expRatioPtr)
+				utils.CheckStringVal(&config.PrintHeader, printHeaderPtr)
+				utils.CheckStringVal(&config.UseTestbed, useTestbedPtr)
+				utils.CheckStringVal(&config.QoE, QoEPtr)
+				utils.CheckStringVal(&config.P1203Python, p1203PythonPtr)
+				utils.CheckStringVal(&config.LogFile, LogFilePtr)
+				utils.CheckStringVal(&config.CollabPrint, collabPrintPtr)
+				utils.CheckStringVal(&config.Headers, headersPtr)
+				utils.CheckStringVal(&config.Cookies, cookiesPtr)
+				utils.CheckStringVal(&config.CookieJar, cookieJarPtr)
+				utils.CheckStringVal(&config.Proxy, proxyPtr)
+				utils.CheckStringVal(&config.TokenScript, tokenScriptPtr)
+				utils.CheckStringVal(&config.TokenURL, tokenURLPtr)
+				utils.CheckIntVal(&config.TokenRefresh, tokenRefreshPtr)
+				utils.CheckStringVal(&config.LicenseURL, licenseURLPtr)
+				utils.CheckStringVal(&config.StallModel, stallModelPtr)
+				utils.CheckIntVal(&config.StallWindow, stallWindowPtr)
+				utils.CheckFloatVal(&config.StallThreshold, stallThresholdPtr)
+				utils.CheckStringVal(&config.StallAbort, stallAbortPtr)
+				stallPredictorSet = config.StallModel != "" || config.StallWindow != 0 || config.StallThreshold != 0 || config.StallAbort != ""
+				utils.CheckStringVal(&config.Estimator, estimatorPtr)
+				utils.CheckIntVal(&config.EstimatorWindow, estimatorWindowPtr)
+				utils.CheckIntVal(&config.EstimatorSeason, estimatorSeasonPtr)
+				utils.CheckStringVal(&config.QlogMerge, qlogMergePtr)
+				utils.CheckStringVal(&config.QlogFormat, qlogFormatPtr)
+				utils.CheckIntVal(&config.QlogPlayheadInterval, qlogPlayheadIntervalPtr)
+				utils.CheckStringVal(&config.QlogDir, qlogDirPtr)
+				utils.CheckStringVal(&config.QlogPattern, qlogPatternPtr)
+				utils.CheckStringVal(&config.QlogGzip, qlogGzipPtr)
+				utils.CheckStringVal(&config.QlogCategories, qlogCategoriesPtr)
+				utils.CheckStringVal(&config.LiveAddr, liveAddrPtr)
+				utils.CheckStringVal(&config.MetricsAddr, metricsAddrPtr)
+				utils.CheckStringVal(&config.Report, reportPtr)
 
 				// set our config boolean to true
 				configSet = true
@@ -334,6 +416,359 @@ func main() {
 		}
 	}
 
+	// the request options must be set before we get the MPD url(s)
+	// check the headers argument
+	var requestHeaders map[string]string
+	if utils.IsFlagSet(glob.HeadersName) || configSet {
+
+		// print value to debug log
+		logging.DebugPrint(glob.DebugFile, debugLog, "DEBUG: ", "-"+glob.HeadersName+" set to "+*headersPtr)
+
+		// only unmarhsall json if parameters were passed
+		if *headersPtr != "" {
+			err := json.Unmarshal([]byte(*headersPtr), &requestHeaders)
+			if err != nil {
+				// print error message
+				fmt.Println("*** -" + glob.HeadersName + " must be a json object of header names and values, and not " + *headersPtr + " ***")
+				// stop the app
+				utils.StopApp()
+			}
+		}
+	}
+
+	// check the cookie jar argument
+	if utils.IsFlagSet(glob.CookieJarName) || configSet {
+
+		// print value to debug log
+		logging.DebugPrint(glob.DebugFile, debugLog, "DEBUG: ", "-"+glob.CookieJarName+" set to "+*cookieJarPtr)
+
+		if *cookieJarPtr != glob.CookieJarOn && *cookieJarPtr != glob.CookieJarOff {
+			// print error message
+			fmt.Println("*** -" + glob.CookieJarName + " must be set to either " + glob.CookieJarOn + " or " + glob.CookieJarOff + " (" + glob.CookieJarOff + " by default). ***")
+			// stop the app
+			utils.StopApp()
+		}
+	}
+
+	// check the proxy argument
+	if utils.IsFlagSet(glob.ProxyName) || configSet {
+
+		// print value to debug log
+		logging.DebugPrint(glob.DebugFile, debugLog, "DEBUG: ", "-"+glob.ProxyName+" set to "+*proxyPtr)
+
+		if *proxyPtr != "" {
+			proxyURL, err := url.Parse(*proxyPtr)
+			if err != nil || proxyURL.Host == "" || (proxyURL.Scheme != "http" && proxyURL.Scheme != "https" && proxyURL.Scheme != "socks5") {
+				// print error message
+				fmt.Println("*** -" + glob.ProxyName + " must be a http://, https:// or socks5:// url, and not " + *proxyPtr + " ***")
+				// stop the app
+				utils.StopApp()
+			}
+			// quic can not be sent through a proxy
+			if quicBool {
+				// print error message
+				fmt.Println("*** -" + glob.ProxyName + " can not be used with -" + glob.QuicName + " " + glob.QuicOn + " ***")
+				// stop the app
+				utils.StopApp()
+			}
+		}
+	}
+
+	// check the token arguments
+	if utils.IsFlagSet(glob.TokenScriptName) || utils.IsFlagSet(glob.TokenURLName) || utils.IsFlagSet(glob.TokenRefreshName) || configSet {
+
+		// print value to debug log
+		logging.DebugPrint(glob.DebugFile, debugLog, "DEBUG: ", "-"+glob.TokenScriptName+" set to "+*tokenScriptPtr)
+		logging.DebugPrint(glob.DebugFile, debugLog, "DEBUG: ", "-"+glob.TokenURLName+" set to "+*tokenURLPtr)
+		logging.DebugPrint(glob.DebugFile, debugLog, "DEBUG: ", "-"+glob.TokenRefreshName+" set to "+strconv.Itoa(*tokenRefreshPtr))
+
+		if *tokenScriptPtr != "" && *tokenURLPtr != "" {
+			// print error message
+			fmt.Println("*** only one of -" + glob.TokenScriptName + " or -" + glob.TokenURLName + " can be set ***")
+			// stop the app
+			utils.StopApp()
+		}
+		if *tokenScriptPtr != "" {
+			// check the script can be called
+			if _, err := exec.LookPath(*tokenScriptPtr); err != nil {
+				// print error message
+				fmt.Println("*** -" + glob.TokenScriptName + " " + *tokenScriptPtr + " can not be found or is not executable ***")
+				// stop the app
+				utils.StopApp()
+			}
+		}
+		if *tokenURLPtr != "" && !strings.Contains(*tokenURLPtr, http.TokenURLPlaceholder) {
+			// print error message
+			fmt.Println("*** -" + glob.TokenURLName + " must contain " + http.TokenURLPlaceholder + " ***")
+			// stop the app
+			utils.StopApp()
+		}
+		if *tokenRefreshPtr < 0 {
+			// print error message
+			fmt.Println("*** -" + glob.TokenRefreshName + " must be a positive number and not " + strconv.Itoa(*tokenRefreshPtr) + " ***")
+			// stop the app
+			utils.StopApp()
+		}
+	}
+
+	// check the license url argument
+	if utils.IsFlagSet(glob.LicenseURLName) || configSet {
+
+		// print value to debug log
+		logging.DebugPrint(glob.DebugFile, debugLog, "DEBUG: ", "-"+glob.LicenseURLName+" set to "+*licenseURLPtr)
+
+		if *licenseURLPtr != "" {
+			licenseURL, err := url.Parse(*licenseURLPtr)
+			if err != nil || licenseURL.Host == "" {
+				// print error message
+				fmt.Println("*** -" + glob.LicenseURLName + " must be a url, and not " + *licenseURLPtr + " ***")
+				// stop the app
+				utils.StopApp()
+			}
+		}
+		http.SetLicenseURL(*licenseURLPtr)
+	}
+
+	// check the stall predictor arguments
+	stallPredictorSet = stallPredictorSet || utils.IsFlagSet(glob.StallModelName) || utils.IsFlagSet(glob.StallWindowName) || utils.IsFlagSet(glob.StallThresholdName) || utils.IsFlagSet(glob.StallAbortName)
+	if stallPredictorSet || configSet {
+
+		// print values to debug log
+		logging.DebugPrint(glob.DebugFile, debugLog, "DEBUG: ", "-"+glob.StallModelName+" set to "+*stallModelPtr+", -"+glob.StallWindowName+" set to "+strconv.Itoa(*stallWindowPtr)+
+			", -"+glob.StallThresholdName+" set to "+fmt.Sprintf("%.2f", *stallThresholdPtr)+", -"+glob.StallAbortName+" set to "+*stallAbortPtr)
+
+		if ok, _ := utils.FindInStringArray(stallModelSlice, *stallModelPtr); !ok {
+			// print error message
+			fmt.Printf("*** -"+glob.StallModelName+" must be either %v and not "+*stallModelPtr+" ***\n", stallModelSlice)
+			// stop the app
+			utils.StopApp()
+		}
+		if *stallWindowPtr < 2 {
+			// print error message
+			fmt.Println("*** -" + glob.StallWindowName + " must be at least 2 packets ***")
+			// stop the app
+			utils.StopApp()
+		}
+		if *stallThresholdPtr <= 0 || *stallThresholdPtr > 1 {
+			// print error message
+			fmt.Println("*** -" + glob.StallThresholdName + " must be greater than 0 and at most 1 ***")
+			// stop the app
+			utils.StopApp()
+		}
+		if ok, _ := utils.FindInStringArray(stallAbortSlice, *stallAbortPtr); !ok {
+			// print error message
+			fmt.Printf("*** -"+glob.StallAbortName+" must be either %v and not "+*stallAbortPtr+" ***\n", stallAbortSlice)
+			// stop the app
+			utils.StopApp()
+		}
+	}
+
+	// check the throughput estimator arguments
+	if utils.IsFlagSet(glob.EstimatorName) || utils.IsFlagSet(glob.EstimatorWindowName) || utils.IsFlagSet(glob.EstimatorSeasonName) || configSet {
+
+		// print values to debug log
+		logging.DebugPrint(glob.DebugFile, debugLog, "DEBUG: ", "-"+glob.EstimatorName+" set to "+*estimatorPtr+", -"+glob.EstimatorWindowName+" set to "+strconv.Itoa(*estimatorWindowPtr)+
+			", -"+glob.EstimatorSeasonName+" set to "+strconv.Itoa(*estimatorSeasonPtr))
+
+		if ok, _ := utils.FindInStringArray(estimatorSlice, *estimatorPtr); !ok {
+			// print error message
+			fmt.Printf("*** -"+glob.EstimatorName+" must be either %v and not "+*estimatorPtr+" ***\n", estimatorSlice)
+			// stop the app
+			utils.StopApp()
+		}
+		if *estimatorWindowPtr < 1 {
+			// print error message
+			fmt.Println("*** -" + glob.EstimatorWindowName + " must be at least 1 segment ***")
+			// stop the app
+			utils.StopApp()
+		}
+		if *estimatorSeasonPtr < 0 || *estimatorSeasonPtr == 1 {
+			// print error message
+			fmt.Println("*** -" + glob.EstimatorSeasonName + " must be 0 for no season, or at least 2 segments ***")
+			// stop the app
+			utils.StopApp()
+		}
+		// the buffer based algorithms do not use a throughput estimate
+		if ok, _ := utils.FindInStringArray(bufferBasedAlgorithmSlice, *adaptPtr); ok && *estimatorPtr != glob.EstimatorOff {
+			// print error message
+			fmt.Printf("*** -"+glob.EstimatorName+" cannot be used with the buffer based algorithms %v ***\n", bufferBasedAlgorithmSlice)
+			// stop the app
+			utils.StopApp()
+		}
+	}
+
+	// check the qlog format argument
+	if utils.IsFlagSet(glob.QlogFormatName) || configSet {
+
+		// print value to debug log
+		logging.DebugPrint(glob.DebugFile, debugLog, "DEBUG: ", "-"+glob.QlogFormatName+" set to "+*qlogFormatPtr)
+
+		if ok, _ := utils.FindInStringArray(qlogFormatSlice, *qlogFormatPtr); !ok {
+			// print error message
+			fmt.Printf("*** -"+glob.QlogFormatName+" must be either %v and not "+*qlogFormatPtr+" ***\n", qlogFormatSlice)
+			// stop the app
+			utils.StopApp()
+		}
+	}
+
+	// check the qlog playhead interval argument
+	if utils.IsFlagSet(glob.QlogPlayheadIntervalName) || configSet {
+
+		// print value to debug log
+		logging.DebugPrint(glob.DebugFile, debugLog, "DEBUG: ", "-"+glob.QlogPlayheadIntervalName+" set to "+strconv.Itoa(*qlogPlayheadIntervalPtr))
+
+		if *qlogPlayheadIntervalPtr < 0 {
+			// print error message
+			fmt.Println("*** -" + glob.QlogPlayheadIntervalName + " must be 0 or a positive number of milliseconds ***")
+			// stop the app
+			utils.StopApp()
+		}
+	}
+
+	// check the qlog merge argument
+	if utils.IsFlagSet(glob.QlogMergeName) || configSet {
+
+		// print value to debug log
+		logging.DebugPrint(glob.DebugFile, debugLog, "DEBUG: ", "-"+glob.QlogMergeName+" set to "+*qlogMergePtr)
+
+		if ok, _ := utils.FindInStringArray(qlogMergeSlice, *qlogMergePtr); !ok {
+			// print error message
+			fmt.Printf("*** -"+glob.QlogMergeName+" must be either %v and not "+*qlogMergePtr+" ***\n", qlogMergeSlice)
+			// stop the app
+			utils.StopApp()
+		}
+	}
+
+	// check the qlog file arguments
+	if utils.IsFlagSet(glob.QlogDirName) || utils.IsFlagSet(glob.QlogPatternName) || utils.IsFlagSet(glob.QlogGzipName) || utils.IsFlagSet(glob.QlogCategoriesName) || configSet {
+
+		// print values to debug log
+		logging.DebugPrint(glob.DebugFile, debugLog, "DEBUG: ", "-"+glob.QlogDirName+" set to "+*qlogDirPtr+", -"+glob.QlogPatternName+" set to "+*qlogPatternPtr+
+			", -"+glob.QlogGzipName+" set to "+*qlogGzipPtr+", -"+glob.QlogCategoriesName+" set to "+*qlogCategoriesPtr)
+
+		if *qlogPatternPtr == "" || strings.ContainsAny(*qlogPatternPtr, "/\\") {
+			// print error message
+			fmt.Println("*** -" + glob.QlogPatternName + " must be a file name, without a folder ***")
+			// stop the app
+			utils.StopApp()
+		}
+		if ok, _ := utils.FindInStringArray(qlogGzipSlice, *qlogGzipPtr); !ok {
+			// print error message
+			fmt.Printf("*** -"+glob.QlogGzipName+" must be either %v and not "+*qlogGzipPtr+" ***\n", qlogGzipSlice)
+			// stop the app
+			utils.StopApp()
+		}
+		for _, category := range qlogCategories(*qlogCategoriesPtr) {
+			if ok, _ := utils.FindInStringArray(abrqlog.Categories, category); !ok {
+				// print error message
+				fmt.Printf("*** -"+glob.QlogCategoriesName+" must be a list of %v and not "+*qlogCategoriesPtr+" ***\n", abrqlog.Categories)
+				// stop the app
+				utils.StopApp()
+			}
+		}
+	}
+
+	// check the report argument
+	if utils.IsFlagSet(glob.ReportName) || configSet {
+
+		// print value to debug log
+		logging.DebugPrint(glob.DebugFile, debugLog, "DEBUG: ", "-"+glob.ReportName+" set to "+*reportPtr)
+
+		if ok, _ := utils.FindInStringArray(reportSlice, *reportPtr); !ok {
+			// print error message
+			fmt.Printf("*** -"+glob.ReportName+" must be either %v and not "+*reportPtr+" ***\n", reportSlice)
+			// stop the app
+			utils.StopApp()
+		}
+	}
+
+	// evaluate the estimators on the logs of a previous run, instead of streaming
+	if utils.IsFlagSet(glob.EvaluateEstimatorsName) {
+
+		// print value to debug log
+		logging.DebugPrint(glob.DebugFile, debugLog, "DEBUG: ", "-"+glob.EvaluateEstimatorsName+" set to "+*evaluateEstimatorsPtr)
+
+		paths := strings.Split(strings.Trim(*evaluateEstimatorsPtr, "[]"), ",")
+		evaluations, err := estimators.EvaluateRun(paths, *estimatorWindowPtr, *estimatorSeasonPtr)
+		if err != nil {
+			// print error message
+			fmt.Println("*** -" + glob.EvaluateEstimatorsName + " could not read the run - " + err.Error() + " ***")
+			// stop the app
+			utils.StopApp()
+		}
+		fmt.Printf("%-32s %-12s %7s %11s %12s %12s %8s %6s %6s\n", "Series", "Estimator", "Horizon", "Predictions", "MAE (kbps)", "RMSE (kbps)", "MAPE (%)", "Over", "Under")
+		for _, e := range evaluations {
+			fmt.Printf("%-32s %-12s %7d %11d %12.0f %12.0f %8.2f %6.3f %6.3f\n", e.Series, e.Estimator, e.Horizon, e.Predictions, e.MAE/1000, e.RMSE/1000, e.MAPE, e.Over, e.Under)
+		}
+		// the results are saved next to the logs of the run
+		csvPath, jsonPath, err := estimators.WriteEvaluations(filepath.Dir(paths[0]), evaluations)
+		if err != nil {
+			fmt.Println("*** could not save the estimator evaluation - " + err.Error() + " ***")
+			os.Exit(3)
+		}
+		fmt.Println("Estimator evaluation saved to " + csvPath + " and " + jsonPath)
+		return
+	}
+
+	// the tracer of this run, the player, the download code and the accountant are passed it
+	runID := abrqlog.NewRunID()
+	qlogConfig := abrqlog.Config{
+		Dir:         *qlogDirPtr,
+		Pattern:     *qlogPatternPtr,
+		Perspective: abrqlog.PerspectiveClient,
+		RunID:       runID,
+		Algorithm:   *adaptPtr,
+		MPD:         strings.TrimSpace(http.URLList(*urlPtr)[0]),
+		Format:      qlogFormats[*qlogFormatPtr],
+		Gzip:        *qlogGzipPtr == glob.QlogGzipOn,
+		Categories:  qlogCategories(*qlogCategoriesPtr),
+	}
+	tracer, err := abrqlog.NewFileTracer(qlogConfig)
+	if err != nil {
+		// print error message
+		fmt.Println("*** could not create the qlog-abr file - " + err.Error() + " ***")
+		// stop the app
+		utils.StopApp()
+	}
+
+	// the accountant writes each stall prediction to the tracer
+	accountant.SetStallPredictor(xlayer.StallPredictorConfig{
+		// bba1XL always predicts stalls, the other algorithms only when the predictor is configured
+		Enabled:         *adaptPtr == glob.BB1AAlg_AVXL || stallPredictorSet,
+		Model:           *stallModelPtr,
+		Window:          *stallWindowPtr,
+		BufferThreshold: *stallThresholdPtr,
+		Abort:           *stallAbortPtr == glob.StallAbortOn,
+		// each prediction is a qlog stall_prediction event
+		Report: func(prediction xlayer.StallPrediction) {
+			tracer.StallPrediction(abrqlog.StallPrediction(prediction))
+		},
+	})
+
+	// the live view of the run, fed with the events of the tracer as they are written
+	var liveServer *live.Server
+	if *liveAddrPtr != "" {
+
+		// print value to debug log
+		logging.DebugPrint(glob.DebugFile, debugLog, "DEBUG: ", "-"+glob.LiveAddrName+" set to "+*liveAddrPtr)
+
+		liveServer = live.NewServer(*liveAddrPtr)
+		if err := liveServer.Start(); err != nil {
+			// print error message
+			fmt.Println("*** -" + glob.LiveAddrName + " could not listen on " + *liveAddrPtr + " - " + err.Error() + " ***")
+			// stop the app
+			utils.StopApp()
+		}
+		tracer.AddSink(liveServer.QlogEvent)
+		fmt.Println("Live view of the run on http://" + liveServer.Addr() + "/")
+	}
+	logging.DebugPrint(glob.DebugFile, debugLog, "DEBUG: ", "qlog run ID "+string(runID))
+	tracer.InitialiseStream(true)
+	tracer.ChangeReadyState(abrqlog.ReadyStateHaveNothing)
+
+	// pass the request options to our http client
+	http.SetRequestOptions(requestHeaders, *cookiesPtr, *cookieJarPtr == glob.CookieJarOn, *proxyPtr, *tokenScriptPtr, *tokenURLPtr, *tokenRefreshPtr, glob.DebugFile, debugLog)
+
 	// set url is the fifth check - check the url arguement
 	if utils.IsFlagSet(glob.URLName) || configSet {
 
@@ -341,9 +776,9 @@ func main() {
 		logging.DebugPrint(glob.DebugFile, debugLog, "DEBUG: ", "-"+glob.URLName+" set to "+*urlPtr)
 
 		if !strings.HasPrefix(*urlPtr, "-") {
-			structList = http.ReadURLArray(*urlPtr, debugLog, useTestbedBool, quicBool)
+			structList = http.ReadURLArray(*urlPtr, debugLog, useTestbedBool, quicBool, tracer)
 
-			//abrqlog.MainTracer.ChangeReadyState(abrqlog.ReadyStateHaveMetadata)	//NOTE: not applicable for a headless client
+			tracer.ChangeReadyState(abrqlog.ReadyStateHaveMetadata)
 
 			// save the current MPD Rep_rate Adaptation Set
 			// check if the codec is in the MPD urls passed in
@@ -384,7 +819,7 @@ func main() {
 				// define a new structList
 				var reversedStructList []http.MPD
 				// create it with content
-				reversedStructList = http.ReadURLArray(*urlPtr, debugLog, useTestbedBool, quicBool)
+				reversedStructList = http.ReadURLArray(*urlPtr, debugLog, useTestbedBool, quicBool, tracer)
 
 				// loop over the existing list and reverse the representations
 				i := 0
@@ -427,6 +862,20 @@ func main() {
 		}
 	}
 
+	// check the p1203Python argument
+	if utils.IsFlagSet(glob.P1203PythonName) || configSet {
+
+		// print value to debug log
+		logging.DebugPrint(glob.DebugFile, debugLog, "DEBUG: ", "-"+glob.P1203PythonName+" set to "+*p1203PythonPtr)
+
+		if ok, _ := utils.FindInStringArray(p1203PythonSlice, *p1203PythonPtr); !ok {
+			// print error message
+			fmt.Printf("*** -"+glob.P1203PythonName+" must be either %v and not "+*p1203PythonPtr+" ***\n", p1203PythonSlice)
+			// stop the app
+			utils.StopApp()
+		}
+	}
+
 	// check the QoE argument
 	if utils.IsFlagSet(glob.QoEName) || configSet {
 
@@ -437,13 +886,17 @@ func main() {
 			// set the extend logger boolean to true
 			getQoEBool = true
 
-			// check if P1203 is in the system PATH
-			_, err := exec.LookPath(glob.P1203exec)
-			if err != nil {
-				log.Fatal(glob.P1203exec + " has not been found in $PATH, either turn \"QoE off\" or make sure P1203 has been installed and added to your $PATH")
-				os.Exit(3)
+			// the P1203 value is computed natively, P1203 is only needed to cross-check it
+			if *p1203PythonPtr == glob.P1203PythonOn {
+				// check if P1203 is in the system PATH
+				_, err := exec.LookPath(glob.P1203exec)
+				if err != nil {
+					log.Fatal(glob.P1203exec + " has not been found in $PATH, either turn \"" + glob.P1203PythonName + " off\" or make sure P1203 has been installed and added to your $PATH")
+					os.Exit(3)
+				}
+				logging.DebugPrint(glob.DebugFile, debugLog, "DEBUG: ", glob.P1203exec+" is installed")
+				qoe.SetP1203PythonCheck(true)
 			}
-			logging.DebugPrint(glob.DebugFile, debugLog, "DEBUG: ", glob.P1203exec+" is installed")
 
 		} else if *QoEPtr == glob.QoEOff || onlyAudio {
 			// set the extend logger boolean to false
@@ -864,6 +1317,8 @@ func main() {
 			utils.StopApp()
 		} else if *storeFilesPtr != "off" {
 			saveFilesBool = true
+			// also write each track as a single file
+			http.SetStoreDASH(*storeFilesPtr == glob.StoreFilesConcat)
 		}
 		// we need to save files, so we can share them
 		if *collabPrintPtr == glob.CollabPrintOn {
@@ -873,9 +1328,60 @@ func main() {
 
 	accountant.SetTrackingEvents(true)
 
+	// the Prometheus metrics of the player, labelled with the client, algorithm and content
+	var playerMetrics *metrics.Metrics
+	if *metricsAddrPtr != "" {
+
+		// print value to debug log
+		logging.DebugPrint(glob.DebugFile, debugLog, "DEBUG: ", "-"+glob.MetricsAddrName+" set to "+*metricsAddrPtr)
+
+		client := Noden.ClientName
+		if client == "" || client == glob.CollabPrintOff {
+			client, _ = os.Hostname()
+		}
+		playerMetrics = metrics.New(metrics.Labels{Client: client, Algorithm: *adaptPtr, Content: strings.TrimSpace(http.URLList(*urlPtr)[0])})
+		if err := playerMetrics.Start(*metricsAddrPtr); err != nil {
+			// print error message
+			fmt.Println("*** -" + glob.MetricsAddrName + " could not listen on " + *metricsAddrPtr + " - " + err.Error() + " ***")
+			// stop the app
+			utils.StopApp()
+		}
+		fmt.Println("Prometheus metrics of the run on http://" + playerMetrics.Addr() + "/metrics")
+	}
+
 	// its time to stream, call the algorithm file in player.go
 	player.Stream(structList, glob.DebugFile, debugLog, *codecPtr, glob.CodecName, *maxHeightPtr,
-		*streamDurationPtr, *streamSpeedPtr, *maxBufferPtr, *initBufferPtr, *adaptPtr, *urlPtr, fileDownloadLocation, extendPrintLog, *hlsPtr, hlsBool, *quicPtr, quicBool, getHeaderBool, *getHeaderPtr, exponentialRatio, printHeadersData, printLog, useTestbedBool, getQoEBool, saveFilesBool, Noden, accountant)
+		*streamDurationPtr, *streamSpeedPtr, *maxBufferPtr, *initBufferPtr, *adaptPtr, *urlPtr, fileDownloadLocation, extendPrintLog, *hlsPtr, hlsBool, *quicPtr, quicBool, getHeaderBool, *getHeaderPtr, exponentialRatio, printHeadersData, printLog, useTestbedBool, getQoEBool, saveFilesBool, *estimatorPtr, *estimatorWindowPtr, *estimatorSeasonPtr, *qlogPlayheadIntervalPtr, tracer, liveServer, playerMetrics, Noden, accountant)
+
+	// the qlog-abr file is closed, the live view has nothing left to send
+	liveServer.Close()
+	playerMetrics.Close()
+
+	// merge the application and transport qlog files of this run
+	if *qlogMergePtr == glob.QlogMergeOn {
+		// the transport qlogs are always in the logs folder
+		logs, err := qlogreader.ReadFolder(*qlogDirPtr)
+		if err == nil && filepath.Clean(*qlogDirPtr) != filepath.Clean(glob.DebugFolder) {
+			var transport []*qlogreader.Log
+			transport, err = qlogreader.ReadFolder(glob.DebugFolder)
+			logs = append(logs, qlogreader.Transport(transport)...)
+		}
+		if err != nil {
+			fmt.Println("*** could not read the qlog files - " + err.Error() + " ***")
+		} else {
+			mergeQlogs(*qlogDirPtr, qlogreader.Session(logs))
+		}
+	}
+
+	// the report of this run, from its qlog-abr file and its segment log
+	if *reportPtr == glob.ReportOn {
+		l, err := qlogreader.ReadFile(qlogConfig.Filename())
+		if err != nil {
+			fmt.Println("*** could not read the qlog-abr file - " + err.Error() + " ***")
+		} else {
+			writeReport(report.Filename(l.Path), l, filepath.Join(fileDownloadLocation, glob.LogDownload))
+		}
+	}
 
 	// ending consul
 	if *collabPrintPtr == glob.CollabPrintOn {
@@ -886,3 +1392,16 @@ func main() {
 		logging.DebugPrint(glob.DebugFile, debugLog, "DEBUG: ", "Leaving consul")
 	}
 }
+
+// qlogCategories : the categories of the -qlogCategories argument, nil for every category
+func qlogCategories(categories string) []string {
+	categories = strings.Trim(categories, "[]")
+	if categories == "" {
+		return nil
+	}
+	list := strings.Split(categories, ",")
+	for i := range list {
+		list[i] = strings.TrimSpace(list[i])
+	}
+	return list
+}
@@ -0,0 +1,267 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+// Package metrics exposes the state of a running player to Prometheus, in its text format at /metrics:
+// the buffer, representation, stalls, downloads and throughput of the player, and the sources of the
+// segments in collaborative mode. Every series carries the client, algorithm and content labels.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Sources : the sources of a segment in collaborative mode, a peer found by the P2P search or the origin server
+var Sources = []string{SourceOrigin, SourceLocalClient, SourceClients, SourceConsul}
+
+// SourceOrigin : constants for the sources - the segment is not on a peer
+const SourceOrigin = "origin"
+
+// SourceLocalClient : constants for the sources - a peer already known to have the segment
+const SourceLocalClient = "localclient"
+
+// SourceClients : constants for the sources - a peer found by asking the known peers
+const SourceClients = "clients"
+
+// SourceConsul : constants for the sources - a peer found in consul
+const SourceConsul = "consul"
+
+// Labels : the labels of every series of a player
+type Labels struct {
+	Client    string
+	Algorithm string
+	Content   string
+}
+
+// media : the state of the adaptation set of a media type
+type media struct {
+	bufferLevel time.Duration
+	bitrate     int
+	index       int
+	throughput  float64
+	estimate    float64
+	segments    uint64
+	switches    uint64
+}
+
+// Segment : a downloaded segment of a media type
+type Segment struct {
+	// the rep_rate index and bitrate in bits/second of the segment
+	Index   int
+	Bitrate int
+	// the buffer level once the segment is in the buffer
+	BufferLevel time.Duration
+	// the throughput of the segment and the estimate of the next segment, in bits/second
+	Throughput float64
+	Estimate   float64
+	RTT        time.Duration
+}
+
+// Metrics : the metrics of a player
+type Metrics struct {
+	mutex     sync.Mutex
+	labels    string
+	media     map[string]*media
+	stalls    uint64
+	stallTime time.Duration
+	rtt       time.Duration
+	fetches   map[string]uint64
+	server    *http.Server
+	listener  net.Listener
+}
+
+// New : the metrics of a player, with the labels of its series
+func New(labels Labels) *Metrics {
+	return &Metrics{
+		labels:  fmt.Sprintf(`client="%s",algorithm="%s",content="%s"`, escape(labels.Client), escape(labels.Algorithm), escape(labels.Content)),
+		media:   make(map[string]*media),
+		fetches: make(map[string]uint64),
+	}
+}
+
+// escape : the value of a label, with its backslashes, quotes and line feeds escaped
+func escape(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+func (m *Metrics) mediaType(mediaType string) *media {
+	s, ok := m.media[mediaType]
+	if !ok {
+		s = &media{}
+		m.media[mediaType] = s
+	}
+	return s
+}
+
+// SegmentDownloaded : a segment of a media type is in the buffer.
+// A nil Metrics records nothing, so the player can record without checking for one.
+func (m *Metrics) SegmentDownloaded(mediaType string, segment Segment) {
+	if m == nil {
+		return
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	s := m.mediaType(mediaType)
+	s.segments++
+	s.index = segment.Index
+	s.bitrate = segment.Bitrate
+	s.bufferLevel = segment.BufferLevel
+	s.throughput = segment.Throughput
+	s.estimate = segment.Estimate
+	if segment.RTT > 0 {
+		m.rtt = segment.RTT
+	}
+}
+
+// Switch : the algorithm has chosen another representation for a media type
+func (m *Metrics) Switch(mediaType string) {
+	if m == nil {
+		return
+	}
+	m.mutex.Lock()
+	m.mediaType(mediaType).switches++
+	m.mutex.Unlock()
+}
+
+// Stall : playback stalled for a duration
+func (m *Metrics) Stall(duration time.Duration) {
+	if m == nil {
+		return
+	}
+	m.mutex.Lock()
+	m.stalls++
+	m.stallTime += duration
+	m.mutex.Unlock()
+}
+
+// Fetch : a segment is downloaded from a source, in collaborative mode
+func (m *Metrics) Fetch(source string) {
+	if m == nil {
+		return
+	}
+	m.mutex.Lock()
+	m.fetches[source]++
+	m.mutex.Unlock()
+}
+
+// FetchSource : the source of a segment, from the URL returned by the P2P search -
+// the URL of a peer ends in "::<source>::<search time>", the URL of the origin in "::<search time>"
+func FetchSource(searchURL string) string {
+	parts := strings.Split(searchURL, "::")
+	if len(parts) < 3 {
+		return SourceOrigin
+	}
+	return parts[len(parts)-2]
+}
+
+// ServeHTTP : write the metrics in the Prometheus text format
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.WriteTo(w)
+}
+
+// WriteTo : write the metrics in the Prometheus text format
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	b := &strings.Builder{}
+	mediaTypes := make([]string, 0, len(m.media))
+	for mediaType := range m.media {
+		mediaTypes = append(mediaTypes, mediaType)
+	}
+	sort.Strings(mediaTypes)
+	perMedia := func(name string, kind string, help string, value func(s *media) float64) {
+		fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+		for _, mediaType := range mediaTypes {
+			fmt.Fprintf(b, "%s{%s,media_type=\"%s\"} %g\n", name, m.labels, escape(mediaType), value(m.media[mediaType]))
+		}
+	}
+	single := func(name string, kind string, help string, value float64) {
+		fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n%s{%s} %g\n", name, help, name, kind, name, m.labels, value)
+	}
+
+	perMedia("godash_buffer_level_seconds", "gauge", "Buffer level once the last segment is in the buffer.",
+		func(s *media) float64 { return s.bufferLevel.Seconds() })
+	perMedia("godash_representation_bitrate_bits_per_second", "gauge", "Bitrate of the representation of the last segment.",
+		func(s *media) float64 { return float64(s.bitrate) })
+	perMedia("godash_representation_index", "gauge", "Index of the representation of the last segment, 0 is the highest bitrate.",
+		func(s *media) float64 { return float64(s.index) })
+	perMedia("godash_throughput_bits_per_second", "gauge", "Throughput of the last segment.",
+		func(s *media) float64 { return s.throughput })
+	perMedia("godash_throughput_estimate_bits_per_second", "gauge", "Throughput estimate for the next segment, 0 without an estimator.",
+		func(s *media) float64 { return s.estimate })
+	perMedia("godash_segments_downloaded_total", "counter", "Segments downloaded.",
+		func(s *media) float64 { return float64(s.segments) })
+	perMedia("godash_switches_total", "counter", "Switches of representation.",
+		func(s *media) float64 { return float64(s.switches) })
+	single("godash_stalls_total", "counter", "Stalls of the playback.", float64(m.stalls))
+	single("godash_stall_seconds_total", "counter", "Time the playback stalled.", m.stallTime.Seconds())
+	single("godash_rtt_seconds", "gauge", "Round-trip time of the last request.", m.rtt.Seconds())
+
+	// the sources are only known in collaborative mode
+	if len(m.fetches) > 0 {
+		name := "godash_p2p_fetches_total"
+		fmt.Fprintf(b, "# HELP %s Segments downloaded by source, a peer or the origin server.\n# TYPE %s counter\n", name, name)
+		for _, source := range Sources {
+			fmt.Fprintf(b, "%s{%s,source=\"%s\"} %d\n", name, m.labels, source, m.fetches[source])
+		}
+	}
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// Start : serve the metrics at /metrics on a local address, such as "localhost:9100", in the background
+func (m *Metrics) Start(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", m)
+	m.listener = listener
+	m.server = &http.Server{Handler: mux}
+	go m.server.Serve(listener)
+	return nil
+}
+
+// Addr : the address the metrics are served on, once started
+func (m *Metrics) Addr() string {
+	if m.listener == nil {
+		return ""
+	}
+	return m.listener.Addr().String()
+}
+
+// Close : stop serving the metrics
+func (m *Metrics) Close() error {
+	if m == nil || m.server == nil {
+		return nil
+	}
+	return m.server.Close()
+}
@@ -0,0 +1,73 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package metrics
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetrics(t *testing.T) {
+	var none *Metrics
+	none.Stall(time.Second)
+
+	m := New(Labels{Client: "client1", Algorithm: "bba", Content: `big"buck`})
+	m.SegmentDownloaded("video", Segment{Index: 2, Bitrate: 1500000, BufferLevel: 4 * time.Second, Throughput: 3000000, RTT: 20 * time.Millisecond})
+	m.SegmentDownloaded("video", Segment{Index: 1, Bitrate: 2500000, BufferLevel: 6 * time.Second, Throughput: 3500000, Estimate: 3200000})
+	m.Switch("video")
+	m.Stall(1500 * time.Millisecond)
+	m.Fetch(FetchSource("http://peer:8080/2sec_seg3.m4s::clients::1.2ms"))
+	m.Fetch(FetchSource("http://origin/seg4.m4s::0.8ms"))
+
+	server := httptest.NewServer(m)
+	defer server.Close()
+	resp, err := server.Client().Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	labels := `client="client1",algorithm="bba",content="big\"buck"`
+	for _, want := range []string{
+		"# TYPE godash_buffer_level_seconds gauge",
+		"godash_buffer_level_seconds{" + labels + `,media_type="video"} 6`,
+		"godash_representation_bitrate_bits_per_second{" + labels + `,media_type="video"} 2.5e+06`,
+		"godash_representation_index{" + labels + `,media_type="video"} 1`,
+		"godash_throughput_estimate_bits_per_second{" + labels + `,media_type="video"} 3.2e+06`,
+		"godash_segments_downloaded_total{" + labels + `,media_type="video"} 2`,
+		"godash_switches_total{" + labels + `,media_type="video"} 1`,
+		"godash_stalls_total{" + labels + "} 1",
+		"godash_stall_seconds_total{" + labels + "} 1.5",
+		"godash_rtt_seconds{" + labels + "} 0.02",
+		"godash_p2p_fetches_total{" + labels + `,source="origin"} 1`,
+		"godash_p2p_fetches_total{" + labels + `,source="clients"} 1`,
+		"godash_p2p_fetches_total{" + labels + `,source="consul"} 0`,
+	} {
+		if !strings.Contains(string(body), want+"\n") {
+			t.Errorf("no %s in\n%s", want, body)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package player
+
+import (
+	"sync"
+	"time"
+
+	abrqlog "github.com/uccmisl/godash/qlog"
+)
+
+// playbackTrace : logs the playback of the stream to the qlog-abr file - the playhead, the buffer of each media type and the readyState
+// the player updates it when a segment arrives and when it has slept on a full buffer, and in between, while playing,
+// the buffers are drained at the stream speed and logged with the playhead at each interval
+type playbackTrace struct {
+	mutex    sync.Mutex
+	tracer   *abrqlog.StreamTracer
+	interval time.Duration
+	speed    float64
+	buffers  []*mediaBuffer
+	playing  bool
+	// the manifest is parsed before the stream starts
+	readyState abrqlog.ReadyState
+	stop       chan struct{}
+}
+
+// mediaBuffer : the segments in the buffer of a media type, at its last update
+type mediaBuffer struct {
+	mediaType abrqlog.MediaType
+	max       time.Duration
+	// the buffer level of the initial buffer, from which the stream can play through
+	enough time.Duration
+	// the media time at the end of the buffer
+	end      time.Duration
+	level    time.Duration
+	updated  time.Time
+	segments []bufferedSegment
+}
+
+// bufferedSegment : a segment in the buffer, the oldest segment can be partly played out
+type bufferedSegment struct {
+	duration time.Duration
+	bytes    int64
+}
+
+func newPlaybackTrace(tracer *abrqlog.StreamTracer, interval time.Duration, speed float64) *playbackTrace {
+	return &playbackTrace{tracer: tracer, interval: interval, speed: speed, readyState: abrqlog.ReadyStateHaveMetadata}
+}
+
+// addMediaType : adds the buffer of an adaptation set, in the order of the mimeTypes
+func (p *playbackTrace) addMediaType(mediaType abrqlog.MediaType, max time.Duration, enough time.Duration) {
+	p.mutex.Lock()
+	p.buffers = append(p.buffers, &mediaBuffer{mediaType: mediaType, max: max, enough: enough})
+	p.mutex.Unlock()
+}
+
+// startPlaying : playback has started, the buffers are drained from now on
+func (p *playbackTrace) startPlaying() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	now := time.Now()
+	p.playing = true
+	for _, b := range p.buffers {
+		b.updated = now
+	}
+	if p.interval > 0 {
+		p.stop = make(chan struct{})
+		go p.run(p.stop)
+	}
+}
+
+// segmentBuffered : a segment of a media type has arrived, and its buffer is now at level
+func (p *playbackTrace) segmentBuffered(mimeTypeIndex int, duration time.Duration, bytes int64, level time.Duration) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	b := p.buffers[mimeTypeIndex]
+	b.segments = append(b.segments, bufferedSegment{duration: duration, bytes: bytes})
+	b.end += duration
+	p.update(b, level)
+	// the playhead follows the first media type
+	if p.playing && mimeTypeIndex == 0 {
+		p.logPlayhead(time.Now())
+	}
+}
+
+// drained : the player has slept on a full buffer of a media type, which is now at level
+func (p *playbackTrace) drained(mimeTypeIndex int, level time.Duration) {
+	p.mutex.Lock()
+	p.update(p.buffers[mimeTypeIndex], level)
+	p.mutex.Unlock()
+}
+
+// rebuffer : playback stalled as the buffer of a media type ran out before its segment arrived
+func (p *playbackTrace) rebuffer(mimeTypeIndex int) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	playhead := abrqlog.NewPlayheadStatus()
+	playhead.PlayheadTime = p.playhead(time.Now())
+	p.tracer.Rebuffer(playhead)
+	p.update(p.buffers[mimeTypeIndex], 0)
+}
+
+// end : logs the end of the stream and stops the interval updates
+func (p *playbackTrace) end() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.stop != nil {
+		close(p.stop)
+		p.stop = nil
+	}
+	playhead := abrqlog.NewPlayheadStatus()
+	playhead.PlayheadTime = p.playhead(time.Now())
+	p.tracer.EndStream(playhead)
+	p.playing = false
+}
+
+func (p *playbackTrace) run(stop chan struct{}) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			p.mutex.Lock()
+			now := time.Now()
+			for _, b := range p.buffers {
+				p.logOccupancy(b, p.level(b, now))
+			}
+			p.logPlayhead(now)
+			p.changeReadyState(now)
+			p.mutex.Unlock()
+		}
+	}
+}
+
+// update : sets the buffer of a media type to level, drops the segments that were played out and logs the buffer
+func (p *playbackTrace) update(b *mediaBuffer, level time.Duration) {
+	now := time.Now()
+	b.level, b.updated = level, now
+	var buffered time.Duration
+	for i := len(b.segments) - 1; i >= 0; i-- {
+		buffered += b.segments[i].duration
+		if buffered >= level {
+			b.segments = b.segments[i:]
+			break
+		}
+	}
+	p.logOccupancy(b, level)
+	p.changeReadyState(now)
+}
+
+// level : the buffer level of a media type now, drained at the stream speed since its last update while playing
+func (p *playbackTrace) level(b *mediaBuffer, now time.Time) time.Duration {
+	if !p.playing {
+		return b.level
+	}
+	level := b.level - time.Duration(float64(now.Sub(b.updated))*p.speed)
+	if level < 0 {
+		return 0
+	}
+	return level
+}
+
+// playhead : the media time being played, the end of the buffer of the first media type less what is left in it
+func (p *playbackTrace) playhead(now time.Time) time.Duration {
+	if len(p.buffers) == 0 {
+		return 0
+	}
+	return p.buffers[0].end - p.level(p.buffers[0], now)
+}
+
+func (p *playbackTrace) logPlayhead(now time.Time) {
+	playhead := abrqlog.NewPlayheadStatus()
+	playhead.PlayheadTime = p.playhead(now)
+	p.tracer.PlayheadProgress(playhead)
+}
+
+// logOccupancy : logs the buffer of a media type at level, with the bytes of the segments left in it
+func (p *playbackTrace) logOccupancy(b *mediaBuffer, level time.Duration) {
+	bufferStats := abrqlog.NewBufferStats()
+	bufferStats.PlayoutTime = level
+	bufferStats.PlayoutBytes = 0
+	for i := len(b.segments) - 1; i >= 0 && level > 0; i-- {
+		segment := b.segments[i]
+		if segment.duration <= level {
+			bufferStats.PlayoutBytes += segment.bytes
+			level -= segment.duration
+			continue
+		}
+		// the part of the oldest segment that is not played out yet
+		bufferStats.PlayoutBytes += int64(float64(segment.bytes) * float64(level) / float64(segment.duration))
+		level = 0
+	}
+	bufferStats.MaxTime = b.max
+	p.tracer.UpdateBufferOccupancy(b.mediaType, bufferStats)
+}
+
+// changeReadyState : logs the readyState when it changes, the media type with the least in its buffer sets it:
+// have metadata while nothing is buffered before playback starts, have current data once the buffer has run out
+// during playback, have future data below the initial buffer and have enough data from the initial buffer on
+func (p *playbackTrace) changeReadyState(now time.Time) {
+	if len(p.buffers) == 0 {
+		return
+	}
+	state := abrqlog.ReadyStateHaveEnoughData
+	for _, b := range p.buffers {
+		bufferState := abrqlog.ReadyStateHaveEnoughData
+		level := p.level(b, now)
+		switch {
+		case level <= 0 && !p.playing:
+			bufferState = abrqlog.ReadyStateHaveMetadata
+		case level <= 0:
+			bufferState = abrqlog.ReadyStateHaveCurrentData
+		case level < b.enough:
+			bufferState = abrqlog.ReadyStateHaveFutureData
+		}
+		if bufferState < state {
+			state = bufferState
+		}
+	}
+	if state != p.readyState {
+		p.readyState = state
+		p.tracer.ChangeReadyState(state)
+	}
+}
@@ -0,0 +1,59 @@
+package player
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	abrqlog "github.com/uccmisl/godash/qlog"
+	qlogreader "github.com/uccmisl/godash/qlog/reader"
+)
+
+type nopCloser struct{ *bytes.Buffer }
+
+func (nopCloser) Close() error { return nil }
+
+func TestPlaybackTrace(t *testing.T) {
+	buf := &bytes.Buffer{}
+	tracer := abrqlog.NewStreamTracer(nopCloser{buf}, abrqlog.PerspectiveClient, "")
+
+	// two 2s segments fill the initial buffer, then a second is played out before the buffer runs out
+	p := newPlaybackTrace(tracer, 0, 1)
+	p.addMediaType(abrqlog.MediaTypeVideo, 30*time.Second, 4*time.Second)
+	p.segmentBuffered(0, 2*time.Second, 1000, 2*time.Second)
+	p.segmentBuffered(0, 2*time.Second, 3000, 4*time.Second)
+	p.startPlaying()
+	p.drained(0, 3*time.Second)
+	p.rebuffer(0)
+	p.end()
+	tracer.Close()
+
+	l, err := qlogreader.Parse(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []interface{}{
+		qlogreader.BufferOccupancy{MediaType: "video", Playout: 2 * time.Second, PlayoutBytes: 1000, Max: 30 * time.Second, MaxBytes: -1},
+		qlogreader.ReadyStateChange{State: "have future data"},
+		qlogreader.BufferOccupancy{MediaType: "video", Playout: 4 * time.Second, PlayoutBytes: 4000, Max: 30 * time.Second, MaxBytes: -1},
+		qlogreader.ReadyStateChange{State: "have enough data"},
+		// half of the first segment is played out
+		qlogreader.BufferOccupancy{MediaType: "video", Playout: 3 * time.Second, PlayoutBytes: 3500, Max: 30 * time.Second, MaxBytes: -1},
+		qlogreader.ReadyStateChange{State: "have future data"},
+		qlogreader.Playhead{Playhead: time.Second, Frame: -1},
+		qlogreader.BufferOccupancy{MediaType: "video", Playout: 0, PlayoutBytes: 0, Max: 30 * time.Second, MaxBytes: -1},
+		qlogreader.ReadyStateChange{State: "have current data"},
+		qlogreader.Playhead{Playhead: 4 * time.Second, Frame: -1},
+	}
+	if len(l.Events) != len(want) {
+		t.Fatalf("%d events, want %d", len(l.Events), len(want))
+	}
+	for i, e := range l.Events {
+		if e.Details != want[i] {
+			t.Errorf("event %d %s: %#v, want %#v", i, e.Name, e.Details, want[i])
+		}
+	}
+	if l.Events[6].Name != "rebuffer" || l.Events[9].Name != "stream_end" {
+		t.Errorf("events %s and %s, want rebuffer and stream_end", l.Events[6].Name, l.Events[9].Name)
+	}
+}
@@ -30,14 +30,19 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/uccmisl/godash/P2Pconsul"
 	algo "github.com/uccmisl/godash/algorithms"
+	"github.com/uccmisl/godash/estimators"
 	glob "github.com/uccmisl/godash/global"
 	"github.com/uccmisl/godash/hlsfunc"
 	"github.com/uccmisl/godash/http"
+	"github.com/uccmisl/godash/isobmff"
+	"github.com/uccmisl/godash/live"
 	"github.com/uccmisl/godash/logging"
+	"github.com/uccmisl/godash/metrics"
 	"github.com/uccmisl/godash/qoe"
 	"github.com/uccmisl/godash/utils"
 
@@ -59,6 +64,9 @@ var maxBufferLevel int
 var waitToPlayCounter = 0
 var stallTime = 0
 
+// time taken to get the license for encrypted content (in milliseconds)
+var licenseTime = 0
+
 // current mpd file
 var mpdListIndex = 0
 var lowestMPDrepRateIndex []int
@@ -158,6 +166,21 @@ var getQoEBool bool
 // variable to determine if we should save our streaming files
 var saveFilesBool bool
 
+// the throughput estimator, and the estimator of each adaptation set
+var estimatorName string
+var estimatorWindow int
+var estimatorSeason int
+var throughputEstimators = make(map[int]estimators.Estimator)
+
+// the playback of the stream in the qlog-abr file
+var playback *playbackTrace
+
+// the live view of the stream, nil if it is off
+var liveServer *live.Server
+
+// the Prometheus metrics of the stream, nil if they are off
+var playerMetrics *metrics.Metrics
+
 // other QoE variables
 var segRates []float64
 var sumSegRate float64
@@ -181,7 +204,7 @@ var streamStructs []http.StreamStruct
  * call streamLoop to begin to stream
  */
 func Stream(mpdList []http.MPD, debugFile string, debugLog bool, codec string, codecName string, maxHeight int, streamDuration int, streamSpeed float64, maxBuffer int, initBuffer int, adapt string, urlString string, fileDownloadLocationIn string, extendPrintLog bool, hls string, hlsBool bool, quic string, quicBool bool, getHeaderBool bool, getHeaderReadFromFile string, exponentialRatioIn float64, printHeadersDataIn map[string]string, printLogIn bool,
-	useTestbedBoolIn bool, getQoEBoolIn bool, saveFilesBoolIn bool, Noden P2Pconsul.NodeUrl, accountant *xlayer.CrossLayerAccountant) {
+	useTestbedBoolIn bool, getQoEBoolIn bool, saveFilesBoolIn bool, estimatorIn string, estimatorWindowIn int, estimatorSeasonIn int, qlogPlayheadInterval int, tracer *abrqlog.StreamTracer, liveServerIn *live.Server, playerMetricsIn *metrics.Metrics, Noden P2Pconsul.NodeUrl, accountant *xlayer.CrossLayerAccountant) {
 
 	// set debug logs for the collab clients
 	if Noden.ClientName != glob.CollabPrintOff && Noden.ClientName != "" {
@@ -207,6 +230,12 @@ func Stream(mpdList []http.MPD, debugFile string, debugLog bool, codec string, c
 	useTestbedBool = useTestbedBoolIn
 	getQoEBool = getQoEBoolIn
 	saveFilesBool = saveFilesBoolIn
+	estimatorName = estimatorIn
+	estimatorWindow = estimatorWindowIn
+	estimatorSeason = estimatorSeasonIn
+	liveServer = liveServerIn
+	playerMetrics = playerMetricsIn
+	playback = newPlaybackTrace(tracer, time.Duration(qlogPlayheadInterval)*time.Millisecond, streamSpeed)
 
 	// check the codec and print error is false
 	// if !usedVideoCodec {
@@ -228,6 +257,15 @@ func Stream(mpdList []http.MPD, debugFile string, debugLog bool, codec string, c
 		utils.StopApp()
 	}
 
+	// log the manifest, the representations of each adaptation set are logged with its values below
+	manifest := abrqlog.Manifest{
+		URL:            strings.TrimSpace(http.URLList(urlString)[mpdListIndex]),
+		Profiles:       mpdList[mpdListIndex].Profiles,
+		Duration:       time.Duration(http.SplitMPDSegmentDuration(mpdList[mpdListIndex].MediaPresentationDuration)) * time.Second,
+		AdaptationSets: len(mpdList[mpdListIndex].Periods[0].AdaptationSet),
+	}
+	tracer.ManifestLoaded(manifest)
+
 	// the input must be a defined value - loops over the adaptationSets
 	// currently one adaptation set per video and audio
 	for currentMPDRepAdaptSetIndex := range codecIndexList[mpdListIndex] {
@@ -279,6 +317,16 @@ func Stream(mpdList []http.MPD, debugFile string, debugLog bool, codec string, c
 			highestMPDrepRateIndex = append(highestMPDrepRateIndex, l_highestMPDrepRateIndex)
 			lowestMPDrepRateIndex = append(lowestMPDrepRateIndex, l_lowestMPDrepRateIndex)
 
+			// log the representations the algorithm chooses from, with the rep_rate index as their ID as in the switches
+			ladder := abrqlog.Ladder{MediaType: currentMediaType, SegmentDuration: time.Duration(segmentDurationArray[0]) * time.Second}
+			for index := l_highestMPDrepRateIndex; index <= l_lowestMPDrepRateIndex; index++ {
+				rep := mpdList[mpdListIndex].Periods[0].AdaptationSet[currentMPDRepAdaptSet].Representation[index]
+				ladder.Representations = append(ladder.Representations, abrqlog.LadderRepresentation{ID: strconv.Itoa(index),
+					Bitrate: int64(bandwithList[index] / glob.Conversion1000), Width: rep.Width, Height: rep.Height, Codecs: rep.Codecs})
+			}
+			tracer.Ladder(ladder)
+			playback.addMediaType(currentMediaType, time.Duration(maxBufferLevel)*time.Second, time.Duration(initBuffer*segmentDurationArray[0])*time.Second)
+
 			// get the profile for this file
 			profiles := strings.Split(mpdList[mpdListIndex].Profiles, ":")
 			numProfile := len(profiles) - 2
@@ -341,6 +389,7 @@ func Stream(mpdList []http.MPD, debugFile string, debugLog bool, codec string, c
 			urlHeaderString := http.JoinURL(currentURL, baseURL+headerURL, debugLog)
 			if Noden.ClientName != glob.CollabPrintOff && Noden.ClientName != "" {
 				currentURL = Noden.Search(urlHeaderString, segmentDuration, true, profile)
+				playerMetrics.Fetch(metrics.FetchSource(currentURL))
 
 				logging.DebugPrint(debugFile, debugLog, "\nDEBUG: ", "current URL joined: "+currentURL)
 				currentURL = strings.Split(currentURL, "::")[0]
@@ -353,31 +402,39 @@ func Stream(mpdList []http.MPD, debugFile string, debugLog bool, codec string, c
 
 			ctx2 := context.Background()
 
+			// the init segment of each representation of this stream, starting with the header of the lowest representation
+			segmentInits := http.NewSegmentInits()
+			headerRep := http.RepresentationKey{MPD: mpdListIndex, AdaptationSet: currentMPDRepAdaptSet, Representation: l_lowestMPDrepRateIndex}
+
+			// get the keys for encrypted content - this is part of the startup delay
+			// the init segment is only saved as clear content if we have the key, so get them first
+			licenseTime += int(http.GetLicense(mpdList[mpdListIndex], currentMPDRepAdaptSet, quicBool, debugFile, debugLog, useTestbedBool).Milliseconds())
+
 			// determine the inital variables to set, based on the algorithm choice
 			switch adapt {
 			case glob.ConventionalAlg:
 				// there is no byte range in this file, so we set byte-range bool to false
 				// we don't want to add the seg duration to this file, so 'addSegDuration' is false
 				http.GetFile(currentURL, baseJoined, fileDownloadLocation, false, startRange, endRange, segmentNumber,
-					segmentDuration, true, quicBool, debugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, currentMediaType, ctx2)
+					segmentDuration, true, quicBool, debugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, currentMediaType, ctx2, segmentInits, headerRep, tracer)
 				// set the inital rep_rate to the lowest value index
 				repRate = l_lowestMPDrepRateIndex
 			case glob.ElasticAlg:
 				//fmt.Println("Elastic / in player.go")
 				//fmt.Println("currentURL: ", currentURL)
 				http.GetFile(currentURL, baseJoined, fileDownloadLocation, false, startRange, endRange, segmentNumber,
-					segmentDuration, true, quicBool, debugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, currentMediaType, ctx2)
+					segmentDuration, true, quicBool, debugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, currentMediaType, ctx2, segmentInits, headerRep, tracer)
 				repRate = l_lowestMPDrepRateIndex
 				///fmt.Println("MPD file repRate index: ", repRate)
 				//fmt.Println("MPD file bandwithList[repRate]", bandwithList[repRate])
 			case glob.ProgressiveAlg:
 				// get the header file
 				// there is no byte range in this file, so we set byte-range bool to false
-				http.GetFileProgressively(currentURL, baseJoined, fileDownloadLocation, false, startRange, endRange, segmentNumber, segmentDuration, false, debugLog, AudioByteRange, profile)
+				http.GetFileProgressively(currentURL, baseJoined, fileDownloadLocation, false, startRange, endRange, segmentNumber, segmentDuration, false, quicBool, debugLog, useTestbedBool, AudioByteRange, profile, tracer)
 			case glob.TestAlg:
 				fmt.Println("testAlg / in player.go")
 				http.GetFile(currentURL, baseJoined, fileDownloadLocation, AudioByteRange, startRange, endRange, segmentNumber,
-					segmentDuration, true, quicBool, debugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, currentMediaType, ctx2)
+					segmentDuration, true, quicBool, debugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, currentMediaType, ctx2, segmentInits, headerRep, tracer)
 
 				//fmt.Println("lowestmpd: ", lowestMPDrepRateIndex)
 				repRate = l_lowestMPDrepRateIndex
@@ -385,44 +442,44 @@ func Stream(mpdList []http.MPD, debugFile string, debugLog bool, codec string, c
 			case glob.BBAAlg:
 				//fmt.Println("BBAAlg / in player.go")
 				http.GetFile(currentURL, baseJoined, fileDownloadLocation, false, startRange, endRange, segmentNumber,
-					segmentDuration, true, quicBool, debugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, currentMediaType, ctx2)
+					segmentDuration, true, quicBool, debugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, currentMediaType, ctx2, segmentInits, headerRep, tracer)
 
 				repRate = l_lowestMPDrepRateIndex
 
 			case glob.ArbiterAlg:
 				//fmt.Println("ArbiterAlg / in player.go")
 				http.GetFile(currentURL, baseJoined, fileDownloadLocation, false, startRange, endRange, segmentNumber,
-					segmentDuration, true, quicBool, debugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, currentMediaType, ctx2)
+					segmentDuration, true, quicBool, debugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, currentMediaType, ctx2, segmentInits, headerRep, tracer)
 
 				repRate = l_lowestMPDrepRateIndex
 
 			case glob.LogisticAlg:
 				http.GetFile(currentURL, baseJoined, fileDownloadLocation, false, startRange, endRange, segmentNumber,
-					segmentDuration, true, quicBool, debugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, currentMediaType, ctx2)
+					segmentDuration, true, quicBool, debugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, currentMediaType, ctx2, segmentInits, headerRep, tracer)
 				repRate = l_lowestMPDrepRateIndex
 			case glob.MeanAverageAlg:
 				http.GetFile(currentURL, baseJoined, fileDownloadLocation, false, startRange, endRange, segmentNumber,
-					segmentDuration, true, quicBool, debugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, currentMediaType, ctx2)
+					segmentDuration, true, quicBool, debugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, currentMediaType, ctx2, segmentInits, headerRep, tracer)
 			case glob.GeomAverageAlg:
 				http.GetFile(currentURL, baseJoined, fileDownloadLocation, false, startRange, endRange, segmentNumber,
-					segmentDuration, true, quicBool, debugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, currentMediaType, ctx2)
+					segmentDuration, true, quicBool, debugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, currentMediaType, ctx2, segmentInits, headerRep, tracer)
 			case glob.EMWAAverageAlg:
 				http.GetFile(currentURL, baseJoined, fileDownloadLocation, false, startRange, endRange, segmentNumber,
-					segmentDuration, true, quicBool, debugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, currentMediaType, ctx2)
+					segmentDuration, true, quicBool, debugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, currentMediaType, ctx2, segmentInits, headerRep, tracer)
 			case glob.MeanAverageXLAlg:
 				http.GetFile(currentURL, baseJoined, fileDownloadLocation, false, startRange, endRange, segmentNumber,
-					segmentDuration, true, quicBool, debugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, currentMediaType, ctx2)
+					segmentDuration, true, quicBool, debugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, currentMediaType, ctx2, segmentInits, headerRep, tracer)
 			case glob.MeanAverageRecentXLAlg:
 				http.GetFile(currentURL, baseJoined, fileDownloadLocation, false, startRange, endRange, segmentNumber,
-					segmentDuration, true, quicBool, debugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, currentMediaType, ctx2)
+					segmentDuration, true, quicBool, debugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, currentMediaType, ctx2, segmentInits, headerRep, tracer)
 			case glob.BB1AAlg_AV:
 				http.GetFile(currentURL, baseJoined, fileDownloadLocation, false, startRange, endRange, segmentNumber,
-					segmentDuration, true, quicBool, debugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, currentMediaType, ctx2)
+					segmentDuration, true, quicBool, debugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, currentMediaType, ctx2, segmentInits, headerRep, tracer)
 
 				repRate = l_lowestMPDrepRateIndex
 			case glob.BB1AAlg_AVXL:
 				http.GetFile(currentURL, baseJoined, fileDownloadLocation, false, startRange, endRange, segmentNumber,
-					segmentDuration, true, quicBool, debugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, currentMediaType, ctx2)
+					segmentDuration, true, quicBool, debugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, currentMediaType, ctx2, segmentInits, headerRep, tracer)
 
 				repRate = l_lowestMPDrepRateIndex
 			}
@@ -444,7 +501,7 @@ func Stream(mpdList []http.MPD, debugFile string, debugLog bool, codec string, c
 			// get the segment headers and stop this run
 			if getHeaderBool {
 				// get the segment headers for all MPD url passed as arguments - print to file
-				http.GetAllSegmentHeaders(mpdList, codecIndexList, maxHeight, 1, streamDuration, isByteRangeMPD, maxBuffer, headerURL, codec, urlInput, debugLog, true, client)
+				http.GetAllSegmentHeaders(mpdList, codecIndexList, maxHeight, 1, streamDuration, isByteRangeMPD, maxBuffer, headerURL, codec, urlInput, debugLog, true, client, tracer)
 
 				// print error message
 				fmt.Printf("*** - All segment header have been downloaded to " + glob.DebugFolder + " - ***\n")
@@ -453,12 +510,12 @@ func Stream(mpdList []http.MPD, debugFile string, debugLog bool, codec string, c
 			} else {
 				if getHeaderReadFromFile == glob.GetHeaderOnline {
 					// get the segment headers for all MPD url passed as arguments - not from file
-					segHeadValues = http.GetAllSegmentHeaders(mpdList, codecIndexList, maxHeight, 1, streamDuration, isByteRangeMPD, maxBuffer, headerURL, codec, urlInput, debugLog, false, client)
+					segHeadValues = http.GetAllSegmentHeaders(mpdList, codecIndexList, maxHeight, 1, streamDuration, isByteRangeMPD, maxBuffer, headerURL, codec, urlInput, debugLog, false, client, tracer)
 				} else if getHeaderReadFromFile == glob.GetHeaderOffline {
 					// get the segment headers for all MPD url passed as arguments - yes from file
 					// get headers from file for a given number of seconds of stream time
 					// let's assume every n seconds
-					segHeadValues = http.GetNSegmentHeaders(mpdList, codecIndexList, maxHeight, 1, streamDuration, isByteRangeMPD, maxBuffer, headerURL, codec, urlInput, debugLog, true, client)
+					segHeadValues = http.GetNSegmentHeaders(mpdList, codecIndexList, maxHeight, 1, streamDuration, isByteRangeMPD, maxBuffer, headerURL, codec, urlInput, debugLog, true, client, tracer)
 
 				}
 			}
@@ -499,6 +556,7 @@ func Stream(mpdList []http.MPD, debugFile string, debugLog bool, codec string, c
 				RepRate:               repRate,
 				BandwithList:          bandwithList,
 				Profile:               profile,
+				SegmentInits:          segmentInits,
 			}
 			streamStructs = append(streamStructs, streaminfo)
 			mapSegmentLogPrintouts = append(mapSegmentLogPrintouts, mapSegmentLogPrintout)
@@ -511,12 +569,21 @@ func Stream(mpdList []http.MPD, debugFile string, debugLog bool, codec string, c
 	// print the output log headers
 	logging.PrintHeaders(extendPrintLog, fileDownloadLocation, glob.LogDownload, debugFile, debugLog, printLog, printHeadersData)
 
-	if adapt == glob.BB1AAlg_AVXL {
-		accountant.InitialisePredictor()
+	if accountant.StallPredictorEnabled() {
+		accountant.InitialisePredictor(debugFile, debugLog)
 	}
 
 	// Streaming loop function - using the first MPD index - 0, and hlsUsed false
-	segmentNumber, mapSegmentLogPrintouts = streamLoop(streamStructs, Noden, accountant)
+	segmentNumber, mapSegmentLogPrintouts = streamLoop(streamStructs, tracer, Noden, accountant)
+
+	// score the stall predictions against the stalls we had
+	if accountant.StallPredictorEnabled() {
+		score := accountant.StallPredictionScore()
+		scoreString := fmt.Sprintf("Stall prediction - precision %.2f, recall %.2f (true positives %d, false positives %d, false negatives %d, true negatives %d)",
+			score.Precision(), score.Recall(), score.TruePositives, score.FalsePositives, score.FalseNegatives, score.TrueNegatives)
+		fmt.Println(scoreString)
+		logging.DebugPrint(debugFile, debugLog, "DEBUG: ", scoreString)
+	}
 
 	// print sections of the map to the debug log - if debug is true
 	if debugLog {
@@ -527,8 +594,25 @@ func Stream(mpdList []http.MPD, debugFile string, debugLog bool, codec string, c
 	// and an end time that includes for the original initial buffer size in seconds
 	logging.PrintPlayOutLog(mapSegmentLogPrintouts[0][segmentNumber-1].PlayStartPosition+mapSegmentLogPrintouts[0][initBuffer].PlayStartPosition, initBuffer, mapSegmentLogPrintouts, glob.LogDownload, printLog, printHeadersData)
 
+	// save the QoE KPIs of the session, with the highest bitrate of each media type for Claye
+	maxRepRates := make([]int, len(streamStructs))
+	for i := range streamStructs {
+		if i < len(highestMPDrepRateIndex) {
+			maxRepRates[i] = streamStructs[i].BandwithList[highestMPDrepRateIndex[i]]
+		}
+	}
+	summary := qoe.Summarise(mapSegmentLogPrintouts, initBuffer, maxRepRates)
+	if err := qoe.WriteSummary(fileDownloadLocation+"/"+glob.SummaryFile, summary); err != nil {
+		logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "can't save the session summary - "+err.Error())
+	}
+
+	// write the MPD (and tracks) that play back the stored segments
+	if saveFilesBool {
+		http.WriteStoredSession(mpdList, mapSegmentLogPrintouts, mimeTypesMediaType, urlInput, isByteRangeMPD, fileDownloadLocation, quicBool, debugFile, debugLog, useTestbedBool, tracer)
+	}
+
 	time.Sleep(1 * time.Second)
-	abrqlog.MainTracer.Close()
+	tracer.Close()
 }
 
 var currently_playing = false
@@ -538,7 +622,7 @@ var currently_playing = false
  * take the first segment number, download it with a low quality
  * call itself with the next segment number
  */
-func streamLoop(streamStructs []http.StreamStruct, Noden P2Pconsul.NodeUrl, accountant *xlayer.CrossLayerAccountant) (int, []map[int]logging.SegPrintLogInformation) {
+func streamLoop(streamStructs []http.StreamStruct, tracer *abrqlog.StreamTracer, Noden P2Pconsul.NodeUrl, accountant *xlayer.CrossLayerAccountant) (int, []map[int]logging.SegPrintLogInformation) {
 
 	// variable for rtt for this segment
 	var rtt time.Duration
@@ -561,6 +645,12 @@ func streamLoop(streamStructs []http.StreamStruct, Noden P2Pconsul.NodeUrl, acco
 	//
 	var P1203Header float64
 
+	// the boxes read from the last segment
+	var segment *isobmff.Segment
+
+	// the timing of each phase of the last segment request
+	var timing http.RequestTiming
+
 	// logging info
 	// var mapSegmentLogPrintouts []map[int]logging.SegPrintLogInformation
 
@@ -602,6 +692,7 @@ func streamLoop(streamStructs []http.StreamStruct, Noden P2Pconsul.NodeUrl, acco
 		repRate := streamStructs[mimeTypeIndex].RepRate
 		bandwithList := streamStructs[mimeTypeIndex].BandwithList
 		profile := streamStructs[mimeTypeIndex].Profile
+		segmentInits := streamStructs[mimeTypeIndex].SegmentInits
 
 		// determine the MimeType and mimeTypeIndex - set video by default
 		// get the mimeType of this adaptationSet
@@ -638,7 +729,10 @@ func streamLoop(streamStructs []http.StreamStruct, Noden P2Pconsul.NodeUrl, acco
 					// replace a previously downloaded segment with this call
 					nextSegmentNumber, mapSegmentLogPrintouts, bufferDifference, thisRunTimeVal, nextRunTime =
 						hlsfunc.GetHlsSegment(
-							streamLoop,
+							// the replaced segment is logged to the tracer of this stream
+							func(streamStructs []http.StreamStruct, Noden P2Pconsul.NodeUrl, accountant *xlayer.CrossLayerAccountant) (int, []map[int]logging.SegPrintLogInformation) {
+								return streamLoop(streamStructs, tracer, Noden, accountant)
+							},
 							chunkReplace,
 							mapSegmentLogPrintouts,
 							maxHeight,
@@ -752,9 +846,7 @@ func streamLoop(streamStructs []http.StreamStruct, Noden P2Pconsul.NodeUrl, acco
 				mapSegmentLogPrintouts = append(mapSegmentLogPrintouts, streamStructs[mimeTypeIndex].MapSegmentLogPrintout)
 			}
 
-			playhead := abrqlog.NewPlayheadStatus()
-			playhead.PlayheadTime = time.Duration(playPosition) * time.Millisecond
-			abrqlog.MainTracer.EndStream(playhead)
+			playback.end()
 
 			return segmentNumber, mapSegmentLogPrintouts
 		}
@@ -783,6 +875,7 @@ func streamLoop(streamStructs []http.StreamStruct, Noden P2Pconsul.NodeUrl, acco
 		urlHeaderString := http.JoinURL(currentURL, baseURL+segURL, debugLog)
 		if Noden.ClientName != glob.CollabPrintOff && Noden.ClientName != "" {
 			currentURL = Noden.Search(urlHeaderString, segmentDuration, true, profile)
+			playerMetrics.Fetch(metrics.FetchSource(currentURL))
 
 			logging.DebugPrint(glob.DebugFile, debugLog, "\nDEBUG: ", "current URL joined: "+currentURL)
 			currentURL = strings.Split(currentURL, "::")[0]
@@ -793,8 +886,21 @@ func streamLoop(streamStructs []http.StreamStruct, Noden P2Pconsul.NodeUrl, acco
 		}
 		// Collaborative Code - End
 
+		// read each media segment with the init segment of its representation, get it before the first segment of a representation we switched to
+		rep := http.RepresentationKey{MPD: mpdListIndex, AdaptationSet: mimeTypes[mimeTypeIndex], Representation: repRate}
+		if !isByteRangeMPD && !segmentInits.Has(rep) {
+			segmentInits.Fetch(mpdList[mpdListIndex], strings.TrimSpace(urlInput[mpdListIndex]), rep, quicBool, glob.DebugFile, debugLog, useTestbedBool, tracer)
+		}
+
 		ctx, cancel := context.WithCancel(context.Background())
-		aborted := false
+		// the bytes received and the time taken by the download, for when it is abandoned
+		progress := http.NewDownloadProgress()
+		ctx = http.WithDownloadProgress(ctx, progress)
+		// set by the stall predictor, which runs on the goroutine of the crosslayer intake
+		var aborted atomic.Bool
+		// the stall predictor is only used above the lowest bitrate, and these segments are scored
+		predictingStall := false
+		stalled := false
 
 		// Start Time of this segment
 		currentTime := time.Now()
@@ -805,10 +911,11 @@ func streamLoop(streamStructs []http.StreamStruct, Noden P2Pconsul.NodeUrl, acco
 			accountant.StartTiming()
 		case glob.BB1AAlg_AV:
 			accountant.StartTiming()
-		case glob.BB1AAlg_AVXL:
-			if repRate != lowestMPDrepRateIndex[mimeTypeIndex] {
-				accountant.SegmentStart_predictStall(segmentDuration, bandwithList[repRate], bufferLevel, cancel, &aborted, maxBuffer*glob.Conversion1000, bandwithList[lowestMPDrepRateIndex[mimeTypeIndex]/glob.Conversion1000])
-			}
+		}
+		if accountant.StallPredictorEnabled() && repRate != lowestMPDrepRateIndex[mimeTypeIndex] {
+			// segment duration and buffers in ms, bitrates in bits/second
+			accountant.SegmentStart_predictStall(segmentDuration*glob.Conversion1000, bandwithList[repRate], bufferLevel, cancel, &aborted, maxBuffer*glob.Conversion1000, bandwithList[lowestMPDrepRateIndex[mimeTypeIndex]])
+			predictingStall = true
 		}
 
 		var status int
@@ -819,33 +926,33 @@ func streamLoop(streamStructs []http.StreamStruct, Noden P2Pconsul.NodeUrl, acco
 		// Download the segment - add the segment duration to the file name
 		switch adapt {
 		case glob.ConventionalAlg:
-			rtt, segSize, protocol, segmentFileName, P1203Header, status = http.GetFile(currentURL, baseJoined, fileDownloadLocation, isByteRangeMPD, startRange, endRange, segmentNumber, segmentDuration, true, quicBool, glob.DebugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, mimeTypesMediaType[mimeTypeIndex], ctx)
+			rtt, segSize, protocol, segmentFileName, P1203Header, status, segment, timing = http.GetFile(currentURL, baseJoined, fileDownloadLocation, isByteRangeMPD, startRange, endRange, segmentNumber, segmentDuration, true, quicBool, glob.DebugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, mimeTypesMediaType[mimeTypeIndex], ctx, segmentInits, rep, tracer)
 		case glob.ElasticAlg:
-			rtt, segSize, protocol, segmentFileName, P1203Header, status = http.GetFile(currentURL, baseJoined, fileDownloadLocation, isByteRangeMPD, startRange, endRange, segmentNumber, segmentDuration, true, quicBool, glob.DebugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, mimeTypesMediaType[mimeTypeIndex], ctx)
+			rtt, segSize, protocol, segmentFileName, P1203Header, status, segment, timing = http.GetFile(currentURL, baseJoined, fileDownloadLocation, isByteRangeMPD, startRange, endRange, segmentNumber, segmentDuration, true, quicBool, glob.DebugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, mimeTypesMediaType[mimeTypeIndex], ctx, segmentInits, rep, tracer)
 		case glob.ProgressiveAlg:
-			rtt, segSize = http.GetFileProgressively(currentURL, baseJoined, fileDownloadLocation, isByteRangeMPD, startRange, endRange, segmentNumber, segmentDuration, true, debugLog, AudioByteRange, profile)
+			rtt, segSize = http.GetFileProgressively(currentURL, baseJoined, fileDownloadLocation, isByteRangeMPD, startRange, endRange, segmentNumber, segmentDuration, true, quicBool, debugLog, useTestbedBool, AudioByteRange, profile, tracer)
 		case glob.LogisticAlg:
-			rtt, segSize, protocol, segmentFileName, P1203Header, status = http.GetFile(currentURL, baseJoined, fileDownloadLocation, isByteRangeMPD, startRange, endRange, segmentNumber, segmentDuration, true, quicBool, glob.DebugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, mimeTypesMediaType[mimeTypeIndex], ctx)
+			rtt, segSize, protocol, segmentFileName, P1203Header, status, segment, timing = http.GetFile(currentURL, baseJoined, fileDownloadLocation, isByteRangeMPD, startRange, endRange, segmentNumber, segmentDuration, true, quicBool, glob.DebugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, mimeTypesMediaType[mimeTypeIndex], ctx, segmentInits, rep, tracer)
 		case glob.MeanAverageAlg:
-			rtt, segSize, protocol, segmentFileName, P1203Header, status = http.GetFile(currentURL, baseJoined, fileDownloadLocation, isByteRangeMPD, startRange, endRange, segmentNumber, segmentDuration, true, quicBool, glob.DebugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, mimeTypesMediaType[mimeTypeIndex], ctx)
+			rtt, segSize, protocol, segmentFileName, P1203Header, status, segment, timing = http.GetFile(currentURL, baseJoined, fileDownloadLocation, isByteRangeMPD, startRange, endRange, segmentNumber, segmentDuration, true, quicBool, glob.DebugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, mimeTypesMediaType[mimeTypeIndex], ctx, segmentInits, rep, tracer)
 		case glob.GeomAverageAlg:
-			rtt, segSize, protocol, segmentFileName, P1203Header, status = http.GetFile(currentURL, baseJoined, fileDownloadLocation, isByteRangeMPD, startRange, endRange, segmentNumber, segmentDuration, true, quicBool, glob.DebugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, mimeTypesMediaType[mimeTypeIndex], ctx)
+			rtt, segSize, protocol, segmentFileName, P1203Header, status, segment, timing = http.GetFile(currentURL, baseJoined, fileDownloadLocation, isByteRangeMPD, startRange, endRange, segmentNumber, segmentDuration, true, quicBool, glob.DebugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, mimeTypesMediaType[mimeTypeIndex], ctx, segmentInits, rep, tracer)
 		case glob.EMWAAverageAlg:
-			rtt, segSize, protocol, segmentFileName, P1203Header, status = http.GetFile(currentURL, baseJoined, fileDownloadLocation, isByteRangeMPD, startRange, endRange, segmentNumber, segmentDuration, true, quicBool, glob.DebugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, mimeTypesMediaType[mimeTypeIndex], ctx)
+			rtt, segSize, protocol, segmentFileName, P1203Header, status, segment, timing = http.GetFile(currentURL, baseJoined, fileDownloadLocation, isByteRangeMPD, startRange, endRange, segmentNumber, segmentDuration, true, quicBool, glob.DebugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, mimeTypesMediaType[mimeTypeIndex], ctx, segmentInits, rep, tracer)
 		case glob.TestAlg:
-			rtt, segSize, protocol, segmentFileName, P1203Header, status = http.GetFile(currentURL, baseJoined, fileDownloadLocation, isByteRangeMPD, startRange, endRange, segmentNumber, segmentDuration, true, quicBool, glob.DebugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, mimeTypesMediaType[mimeTypeIndex], ctx)
+			rtt, segSize, protocol, segmentFileName, P1203Header, status, segment, timing = http.GetFile(currentURL, baseJoined, fileDownloadLocation, isByteRangeMPD, startRange, endRange, segmentNumber, segmentDuration, true, quicBool, glob.DebugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, mimeTypesMediaType[mimeTypeIndex], ctx, segmentInits, rep, tracer)
 		case glob.ArbiterAlg:
-			rtt, segSize, protocol, segmentFileName, P1203Header, status = http.GetFile(currentURL, baseJoined, fileDownloadLocation, isByteRangeMPD, startRange, endRange, segmentNumber, segmentDuration, true, quicBool, glob.DebugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, mimeTypesMediaType[mimeTypeIndex], ctx)
+			rtt, segSize, protocol, segmentFileName, P1203Header, status, segment, timing = http.GetFile(currentURL, baseJoined, fileDownloadLocation, isByteRangeMPD, startRange, endRange, segmentNumber, segmentDuration, true, quicBool, glob.DebugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, mimeTypesMediaType[mimeTypeIndex], ctx, segmentInits, rep, tracer)
 		case glob.BBAAlg:
-			rtt, segSize, protocol, segmentFileName, P1203Header, status = http.GetFile(currentURL, baseJoined, fileDownloadLocation, isByteRangeMPD, startRange, endRange, segmentNumber, segmentDuration, true, quicBool, glob.DebugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, mimeTypesMediaType[mimeTypeIndex], ctx)
+			rtt, segSize, protocol, segmentFileName, P1203Header, status, segment, timing = http.GetFile(currentURL, baseJoined, fileDownloadLocation, isByteRangeMPD, startRange, endRange, segmentNumber, segmentDuration, true, quicBool, glob.DebugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, mimeTypesMediaType[mimeTypeIndex], ctx, segmentInits, rep, tracer)
 		case glob.MeanAverageXLAlg:
-			rtt, segSize, protocol, segmentFileName, P1203Header, status = http.GetFile(currentURL, baseJoined, fileDownloadLocation, isByteRangeMPD, startRange, endRange, segmentNumber, segmentDuration, true, quicBool, glob.DebugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, mimeTypesMediaType[mimeTypeIndex], ctx)
+			rtt, segSize, protocol, segmentFileName, P1203Header, status, segment, timing = http.GetFile(currentURL, baseJoined, fileDownloadLocation, isByteRangeMPD, startRange, endRange, segmentNumber, segmentDuration, true, quicBool, glob.DebugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, mimeTypesMediaType[mimeTypeIndex], ctx, segmentInits, rep, tracer)
 		case glob.MeanAverageRecentXLAlg:
-			rtt, segSize, protocol, segmentFileName, P1203Header, status = http.GetFile(currentURL, baseJoined, fileDownloadLocation, isByteRangeMPD, startRange, endRange, segmentNumber, segmentDuration, true, quicBool, glob.DebugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, mimeTypesMediaType[mimeTypeIndex], ctx)
+			rtt, segSize, protocol, segmentFileName, P1203Header, status, segment, timing = http.GetFile(currentURL, baseJoined, fileDownloadLocation, isByteRangeMPD, startRange, endRange, segmentNumber, segmentDuration, true, quicBool, glob.DebugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, mimeTypesMediaType[mimeTypeIndex], ctx, segmentInits, rep, tracer)
 		case glob.BB1AAlg_AV:
-			rtt, segSize, protocol, segmentFileName, P1203Header, status = http.GetFile(currentURL, baseJoined, fileDownloadLocation, isByteRangeMPD, startRange, endRange, segmentNumber, segmentDuration, true, quicBool, glob.DebugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, mimeTypesMediaType[mimeTypeIndex], ctx)
+			rtt, segSize, protocol, segmentFileName, P1203Header, status, segment, timing = http.GetFile(currentURL, baseJoined, fileDownloadLocation, isByteRangeMPD, startRange, endRange, segmentNumber, segmentDuration, true, quicBool, glob.DebugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, mimeTypesMediaType[mimeTypeIndex], ctx, segmentInits, rep, tracer)
 		case glob.BB1AAlg_AVXL:
-			rtt, segSize, protocol, segmentFileName, P1203Header, status = http.GetFile(currentURL, baseJoined, fileDownloadLocation, isByteRangeMPD, startRange, endRange, segmentNumber, segmentDuration, true, quicBool, glob.DebugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, mimeTypesMediaType[mimeTypeIndex], ctx)
+			rtt, segSize, protocol, segmentFileName, P1203Header, status, segment, timing = http.GetFile(currentURL, baseJoined, fileDownloadLocation, isByteRangeMPD, startRange, endRange, segmentNumber, segmentDuration, true, quicBool, glob.DebugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, mimeTypesMediaType[mimeTypeIndex], ctx, segmentInits, rep, tracer)
 
 		}
 
@@ -861,9 +968,12 @@ func streamLoop(streamStructs []http.StreamStruct, Noden P2Pconsul.NodeUrl, acco
 		//fmt.Println("deliveryTime: ", deliveryTime)
 		accountant.StopTiming()
 
-		fmt.Println(status, aborted)
+		fmt.Println(status, aborted.Load())
 
-		if aborted {
+		// an abandoned download, or a segment that could not be read, is requested again at the lowest rep_rate
+		if aborted.Load() || status == http.StatusIncomplete {
+			logging.DebugPrint(glob.DebugFile, debugLog, "DEBUG: ", "segment "+strconv.Itoa(segmentNumber)+" abandoned after "+strconv.FormatInt(progress.Received(), 10)+
+				" bytes in "+progress.Elapsed().String()+", stall predicted "+strconv.FormatBool(aborted.Load()))
 			//fmt.Println("After sleep")
 			//time.Sleep(8 * time.Second)
 			///fmt.Println("After sleep")
@@ -894,6 +1004,7 @@ func streamLoop(streamStructs []http.StreamStruct, Noden P2Pconsul.NodeUrl, acco
 			urlHeaderString := http.JoinURL(currentURL, baseURL+segURL, debugLog)
 			if Noden.ClientName != glob.CollabPrintOff && Noden.ClientName != "" {
 				currentURL = Noden.Search(urlHeaderString, segmentDuration, true, profile)
+				playerMetrics.Fetch(metrics.FetchSource(currentURL))
 
 				logging.DebugPrint(glob.DebugFile, debugLog, "\nDEBUG: ", "current URL joined: "+currentURL)
 				currentURL = strings.Split(currentURL, "::")[0]
@@ -903,13 +1014,23 @@ func streamLoop(streamStructs []http.StreamStruct, Noden P2Pconsul.NodeUrl, acco
 				baseJoined = urlSplit[len(urlSplit)-1]
 			}
 
+			rep = http.RepresentationKey{MPD: mpdListIndex, AdaptationSet: mimeTypes[mimeTypeIndex], Representation: repRate}
+			if !isByteRangeMPD && !segmentInits.Has(rep) {
+				segmentInits.Fetch(mpdList[mpdListIndex], strings.TrimSpace(urlInput[mpdListIndex]), rep, quicBool, glob.DebugFile, debugLog, useTestbedBool, tracer)
+			}
 			ctxaborted := context.Background()
 
 			// Start Time of this segment
 			fmt.Println("GETTINGSEGMENT", time.Now().UnixMilli())
 			logging.DebugPrint(glob.DebugFile, debugLog, "\nDEBUG: ", "ABORT has choosen rep_Rate "+strconv.Itoa(repRate)+" @ a rate of "+strconv.Itoa(bandwithList[repRate]/glob.Conversion1000))
 			currentTime = time.Now()
-			rtt, segSize, protocol, segmentFileName, P1203Header, status = http.GetFile(currentURL, baseJoined, fileDownloadLocation, isByteRangeMPD, startRange, endRange, segmentNumber, segmentDuration, true, quicBool, glob.DebugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, mimeTypesMediaType[mimeTypeIndex], ctxaborted)
+			rtt, segSize, protocol, segmentFileName, P1203Header, status, segment, timing = http.GetFile(currentURL, baseJoined, fileDownloadLocation, isByteRangeMPD, startRange, endRange, segmentNumber, segmentDuration, true, quicBool, glob.DebugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, mimeTypesMediaType[mimeTypeIndex], ctxaborted, segmentInits, rep, tracer)
+			if status == http.StatusIncomplete {
+				// print error message
+				fmt.Println("*** segment " + strconv.Itoa(segmentNumber) + " could not be downloaded at the lowest rep_rate ***")
+				// stop the app
+				utils.StopApp()
+			}
 			logging.DebugPrint(glob.DebugFile, debugLog, "\nDEBUG: ", "Abort segment arrived")
 			fmt.Println("SEGMENTARRIVED", bandwithList[repRate], time.Now().UnixMilli())
 			// arrival and delivery times for this segment
@@ -932,7 +1053,8 @@ func streamLoop(streamStructs []http.StreamStruct, Noden P2Pconsul.NodeUrl, acco
 				playhead := abrqlog.NewPlayheadStatus()
 				playhead.PlayheadTime = 0
 				playhead.PlayheadFrame = 0
-				abrqlog.MainTracer.PlayerInteraction(abrqlog.InteractionStatePlay, playhead, streamSpeed)
+				tracer.PlayerInteraction(abrqlog.InteractionStatePlay, playhead, streamSpeed)
+				playback.startPlaying()
 			}
 
 			// get the segment less the initial buffer
@@ -958,16 +1080,10 @@ func streamLoop(streamStructs []http.StreamStruct, Noden P2Pconsul.NodeUrl, acco
 				// if the buffer is empty, then we need to calculate
 			} else {
 				stallTime = currentBuffer
+				stalled = true
 
-				playhead := abrqlog.NewPlayheadStatus()
-				playhead.PlayheadTime = time.Duration(playPosition) * time.Millisecond
-				abrqlog.MainTracer.Rebuffer(playhead)
-
-				bufferStats := abrqlog.NewBufferStats()
-				bufferStats.PlayoutTime = time.Duration(0)
-				bufferStats.MaxTime = time.Duration(streamStructs[mimeTypeIndex].MaxBuffer) * time.Second
-				abrqlog.MainTracer.UpdateBufferOccupancy(mimeTypesMediaType[mimeTypeIndex],
-					bufferStats)
+				playback.rebuffer(mimeTypeIndex)
+				playerMetrics.Stall(time.Duration(-stallTime) * time.Millisecond)
 			}
 
 			// To have the bufferLevel we take the max between the remaining buffer and 0, we add the duration of the segment we downloaded
@@ -976,6 +1092,8 @@ func streamLoop(streamStructs []http.StreamStruct, Noden P2Pconsul.NodeUrl, acco
 			// increment the waitToPlayCounter
 			waitToPlayCounter++
 
+			playback.segmentBuffered(mimeTypeIndex, time.Duration(segmentDuration)*time.Second, int64(segSize), time.Duration(bufferLevel)*time.Millisecond)
+
 		} else {
 			// If we reach this it means that the buffer has once reached the initial desired level, after this we never want to wait for it to fill up again before we start playing
 			//inStartupPhase = false
@@ -983,6 +1101,13 @@ func streamLoop(streamStructs []http.StreamStruct, Noden P2Pconsul.NodeUrl, acco
 			bufferLevel += (segmentDuration * glob.Conversion1000)
 			// increment the waitToPlayCounter
 			waitToPlayCounter++
+
+			playback.segmentBuffered(mimeTypeIndex, time.Duration(segmentDuration)*time.Second, int64(segSize), time.Duration(bufferLevel)*time.Millisecond)
+		}
+
+		// match the stall predictions of this segment with whether it stalled
+		if predictingStall {
+			accountant.ScoreStallPrediction(stalled)
 		}
 
 		// check if the buffer level is higher than the max buffer
@@ -995,6 +1120,7 @@ func streamLoop(streamStructs []http.StreamStruct, Noden P2Pconsul.NodeUrl, acco
 
 			// reset the buffer to the new value less sleep time - should equal maxBuffer
 			bufferLevel -= int(float64(sleepTime) * streamSpeed)
+			playback.drained(mimeTypeIndex, time.Duration(bufferLevel)*time.Millisecond)
 		}
 
 		// some times we want to wait for an initial number of segments before stream begins
@@ -1055,6 +1181,15 @@ func streamLoop(streamStructs []http.StreamStruct, Noden P2Pconsul.NodeUrl, acco
 		thr := algo.CalculateThroughtput(segSize*8, deliveryTime)
 		//fmt.Println("THROUGHPUT: ", strconv.Itoa(thr))
 
+		// the estimate of the throughput of the next segment, if we use an estimator
+		estimator := throughputEstimator(mimeTypeIndex)
+		var estimate float64
+		if estimator != nil {
+			estimator.Observe(estimators.Sample{Bytes: segSize, Duration: time.Duration(deliveryTime) * time.Millisecond})
+			estimate = estimator.Estimate()
+			logging.DebugPrint(glob.DebugFile, debugLog, "\nDEBUG: ", estimatorName+" estimator estimates "+strconv.Itoa(int(estimate))+" bits/second from a throughput of "+strconv.Itoa(thr))
+		}
+
 		// save the bitrate from the input segment (less the header info)
 		var kbps float64
 		if getQoEBool {
@@ -1167,6 +1302,35 @@ func streamLoop(streamStructs []http.StreamStruct, Noden P2Pconsul.NodeUrl, acco
 			Profile:              profile,
 		}
 
+		// the license is only part of the startup delay of the first segment
+		if segmentNumber == 1 {
+			printInformation.LicenseTime = licenseTime
+		}
+
+		// values read from the boxes of the segment
+		if segment != nil {
+			printInformation.PayloadSize = int(segment.PayloadBytes())
+			printInformation.SampleCount = segment.SampleCount()
+			printInformation.FrameRate = segment.FrameRate()
+			printInformation.KeyframePositions = segment.KeyframePositions()
+			if times := segment.DecodeTimes(); len(times) > 0 {
+				printInformation.DecodeTime = int(times[0].Milliseconds())
+			}
+		}
+
+		// the timing of each phase of the request, in milliseconds
+		printInformation.DNSTime = float64(timing.DNS.Nanoseconds()) / (glob.Conversion1000 * glob.Conversion1000)
+		printInformation.ConnectTime = float64(timing.Connect.Nanoseconds()) / (glob.Conversion1000 * glob.Conversion1000)
+		printInformation.TLSTime = float64(timing.TLS.Nanoseconds()) / (glob.Conversion1000 * glob.Conversion1000)
+		printInformation.TTFB = float64(timing.TTFB.Nanoseconds()) / (glob.Conversion1000 * glob.Conversion1000)
+		printInformation.TTLB = float64(timing.TTLB.Nanoseconds()) / (glob.Conversion1000 * glob.Conversion1000)
+		printInformation.ConnReused = timing.Reused
+
+		// the throughput estimate in bits/second
+		printInformation.Estimate = int(estimate)
+		// the bottleneck link capacity of the connection of this segment in bits/second
+		printInformation.Capacity = int(accountant.BottleneckCapacity())
+
 		// this saves per segment number so from 1 on, and not 0 on
 		// remember this :)
 		mapSegmentLogPrintout[segmentNumber] = printInformation
@@ -1182,8 +1346,20 @@ func streamLoop(streamStructs []http.StreamStruct, Noden P2Pconsul.NodeUrl, acco
 		if getQoEBool {
 			qoe.CreateQoE(&mapSegmentLogPrintout, debugLog, initBuffer, bandwithList[highestMPDrepRateIndex[mimeTypeIndex]], printHeadersData, saveCollabFilesBool, audioRate, audioCodec)
 		}
+		// the record of the segment, with its QoE, to the live view
+		liveServer.Segment(mapSegmentLogPrintout[segmentNumber])
+		playerMetrics.SegmentDownloaded(mimeTypesMediaType[mimeTypeIndex].String(), metrics.Segment{
+			Index:       repRate,
+			Bitrate:     bandwithList[repRate],
+			BufferLevel: time.Duration(bufferLevel) * time.Millisecond,
+			Throughput:  float64(thr),
+			Estimate:    estimate,
+			RTT:         rtt,
+		})
 
 		preRepRate := repRate
+		// the algorithm adds its own inputs and intermediate values to the decision of this segment
+		decision := &algo.Decision{Capacity: accountant.BottleneckCapacity()}
 
 		fmt.Println("BUFFERLEVEL: ", bufferLevel)
 
@@ -1192,13 +1368,21 @@ func streamLoop(streamStructs []http.StreamStruct, Noden P2Pconsul.NodeUrl, acco
 		//Conventional Algo
 		case glob.ConventionalAlg:
 			//fmt.Println("old: ", repRate)
-			algo.Conventional(&thrList, thr, &repRate, bandwithList, lowestMPDrepRateIndex[mimeTypeIndex])
+			if estimator != nil {
+				algo.EstimatorAlgo(&thrList, thr, estimate, &repRate, bandwithList, lowestMPDrepRateIndex[mimeTypeIndex], decision)
+			} else {
+				algo.Conventional(&thrList, thr, &repRate, bandwithList, lowestMPDrepRateIndex[mimeTypeIndex], decision)
+			}
 			//fmt.Println("new: ", repRate)
 			//Harmonic Mean Algo
 		case glob.ElasticAlg:
 			//fmt.Println("old repRate index: ", repRate)
 			//fmt.Println("old bandwithList[repRate]", bandwithList[repRate])
-			algo.ElasticAlgo(&thrList, thr, deliveryTime, maxBuffer, &repRate, bandwithList, &staticAlgParameter, bufferLevel, kP, kI, lowestMPDrepRateIndex[mimeTypeIndex])
+			if estimator != nil {
+				algo.ElasticEstimatorAlgo(&thrList, thr, estimate, deliveryTime, maxBuffer, &repRate, bandwithList, &staticAlgParameter, bufferLevel, kP, kI, lowestMPDrepRateIndex[mimeTypeIndex], decision)
+			} else {
+				algo.ElasticAlgo(&thrList, thr, deliveryTime, maxBuffer, &repRate, bandwithList, &staticAlgParameter, bufferLevel, kP, kI, lowestMPDrepRateIndex[mimeTypeIndex], decision)
+			}
 			//fmt.Println("new repRate index: ", repRate)
 			//fmt.Println("new bandwithList[repRate]", bandwithList[repRate])
 			//fmt.Println("elastic segmentNumber: ", segmentNumber)
@@ -1206,30 +1390,46 @@ func streamLoop(streamStructs []http.StreamStruct, Noden P2Pconsul.NodeUrl, acco
 		//Progressive Algo
 		case glob.ProgressiveAlg:
 			// fmt.Println("old: ", repRate)
-			algo.Conventional(&thrList, thr, &repRate, bandwithList, lowestMPDrepRateIndex[mimeTypeIndex])
+			if estimator != nil {
+				algo.EstimatorAlgo(&thrList, thr, estimate, &repRate, bandwithList, lowestMPDrepRateIndex[mimeTypeIndex], decision)
+			} else {
+				algo.Conventional(&thrList, thr, &repRate, bandwithList, lowestMPDrepRateIndex[mimeTypeIndex], decision)
+			}
 			// fmt.Println("new: ", repRate)
 		//Logistic Algo
 		case glob.LogisticAlg:
 			// fmt.Println("old: ", repRate)
 			algo.Logistic(&thrList, thr, &repRate, bandwithList, bufferLevel,
 				highestMPDrepRateIndex[mimeTypeIndex], lowestMPDrepRateIndex[mimeTypeIndex], glob.DebugFile, debugLog,
-				maxBufferLevel)
+				maxBufferLevel, decision)
 			// fmt.Println("new: ", repRate)
 			logging.DebugPrint(glob.DebugFile, debugLog, "\nDEBUG: ", "reprate returned: "+strconv.Itoa(repRate))
 		//Mean Average Algo
 		case glob.MeanAverageAlg:
 			//fmt.Println("old: ", repRate)
-			algo.MeanAverageAlgo(&thrList, thr, &repRate, bandwithList, lowestMPDrepRateIndex[mimeTypeIndex])
+			if estimator != nil {
+				algo.EstimatorAlgo(&thrList, thr, estimate, &repRate, bandwithList, lowestMPDrepRateIndex[mimeTypeIndex], decision)
+			} else {
+				algo.MeanAverageAlgo(&thrList, thr, &repRate, bandwithList, lowestMPDrepRateIndex[mimeTypeIndex], decision)
+			}
 			//fmt.Println("new: ", repRate)
 		//Geometric Average Algo
 		case glob.GeomAverageAlg:
 			//fmt.Println("old: ", repRate)
-			algo.GeomAverageAlgo(&thrList, thr, &repRate, bandwithList, lowestMPDrepRateIndex[mimeTypeIndex])
+			if estimator != nil {
+				algo.EstimatorAlgo(&thrList, thr, estimate, &repRate, bandwithList, lowestMPDrepRateIndex[mimeTypeIndex], decision)
+			} else {
+				algo.GeomAverageAlgo(&thrList, thr, &repRate, bandwithList, lowestMPDrepRateIndex[mimeTypeIndex], decision)
+			}
 			//fmt.Println("new: ", repRate)
 		//Exponential Average Algo
 		case glob.EMWAAverageAlg:
 			//fmt.Println("old: ", repRate)
-			algo.EMWAAverageAlgo(&thrList, &repRate, exponentialRatio, 3, thr, bandwithList, lowestMPDrepRateIndex[mimeTypeIndex])
+			if estimator != nil {
+				algo.EstimatorAlgo(&thrList, thr, estimate, &repRate, bandwithList, lowestMPDrepRateIndex[mimeTypeIndex], decision)
+			} else {
+				algo.EMWAAverageAlgo(&thrList, &repRate, exponentialRatio, 3, thr, bandwithList, lowestMPDrepRateIndex[mimeTypeIndex], decision)
+			}
 
 		case glob.ArbiterAlg:
 
@@ -1237,7 +1437,7 @@ func streamLoop(streamStructs []http.StreamStruct, Noden P2Pconsul.NodeUrl, acco
 				repRate, &thrList, streamDuration, mpdList[mpdListIndex], currentURL,
 				mimeTypes[mimeTypeIndex], segmentNumber, baseURL, debugLog, deliveryTime, bufferLevel,
 				highestMPDrepRateIndex[mimeTypeIndex], lowestMPDrepRateIndex[mimeTypeIndex], bandwithList,
-				segSize, quicBool, useTestbedBool)
+				segSize, quicBool, useTestbedBool, estimate, tracer, decision)
 			//fmt.Println("new: ", repRate)
 		case glob.BBAAlg:
 			//fmt.Println("segDur: ", segmentDuration*1000)
@@ -1251,25 +1451,47 @@ func streamLoop(streamStructs []http.StreamStruct, Noden P2Pconsul.NodeUrl, acco
 			repRate = algo.CalculateSelectedIndexBba(thr, segmentDuration*1000, segmentNumber, maxBufferLevel,
 				repRate, &thrList, streamDuration, mpdList[mpdListIndex], currentURL,
 				mimeTypes[mimeTypeIndex], segmentNumber, baseURL, debugLog, deliveryTime, bufferLevel,
-				highestMPDrepRateIndex[mimeTypeIndex], lowestMPDrepRateIndex[mimeTypeIndex], bandwithList, quicBool, useTestbedBool)
+				highestMPDrepRateIndex[mimeTypeIndex], lowestMPDrepRateIndex[mimeTypeIndex], bandwithList, quicBool, useTestbedBool, decision)
 
 		case glob.TestAlg:
 			//fmt.Println("")
 
 		case glob.MeanAverageXLAlg:
 			//fmt.Println("old: ", repRate)
-			algo.MeanAverageXLAlgo(accountant, &thrList, thr, &repRate, bandwithList, lowestMPDrepRateIndex[mimeTypeIndex])
+			if estimator != nil {
+				algo.EstimatorAlgo(&thrList, thr, estimate, &repRate, bandwithList, lowestMPDrepRateIndex[mimeTypeIndex], decision)
+			} else {
+				algo.MeanAverageXLAlgo(accountant, &thrList, thr, &repRate, bandwithList, lowestMPDrepRateIndex[mimeTypeIndex], decision)
+			}
 		case glob.MeanAverageRecentXLAlg:
 			//fmt.Println("old: ", repRate)
-			algo.MeanAverageRecentXLAlgo(accountant, &thrList, thr, &repRate, bandwithList, lowestMPDrepRateIndex[mimeTypeIndex])
+			if estimator != nil {
+				algo.EstimatorAlgo(&thrList, thr, estimate, &repRate, bandwithList, lowestMPDrepRateIndex[mimeTypeIndex], decision)
+			} else {
+				algo.MeanAverageRecentXLAlgo(accountant, &thrList, thr, &repRate, bandwithList, lowestMPDrepRateIndex[mimeTypeIndex], decision)
+			}
 		case glob.BB1AAlg_AV:
-			repRate = algo.BBA(bufferLevel, maxBufferLevel, highestMPDrepRateIndex[mimeTypeIndex], lowestMPDrepRateIndex[mimeTypeIndex], bandwithList, segmentDuration*1000, debugLog, glob.DebugFile, &thrList, thr)
+			repRate = algo.BBA(bufferLevel, maxBufferLevel, highestMPDrepRateIndex[mimeTypeIndex], lowestMPDrepRateIndex[mimeTypeIndex], bandwithList, segmentDuration*1000, debugLog, glob.DebugFile, &thrList, thr, decision)
 		case glob.BB1AAlg_AVXL:
-			repRate = algo.BBA(bufferLevel, maxBufferLevel, highestMPDrepRateIndex[mimeTypeIndex], lowestMPDrepRateIndex[mimeTypeIndex], bandwithList, segmentDuration*1000, debugLog, glob.DebugFile, &thrList, thr)
+			repRate = algo.BBA(bufferLevel, maxBufferLevel, highestMPDrepRateIndex[mimeTypeIndex], lowestMPDrepRateIndex[mimeTypeIndex], bandwithList, segmentDuration*1000, debugLog, glob.DebugFile, &thrList, thr, decision)
 
 		}
 		logging.DebugPrint(glob.DebugFile, debugLog, "\nDEBUG: ", adapt+" has choosen rep_Rate "+strconv.Itoa(repRate)+" @ a rate of "+strconv.Itoa(bandwithList[repRate]/glob.Conversion1000))
 
+		// log the decision of every segment, even when we stay on the same rep_rate
+		tracer.Decision(mimeTypesMediaType[mimeTypeIndex], abrqlog.Decision{
+			Algorithm:    adapt,
+			Segment:      segmentNumber + 1,
+			Throughput:   float64(thr),
+			Estimate:     estimate,
+			BufferLevel:  time.Duration(bufferLevel) * time.Millisecond,
+			SegmentBytes: int64(segSize),
+			LastIndex:    preRepRate,
+			Index:        repRate,
+			Bitrate:      int64(bandwithList[repRate] / glob.Conversion1000),
+			Details:      decision.Details(),
+		})
+
 		postRepRate := repRate
 		if preRepRate != postRepRate {
 			from := abrqlog.NewRepresentation()
@@ -1278,7 +1500,8 @@ func streamLoop(streamStructs []http.StreamStruct, Noden P2Pconsul.NodeUrl, acco
 			to := abrqlog.NewRepresentation()
 			to.ID = strconv.Itoa(postRepRate)
 			to.Bitrate = int64(bandwithList[postRepRate] / glob.Conversion1000)
-			abrqlog.MainTracer.Switch(mimeTypesMediaType[mimeTypeIndex], from, to)
+			tracer.Switch(mimeTypesMediaType[mimeTypeIndex], from, to)
+			playerMetrics.Switch(mimeTypesMediaType[mimeTypeIndex].String())
 		}
 
 		//Increase the segment number
@@ -1296,9 +1519,7 @@ func streamLoop(streamStructs []http.StreamStruct, Noden P2Pconsul.NodeUrl, acco
 					mapSegmentLogPrintouts = append(mapSegmentLogPrintouts, streamStructs[thisMimeTypeIndex].MapSegmentLogPrintout)
 				}
 
-				playhead := abrqlog.NewPlayheadStatus()
-				playhead.PlayheadTime = time.Duration(playPosition) * time.Millisecond
-				abrqlog.MainTracer.EndStream(playhead)
+				playback.end()
 
 				return segmentNumber, mapSegmentLogPrintouts
 			}
@@ -1342,16 +1563,6 @@ func streamLoop(streamStructs []http.StreamStruct, Noden P2Pconsul.NodeUrl, acco
 			Profile:               profile,
 		}
 		streamStructs[mimeTypeIndex] = streaminfo
-
-		bufferStats := abrqlog.NewBufferStats()
-		bufferStats.PlayoutTime = time.Duration(bufferLevel) * time.Millisecond
-		bufferStats.MaxTime = time.Duration(streamStructs[mimeTypeIndex].MaxBuffer) * time.Second
-		abrqlog.MainTracer.UpdateBufferOccupancy(mimeTypesMediaType[mimeTypeIndex],
-			bufferStats)
-
-		playhead := abrqlog.NewPlayheadStatus()
-		playhead.PlayheadTime = time.Duration(playPosition) * time.Millisecond
-		abrqlog.MainTracer.PlayheadProgress(playhead)
 	}
 	//}
 
@@ -1371,9 +1582,20 @@ func streamLoop(streamStructs []http.StreamStruct, Noden P2Pconsul.NodeUrl, acco
 
 	// stream the next chunk
 	if !stopPlayer {
-		segmentNumber, mapSegmentLogPrintouts = streamLoop(streamStructs, Noden, accountant)
+		segmentNumber, mapSegmentLogPrintouts = streamLoop(streamStructs, tracer, Noden, accountant)
 	}
 
 	return segmentNumber, mapSegmentLogPrintouts
 
 }
+
+// throughputEstimator : the throughput estimator of the adaptation set, nil if we do not use an estimator
+func throughputEstimator(mimeTypeIndex int) estimators.Estimator {
+	if estimatorName == glob.EstimatorOff || estimatorName == "" {
+		return nil
+	}
+	if _, ok := throughputEstimators[mimeTypeIndex]; !ok {
+		throughputEstimators[mimeTypeIndex] = estimators.New(estimatorName, estimatorWindow, estimatorSeason)
+	}
+	return throughputEstimators[mimeTypeIndex]
+}
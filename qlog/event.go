@@ -1,6 +1,8 @@
 package qlog
 
 import (
+	"fmt"
+	"math"
 	"time"
 
 	"github.com/francoispqt/gojay"
@@ -150,6 +152,21 @@ func (e eventPlaybackPlayheadProgress) MarshalJSONObject(enc *gojay.Encoder) {
 	}
 }
 
+type eventPlaybackManifestLoaded struct {
+	manifest Manifest
+}
+
+func (e eventPlaybackManifestLoaded) Category() category { return categoryPlayback }
+func (e eventPlaybackManifestLoaded) Name() string       { return "manifest_loaded" }
+func (e eventPlaybackManifestLoaded) IsNil() bool        { return false }
+
+func (e eventPlaybackManifestLoaded) MarshalJSONObject(enc *gojay.Encoder) {
+	enc.StringKey("resource_url", e.manifest.URL)
+	enc.StringKeyOmitEmpty("profiles", e.manifest.Profiles)
+	enc.Int64Key("duration_ms", e.manifest.Duration.Milliseconds())
+	enc.IntKey("adaptation_sets", e.manifest.AdaptationSets)
+}
+
 // ABR
 
 type eventABRSwitch struct {
@@ -174,6 +191,25 @@ func (e eventABRSwitch) MarshalJSONObject(enc *gojay.Encoder) {
 	}
 }
 
+type eventABRStallPrediction struct {
+	prediction StallPrediction
+}
+
+func (e eventABRStallPrediction) Category() category { return categoryABR }
+func (e eventABRStallPrediction) Name() string       { return "stall_prediction" }
+func (e eventABRStallPrediction) IsNil() bool        { return false }
+
+func (e eventABRStallPrediction) MarshalJSONObject(enc *gojay.Encoder) {
+	enc.StringKey("model", e.prediction.Model)
+	enc.FloatKey("buffer_ms", milliseconds(e.prediction.BufferLevel))
+	enc.FloatKey("threshold_ms", milliseconds(e.prediction.Threshold))
+	enc.FloatKey("throughput_bps", e.prediction.Throughput)
+	enc.Int64Key("remaining_bits", e.prediction.RemainingBits)
+	enc.FloatKey("required_ms", milliseconds(e.prediction.RequiredTime))
+	enc.FloatKey("lowest_ms", milliseconds(e.prediction.LowestTime))
+	enc.BoolKey("stall", e.prediction.Stall)
+}
+
 type eventABRReadyStateChange struct {
 	state ReadyState
 }
@@ -186,6 +222,94 @@ func (e eventABRReadyStateChange) MarshalJSONObject(enc *gojay.Encoder) {
 	enc.StringKey("state", e.state.String())
 }
 
+type decisionDetails []DecisionDetail
+
+func (d decisionDetails) IsNil() bool { return false }
+func (d decisionDetails) MarshalJSONObject(enc *gojay.Encoder) {
+	for _, detail := range d {
+		switch v := detail.Value.(type) {
+		case int:
+			enc.IntKey(detail.Key, v)
+		case int64:
+			enc.Int64Key(detail.Key, v)
+		case float64:
+			// JSON has no infinity or NaN
+			if math.IsInf(v, 0) || math.IsNaN(v) {
+				enc.StringKey(detail.Key, fmt.Sprint(v))
+			} else {
+				enc.FloatKey(detail.Key, v)
+			}
+		case bool:
+			enc.BoolKey(detail.Key, v)
+		case string:
+			enc.StringKey(detail.Key, v)
+		default:
+			enc.StringKey(detail.Key, fmt.Sprint(v))
+		}
+	}
+}
+
+type eventABRDecision struct {
+	mediaType MediaType
+	decision  Decision
+}
+
+func (e eventABRDecision) Category() category { return categoryABR }
+func (e eventABRDecision) Name() string       { return "decision" }
+func (e eventABRDecision) IsNil() bool        { return false }
+
+func (e eventABRDecision) MarshalJSONObject(enc *gojay.Encoder) {
+	enc.StringKey("media_type", e.mediaType.String())
+	enc.StringKey("algorithm", e.decision.Algorithm)
+	enc.IntKey("segment_number", e.decision.Segment)
+	enc.FloatKey("throughput_bps", e.decision.Throughput)
+	if e.decision.Estimate > 0 {
+		enc.FloatKey("estimate_bps", e.decision.Estimate)
+	}
+	enc.FloatKey("buffer_ms", milliseconds(e.decision.BufferLevel))
+	enc.Int64Key("segment_bytes", e.decision.SegmentBytes)
+	enc.IntKey("last_index", e.decision.LastIndex)
+	enc.IntKey("index", e.decision.Index)
+	enc.Int64Key("bitrate", e.decision.Bitrate)
+	if len(e.decision.Details) > 0 {
+		enc.ObjectKey("details", decisionDetails(e.decision.Details))
+	}
+}
+
+type ladderRepresentations []LadderRepresentation
+
+func (l ladderRepresentations) IsNil() bool { return false }
+func (l ladderRepresentations) MarshalJSONArray(enc *gojay.Encoder) {
+	for _, r := range l {
+		enc.Object(ladderRepresentation(r))
+	}
+}
+
+type ladderRepresentation LadderRepresentation
+
+func (r ladderRepresentation) IsNil() bool { return false }
+func (r ladderRepresentation) MarshalJSONObject(enc *gojay.Encoder) {
+	enc.StringKey("id", r.ID)
+	enc.Int64Key("bitrate", r.Bitrate)
+	enc.IntKeyOmitEmpty("width", r.Width)
+	enc.IntKeyOmitEmpty("height", r.Height)
+	enc.StringKeyOmitEmpty("codecs", r.Codecs)
+}
+
+type eventABRLadder struct {
+	ladder Ladder
+}
+
+func (e eventABRLadder) Category() category { return categoryABR }
+func (e eventABRLadder) Name() string       { return "ladder" }
+func (e eventABRLadder) IsNil() bool        { return false }
+
+func (e eventABRLadder) MarshalJSONObject(enc *gojay.Encoder) {
+	enc.StringKey("media_type", e.ladder.MediaType.String())
+	enc.Int64Key("segment_duration_ms", e.ladder.SegmentDuration.Milliseconds())
+	enc.ArrayKey("representations", ladderRepresentations(e.ladder.Representations))
+}
+
 // Buffer
 
 type eventBufferOccupancyUpdated struct {
@@ -237,6 +361,8 @@ func (e eventNetworkRequest) MarshalJSONObject(enc *gojay.Encoder) {
 type eventNetworkRequestUpdate struct {
 	resource_url  string
 	bytesReceived int64
+	// only set on the last update of a request
+	timing *RequestTiming
 }
 
 func (e eventNetworkRequestUpdate) Category() category { return categoryNetwork }
@@ -246,6 +372,25 @@ func (e eventNetworkRequestUpdate) IsNil() bool        { return false }
 func (e eventNetworkRequestUpdate) MarshalJSONObject(enc *gojay.Encoder) {
 	enc.StringKey("resource_url", e.resource_url)
 	enc.Int64Key("bytes_received", e.bytesReceived)
+	if e.timing == nil {
+		return
+	}
+	if e.timing.DNS >= 0 {
+		enc.FloatKey("dns_ms", milliseconds(e.timing.DNS))
+	}
+	if e.timing.Connect >= 0 {
+		enc.FloatKey("connect_ms", milliseconds(e.timing.Connect))
+	}
+	if e.timing.TLS >= 0 {
+		enc.FloatKey("tls_ms", milliseconds(e.timing.TLS))
+	}
+	if e.timing.TTFB >= 0 {
+		enc.FloatKey("ttfb_ms", milliseconds(e.timing.TTFB))
+	}
+	if e.timing.TTLB >= 0 {
+		enc.FloatKey("ttlb_ms", milliseconds(e.timing.TTLB))
+	}
+	enc.BoolKey("connection_reused", e.timing.Reused)
 }
 
 type eventNetworkAbort struct {
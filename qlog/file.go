@@ -0,0 +1,155 @@
+package qlog
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultPattern is the name of the qlog file of a run, without its extension
+const DefaultPattern = "{perspective}_abr_{run}_{algorithm}_{mpd}"
+
+// Config is the output of a file tracer
+type Config struct {
+	// the folder of the qlog file, it is created if it does not exist
+	Dir string
+	// the name of the file, without its extension - {perspective}, {run}, {algorithm} and {mpd} are
+	// replaced by the perspective, the run ID, the adaptation algorithm and the name of the first MPD
+	Pattern     string
+	Perspective Perspective
+	RunID       StreamID
+	Algorithm   string
+	MPD         string
+	Format      Format
+	// compress the file with gzip, ".gz" is added to its extension
+	Gzip bool
+	// only the events of these categories are recorded, every event if it is empty
+	Categories []string
+}
+
+// Filename returns the path of the qlog file of the config
+func (c Config) Filename() string {
+	pattern := c.Pattern
+	if pattern == "" {
+		pattern = DefaultPattern
+	}
+	// the name of the MPD, without its query and extension
+	mpd := path.Base(strings.Split(c.MPD, "?")[0])
+	mpd = strings.TrimSuffix(mpd, path.Ext(mpd))
+	name := strings.NewReplacer(
+		"{perspective}", filenamePart(strings.ToLower(c.Perspective.String())),
+		"{run}", filenamePart(string(c.RunID)),
+		"{algorithm}", filenamePart(c.Algorithm),
+		"{mpd}", filenamePart(mpd),
+	).Replace(pattern)
+	name += c.Format.Extension()
+	if c.Gzip {
+		name += ".gz"
+	}
+	return filepath.Join(c.Dir, name)
+}
+
+// filenamePart keeps the letters, digits, '-', '_' and '.' of a value of the pattern
+func filenamePart(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		default:
+			return '-'
+		}
+	}, s)
+}
+
+// NewFileTracer creates the tracer of a run, which writes its qlog to the file of the config.
+func NewFileTracer(config Config) (*StreamTracer, error) {
+	categories, err := parseCategories(config.Categories)
+	if err != nil {
+		return nil, err
+	}
+	if config.Dir != "" {
+		if err := os.MkdirAll(config.Dir, os.ModePerm); err != nil {
+			return nil, err
+		}
+	}
+	filename := config.Filename()
+	f, err := os.Create(filename)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Creating ABR qlog file %s.\n", filename)
+
+	w := &fileWriter{f: f}
+	if config.Gzip {
+		w.gz = gzip.NewWriter(f)
+		w.Writer = bufio.NewWriter(w.gz)
+	} else {
+		w.Writer = bufio.NewWriter(f)
+	}
+	t := NewStreamTracer(w, config.Perspective, config.RunID)
+	t.SetFormat(config.Format)
+	t.categories = categories
+	return t, nil
+}
+
+// parseCategories returns the set of the named categories, or nil for every category
+func parseCategories(names []string) (map[category]bool, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	categories := make(map[category]bool)
+	for _, name := range names {
+		c, ok := categoryNames[strings.TrimSpace(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown qlog category %q, the categories are %v", name, Categories)
+		}
+		categories[c] = true
+	}
+	return categories, nil
+}
+
+// Categories are the names of the categories of the qlog events
+var Categories = []string{categoryPlayback.String(), categoryABR.String(), categoryBuffer.String(), categoryNetwork.String(), categoryGeneric.String()}
+
+var categoryNames = map[string]category{
+	categoryPlayback.String(): categoryPlayback,
+	categoryABR.String():      categoryABR,
+	categoryBuffer.String():   categoryBuffer,
+	categoryNetwork.String():  categoryNetwork,
+	categoryGeneric.String():  categoryGeneric,
+}
+
+// fileWriter writes a qlog file through a buffer, and through gzip if the file is compressed
+type fileWriter struct {
+	*bufio.Writer
+	gz *gzip.Writer
+	f  *os.File
+}
+
+// Flush writes the buffered records to the file, the streamed formats flush each record
+func (w *fileWriter) Flush() error {
+	if err := w.Writer.Flush(); err != nil {
+		return err
+	}
+	if w.gz != nil {
+		return w.gz.Flush()
+	}
+	return nil
+}
+
+func (w *fileWriter) Close() error {
+	if err := w.Writer.Flush(); err != nil {
+		return err
+	}
+	if w.gz != nil {
+		if err := w.gz.Close(); err != nil {
+			return err
+		}
+	}
+	return w.f.Close()
+}
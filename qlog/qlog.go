@@ -74,6 +74,7 @@ type streamTracer interface {
 
 	// ABR
 	Switch(mediaType MediaType, from, to representation)
+	StallPrediction(prediction StallPrediction)
 	ChangeReadyState(state ReadyState)
 
 	// Buffer
@@ -96,6 +97,10 @@ type StreamTracer struct {
 	events     chan event
 	encodeErr  error
 	runStopped chan struct{}
+	format     Format
+	// the categories of the recorded events, every category if it is nil
+	categories map[category]bool
+	sinks      []func(record []byte)
 
 	RTT         *RTTStats
 	lastMetrics *metrics
@@ -117,65 +122,134 @@ func NewStreamTracer(w io.WriteCloser, p Perspective, sid StreamID) *StreamTrace
 	return t
 }
 
+// AddSink passes each recorded event to sink, as a JSON object, once it is written to the qlog.
+// It must be called before the first event is recorded. The sink is called from the goroutine that
+// writes the qlog, the record is only valid during the call.
+func (t *StreamTracer) AddSink(sink func(record []byte)) {
+	t.sinks = append(t.sinks, sink)
+}
+
+// SetFormat sets the serialization of the qlog, it must be called before the first event is recorded
+func (t *StreamTracer) SetFormat(format Format) {
+	t.format = format
+}
+
 func (t *StreamTracer) run() {
 	defer close(t.runStopped)
-	buf := &bytes.Buffer{}
-	enc := gojay.NewEncoder(buf)
-	tl := &topLevel{
-		traces: []trace{
-			{
-				Title:        "MPEG-DASH goDash",
-				Description:  "MPEG-DASH goDash [" + time.Now().String() + "]",
-				VantagePoint: vantagePoint{Type: t.perspective, Name: "goDash application layer"},
-				CommonFields: commonFields{
-					ProtocolType:  "QLOG_ABR",
-					ReferenceTime: t.referenceTime,
-				},
-			},
-		},
-	}
-	if err := enc.Encode(tl); err != nil {
-		panic(fmt.Sprintf("qlog encoding into a bytes.Buffer failed: %s", err))
-	}
-	buf.Truncate(buf.Len() - 3)
-	if err := buf.WriteByte('\n'); err != nil {
-		panic(fmt.Sprintf("qlog encoding into a bytes.Buffer failed: %s", err))
-	}
-	if _, err := t.w.Write(buf.Bytes()); err != nil {
-		t.encodeErr = err
-	}
-
-	if _, err := t.w.Write([]byte(",\"events\": [\n")); err != nil {
-		panic(fmt.Sprintf("qlog encoding events key failed: %s", err))
-	}
 
-	firsteventwritten := false
+	// the header is written with the first event, so a tracer that records nothing writes nothing
+	headerWritten := false
 
-	enc = gojay.NewEncoder(t.w)
+	// each event is encoded on its own, to be written to the qlog and passed to the sinks
+	record := &bytes.Buffer{}
+	enc := gojay.NewEncoder(record)
 	for ev := range t.events {
 		if t.encodeErr != nil { // if encoding failed, just continue draining the event channel
 			continue
 		}
-		if firsteventwritten {
+		if !headerWritten {
+			t.writeHeader()
+			headerWritten = true
+		} else if t.format == FormatJSON {
 			if _, err := t.w.Write([]byte(",")); err != nil {
 				t.encodeErr = err
 			}
-		} else {
-			firsteventwritten = true
 		}
+		if t.format == FormatJSONSeq {
+			if _, err := t.w.Write([]byte{recordSeparator}); err != nil {
+				t.encodeErr = err
+			}
+		}
+		record.Reset()
 		if err := enc.Encode(ev); err != nil {
 			t.encodeErr = err
 			continue
 		}
+		if _, err := t.w.Write(record.Bytes()); err != nil {
+			t.encodeErr = err
+		}
 		if _, err := t.w.Write([]byte("\n")); err != nil {
 			t.encodeErr = err
 		}
+		for _, sink := range t.sinks {
+			sink(record.Bytes())
+		}
+		// a streamed record is complete once written, so a run that is stopped leaves a valid file
+		if t.format != FormatJSON {
+			t.flush()
+		}
+	}
+	if !headerWritten {
+		t.writeHeader()
+	}
+	if t.format != FormatJSON {
+		return
 	}
 	if _, err := t.w.Write([]byte("]}]}\n")); err != nil {
 		panic(fmt.Sprintf("qlog encoding close events key failed: %s", err))
 	}
 }
 
+// recordSeparator starts each record of a JSON Text Sequence
+const recordSeparator = 0x1e
+
+// flush writes the buffered records to the file, if the writer is buffered
+func (t *StreamTracer) flush() {
+	if f, ok := t.w.(interface{ Flush() error }); ok {
+		if err := f.Flush(); err != nil {
+			t.encodeErr = err
+		}
+	}
+}
+
+// writeHeader writes the fields of the qlog and of its trace. The JSON format then opens the
+// events array, the streamed formats write them as the first record.
+func (t *StreamTracer) writeHeader() {
+	tr := trace{
+		Title:        "MPEG-DASH goDash",
+		Description:  "MPEG-DASH goDash [" + time.Now().String() + "]",
+		VantagePoint: vantagePoint{Type: t.perspective, Name: "goDash application layer"},
+		CommonFields: commonFields{
+			ProtocolType:  "QLOG_ABR",
+			ReferenceTime: t.referenceTime,
+			GroupID:       t.sid,
+		},
+	}
+
+	buf := &bytes.Buffer{}
+	enc := gojay.NewEncoder(buf)
+	if t.format != FormatJSON {
+		if t.format == FormatJSONSeq {
+			buf.WriteByte(recordSeparator)
+		}
+		if err := enc.Encode(&topLevelSeq{format: t.format, trace: tr}); err != nil {
+			panic(fmt.Sprintf("qlog encoding into a bytes.Buffer failed: %s", err))
+		}
+		buf.WriteByte('\n')
+		if _, err := t.w.Write(buf.Bytes()); err != nil {
+			t.encodeErr = err
+		}
+		t.flush()
+		return
+	}
+
+	tl := &topLevel{traces: []trace{tr}}
+	if err := enc.Encode(tl); err != nil {
+		panic(fmt.Sprintf("qlog encoding into a bytes.Buffer failed: %s", err))
+	}
+	buf.Truncate(buf.Len() - 3)
+	if err := buf.WriteByte('\n'); err != nil {
+		panic(fmt.Sprintf("qlog encoding into a bytes.Buffer failed: %s", err))
+	}
+	if _, err := t.w.Write(buf.Bytes()); err != nil {
+		t.encodeErr = err
+	}
+
+	if _, err := t.w.Write([]byte(",\"events\": [\n")); err != nil {
+		panic(fmt.Sprintf("qlog encoding events key failed: %s", err))
+	}
+}
+
 func (t *StreamTracer) Close() {
 	if err := t.export(); err != nil {
 		log.Printf("exporting qlog failed: %s\n", err)
@@ -193,6 +267,9 @@ func (t *StreamTracer) export() error {
 }
 
 func (t *StreamTracer) recordEvent(eventTime time.Time, details eventDetails) {
+	if t.categories != nil && !t.categories[details.Category()] {
+		return
+	}
 	t.events <- event{
 		RelativeTime: eventTime.Sub(t.referenceTime),
 		eventDetails: details,
@@ -256,6 +333,12 @@ func (t *StreamTracer) PlayheadProgress(playhead playheadStatus) {
 	t.mutex.Unlock()
 }
 
+func (t *StreamTracer) ManifestLoaded(manifest Manifest) {
+	t.mutex.Lock()
+	t.recordEvent(time.Now(), &eventPlaybackManifestLoaded{manifest: manifest})
+	t.mutex.Unlock()
+}
+
 // ABR
 
 func (t *StreamTracer) Switch(mediaType MediaType, from, to representation) {
@@ -264,12 +347,30 @@ func (t *StreamTracer) Switch(mediaType MediaType, from, to representation) {
 	t.mutex.Unlock()
 }
 
+func (t *StreamTracer) StallPrediction(prediction StallPrediction) {
+	t.mutex.Lock()
+	t.recordEvent(time.Now(), &eventABRStallPrediction{prediction: prediction})
+	t.mutex.Unlock()
+}
+
 func (t *StreamTracer) ChangeReadyState(state ReadyState) {
 	t.mutex.Lock()
 	t.recordEvent(time.Now(), &eventABRReadyStateChange{state: state})
 	t.mutex.Unlock()
 }
 
+func (t *StreamTracer) Decision(mediaType MediaType, decision Decision) {
+	t.mutex.Lock()
+	t.recordEvent(time.Now(), &eventABRDecision{mediaType: mediaType, decision: decision})
+	t.mutex.Unlock()
+}
+
+func (t *StreamTracer) Ladder(ladder Ladder) {
+	t.mutex.Lock()
+	t.recordEvent(time.Now(), &eventABRLadder{ladder: ladder})
+	t.mutex.Unlock()
+}
+
 // Buffer
 
 func (t *StreamTracer) UpdateBufferOccupancy(mediaType MediaType, bufferStats bufferStats) {
@@ -292,6 +393,12 @@ func (t *StreamTracer) RequestUpdate(resourceURL string, bytesReceived int64) {
 	t.mutex.Unlock()
 }
 
+func (t *StreamTracer) RequestComplete(resourceURL string, bytesReceived int64, timing RequestTiming) {
+	t.mutex.Lock()
+	t.recordEvent(time.Now(), &eventNetworkRequestUpdate{resource_url: resourceURL, bytesReceived: bytesReceived, timing: &timing})
+	t.mutex.Unlock()
+}
+
 func (t *StreamTracer) AbortRequest(resourceURL string) {
 	t.mutex.Lock()
 	t.recordEvent(time.Now(), &eventNetworkAbort{resource_url: resourceURL})
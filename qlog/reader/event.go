@@ -0,0 +1,372 @@
+package reader
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Playback
+
+// StreamInitialised is the playback:stream_initialised event
+type StreamInitialised struct {
+	Autoplay bool
+}
+
+// PlayerInteraction is the playback:player_interaction event
+type PlayerInteraction struct {
+	State    string
+	Playhead time.Duration
+	Speed    float64
+}
+
+// Playhead is the playback:rebuffer, playback:stream_end and playback:playhead_progress events
+type Playhead struct {
+	Playhead time.Duration
+	// -1 if not logged
+	Frame int64
+}
+
+// ManifestLoaded is the playback:manifest_loaded event
+type ManifestLoaded struct {
+	URL            string
+	Profiles       string
+	Duration       time.Duration
+	AdaptationSets int
+}
+
+// ABR
+
+// Switch is the abr:switch event, the bitrates are in kbps and -1 if not logged
+type Switch struct {
+	MediaType   string
+	FromID      string
+	FromBitrate int64
+	ToID        string
+	ToBitrate   int64
+}
+
+// StallPrediction is the abr:stall_prediction event
+type StallPrediction struct {
+	Model         string
+	BufferLevel   time.Duration
+	Threshold     time.Duration
+	Throughput    float64
+	RemainingBits int64
+	RequiredTime  time.Duration
+	LowestTime    time.Duration
+	Stall         bool
+}
+
+// ReadyStateChange is the abr:readystate_change event
+type ReadyStateChange struct {
+	State string
+}
+
+// Decision is the abr:decision event, Estimate is 0 without a throughput estimator, and
+// Details holds the inputs and intermediate values the algorithm added
+type Decision struct {
+	MediaType    string
+	Algorithm    string
+	Segment      int
+	Throughput   float64
+	Estimate     float64
+	BufferLevel  time.Duration
+	SegmentBytes int64
+	LastIndex    int
+	Index        int
+	Bitrate      int64
+	Details      map[string]interface{}
+}
+
+// Ladder is the abr:ladder event, the representations the adaptation algorithm chooses from
+type Ladder struct {
+	MediaType       string
+	SegmentDuration time.Duration
+	Representations []LadderRepresentation
+}
+
+// LadderRepresentation is a representation of a ladder, the bitrate is in kbps as in the switches
+type LadderRepresentation struct {
+	ID      string `json:"id"`
+	Bitrate int64  `json:"bitrate"`
+	Width   int    `json:"width"`
+	Height  int    `json:"height"`
+	Codecs  string `json:"codecs"`
+}
+
+// Buffer
+
+// BufferOccupancy is the buffer:occupancy_update event, the bytes are -1 if not logged
+type BufferOccupancy struct {
+	MediaType    string
+	Playout      time.Duration
+	PlayoutBytes int64
+	Max          time.Duration
+	MaxBytes     int64
+}
+
+// Network
+
+// Request is the network:request event
+type Request struct {
+	MediaType string
+	URL       string
+	Range     string
+}
+
+// RequestTiming is the phases of a request, -1 if not measured
+type RequestTiming struct {
+	DNS     time.Duration
+	Connect time.Duration
+	TLS     time.Duration
+	TTFB    time.Duration
+	TTLB    time.Duration
+	Reused  bool
+}
+
+// RequestUpdate is the network:request_update event, Timing is only set on the last update of a request
+type RequestUpdate struct {
+	URL           string
+	BytesReceived int64
+	Timing        *RequestTiming
+}
+
+// Abort is the network:abort event
+type Abort struct {
+	URL string
+}
+
+// Generic
+
+// Generic is an event with a message, written by StreamTracer.Debug
+type Generic struct {
+	Details string
+}
+
+// Metrics is the generic:metrics_updated event of the StreamTracer, and the
+// recovery:metrics_updated event of the transport. Only the metrics that changed
+// are logged, the others are 0.
+type Metrics struct {
+	MinRTT           time.Duration
+	SmoothedRTT      time.Duration
+	LatestRTT        time.Duration
+	RTTVariance      time.Duration
+	CongestionWindow int64
+	BytesInFlight    int64
+}
+
+// Transport
+
+// Packet is the transport:packet_received, transport:packet_sent and recovery:packet_lost events
+type Packet struct {
+	Type   string
+	Number int64
+	// the size of the packet in bytes, 0 if not logged
+	Length int
+}
+
+var decoders = map[string]func(json.RawMessage) (interface{}, error){
+	"playback:stream_initialised": func(data json.RawMessage) (interface{}, error) {
+		var d struct {
+			Autoplay bool `json:"autoplay"`
+		}
+		err := json.Unmarshal(data, &d)
+		return StreamInitialised{Autoplay: d.Autoplay}, err
+	},
+	"playback:player_interaction": func(data json.RawMessage) (interface{}, error) {
+		var d struct {
+			State      string  `json:"state"`
+			PlayheadMs float64 `json:"playhead_ms"`
+			Speed      float64 `json:"speed"`
+		}
+		err := json.Unmarshal(data, &d)
+		return PlayerInteraction{State: d.State, Playhead: milliseconds(d.PlayheadMs), Speed: d.Speed}, err
+	},
+	"playback:manifest_loaded": func(data json.RawMessage) (interface{}, error) {
+		var d struct {
+			URL            string  `json:"resource_url"`
+			Profiles       string  `json:"profiles"`
+			DurationMs     float64 `json:"duration_ms"`
+			AdaptationSets int     `json:"adaptation_sets"`
+		}
+		err := json.Unmarshal(data, &d)
+		return ManifestLoaded{URL: d.URL, Profiles: d.Profiles, Duration: milliseconds(d.DurationMs), AdaptationSets: d.AdaptationSets}, err
+	},
+	"playback:rebuffer":          decodePlayhead,
+	"playback:stream_end":        decodePlayhead,
+	"playback:playhead_progress": decodePlayhead,
+	"abr:switch": func(data json.RawMessage) (interface{}, error) {
+		d := struct {
+			MediaType   string `json:"media_type"`
+			FromID      string `json:"from_id"`
+			FromBitrate int64  `json:"from_bitrate"`
+			ToID        string `json:"to_id"`
+			ToBitrate   int64  `json:"to_bitrate"`
+		}{FromBitrate: -1, ToBitrate: -1}
+		err := json.Unmarshal(data, &d)
+		return Switch(d), err
+	},
+	"abr:stall_prediction": func(data json.RawMessage) (interface{}, error) {
+		var d struct {
+			Model         string  `json:"model"`
+			BufferMs      float64 `json:"buffer_ms"`
+			ThresholdMs   float64 `json:"threshold_ms"`
+			Throughput    float64 `json:"throughput_bps"`
+			RemainingBits int64   `json:"remaining_bits"`
+			RequiredMs    float64 `json:"required_ms"`
+			LowestMs      float64 `json:"lowest_ms"`
+			Stall         bool    `json:"stall"`
+		}
+		err := json.Unmarshal(data, &d)
+		return StallPrediction{Model: d.Model, BufferLevel: milliseconds(d.BufferMs), Threshold: milliseconds(d.ThresholdMs),
+			Throughput: d.Throughput, RemainingBits: d.RemainingBits, RequiredTime: milliseconds(d.RequiredMs),
+			LowestTime: milliseconds(d.LowestMs), Stall: d.Stall}, err
+	},
+	"abr:readystate_change": func(data json.RawMessage) (interface{}, error) {
+		var d struct {
+			State string `json:"state"`
+		}
+		err := json.Unmarshal(data, &d)
+		return ReadyStateChange{State: d.State}, err
+	},
+	"abr:decision": func(data json.RawMessage) (interface{}, error) {
+		var d struct {
+			MediaType    string                 `json:"media_type"`
+			Algorithm    string                 `json:"algorithm"`
+			Segment      int                    `json:"segment_number"`
+			Throughput   float64                `json:"throughput_bps"`
+			Estimate     float64                `json:"estimate_bps"`
+			BufferMs     float64                `json:"buffer_ms"`
+			SegmentBytes int64                  `json:"segment_bytes"`
+			LastIndex    int                    `json:"last_index"`
+			Index        int                    `json:"index"`
+			Bitrate      int64                  `json:"bitrate"`
+			Details      map[string]interface{} `json:"details"`
+		}
+		err := json.Unmarshal(data, &d)
+		return Decision{MediaType: d.MediaType, Algorithm: d.Algorithm, Segment: d.Segment, Throughput: d.Throughput, Estimate: d.Estimate,
+			BufferLevel: milliseconds(d.BufferMs), SegmentBytes: d.SegmentBytes, LastIndex: d.LastIndex, Index: d.Index, Bitrate: d.Bitrate,
+			Details: d.Details}, err
+	},
+	"abr:ladder": func(data json.RawMessage) (interface{}, error) {
+		var d struct {
+			MediaType         string                 `json:"media_type"`
+			SegmentDurationMs float64                `json:"segment_duration_ms"`
+			Representations   []LadderRepresentation `json:"representations"`
+		}
+		err := json.Unmarshal(data, &d)
+		return Ladder{MediaType: d.MediaType, SegmentDuration: milliseconds(d.SegmentDurationMs), Representations: d.Representations}, err
+	},
+	"buffer:occupancy_update": func(data json.RawMessage) (interface{}, error) {
+		d := struct {
+			MediaType    string  `json:"media_type"`
+			PlayoutMs    float64 `json:"playout_ms"`
+			PlayoutBytes int64   `json:"playout_bytes"`
+			MaxMs        float64 `json:"max_ms"`
+			MaxBytes     int64   `json:"max_bytes"`
+		}{PlayoutBytes: -1, MaxBytes: -1}
+		err := json.Unmarshal(data, &d)
+		return BufferOccupancy{MediaType: d.MediaType, Playout: milliseconds(d.PlayoutMs), PlayoutBytes: d.PlayoutBytes,
+			Max: milliseconds(d.MaxMs), MaxBytes: d.MaxBytes}, err
+	},
+	"network:request": func(data json.RawMessage) (interface{}, error) {
+		var d struct {
+			MediaType string `json:"media_type"`
+			URL       string `json:"resource_url"`
+			Range     string `json:"range"`
+		}
+		err := json.Unmarshal(data, &d)
+		return Request(d), err
+	},
+	"network:request_update": func(data json.RawMessage) (interface{}, error) {
+		d := struct {
+			URL           string   `json:"resource_url"`
+			BytesReceived int64    `json:"bytes_received"`
+			DNSMs         *float64 `json:"dns_ms"`
+			ConnectMs     *float64 `json:"connect_ms"`
+			TLSMs         *float64 `json:"tls_ms"`
+			TTFBMs        *float64 `json:"ttfb_ms"`
+			TTLBMs        *float64 `json:"ttlb_ms"`
+			Reused        *bool    `json:"connection_reused"`
+		}{}
+		err := json.Unmarshal(data, &d)
+		update := RequestUpdate{URL: d.URL, BytesReceived: d.BytesReceived}
+		// the timing is only logged on the last update, which always has connection_reused
+		if d.Reused != nil {
+			update.Timing = &RequestTiming{DNS: optional(d.DNSMs), Connect: optional(d.ConnectMs), TLS: optional(d.TLSMs),
+				TTFB: optional(d.TTFBMs), TTLB: optional(d.TTLBMs), Reused: *d.Reused}
+		}
+		return update, err
+	},
+	"network:abort": func(data json.RawMessage) (interface{}, error) {
+		var d struct {
+			URL string `json:"resource_url"`
+		}
+		err := json.Unmarshal(data, &d)
+		return Abort{URL: d.URL}, err
+	},
+	"generic:metrics_updated":   decodeMetrics,
+	"recovery:metrics_updated":  decodeMetrics,
+	"transport:packet_received": decodePacket,
+	"transport:packet_sent":     decodePacket,
+	"recovery:packet_lost":      decodePacket,
+}
+
+func decodePlayhead(data json.RawMessage) (interface{}, error) {
+	d := struct {
+		PlayheadMs float64 `json:"playhead_ms"`
+		Frame      int64   `json:"playhead_frame"`
+	}{Frame: -1}
+	err := json.Unmarshal(data, &d)
+	return Playhead{Playhead: milliseconds(d.PlayheadMs), Frame: d.Frame}, err
+}
+
+func decodeGeneric(data json.RawMessage) (interface{}, error) {
+	var d struct {
+		Details string `json:"details"`
+	}
+	err := json.Unmarshal(data, &d)
+	return Generic{Details: d.Details}, err
+}
+
+func decodeMetrics(data json.RawMessage) (interface{}, error) {
+	var d struct {
+		MinRTT           float64 `json:"min_rtt"`
+		SmoothedRTT      float64 `json:"smoothed_rtt"`
+		LatestRTT        float64 `json:"latest_rtt"`
+		RTTVariance      float64 `json:"rtt_variance"`
+		CongestionWindow int64   `json:"congestion_window"`
+		BytesInFlight    int64   `json:"bytes_in_flight"`
+	}
+	err := json.Unmarshal(data, &d)
+	return Metrics{MinRTT: milliseconds(d.MinRTT), SmoothedRTT: milliseconds(d.SmoothedRTT), LatestRTT: milliseconds(d.LatestRTT),
+		RTTVariance: milliseconds(d.RTTVariance), CongestionWindow: d.CongestionWindow, BytesInFlight: d.BytesInFlight}, err
+}
+
+// decodePacket reads the length of a packet from raw.length, or from header.packet_size
+// in the qlogs of older quic-go versions
+func decodePacket(data json.RawMessage) (interface{}, error) {
+	var d struct {
+		Header struct {
+			PacketType   string `json:"packet_type"`
+			PacketNumber int64  `json:"packet_number"`
+			PacketSize   int    `json:"packet_size"`
+		} `json:"header"`
+		Raw struct {
+			Length int `json:"length"`
+		} `json:"raw"`
+	}
+	err := json.Unmarshal(data, &d)
+	p := Packet{Type: d.Header.PacketType, Number: d.Header.PacketNumber, Length: d.Raw.Length}
+	if p.Length == 0 {
+		p.Length = d.Header.PacketSize
+	}
+	return p, err
+}
+
+func optional(ms *float64) time.Duration {
+	if ms == nil {
+		return -1
+	}
+	return milliseconds(*ms)
+}
@@ -0,0 +1,158 @@
+package reader
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MergedTitle is the title of the qlog written by Merge
+const MergedTitle = "goDASH merged qlog"
+
+// ABRGroup is the group_id of the events of the qlog-abr log in a merged qlog
+const ABRGroup = "abr"
+
+// IsMerged reports whether the log was written by Merge
+func (l *Log) IsMerged() bool {
+	return l.Title == MergedTitle
+}
+
+// Session returns the logs of the run of the latest qlog-abr log: the qlog-abr log, and the transport
+// logs of the connections opened while it was recording. Each connection has its own qlog file, so
+// the logs folder also keeps the transport logs of earlier runs. Without a qlog-abr log, every log is
+// returned. Merged logs are never returned.
+func Session(logs []*Log) []*Log {
+	var abr *Log
+	for _, l := range ABR(logs) {
+		if abr == nil || l.ReferenceTime.After(abr.ReferenceTime) {
+			abr = l
+		}
+	}
+	var session []*Log
+	if abr == nil {
+		for _, l := range logs {
+			if !l.IsMerged() {
+				session = append(session, l)
+			}
+		}
+		return session
+	}
+	end := abr.ReferenceTime
+	if len(abr.Events) > 0 {
+		end = end.Add(abr.Events[len(abr.Events)-1].Time)
+	}
+	session = append(session, abr)
+	for _, l := range Transport(logs) {
+		if !l.ReferenceTime.Before(abr.ReferenceTime) && !l.ReferenceTime.After(end) {
+			session = append(session, l)
+		}
+	}
+	return session
+}
+
+type mergedEvent struct {
+	Time    float64         `json:"time"`
+	Name    string          `json:"name"`
+	GroupID string          `json:"group_id"`
+	Data    json.RawMessage `json:"data"`
+}
+
+type mergedTrace struct {
+	Title        string `json:"title"`
+	Description  string `json:"description"`
+	VantagePoint struct {
+		Name string `json:"name"`
+		Type string `json:"type"`
+	} `json:"vantage_point"`
+	CommonFields struct {
+		ReferenceTime float64 `json:"reference_time"`
+		TimeFormat    string  `json:"time_format"`
+	} `json:"common_fields"`
+}
+
+type mergedHeader struct {
+	Format  string        `json:"qlog_format"`
+	Version string        `json:"qlog_version"`
+	Title   string        `json:"title"`
+	Traces  []mergedTrace `json:"traces"`
+}
+
+// Merge writes the events of the logs of a run as a single trace, in the order they happened, with
+// the reference time of Start. The group_id of each event is ABRGroup for the events of the qlog-abr
+// log, and the name of the file of the connection for the transport events. The file has the layout
+// of the files of the StreamTracer, one event per line.
+func Merge(w io.Writer, logs []*Log) error {
+	start := Start(logs)
+
+	type timedEvent struct {
+		time  time.Duration
+		event mergedEvent
+	}
+	var events []timedEvent
+	for _, l := range logs {
+		group := ABRGroup
+		if !l.IsABR() {
+			group = strings.TrimSuffix(filepath.Base(l.Path), filepath.Ext(l.Path))
+		}
+		offset := l.ReferenceTime.Sub(start)
+		for _, e := range l.Events {
+			name := e.Name
+			if e.Category != "" {
+				name = e.Category + ":" + e.Name
+			}
+			data := e.Data
+			if len(data) == 0 {
+				data = json.RawMessage("{}")
+			}
+			t := offset + e.Time
+			events = append(events, timedEvent{t, mergedEvent{Time: float64(t) / float64(time.Millisecond), Name: name, GroupID: group, Data: data}})
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].time < events[j].time })
+
+	trace := mergedTrace{Title: "MPEG-DASH goDash", Description: "the application and transport events of a goDash run"}
+	trace.VantagePoint.Name = "goDash application and transport layers"
+	trace.VantagePoint.Type = "client"
+	trace.CommonFields.ReferenceTime = float64(start.UnixNano()) / 1e6
+	trace.CommonFields.TimeFormat = "relative"
+	header, err := json.Marshal(mergedHeader{Format: "JSON", Version: "draft-02", Title: MergedTitle, Traces: []mergedTrace{trace}})
+	if err != nil {
+		return err
+	}
+
+	// the header without the closing of the trace, the traces and the top level
+	if _, err := w.Write(append(header[:len(header)-3], "\n,\"events\": [\n"...)); err != nil {
+		return err
+	}
+	for i, e := range events {
+		line, err := json.Marshal(e.event)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			line = append([]byte(","), line...)
+		}
+		if _, err := w.Write(append(line, '\n')); err != nil {
+			return err
+		}
+	}
+	_, err = w.Write([]byte("]}]}\n"))
+	return err
+}
+
+// MergeFile writes the merged qlog of the logs to a file
+func MergeFile(path string, logs []*Log) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := Merge(f, logs); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
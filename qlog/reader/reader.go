@@ -0,0 +1,292 @@
+// Package reader loads the qlog files of a run: the qlog-abr files of the StreamTracer
+// and the transport qlogs that quic-go writes for each connection.
+package reader
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ABRProtocolType is the protocol_type of the qlog-abr files written by the StreamTracer
+const ABRProtocolType = "QLOG_ABR"
+
+// Log is a qlog file, with the events of its first trace
+type Log struct {
+	Path    string
+	Version string
+	Title   string
+
+	TraceTitle   string
+	VantagePoint string
+	ProtocolType string
+	// the run ID of a qlog-abr file
+	GroupID string
+	// the events are at their time from the reference time
+	ReferenceTime time.Time
+	Events        []Event
+
+	// the events array was not closed, the client was stopped during the run
+	Truncated bool
+}
+
+// IsABR reports whether the log was written by the StreamTracer, rather than by the transport
+func (l *Log) IsABR() bool {
+	return !l.IsMerged() && (l.ProtocolType == ABRProtocolType || l.Title == "qlog-abr")
+}
+
+// Event is a qlog event, with its data decoded into one of the event types of this package
+type Event struct {
+	Time     time.Duration
+	Category string
+	Name     string
+	// nil for the events the reader does not know, their data is still in Data
+	Details interface{}
+	Data    json.RawMessage
+}
+
+type traceHeader struct {
+	Title        string `json:"title"`
+	VantagePoint struct {
+		Type string `json:"type"`
+	} `json:"vantage_point"`
+	CommonFields struct {
+		ProtocolType  string  `json:"protocol_type"`
+		ReferenceTime float64 `json:"reference_time"`
+		GroupID       string  `json:"group_id"`
+	} `json:"common_fields"`
+}
+
+type header struct {
+	Format  string        `json:"qlog_format"`
+	Version string        `json:"qlog_version"`
+	Title   string        `json:"title"`
+	Traces  []traceHeader `json:"traces"`
+	// the single trace of a streamed qlog
+	Trace *traceHeader `json:"trace"`
+}
+
+func newLog(h header) *Log {
+	l := &Log{Version: h.Version, Title: h.Title}
+	trace := h.Trace
+	if trace == nil && len(h.Traces) > 0 {
+		trace = &h.Traces[0]
+	}
+	if trace != nil {
+		l.TraceTitle = trace.Title
+		l.VantagePoint = trace.VantagePoint.Type
+		l.ProtocolType = trace.CommonFields.ProtocolType
+		l.GroupID = trace.CommonFields.GroupID
+		l.ReferenceTime = time.Unix(0, int64(trace.CommonFields.ReferenceTime*1e6))
+	}
+	return l
+}
+
+type rawEvent struct {
+	Time float64         `json:"time"`
+	Name string          `json:"name"`
+	Data json.RawMessage `json:"data"`
+}
+
+// ReadFile reads a qlog file, gzip compressed if its name ends in ".gz". The events are read one
+// at a time, so the events of a file that was not closed are read up to the last complete event.
+func ReadFile(path string) (*Log, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasSuffix(path, GzipExtension) {
+		if data, err = gunzip(data); err != nil {
+			return nil, fmt.Errorf("%s: %s", path, err)
+		}
+	}
+	l, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	l.Path = path
+	return l, nil
+}
+
+// Parse reads the qlog of a file already in memory, in the JSON format, or in one of the streamed
+// formats: JSON Text Sequences (.sqlog) or NDJSON, where the first record is the header.
+func Parse(data []byte) (*Log, error) {
+	data = bytes.TrimLeft(data, " \t\r\n")
+	if len(data) > 0 && data[0] == recordSeparator {
+		return parseRecords(bytes.Split(data, []byte{recordSeparator})[1:])
+	}
+	if first := bytes.IndexByte(data, '\n'); first >= 0 {
+		var h header
+		if json.Unmarshal(data[:first], &h) == nil && h.Trace != nil {
+			return parseRecords(bytes.Split(data, []byte("\n")))
+		}
+	}
+
+	key := bytes.Index(data, []byte(`"events"`))
+	if key < 0 {
+		return nil, fmt.Errorf("no events")
+	}
+	open := bytes.IndexByte(data[key:], '[')
+	if open < 0 {
+		return nil, fmt.Errorf("no events")
+	}
+
+	// the fields before the events, with the objects they are in closed
+	var h header
+	fields := bytes.TrimRight(bytes.TrimSpace(data[:key]), ",")
+	if err := json.Unmarshal(append(append([]byte{}, fields...), "}]}"...), &h); err != nil {
+		return nil, fmt.Errorf("reading the qlog header: %s", err)
+	}
+	l := newLog(h)
+
+	decoder := json.NewDecoder(bytes.NewReader(data[key+open:]))
+	if _, err := decoder.Token(); err != nil {
+		return nil, err
+	}
+	for decoder.More() {
+		var raw rawEvent
+		if err := decoder.Decode(&raw); err != nil {
+			l.Truncated = true
+			return l, nil
+		}
+		l.Events = append(l.Events, newEvent(raw))
+	}
+	if _, err := decoder.Token(); err != nil {
+		l.Truncated = true
+	}
+	return l, nil
+}
+
+// recordSeparator starts each record of a JSON Text Sequence
+const recordSeparator = 0x1e
+
+// parseRecords reads the records of a streamed qlog. Every record is complete once written, only
+// the last record of a run that was stopped can be cut short.
+func parseRecords(records [][]byte) (*Log, error) {
+	var l *Log
+	for i, record := range records {
+		record = bytes.TrimSpace(record)
+		if len(record) == 0 {
+			continue
+		}
+		if l == nil {
+			var h header
+			if err := json.Unmarshal(record, &h); err != nil {
+				return nil, fmt.Errorf("reading the qlog header: %s", err)
+			}
+			l = newLog(h)
+			continue
+		}
+		var raw rawEvent
+		if err := json.Unmarshal(record, &raw); err != nil {
+			if i == len(records)-1 {
+				l.Truncated = true
+				break
+			}
+			return nil, fmt.Errorf("reading event %d: %s", len(l.Events), err)
+		}
+		l.Events = append(l.Events, newEvent(raw))
+	}
+	if l == nil {
+		return nil, fmt.Errorf("no header")
+	}
+	return l, nil
+}
+
+func newEvent(raw rawEvent) Event {
+	e := Event{Time: milliseconds(raw.Time), Name: raw.Name, Data: raw.Data}
+	if i := strings.IndexByte(raw.Name, ':'); i >= 0 {
+		e.Category, e.Name = raw.Name[:i], raw.Name[i+1:]
+	}
+	decode, ok := decoders[raw.Name]
+	if !ok && e.Category == "generic" {
+		// the name of a generic event is the name passed to StreamTracer.Debug
+		decode, ok = decodeGeneric, true
+	}
+	if ok {
+		// an event whose data does not decode is kept without its details
+		e.Details, _ = decode(raw.Data)
+	}
+	return e
+}
+
+// Extensions are the extensions of the qlog files, in the JSON format and in the streamed formats
+var Extensions = []string{".qlog", ".sqlog", ".ndjson"}
+
+// GzipExtension is added to the extension of a compressed qlog file
+const GzipExtension = ".gz"
+
+// gunzip decompresses a qlog file, the file of a client that was stopped is read up to where it stops
+func gunzip(data []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	data, err = ioutil.ReadAll(r)
+	if err == io.ErrUnexpectedEOF {
+		err = nil
+	}
+	return data, err
+}
+
+// ReadFolder reads every qlog file in a folder, compressed or not, in the order of their names.
+// Empty files, such as the qlog of a tracer that recorded nothing, are skipped.
+func ReadFolder(folder string) ([]*Log, error) {
+	var paths []string
+	for _, extension := range Extensions {
+		for _, suffix := range []string{extension, extension + GzipExtension} {
+			matches, err := filepath.Glob(filepath.Join(folder, "*"+suffix))
+			if err != nil {
+				return nil, err
+			}
+			paths = append(paths, matches...)
+		}
+	}
+	sort.Strings(paths)
+	var logs []*Log
+	for _, path := range paths {
+		if info, err := os.Stat(path); err != nil || info.Size() == 0 {
+			continue
+		}
+		l, err := ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		logs = append(logs, l)
+	}
+	return logs, nil
+}
+
+// ABR returns the qlog-abr logs of a run
+func ABR(logs []*Log) []*Log {
+	var abr []*Log
+	for _, l := range logs {
+		if l.IsABR() {
+			abr = append(abr, l)
+		}
+	}
+	return abr
+}
+
+// Transport returns the transport logs of a run, one for each connection
+func Transport(logs []*Log) []*Log {
+	var transport []*Log
+	for _, l := range logs {
+		if !l.IsABR() && !l.IsMerged() {
+			transport = append(transport, l)
+		}
+	}
+	return transport
+}
+
+func milliseconds(ms float64) time.Duration {
+	return time.Duration(ms * float64(time.Millisecond))
+}
@@ -0,0 +1,334 @@
+package reader
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	abrqlog "github.com/uccmisl/godash/qlog"
+)
+
+type nopCloser struct{ *bytes.Buffer }
+
+func (nopCloser) Close() error { return nil }
+
+func TestReadStreamTracer(t *testing.T) {
+	buf := &bytes.Buffer{}
+	tracer := abrqlog.NewStreamTracer(nopCloser{buf}, abrqlog.PerspectiveClient, "")
+	tracer.InitialiseStream(true)
+	from, to := abrqlog.NewRepresentation(), abrqlog.NewRepresentation()
+	from.ID, from.Bitrate = "0", 254
+	to.ID, to.Bitrate = "3", 1500
+	tracer.Switch(abrqlog.MediaTypeVideo, from, to)
+	timing := abrqlog.NewRequestTiming()
+	timing.TTFB = 20 * time.Millisecond
+	tracer.RequestComplete("seg1.m4s", 1000, timing)
+	tracer.Debug("note", "hello")
+	tracer.Close()
+
+	l, err := Parse(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !l.IsABR() || l.Truncated || len(l.Events) != 4 {
+		t.Fatalf("abr %v, truncated %v, %d events", l.IsABR(), l.Truncated, len(l.Events))
+	}
+	if d, ok := l.Events[0].Details.(StreamInitialised); !ok || !d.Autoplay {
+		t.Errorf("stream_initialised %#v", l.Events[0])
+	}
+	if d := l.Events[1].Details.(Switch); d != (Switch{MediaType: "video", FromID: "0", FromBitrate: 254, ToID: "3", ToBitrate: 1500}) {
+		t.Errorf("switch %#v", d)
+	}
+	d := l.Events[2].Details.(RequestUpdate)
+	if d.BytesReceived != 1000 || d.Timing == nil || d.Timing.TTFB != 20*time.Millisecond || d.Timing.DNS != -1 {
+		t.Errorf("request_update %#v %#v", d, d.Timing)
+	}
+	if d := l.Events[3].Details.(Generic); d.Details != "hello" || l.Events[3].Name != "note" {
+		t.Errorf("generic %#v", l.Events[3])
+	}
+}
+
+func TestReadManifest(t *testing.T) {
+	buf := &bytes.Buffer{}
+	tracer := abrqlog.NewStreamTracer(nopCloser{buf}, abrqlog.PerspectiveClient, "")
+	tracer.ManifestLoaded(abrqlog.Manifest{URL: "http://server/stream.mpd", Profiles: "urn:mpeg:dash:profile:isoff-live:2011", Duration: 10 * time.Minute, AdaptationSets: 2})
+	tracer.Ladder(abrqlog.Ladder{MediaType: abrqlog.MediaTypeVideo, SegmentDuration: 2 * time.Second, Representations: []abrqlog.LadderRepresentation{
+		{ID: "0", Bitrate: 4000, Width: 1920, Height: 1080, Codecs: "avc1.640028"},
+		{ID: "1", Bitrate: 1000, Width: 640, Height: 360, Codecs: "avc1.4d401e"},
+	}})
+	tracer.Close()
+
+	l, err := Parse(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(l.Events) != 2 {
+		t.Fatalf("%d events", len(l.Events))
+	}
+	if d := l.Events[0].Details.(ManifestLoaded); d != (ManifestLoaded{URL: "http://server/stream.mpd", Profiles: "urn:mpeg:dash:profile:isoff-live:2011", Duration: 10 * time.Minute, AdaptationSets: 2}) {
+		t.Errorf("manifest_loaded %#v", d)
+	}
+	d := l.Events[1].Details.(Ladder)
+	if d.MediaType != "video" || d.SegmentDuration != 2*time.Second || len(d.Representations) != 2 ||
+		d.Representations[1] != (LadderRepresentation{ID: "1", Bitrate: 1000, Width: 640, Height: 360, Codecs: "avc1.4d401e"}) {
+		t.Errorf("ladder %#v", d)
+	}
+}
+
+// a transport qlog of a client that was stopped, so the events are not closed
+const transportQlog = `{"qlog_format":"JSON","qlog_version":"draft-02","title":"quic-go qlog","traces":[{"vantage_point":{"type":"client"},"common_fields":{"ODCID":"01","reference_time":1000500,"time_format":"relative"}
+,"events":[
+{"time":100,"name":"transport:packet_received","data":{"header":{"packet_type":"1RTT","packet_number":1},"raw":{"length":1252}}},
+{"time":600,"name":"transport:packet_received","data":{"header":{"packet_type":"1RTT","packet_number":2,"packet_size":1200}}},
+{"time":700,"name":"recovery:metrics_updated","data":{"smoothed_rtt":30.5,"congestion_window":14000}},
+{"time":1200,"name":"transport:packet_received","data":{"header":{"packet_type":"1RTT","packet_number":3},"raw":{"length":1000}}},
+{"time":1300,"name":"transport:packet_sent","data":{"head`
+
+func TestReadTransportQlog(t *testing.T) {
+	l, err := Parse([]byte(transportQlog))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.IsABR() || !l.Truncated || len(l.Events) != 4 || l.VantagePoint != "client" {
+		t.Fatalf("abr %v, truncated %v, %d events, vantage point %s", l.IsABR(), l.Truncated, len(l.Events), l.VantagePoint)
+	}
+	if !l.ReferenceTime.Equal(time.Unix(1000, 500000000)) {
+		t.Errorf("reference time %v", l.ReferenceTime)
+	}
+	if d := l.Events[1].Details.(Packet); d.Length != 1200 || d.Number != 2 {
+		t.Errorf("packet %#v", d)
+	}
+	if d := l.Events[2].Details.(Metrics); d.SmoothedRTT != 30500*time.Microsecond || d.CongestionWindow != 14000 {
+		t.Errorf("metrics %#v", d)
+	}
+}
+
+// a session that starts playing after 500ms, switches up after 1s and down after 3s,
+// and stalls 200ms after the 1s of buffer of the update at 3.5s runs out
+const abrQlog = `{"qlog_version":"draft-02","title":"qlog-abr","traces":[{"common_fields":{"protocol_type":"QLOG_ABR","reference_time":1000000}
+,"events": [
+{"time":0,"name":"playback:stream_initialised","data":{"autoplay":true}}
+,{"time":100,"name":"network:request","data":{"media_type":"video","resource_url":"seg1"}}
+,{"time":500,"name":"playback:player_interaction","data":{"state":"play","playhead_ms":0}}
+,{"time":1500,"name":"abr:switch","data":{"media_type":"video","from_id":"0","from_bitrate":1000,"to_id":"1","to_bitrate":3000}}
+,{"time":3500,"name":"abr:switch","data":{"media_type":"video","from_id":"1","from_bitrate":3000,"to_id":"0","to_bitrate":1000}}
+,{"time":3500,"name":"buffer:occupancy_update","data":{"media_type":"video","playout_ms":1000,"max_ms":30000}}
+,{"time":4700,"name":"playback:rebuffer","data":{"playhead_ms":4000}}
+,{"time":4700,"name":"buffer:occupancy_update","data":{"media_type":"video","playout_ms":0,"max_ms":30000}}
+,{"time":5500,"name":"playback:stream_end","data":{"playhead_ms":5000}}
+]}]}
+`
+
+func TestSummarise(t *testing.T) {
+	l, err := Parse([]byte(abrQlog))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := Summarise(l)
+	if s.StartupDelay != 500*time.Millisecond || s.PlaybackTime != 5*time.Second || s.Stalls != 1 || s.StallTime != 200*time.Millisecond {
+		t.Fatalf("summary %#v", s)
+	}
+	if len(s.MediaTypes) != 1 {
+		t.Fatalf("media types %#v", s.MediaTypes)
+	}
+	m := s.MediaTypes[0]
+	if m.Switches != 2 || m.SwitchesUp != 1 || m.SwitchesDown != 1 {
+		t.Errorf("switches %#v", m)
+	}
+	// 1s at 1000, 2s at 3000, 2s at 1000
+	want := []RepresentationTime{{"0", 1000, 3 * time.Second}, {"1", 3000, 2 * time.Second}}
+	if len(m.Representations) != 2 || m.Representations[0] != want[0] || m.Representations[1] != want[1] {
+		t.Errorf("representations %#v", m.Representations)
+	}
+	if m.AverageBitrate != 1800 {
+		t.Errorf("average bitrate %v, want 1800", m.AverageBitrate)
+	}
+}
+
+// a session without a switch, video starts at the representation of the first decision and audio at the lowest of its ladder
+const noSwitchQlog = `{"qlog_version":"draft-02","title":"qlog-abr","traces":[{"common_fields":{"protocol_type":"QLOG_ABR","reference_time":1000000}
+,"events": [
+{"time":0,"name":"playback:stream_initialised","data":{"autoplay":true}}
+,{"time":10,"name":"abr:ladder","data":{"media_type":"video","representations":[{"id":"0","bitrate":3000},{"id":"1","bitrate":1000}]}}
+,{"time":10,"name":"abr:ladder","data":{"media_type":"audio","representations":[{"id":"0","bitrate":128},{"id":"1","bitrate":64}]}}
+,{"time":100,"name":"network:request","data":{"media_type":"video","resource_url":"seg1"}}
+,{"time":500,"name":"playback:player_interaction","data":{"state":"play","playhead_ms":0}}
+,{"time":1500,"name":"abr:decision","data":{"media_type":"video","segment_number":2,"last_index":1,"index":1,"bitrate":1000}}
+,{"time":2500,"name":"playback:stream_end","data":{"playhead_ms":2000}}
+]}]}
+`
+
+func TestSummariseWithoutSwitch(t *testing.T) {
+	l, err := Parse([]byte(noSwitchQlog))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := Summarise(l)
+	if len(s.MediaTypes) != 2 {
+		t.Fatalf("media types %#v", s.MediaTypes)
+	}
+	want := map[string]RepresentationTime{"video": {"1", 1000, 2 * time.Second}, "audio": {"1", 64, 2 * time.Second}}
+	for _, m := range s.MediaTypes {
+		if m.Switches != 0 || len(m.Representations) != 1 || m.Representations[0] != want[m.MediaType] {
+			t.Errorf("%s representations %#v", m.MediaType, m.Representations)
+		}
+		if m.AverageBitrate != float64(want[m.MediaType].Bitrate) {
+			t.Errorf("%s average bitrate %v", m.MediaType, m.AverageBitrate)
+		}
+	}
+}
+
+func TestWriteSeries(t *testing.T) {
+	folder := t.TempDir()
+	os.WriteFile(filepath.Join(folder, "client_abr_(empty).qlog"), []byte(abrQlog), 0644)
+	os.WriteFile(filepath.Join(folder, "client_01.qlog"), []byte(transportQlog), 0644)
+	os.WriteFile(filepath.Join(folder, "client_02.qlog"), nil, 0644)
+
+	logs, err := ReadFolder(folder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 2 || len(ABR(logs)) != 1 || len(Transport(logs)) != 1 {
+		t.Fatalf("%d logs", len(logs))
+	}
+	paths, err := WriteSeries(folder, logs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	throughput, _ := os.ReadFile(paths[2])
+	// the transport trace starts 500ms after the abr trace, the packets are in the seconds from 0 and from 1
+	want := "time_ms,log,throughput_bps\n500.000,client_01.qlog,19616\n1500.000,client_01.qlog,8000\n"
+	if string(throughput) != want {
+		t.Errorf("throughput series\n%s\nwant\n%s", throughput, want)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	abr, _ := Parse([]byte(abrQlog))
+	abr.Path = "client_abr_(empty).qlog"
+	transport, _ := Parse([]byte(transportQlog))
+	transport.Path = "logs/client_01.qlog"
+	// a connection of an earlier run
+	earlier, _ := Parse([]byte(transportQlog))
+	earlier.ReferenceTime = abr.ReferenceTime.Add(-time.Hour)
+
+	logs := Session([]*Log{earlier, abr, transport})
+	if len(logs) != 2 || logs[0] != abr || logs[1] != transport {
+		t.Fatalf("session %v", logs)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := Merge(buf, logs); err != nil {
+		t.Fatal(err)
+	}
+	merged, err := Parse(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !merged.IsMerged() || merged.IsABR() || merged.Truncated || !merged.ReferenceTime.Equal(abr.ReferenceTime) {
+		t.Fatalf("merged %#v", merged)
+	}
+	if len(merged.Events) != len(abr.Events)+len(transport.Events) {
+		t.Fatalf("%d events", len(merged.Events))
+	}
+	for i := 1; i < len(merged.Events); i++ {
+		if merged.Events[i].Time < merged.Events[i-1].Time {
+			t.Fatalf("event %d at %v is before event %d at %v", i, merged.Events[i].Time, i-1, merged.Events[i-1].Time)
+		}
+	}
+	// the first packet is received 100ms into the connection, which starts 500ms into the session
+	if e := merged.Events[3]; e.Name != "packet_received" || e.Time != 600*time.Millisecond || !bytes.Contains(buf.Bytes(), []byte(`"group_id":"client_01"`)) {
+		t.Errorf("event %#v", e)
+	}
+	if len(Session([]*Log{merged})) != 0 {
+		t.Error("a merged log in the session")
+	}
+}
+
+func TestReadStreamedFormats(t *testing.T) {
+	for _, format := range []abrqlog.Format{abrqlog.FormatJSONSeq, abrqlog.FormatNDJSON} {
+		buf := &bytes.Buffer{}
+		tracer := abrqlog.NewStreamTracer(nopCloser{buf}, abrqlog.PerspectiveClient, "")
+		tracer.SetFormat(format)
+		tracer.InitialiseStream(true)
+		tracer.AbortRequest("seg2.m4s")
+		tracer.Close()
+
+		l, err := Parse(buf.Bytes())
+		if err != nil {
+			t.Fatalf("%s: %s", format, err)
+		}
+		if !l.IsABR() || l.Truncated || len(l.Events) != 2 || l.Events[1].Details.(Abort).URL != "seg2.m4s" {
+			t.Fatalf("%s: abr %v, truncated %v, events %#v", format, l.IsABR(), l.Truncated, l.Events)
+		}
+
+		// a run that is stopped while writing an event keeps the events before it
+		l, err = Parse(buf.Bytes()[:buf.Len()-5])
+		if err != nil {
+			t.Fatalf("%s: %s", format, err)
+		}
+		if !l.Truncated || len(l.Events) != 1 {
+			t.Errorf("%s: truncated %v, %d events", format, l.Truncated, len(l.Events))
+		}
+	}
+}
+
+func TestReadDecision(t *testing.T) {
+	buf := &bytes.Buffer{}
+	tracer := abrqlog.NewStreamTracer(nopCloser{buf}, abrqlog.PerspectiveClient, "")
+	tracer.Decision(abrqlog.MediaTypeVideo, abrqlog.Decision{Algorithm: "bba", Segment: 3, Throughput: 2500000, BufferLevel: 8 * time.Second,
+		SegmentBytes: 625000, LastIndex: 2, Index: 2, Bitrate: 1000, Details: []abrqlog.DecisionDetail{
+			{Key: "zone", Value: "cushion"}, {Key: "cushion_fraction", Value: 0.25}, {Key: "slow_download", Value: false}}})
+	tracer.Close()
+
+	l, err := Parse(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(l.Events) != 1 {
+		t.Fatalf("%d events", len(l.Events))
+	}
+	d := l.Events[0].Details.(Decision)
+	if d.MediaType != "video" || d.Algorithm != "bba" || d.Segment != 3 || d.Throughput != 2500000 || d.Estimate != 0 ||
+		d.BufferLevel != 8*time.Second || d.SegmentBytes != 625000 || d.LastIndex != 2 || d.Index != 2 || d.Bitrate != 1000 {
+		t.Errorf("decision %#v", d)
+	}
+	if d.Details["zone"] != "cushion" || d.Details["cushion_fraction"] != 0.25 || d.Details["slow_download"] != false {
+		t.Errorf("details %#v", d.Details)
+	}
+}
+
+func TestReadFileTracer(t *testing.T) {
+	folder := filepath.Join(t.TempDir(), "qlog")
+	config := abrqlog.Config{Dir: folder, Perspective: abrqlog.PerspectiveClient, RunID: "20260101-120000-abcd", Algorithm: "bba",
+		MPD: "http://server/live/stream.mpd?token=1", Format: abrqlog.FormatNDJSON, Gzip: true, Categories: []string{"playback", "network"}}
+	if path := config.Filename(); path != filepath.Join(folder, "client_abr_20260101-120000-abcd_bba_stream.ndjson.gz") {
+		t.Errorf("file name %s", path)
+	}
+	tracer, err := abrqlog.NewFileTracer(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the abr event is not in the categories of the tracer
+	tracer.InitialiseStream(true)
+	tracer.ChangeReadyState(abrqlog.ReadyStateHaveNothing)
+	tracer.Request(abrqlog.MediaTypeVideo, "http://server/live/seg1.m4s", "")
+	tracer.Close()
+
+	logs, err := ReadFolder(folder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 1 || !logs[0].IsABR() || logs[0].GroupID != "20260101-120000-abcd" {
+		t.Fatalf("logs %#v", logs)
+	}
+	if events := logs[0].Events; len(events) != 2 || events[0].Category != "playback" || events[1].Category != "network" {
+		t.Errorf("events %#v", events)
+	}
+
+	if _, err := abrqlog.NewFileTracer(abrqlog.Config{Dir: folder, Categories: []string{"transport"}}); err == nil {
+		t.Error("unknown category accepted")
+	}
+}
@@ -0,0 +1,146 @@
+package reader
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// ThroughputInterval is the interval over which the packets received are added up in the throughput series
+const ThroughputInterval = time.Second
+
+// the files of the time series, written by WriteSeries
+const (
+	BufferSeriesFile     = "qlog_buffer.csv"
+	BitrateSeriesFile    = "qlog_bitrate.csv"
+	ThroughputSeriesFile = "qlog_throughput.csv"
+	RTTSeriesFile        = "qlog_rtt.csv"
+	PlayheadSeriesFile   = "qlog_playhead.csv"
+)
+
+// Start is the time the series of the logs are relative to: the reference time of
+// the qlog-abr log, or the earliest reference time if the run has none
+func Start(logs []*Log) time.Time {
+	var start time.Time
+	for _, l := range logs {
+		if l.IsABR() {
+			return l.ReferenceTime
+		}
+		if start.IsZero() || l.ReferenceTime.Before(start) {
+			start = l.ReferenceTime
+		}
+	}
+	return start
+}
+
+// WriteSeries writes the time series of the logs of a run to CSV files in the folder, and returns their paths:
+// the buffer level and the bitrate of each media type, the playhead, and the throughput and the RTT of each connection.
+// The times are in milliseconds from Start.
+func WriteSeries(folder string, logs []*Log) ([]string, error) {
+	start := Start(logs)
+	offset := func(l *Log, t time.Duration) string {
+		return formatMs(l.ReferenceTime.Sub(start) + t)
+	}
+
+	var buffer, playhead, bitrate, throughput, rtt [][]string
+	for _, l := range logs {
+		name := filepath.Base(l.Path)
+		var metrics Metrics
+		var bin time.Duration = -1
+		var bytes int
+		flush := func() {
+			if bin >= 0 {
+				bps := float64(bytes*8) / ThroughputInterval.Seconds()
+				throughput = append(throughput, []string{offset(l, bin*ThroughputInterval), name, strconv.FormatFloat(bps, 'f', 0, 64)})
+			}
+		}
+		for _, e := range l.Events {
+			switch d := e.Details.(type) {
+			case BufferOccupancy:
+				buffer = append(buffer, []string{offset(l, e.Time), d.MediaType, formatMs(d.Playout), formatMs(d.Max), strconv.FormatInt(d.PlayoutBytes, 10)})
+			case Playhead:
+				playhead = append(playhead, []string{offset(l, e.Time), formatMs(d.Playhead), e.Name})
+			case PlayerInteraction:
+				playhead = append(playhead, []string{offset(l, e.Time), formatMs(d.Playhead), d.State})
+			case Switch:
+				bitrate = append(bitrate, []string{offset(l, e.Time), d.MediaType, d.ToID, strconv.FormatInt(d.ToBitrate, 10)})
+			case Packet:
+				if e.Name != "packet_received" {
+					continue
+				}
+				if e.Time/ThroughputInterval != bin {
+					flush()
+					bin, bytes = e.Time/ThroughputInterval, 0
+				}
+				bytes += d.Length
+			case Metrics:
+				// only the metrics that changed are logged
+				metrics = updateMetrics(metrics, d)
+				rtt = append(rtt, []string{offset(l, e.Time), name, formatMs(metrics.SmoothedRTT), formatMs(metrics.LatestRTT),
+					formatMs(metrics.MinRTT), strconv.FormatInt(metrics.CongestionWindow, 10)})
+			}
+		}
+		flush()
+	}
+
+	files := []struct {
+		name   string
+		header []string
+		rows   [][]string
+	}{
+		{BufferSeriesFile, []string{"time_ms", "media_type", "playout_ms", "max_ms", "playout_bytes"}, buffer},
+		{BitrateSeriesFile, []string{"time_ms", "media_type", "representation", "bitrate_kbps"}, bitrate},
+		{ThroughputSeriesFile, []string{"time_ms", "log", "throughput_bps"}, throughput},
+		{RTTSeriesFile, []string{"time_ms", "log", "smoothed_rtt_ms", "latest_rtt_ms", "min_rtt_ms", "congestion_window"}, rtt},
+		{PlayheadSeriesFile, []string{"time_ms", "playhead_ms", "event"}, playhead},
+	}
+	var paths []string
+	for _, file := range files {
+		path := filepath.Join(folder, file.name)
+		if err := writeCSV(path, file.header, file.rows); err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+func updateMetrics(last, m Metrics) Metrics {
+	if m.MinRTT != 0 {
+		last.MinRTT = m.MinRTT
+	}
+	if m.SmoothedRTT != 0 {
+		last.SmoothedRTT = m.SmoothedRTT
+	}
+	if m.LatestRTT != 0 {
+		last.LatestRTT = m.LatestRTT
+	}
+	if m.RTTVariance != 0 {
+		last.RTTVariance = m.RTTVariance
+	}
+	if m.CongestionWindow != 0 {
+		last.CongestionWindow = m.CongestionWindow
+	}
+	if m.BytesInFlight != 0 {
+		last.BytesInFlight = m.BytesInFlight
+	}
+	return last
+}
+
+func writeCSV(path string, header []string, rows [][]string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	w.Write(header)
+	w.WriteAll(rows)
+	return w.Error()
+}
+
+func formatMs(d time.Duration) string {
+	return strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', 3, 64)
+}
@@ -0,0 +1,253 @@
+package reader
+
+import (
+	"strconv"
+	"time"
+)
+
+// Summary is the playback of a session, from its qlog-abr log
+type Summary struct {
+	// from the stream being initialised to playback starting
+	StartupDelay time.Duration
+	// from playback starting to the end of the stream, or the last event of a log that was not closed
+	PlaybackTime time.Duration
+	Stalls       int
+	// the time from the buffer running out to each stall being logged, when the segment arrived
+	StallTime  time.Duration
+	MediaTypes []MediaSummary
+}
+
+// MediaSummary is the representations of a media type during playback
+type MediaSummary struct {
+	MediaType    string
+	Switches     int
+	SwitchesUp   int
+	SwitchesDown int
+	// in kbps, over the playback time at which the bitrate is known
+	AverageBitrate float64
+	// the representations in the order they were first used. Without a switch the representation
+	// is the one of the first decision, or the lowest of the ladder. Without either, the time is
+	// under an empty ID with a bitrate of -1
+	Representations []RepresentationTime
+}
+
+// RepresentationTime is the playback time at which a representation was being downloaded
+type RepresentationTime struct {
+	ID      string
+	Bitrate int64
+	Time    time.Duration
+}
+
+// Summarise computes the summary of a qlog-abr log
+func Summarise(l *Log) Summary {
+	var s Summary
+
+	var initialised, start, end time.Duration
+	started, ended := false, false
+	speed := 1.0
+	// the last occupancy update of each media type with media in the buffer
+	lastBuffer := make(map[string]*Event)
+	for i := range l.Events {
+		e := &l.Events[i]
+		switch d := e.Details.(type) {
+		case StreamInitialised:
+			if initialised == 0 {
+				initialised = e.Time
+			}
+		case PlayerInteraction:
+			if d.State == "play" && !started {
+				started = true
+				start = e.Time
+				if d.Speed > 0 {
+					speed = d.Speed
+				}
+			}
+		case BufferOccupancy:
+			// the updates of an empty buffer do not tell when it ran out
+			if d.Playout > 0 {
+				lastBuffer[d.MediaType] = e
+			}
+		case Playhead:
+			if e.Name == "rebuffer" {
+				s.Stalls++
+				s.StallTime += stallTime(lastBuffer, e, speed)
+			} else if e.Name == "stream_end" && !ended {
+				ended = true
+				end = e.Time
+			}
+		}
+	}
+	if !started {
+		return s
+	}
+	if !ended && len(l.Events) > 0 {
+		end = l.Events[len(l.Events)-1].Time
+	}
+	s.StartupDelay = start - initialised
+	s.PlaybackTime = end - start
+	s.MediaTypes = summariseSwitches(l, start, end)
+	return s
+}
+
+// stallTime is how long a buffer had been empty when the stall was logged, from the buffer
+// level of the last occupancy update of each media type before it, drained at the playback speed
+func stallTime(lastBuffer map[string]*Event, stall *Event, speed float64) time.Duration {
+	var stalled time.Duration
+	for _, e := range lastBuffer {
+		empty := e.Time + time.Duration(float64(e.Details.(BufferOccupancy).Playout)/speed)
+		if stall.Time-empty > stalled {
+			stalled = stall.Time - empty
+		}
+	}
+	return stalled
+}
+
+// summariseSwitches splits the playback time between the representations of each media type, at the switches
+func summariseSwitches(l *Log, start, end time.Duration) []MediaSummary {
+	type current struct {
+		summary *MediaSummary
+		rep     RepresentationTime
+		since   time.Duration
+		index   map[string]int
+	}
+	media := make(map[string]*current)
+	var order []string
+
+	// the representation each media type starts at, for the media types without a switch
+	initial := initialRepresentations(l)
+
+	add := func(c *current, until time.Duration) {
+		from, to := c.since, until
+		if from < start {
+			from = start
+		}
+		if to > end {
+			to = end
+		}
+		if to <= from {
+			return
+		}
+		i, ok := c.index[c.rep.ID]
+		if !ok {
+			i = len(c.summary.Representations)
+			c.index[c.rep.ID] = i
+			c.summary.Representations = append(c.summary.Representations, RepresentationTime{ID: c.rep.ID, Bitrate: c.rep.Bitrate})
+		}
+		c.summary.Representations[i].Time += to - from
+	}
+
+	for _, e := range l.Events {
+		sw, ok := e.Details.(Switch)
+		if !ok {
+			continue
+		}
+		c, ok := media[sw.MediaType]
+		if !ok {
+			// the representation before the first switch
+			c = &current{summary: &MediaSummary{MediaType: sw.MediaType}, rep: RepresentationTime{ID: sw.FromID, Bitrate: sw.FromBitrate}, index: make(map[string]int)}
+			media[sw.MediaType] = c
+			order = append(order, sw.MediaType)
+		}
+		add(c, e.Time)
+		c.summary.Switches++
+		if sw.FromBitrate >= 0 && sw.ToBitrate >= 0 {
+			if sw.ToBitrate > sw.FromBitrate {
+				c.summary.SwitchesUp++
+			} else if sw.ToBitrate < sw.FromBitrate {
+				c.summary.SwitchesDown++
+			}
+		}
+		c.rep = RepresentationTime{ID: sw.ToID, Bitrate: sw.ToBitrate}
+		c.since = e.Time
+	}
+
+	// a media type without switches was downloaded at its initial representation, if we know it
+	for _, e := range l.Events {
+		var mediaType string
+		switch d := e.Details.(type) {
+		case Request:
+			mediaType = d.MediaType
+		case Decision:
+			mediaType = d.MediaType
+		case Ladder:
+			mediaType = d.MediaType
+		}
+		if mediaType != "video" && mediaType != "audio" {
+			continue
+		}
+		if _, ok := media[mediaType]; !ok {
+			rep, ok := initial[mediaType]
+			if !ok {
+				rep = RepresentationTime{Bitrate: -1}
+			}
+			media[mediaType] = &current{summary: &MediaSummary{MediaType: mediaType}, rep: rep, index: make(map[string]int)}
+			order = append(order, mediaType)
+		}
+	}
+
+	var summaries []MediaSummary
+	for _, mediaType := range order {
+		c := media[mediaType]
+		add(c, end)
+		var bits float64
+		var known time.Duration
+		for _, rep := range c.summary.Representations {
+			if rep.Bitrate >= 0 {
+				bits += float64(rep.Bitrate) * rep.Time.Seconds()
+				known += rep.Time
+			}
+		}
+		if known > 0 {
+			c.summary.AverageBitrate = bits / known.Seconds()
+		}
+		summaries = append(summaries, *c.summary)
+	}
+	return summaries
+}
+
+// initialRepresentations is the representation each media type starts at: the representation before
+// its first decision, or the lowest bitrate of its ladder, where the player starts
+func initialRepresentations(l *Log) map[string]RepresentationTime {
+	ladders := make(map[string]Ladder)
+	decisions := make(map[string]Decision)
+	for _, e := range l.Events {
+		switch d := e.Details.(type) {
+		case Ladder:
+			if _, ok := ladders[d.MediaType]; !ok {
+				ladders[d.MediaType] = d
+			}
+		case Decision:
+			if _, ok := decisions[d.MediaType]; !ok {
+				decisions[d.MediaType] = d
+			}
+		}
+	}
+
+	initial := make(map[string]RepresentationTime)
+	for mediaType, d := range decisions {
+		rep := RepresentationTime{ID: strconv.Itoa(d.LastIndex), Bitrate: -1}
+		if d.Index == d.LastIndex {
+			rep.Bitrate = d.Bitrate
+		}
+		// the ladder has the bitrate of every index, with the index as the ID
+		for _, r := range ladders[mediaType].Representations {
+			if r.ID == rep.ID {
+				rep.Bitrate = r.Bitrate
+			}
+		}
+		initial[mediaType] = rep
+	}
+	for mediaType, ladder := range ladders {
+		if _, ok := initial[mediaType]; ok || len(ladder.Representations) == 0 {
+			continue
+		}
+		lowest := ladder.Representations[0]
+		for _, r := range ladder.Representations {
+			if r.Bitrate < lowest.Bitrate {
+				lowest = r
+			}
+		}
+		initial[mediaType] = RepresentationTime{ID: lowest.ID, Bitrate: lowest.Bitrate}
+	}
+	return initial
+}
@@ -2,6 +2,8 @@ package qlog
 
 import (
 	"crypto/rand"
+	"encoding/hex"
+	"time"
 )
 
 type StreamID string
@@ -15,6 +17,14 @@ func GenerateStreamID(len int) (StreamID, error) {
 	return StreamID(b), nil
 }
 
+// NewRunID generates the ID of a run, from its start time and a random suffix so that
+// runs started in the same second have different IDs
+func NewRunID() StreamID {
+	b := make([]byte, 2)
+	rand.Read(b)
+	return StreamID(time.Now().Format("20060102-150405") + "-" + hex.EncodeToString(b))
+}
+
 func (c StreamID) String() string {
 	if len(c) == 0 {
 		return "(empty)"
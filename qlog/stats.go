@@ -47,3 +47,88 @@ func NewRepresentation() representation {
 		Bitrate: -1,
 	}
 }
+
+// RequestTiming : the phases of a request, -1 if not measured
+type RequestTiming struct {
+	DNS     time.Duration
+	Connect time.Duration
+	TLS     time.Duration
+	TTFB    time.Duration
+	TTLB    time.Duration
+	Reused  bool
+}
+
+func NewRequestTiming() RequestTiming {
+	return RequestTiming{
+		DNS:     -1,
+		Connect: -1,
+		TLS:     -1,
+		TTFB:    -1,
+		TTLB:    -1,
+		Reused:  false,
+	}
+}
+
+// StallPrediction : the inputs and the outcome of one stall prediction
+type StallPrediction struct {
+	Model string
+	// the buffer level now, and the level below which stalls are predicted
+	BufferLevel time.Duration
+	Threshold   time.Duration
+	// the throughput of the model in bits/second, and the bits of the segment still to download
+	Throughput    float64
+	RemainingBits int64
+	// the time to download the rest of the segment at the throughput, and the whole segment at the lowest bitrate
+	RequiredTime time.Duration
+	LowestTime   time.Duration
+	Stall        bool
+}
+
+// Manifest : the metadata of a parsed manifest
+type Manifest struct {
+	URL            string
+	Profiles       string
+	Duration       time.Duration
+	AdaptationSets int
+}
+
+// Ladder : the representations of an adaptation set that the adaptation algorithm chooses from
+type Ladder struct {
+	MediaType       MediaType
+	SegmentDuration time.Duration
+	Representations []LadderRepresentation
+}
+
+// LadderRepresentation : a representation of a ladder, with its ID and its bitrate in kbps as in the switch events
+type LadderRepresentation struct {
+	ID      string
+	Bitrate int64
+	Width   int
+	Height  int
+	Codecs  string
+}
+
+// Decision : the rep_rate decision of an adaptation algorithm for a segment, made after every segment
+type Decision struct {
+	Algorithm string
+	// the segment the rep_rate is chosen for
+	Segment int
+	// the throughput of the last segment and the estimate of the throughput estimator, in bits/second - 0 without an estimator
+	Throughput float64
+	Estimate   float64
+	// the buffer level and the size in bytes of the last segment
+	BufferLevel  time.Duration
+	SegmentBytes int64
+	// the rep_rate index of the last segment and the chosen index, with its bitrate in kbps
+	LastIndex int
+	Index     int
+	Bitrate   int64
+	// the inputs and intermediate values of the algorithm, in the order it added them
+	Details []DecisionDetail
+}
+
+// DecisionDetail : a named input or intermediate value of an adaptation algorithm
+type DecisionDetail struct {
+	Key   string
+	Value interface{}
+}
@@ -28,6 +28,26 @@ func (l topLevel) MarshalJSONObject(enc *gojay.Encoder) {
 	enc.ArrayKey("traces", traces(l.traces))
 }
 
+// topLevelSeq is the first record of a streamed qlog, with the single trace of the file
+type topLevelSeq struct {
+	format Format
+	trace  trace
+}
+
+func (topLevelSeq) IsNil() bool { return false }
+func (l topLevelSeq) MarshalJSONObject(enc *gojay.Encoder) {
+	enc.StringKey("qlog_format", l.format.String())
+	if l.format == FormatJSONSeq {
+		enc.StringKey("qlog_version", "0.3")
+	} else {
+		// the NDJSON format of qvis is draft-02 with one record per line
+		enc.StringKey("qlog_version", "draft-02")
+	}
+	enc.StringKeyOmitEmpty("title", "qlog-abr")
+	enc.StringKey("code_version", goDashVersion)
+	enc.ObjectKey("trace", l.trace)
+}
+
 type vantagePoint struct {
 	Name string
 	Type Perspective
@@ -47,12 +67,15 @@ func (p vantagePoint) MarshalJSONObject(enc *gojay.Encoder) {
 type commonFields struct {
 	ProtocolType  string
 	ReferenceTime time.Time
+	// the run ID of the tracer
+	GroupID StreamID
 }
 
 func (f commonFields) MarshalJSONObject(enc *gojay.Encoder) {
 	enc.StringKeyOmitEmpty("protocol_type", f.ProtocolType)
 	enc.Float64Key("reference_time", float64(f.ReferenceTime.UnixNano())/1e6)
 	enc.StringKey("time_format", "relative")
+	enc.StringKeyOmitEmpty("group_id", string(f.GroupID))
 }
 
 func (f commonFields) IsNil() bool { return false }
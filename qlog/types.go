@@ -102,3 +102,40 @@ func (c ReadyState) String() string {
 		return "unknown readystate"
 	}
 }
+
+// Format is the serialization of a qlog file
+type Format uint8
+
+const (
+	// FormatJSON is a single draft-02 JSON document, only complete once the tracer is closed
+	FormatJSON Format = iota
+	// FormatJSONSeq is JSON Text Sequences (RFC 7464), the .sqlog format of qlog draft-03 and later
+	FormatJSONSeq
+	// FormatNDJSON is a JSON record per line
+	FormatNDJSON
+)
+
+func (f Format) String() string {
+	switch f {
+	case FormatJSON:
+		return "JSON"
+	case FormatJSONSeq:
+		return "JSON-SEQ"
+	case FormatNDJSON:
+		return "NDJSON"
+	default:
+		return "unknown format"
+	}
+}
+
+// Extension is the file extension of the format
+func (f Format) Extension() string {
+	switch f {
+	case FormatJSONSeq:
+		return ".sqlog"
+	case FormatNDJSON:
+		return ".ndjson"
+	default:
+		return ".qlog"
+	}
+}
@@ -22,8 +22,8 @@
 package qoe
 
 import (
-	"bytes"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"os"
 	"os/exec"
@@ -33,6 +33,7 @@ import (
 
 	glob "github.com/uccmisl/godash/global"
 
+	"github.com/uccmisl/godash/isobmff"
 	"github.com/uccmisl/godash/logging"
 	"github.com/uccmisl/godash/utils"
 )
@@ -114,45 +115,68 @@ func GetOS() string {
 	return runtime.GOOS
 }
 
+// p1203PythonCheck : cross-check the native P.1203 score with the itu_p1203 Python module
+var p1203PythonCheck = false
+
+// SetP1203PythonCheck : cross-check the native P.1203 score of each segment with the itu_p1203 Python module
+func SetP1203PythonCheck(check bool) {
+	p1203PythonCheck = check
+}
+
 // createP1203 : create the P1203 value
-func createP1203(logMap *map[int]logging.SegPrintLogInformation, c chan float64, saveFilesBool bool, audioRate int, audioCodec string) {
+// * the value is computed natively, and cross-checked with the itu_p1203 Python module if it is set
+func createP1203(logMap *map[int]logging.SegPrintLogInformation, c chan float64, saveFilesBool bool, audioRate int, audioCodec string, debugLog bool) {
 
-	// for each of the logs, lets create a P.1203 compliant Json file
-	// get the body
-	bodyString := createP1203body(*logMap, audioRate, audioCodec)
-	// get the stalls
-	stallString := createP1203stalls(*logMap)
-	// add all the output together
-	jsonString := strings.Join([]string{bodyString, stallString, deviceString}, "")
-
-	// save to file or just get the value
-	if saveFilesBool {
-		// write the output to a json file (file for the last map in the log)
-		createP1203file(*logMap, jsonString)
-
-		// calculate the P1203 value and return to the channel
-		// c <- getP1203Val(*logMap)
-		// just get the value
+	// the audio of the P.1203 input file, 192 kbps of aac if we do not have audio
+	audioKbps := 192
+	if audioCodec != "" {
+		audioKbps = audioRate
 	}
-	// lets just get the overall P.1203 value that we want
-	/*out, err := exec.Command("bash", "-c", "python3 -c 'from itu_p1203 import P1203Standalone; print(P1203Standalone("+jsonString+").calculate_complete(True)[\"O46\"])'").Output()
+	p1203Value := P1203Session(*logMap, "aac", float64(audioKbps))
+
+	// we only need the P.1203 Json file to save it, or for the Python module
+	if saveFilesBool || p1203PythonCheck {
+		// for each of the logs, lets create a P.1203 compliant Json file
+		// get the body
+		bodyString := createP1203body(*logMap, audioRate, audioCodec)
+		// get the stalls
+		stallString := createP1203stalls(*logMap)
+		// add all the output together
+		jsonString := strings.Join([]string{bodyString, stallString, deviceString}, "")
+
+		// save to file
+		if saveFilesBool {
+			// write the output to a json file (file for the last map in the log)
+			createP1203file(*logMap, jsonString)
+		}
 
-	if err != nil {
-		log.Fatal(err)
+		// cross-check the native value with the Python module
+		if p1203PythonCheck {
+			pythonValue, err := getP1203Python(jsonString)
+			if err != nil {
+				logging.DebugPrint(glob.DebugFile, debugLog, "\nDEBUG: ", "P1203 Python cross-check failed: "+err.Error())
+			} else {
+				logging.DebugPrint(glob.DebugFile, debugLog, "\nDEBUG: ", fmt.Sprintf("P1203 native O46 %.3f, Python O46 %.3f", p1203Value, pythonValue))
+			}
+		}
 	}
 
-	// get the P1203 value and remove any return carrige
-	stringVal := strings.TrimSuffix(string(out), "\n")
+	// return the P1203 value to the channel
+	c <- p1203Value
+}
+
+// getP1203Python : the P1203 session score (O46) of the itu_p1203 Python module, for a P.1203 Json input
+func getP1203Python(jsonString string) (float64, error) {
 
-	// convert to float
-	p1203Value, err := strconv.ParseFloat(stringVal, 64)
+	cmd := exec.Command("python3", "-c", "import json, sys; from itu_p1203 import P1203Standalone; print(P1203Standalone(json.load(sys.stdin)).calculate_complete()[\"O46\"])")
+	cmd.Stdin = strings.NewReader(jsonString)
+	out, err := cmd.Output()
 	if err != nil {
-		log.Fatal(err)
+		return 0, err
 	}
 
-	// calculate the P1203 value and return to the channel
-	c <- p1203Value*/
-	c <- 0.0
+	// get the P1203 value and remove any return carrige
+	return strconv.ParseFloat(strings.TrimSpace(string(out)), 64)
 }
 
 // getP1203Val : return the P1203 value for this segment
@@ -227,6 +251,10 @@ func createP1203body(log map[int]logging.SegPrintLogInformation, audioRate int,
 		codec := log[a].RepCodec
 		segmentDuration := fmt.Sprintf("%.1f", float64(log[a].SegmentDuration))
 		fps := fmt.Sprintf("%.1f", float64(log[a].RepFps))
+		// use the frame rate read from the segment, if we have it
+		if log[a].FrameRate > 0 {
+			fps = fmt.Sprintf("%.3f", log[a].FrameRate)
+		}
 		resolution := strconv.Itoa(log[a].RepWidth) + "x" + strconv.Itoa(log[a].RepHeight)
 		start := fmt.Sprintf("%.1f", float64(log[a].PlayStartPosition/glob.Conversion1000)-float64(log[a].SegmentDuration))
 
@@ -274,6 +302,10 @@ func createP1203stalls(log map[int]logging.SegPrintLogInformation) (stallValues
 		// stallDuration := fmt.Sprintf("%.3f", float64(utils.Abs(log[a].StallTime)/glob.Conversion1000))
 		// output is a float
 		stallDuration := fmt.Sprintf("%.3f", (float64(utils.Abs(log[a].StallTime)) / float64(glob.Conversion1000)))
+		// the license time is part of the initial loading
+		if a == 1 {
+			stallDuration = fmt.Sprintf("%.3f", (float64(utils.Abs(log[a].StallTime)+log[a].LicenseTime) / float64(glob.Conversion1000)))
+		}
 
 		// local val
 		var stallLoop string
@@ -310,57 +342,22 @@ func GetKBPS(fileInput string, segDuration int64, debugLog bool, isByteRangeMPD
 	// if this is not a byte-range semgent, calcualte the withoutHeaderVal
 	if !isByteRangeMPD {
 
-		// get the correct version of grep based on O/S
-		var grep string
-		var fi os.FileInfo
-		var err error
-
-		// check if file exists
-		if fi, err = os.Stat(fileInput); err != nil {
+		// read the segment file
+		content, err := ioutil.ReadFile(fileInput)
+		if err != nil {
 			// input segment file does not exist, stop the app
 			fmt.Println("*** The segment locationed at " + fileInput + " does not exist or cannot be found.  please check if correct path is used ***")
 			// stop the app
 			utils.StopApp()
 		}
+		withoutHeaderVal = int64(len(content))
 
-		// set the version of grep we will use
-		switch GetOS() {
-		// linux use grep
-		case linux:
-			// size in bytes using grep
-			grep = "grep"
-			logging.DebugPrint(glob.DebugFile, debugLog, "\nDEBUG: ", "grep being used on Linux")
-		// mac use grep
-		case darwin:
-			// size in bytes using ggrep (from brew or port)
-			grep = "ggrep"
-			logging.DebugPrint(glob.DebugFile, debugLog, "\nDEBUG: ", "ggrep being used on OSX")
-		}
-
-		// get the location of this hex value in the input file - return 2 positions
-		out, err := exec.Command("bash", "-c", "LANG=C "+grep+" -obUaP \"\\x00\\x00\\x00\\x04\\x68\\xEF\\xBC\\x80\" "+fileInput+" | awk 'BEGIN{FS=\":\"}{print $1}'").Output()
-		if err != nil {
-			log.Fatal(err)
-		}
-
-		// sometimes we can't read the hex value from the input segment
+		// sometimes we can't read the boxes of the input segment
 		// in this instance we just use the entire segment size as input to P.1203
-		if !(len(out) == 0) {
-			logging.DebugPrint(glob.DebugFile, debugLog, "\nDEBUG: ", "P1203 has the correct hex value ")
-
-			// get the index of the first return carrige
-			returnIndex := bytes.IndexByte(out, 10)
-			// save this value as a string
-			mdatValue := string(out[:returnIndex])
-			// convert this value to an int64
-			mdatValueInt, err := strconv.ParseInt(mdatValue, 10, 64)
-			if err != nil {
-				log.Fatal(err)
-			}
-			// add 8 bits for header
-			mdatValueInt += 8
-			// get the file byte size less the header
-			withoutHeaderVal = fi.Size() - mdatValueInt
+		segment, err := isobmff.ParseSegment(content, nil)
+		if err == nil && segment != nil && segment.PayloadBytes() > 0 {
+			logging.DebugPrint(glob.DebugFile, debugLog, "\nDEBUG: ", "P1203 is using the mdat payload of the segment")
+			withoutHeaderVal = segment.PayloadBytes()
 		}
 	}
 
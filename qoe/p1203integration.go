@@ -0,0 +1,179 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package qoe
+
+import (
+	"math"
+
+	glob "github.com/uccmisl/godash/global"
+	"github.com/uccmisl/godash/logging"
+	"github.com/uccmisl/godash/utils"
+)
+
+// the parametric part of the P.1203.3 integration module (Pq), for a pc display:
+// the audiovisual quality of each second (O34), its temporal pooling (O35) and the stalling
+// indicator, into the session score (O46).
+// Not included: the negative bias, oscillation and adaptation compensations of O35, and the
+// random forest, which P.1203.3 weights by a quarter in O46 - so the native O46 is the
+// parametric score only, and the itu_p1203 Python module can be used to cross-check it
+
+// coefficients of the audiovisual quality of each second (O34)
+const (
+	p1203AV1 = -0.00069084
+	p1203AV2 = 0.15374283
+	p1203AV3 = 0.97153861
+	p1203AV4 = 0.02461776
+)
+
+// coefficients of the temporal weights of the pooling of O34 into O35
+const (
+	p1203PoolT1 = 0.00666620027943848
+	p1203PoolT2 = 0.0000404018840273729
+	p1203PoolT3 = 0.156497800436237
+	p1203PoolT4 = 0.143179744942738
+	p1203PoolT5 = 0.0238641564518876
+)
+
+// coefficients of the stalling indicator
+const (
+	p1203S1 = 9.35158684
+	p1203S2 = 0.91890815
+	p1203S3 = 11.0567558
+)
+
+// p1203InitialLoadingWeight : the weight of the initial loading in the total stall length
+const p1203InitialLoadingWeight = 1.0 / 3
+
+// P1203Stall : a stall of P.1203 (I23), at a media time and for a duration, in seconds
+type P1203Stall struct {
+	Position float64
+	Duration float64
+}
+
+// P1203AudioVisual : the audiovisual quality of each second (O34), from the audio (O21) and
+// video (O22) scores of the second - audio seconds missing at the end count as the last one
+func P1203AudioVisual(audio []float64, video []float64) []float64 {
+
+	scores := make([]float64, len(video))
+	for t := range video {
+		a := p1203MOSMax
+		if len(audio) > 0 {
+			a = audio[int(math.Min(float64(t), float64(len(audio)-1)))]
+		}
+		scores[t] = constrain(p1203AV1+p1203AV2*a+p1203AV3*video[t]+p1203AV4*a*video[t], 1, 5)
+	}
+	return scores
+}
+
+// P1203Pooling :
+// * the audiovisual coding quality of the session (O35, without its compensations)
+// * the mean of O34, weighted towards the later seconds and the seconds of lower quality
+func P1203Pooling(o34 []float64) float64 {
+
+	if len(o34) == 0 {
+		return 1
+	}
+
+	T := float64(len(o34))
+	var sum, weights float64
+	for t, score := range o34 {
+		w1 := p1203PoolT1 + p1203PoolT2*math.Exp((float64(t)/T)/p1203PoolT3)
+		w2 := p1203PoolT4 - p1203PoolT5*score
+		sum += w1 * w2 * score
+		weights += w1 * w2
+	}
+	return sum / weights
+}
+
+// P1203StallingIndicator :
+// * the stalling indicator (1 without stalls, towards 0) of a session of duration seconds
+// * a stall at the start of the session is the initial loading, it only counts for a third of its length
+// * the other stalls count in their number, their total length and their average interval
+func P1203StallingIndicator(stalls []P1203Stall, duration float64) float64 {
+
+	if duration <= 0 {
+		return 1
+	}
+
+	var numStalls int
+	var totalStallLen float64
+	var first, last float64
+	for _, stall := range stalls {
+		if stall.Duration <= 0 {
+			continue
+		}
+		if stall.Position <= 0 {
+			totalStallLen += stall.Duration * p1203InitialLoadingWeight
+			continue
+		}
+		if numStalls == 0 {
+			first = stall.Position
+		}
+		last = stall.Position
+		numStalls++
+		totalStallLen += stall.Duration
+	}
+
+	avgStallInterval := 0.0
+	if numStalls > 1 {
+		avgStallInterval = (last - first) / float64(numStalls-1)
+	}
+
+	return math.Exp(-float64(numStalls)/p1203S1) *
+		math.Exp(-(totalStallLen/duration)/p1203S2) *
+		math.Exp(-(avgStallInterval/duration)/p1203S3)
+}
+
+// P1203Integration : the session score (O46, 1 to 5) of the audio and video scores of each second and the stalls
+func P1203Integration(audio []float64, video []float64, stalls []P1203Stall) float64 {
+
+	o35 := P1203Pooling(P1203AudioVisual(audio, video))
+	si := P1203StallingIndicator(stalls, float64(len(video)))
+	return constrain(1+(o35-1)*si, 1, 5)
+}
+
+// P1203StallsFromLog : the stalls (I23) of a segment log, the same as the P.1203 input file of the log
+// * the first segment is the initial loading, which includes the license time
+func P1203StallsFromLog(log map[int]logging.SegPrintLogInformation) []P1203Stall {
+
+	var stalls []P1203Stall
+	for a := 1; a <= len(log); a++ {
+		duration := float64(utils.Abs(log[a].StallTime)) / float64(glob.Conversion1000)
+		if a == 1 {
+			duration = float64(utils.Abs(log[a].StallTime)+log[a].LicenseTime) / float64(glob.Conversion1000)
+			stalls = append(stalls, P1203Stall{Duration: duration})
+			continue
+		}
+		if duration > 0 {
+			stalls = append(stalls, P1203Stall{Position: float64(log[a].PlaybackTime) / float64(glob.Conversion1000), Duration: duration})
+		}
+	}
+	return stalls
+}
+
+// P1203Session : the native P.1203 session score (O46) of a segment log, with an audio stream of the codec and bitrate in kbps
+func P1203Session(log map[int]logging.SegPrintLogInformation, audioCodec string, audioRate float64) float64 {
+
+	video := P1203VideoPerSecond(log)
+	audio := P1203AudioPerSecond(audioCodec, audioRate, float64(len(video)))
+	return P1203Integration(audio, video, P1203StallsFromLog(log))
+}
@@ -0,0 +1,101 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package qoe
+
+import (
+	"math"
+	"testing"
+
+	"github.com/uccmisl/godash/logging"
+)
+
+func TestP1203Pooling(t *testing.T) {
+	// the weighted mean of a constant quality is the quality
+	if got := P1203Pooling([]float64{3.7, 3.7, 3.7, 3.7}); math.Abs(got-3.7) > 1e-9 {
+		t.Errorf("constant quality pooled to %v", got)
+	}
+	// a drop at the end weighs more than the same drop at the start
+	start := P1203Pooling([]float64{2, 4, 4, 4, 4, 4, 4, 4, 4, 4})
+	end := P1203Pooling([]float64{4, 4, 4, 4, 4, 4, 4, 4, 4, 2})
+	if end >= start {
+		t.Errorf("recency: a drop at the end %v, at the start %v", end, start)
+	}
+	// low quality weighs more than its share
+	if mean := P1203Pooling([]float64{2, 4}); mean >= 3 {
+		t.Errorf("pooling of 2 and 4 is %v, it should be below their mean", mean)
+	}
+	if P1203Pooling(nil) != 1 {
+		t.Error("pooling of no seconds")
+	}
+}
+
+func TestP1203AudioVisual(t *testing.T) {
+	scores := P1203AudioVisual([]float64{4.5}, []float64{1, 3, 5})
+	if len(scores) != 3 {
+		t.Fatalf("audiovisual scores %v", scores)
+	}
+	for i, s := range scores {
+		if s < 1 || s > 5 || (i > 0 && s <= scores[i-1]) {
+			t.Errorf("audiovisual scores %v", scores)
+		}
+	}
+}
+
+func TestP1203StallingIndicator(t *testing.T) {
+	if si := P1203StallingIndicator(nil, 60); si != 1 {
+		t.Errorf("stalling indicator without stalls %v", si)
+	}
+	initial := P1203StallingIndicator([]P1203Stall{{Position: 0, Duration: 3}}, 60)
+	mid := P1203StallingIndicator([]P1203Stall{{Position: 20, Duration: 3}}, 60)
+	two := P1203StallingIndicator([]P1203Stall{{Position: 20, Duration: 3}, {Position: 40, Duration: 3}}, 60)
+	if !(1 > initial && initial > mid && mid > two && two > 0) {
+		t.Errorf("stalling indicators: initial loading %v, one stall %v, two stalls %v", initial, mid, two)
+	}
+	// the initial loading only counts for a third of its length
+	if third := P1203StallingIndicator([]P1203Stall{{Position: 0, Duration: 9}}, 60); math.Abs(third-math.Exp(-(3.0/60)/p1203S2)) > 1e-12 {
+		t.Errorf("stalling indicator of the initial loading %v", third)
+	}
+}
+
+func TestP1203Session(t *testing.T) {
+	log := map[int]logging.SegPrintLogInformation{
+		1: {P1203Kbps: 5000, RepWidth: 1920, RepHeight: 1080, RepFps: 30, SegmentDuration: 4, StallTime: 1000, LicenseTime: 500},
+		2: {P1203Kbps: 5000, RepWidth: 1920, RepHeight: 1080, RepFps: 30, SegmentDuration: 4, PlaybackTime: 4000},
+		3: {P1203Kbps: 5000, RepWidth: 1920, RepHeight: 1080, RepFps: 30, SegmentDuration: 4, PlaybackTime: 8000, StallTime: 2000},
+	}
+	stalls := P1203StallsFromLog(log)
+	if len(stalls) != 2 || stalls[0] != (P1203Stall{Duration: 1.5}) || stalls[1] != (P1203Stall{Position: 8, Duration: 2}) {
+		t.Fatalf("stalls %v", stalls)
+	}
+
+	// without stalls, the session score is the pooled audiovisual score
+	video := P1203VideoPerSecond(log)
+	audio := P1203AudioPerSecond("aac", 128, float64(len(video)))
+	pooled := P1203Pooling(P1203AudioVisual(audio, video))
+	if got := P1203Integration(audio, video, nil); math.Abs(got-pooled) > 1e-12 {
+		t.Errorf("session score without stalls %v, pooled score %v", got, pooled)
+	}
+	want := 1 + (pooled-1)*P1203StallingIndicator(stalls, 12)
+	if got := P1203Session(log, "aac", 128); math.Abs(got-want) > 1e-12 || got >= pooled {
+		t.Errorf("session score %v, want %v", got, want)
+	}
+}
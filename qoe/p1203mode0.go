@@ -0,0 +1,191 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package qoe
+
+import (
+	"math"
+	"strings"
+
+	glob "github.com/uccmisl/godash/global"
+	"github.com/uccmisl/godash/logging"
+)
+
+// the short-term video (P.1203.1 mode 0) and audio (P.1203.2) quality modules of ITU-T P.1203,
+// from the metadata of the segments. Their per-second scores are integrated with the stalls
+// into the session score (O46) in p1203integration.go
+
+// the MOS range of the R to MOS conversion of P.1203
+const (
+	p1203MOSMax = 4.9
+	p1203MOSMin = 1.05
+)
+
+// mode 0 coefficients of the compression degradation of P.1203.1
+const (
+	p1203A1 = 11.9983519
+	p1203A2 = -2.99991847
+	p1203A3 = 41.2475074001
+	p1203A4 = 0.13183165961
+	p1203Q1 = 4.66
+	p1203Q2 = -0.07
+	p1203Q3 = 4.06
+)
+
+// coefficients of the upscaling and frame rate degradations of P.1203.1
+const (
+	p1203U1 = 72.61
+	p1203U2 = 0.32
+	p1203T1 = 30.98
+	p1203T2 = 1.29
+	p1203T3 = 64.65
+)
+
+// p1203AudioCoeffs : the a1A, a2A and a3A coefficients of P.1203.2, per audio codec
+var p1203AudioCoeffs = map[string][3]float64{
+	"mp2":   {100, -0.02, 15.48},
+	"ac3":   {100, -0.03, 15.70},
+	"aaclc": {100, -0.05, 14.60},
+	"heaac": {100, -0.11, 20.06},
+}
+
+// P1203DisplayResolution : the display of the P.1203 input files of goDASH, in pixels
+const P1203DisplayResolution = glob.P1203maxWidth * glob.P1203maxHeight
+
+// constrain : v within min and max
+func constrain(v, min, max float64) float64 {
+	return math.Max(min, math.Min(max, v))
+}
+
+// mosFromR : the MOS (1.05 to 4.9) of a quality on the R scale (0 to 100)
+func mosFromR(q float64) float64 {
+	if q <= 0 {
+		return p1203MOSMin
+	}
+	if q >= 100 {
+		return p1203MOSMax
+	}
+	// the cubic dips below the minimum MOS for low qualities (around 2), so it is clamped to the range
+	return constrain(p1203MOSMin+(p1203MOSMax-p1203MOSMin)/100*q+q*(q-60)*(100-q)*7.0e-6, p1203MOSMin, p1203MOSMax)
+}
+
+// rFromMOS : the quality on the R scale of a MOS, the inverse of mosFromR
+func rFromMOS(mos float64) float64 {
+	if mos <= p1203MOSMin {
+		return 0
+	}
+	if mos >= p1203MOSMax {
+		return 100
+	}
+	// mosFromR never decreases, so bisect it
+	low, high := 0.0, 100.0
+	for i := 0; i < 64; i++ {
+		mid := (low + high) / 2
+		if mosFromR(mid) < mos {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+	return (low + high) / 2
+}
+
+// P1203ModeZeroVideo : the P.1203.1 mode 0 quality (1 to 5) of a video segment, from its bitrate
+// in kbps, its frame rate, and its coding and display resolutions in pixels
+func P1203ModeZeroVideo(bitrate float64, codingRes float64, displayRes float64, fps float64) float64 {
+
+	if bitrate <= 0 || codingRes <= 0 || fps <= 0 {
+		return 1
+	}
+
+	// compression degradation
+	bitsPerPixel := bitrate / (codingRes * fps)
+	quant := p1203A1 + p1203A2*math.Log(p1203A3+math.Log(bitrate)+math.Log(bitrate*bitsPerPixel+p1203A4))
+	mosCod := constrain(p1203Q1+p1203Q2*math.Exp(p1203Q3*quant), 1, 5)
+	degCod := constrain(100-rFromMOS(mosCod), 0, 100)
+
+	// upscaling degradation
+	scaleFactor := math.Max(displayRes/codingRes, 1)
+	degScal := constrain(p1203U1*math.Log10(p1203U2*(scaleFactor-1)+1), 0, 100)
+
+	// frame rate degradation, below 24 frames per second
+	degFrameRate := 0.0
+	if fps < 24 {
+		degFrameRate = constrain((100-degCod-degScal)*(p1203T1-p1203T2*fps)/(p1203T3+fps), 0, 100)
+	}
+
+	degAll := constrain(degCod+degScal+degFrameRate, 0, 100)
+	return constrain(mosFromR(100-degAll), 1, 5)
+}
+
+// p1203AudioCodec : the P.1203.2 codec of an audio codec of the MPD, AAC-LC if it is not known
+func p1203AudioCodec(codec string) string {
+	codec = strings.ToLower(codec)
+	switch {
+	case codec == "heaac" || codec == "mp4a.40.5" || codec == "mp4a.40.29":
+		return "heaac"
+	case codec == "ac3" || codec == "ac-3" || codec == "ec-3":
+		return "ac3"
+	case codec == "mp2" || codec == "mp4a.40.34" || codec == "mp4a.6b":
+		return "mp2"
+	}
+	return "aaclc"
+}
+
+// P1203Audio : the P.1203.2 quality (1.05 to 4.9) of an audio segment, from its codec and bitrate in kbps
+func P1203Audio(codec string, bitrate float64) float64 {
+	a := p1203AudioCoeffs[p1203AudioCodec(codec)]
+	qCod := a[0]*math.Exp(a[1]*bitrate) + a[2]
+	return mosFromR(100 - qCod)
+}
+
+// P1203VideoPerSecond : the P.1203.1 mode 0 quality of each second of the video of a segment log
+// (O22), from the segment playing at the start of the second - the log is indexed from segment 1
+func P1203VideoPerSecond(log map[int]logging.SegPrintLogInformation) []float64 {
+
+	var scores []float64
+	end := 0.0
+	for a := 1; a <= len(log); a++ {
+		// the same inputs as the P.1203 input file of the segment
+		fps := float64(log[a].RepFps)
+		if log[a].FrameRate > 0 {
+			fps = log[a].FrameRate
+		}
+		score := P1203ModeZeroVideo(log[a].P1203Kbps, float64(log[a].RepWidth*log[a].RepHeight), P1203DisplayResolution, fps)
+
+		end += float64(log[a].SegmentDuration)
+		for float64(len(scores)) < end {
+			scores = append(scores, score)
+		}
+	}
+	return scores
+}
+
+// P1203AudioPerSecond : the P.1203.2 quality of each second (O21) of an audio stream of a
+// constant codec and bitrate in kbps, over its duration in seconds
+func P1203AudioPerSecond(codec string, bitrate float64, duration float64) []float64 {
+	scores := make([]float64, int(math.Ceil(duration)))
+	score := P1203Audio(codec, bitrate)
+	for i := range scores {
+		scores[i] = score
+	}
+	return scores
+}
@@ -0,0 +1,109 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package qoe
+
+import (
+	"math"
+	"testing"
+
+	"github.com/uccmisl/godash/logging"
+)
+
+func TestMOSFromR(t *testing.T) {
+	for _, mos := range []float64{1.2, 2, 2.7505, 3.5, 4.2, 4.8} {
+		if got := mosFromR(rFromMOS(mos)); math.Abs(got-mos) > 1e-9 {
+			t.Errorf("MOS %v: %v", mos, got)
+		}
+	}
+	// the MOS never goes below the minimum or decreases, even where the cubic dips
+	previous := mosFromR(0)
+	for q := 0.0; q <= 100; q += 0.25 {
+		mos := mosFromR(q)
+		if mos < previous || mos < p1203MOSMin || mos > p1203MOSMax {
+			t.Fatalf("MOS %v at R %v, after %v", mos, q, previous)
+		}
+		previous = mos
+	}
+	if r := rFromMOS(1.06); mosFromR(r) < 1.06-1e-9 || r < 2 {
+		t.Errorf("R %v of a MOS above the dip", r)
+	}
+	if rFromMOS(1) != 0 || rFromMOS(5) != 100 || mosFromR(-1) != p1203MOSMin || mosFromR(120) != p1203MOSMax {
+		t.Error("limits of the R scale")
+	}
+}
+
+// the expected scores of the mode 0 and audio tests are computed with this implementation,
+// they are not vectors of the itu_p1203 reference, and guard against regressions only
+func TestP1203ModeZeroVideo(t *testing.T) {
+	for _, c := range []struct {
+		bitrate       float64
+		width, height int
+		fps, want     float64
+	}{
+		{5000, 1920, 1080, 30, 4.408670},
+		{300, 1920, 1080, 30, 3.913604},
+		// upscaled to the display
+		{1000, 1280, 720, 30, 3.618848},
+		{400, 640, 360, 24, 1.865110},
+		// below 24 frames per second
+		{800, 1280, 720, 15, 3.074197},
+	} {
+		got := P1203ModeZeroVideo(c.bitrate, float64(c.width*c.height), P1203DisplayResolution, c.fps)
+		if math.Abs(got-c.want) > 1e-5 {
+			t.Errorf("%v kbps %dx%d@%v: %v, want %v", c.bitrate, c.width, c.height, c.fps, got, c.want)
+		}
+	}
+	if P1203ModeZeroVideo(0, 1920*1080, P1203DisplayResolution, 30) != 1 {
+		t.Error("a segment without a bitrate")
+	}
+}
+
+func TestP1203Audio(t *testing.T) {
+	for _, c := range []struct {
+		codec   string
+		bitrate float64
+		want    float64
+	}{
+		{"mp4a.40.2", 128, 4.553814},
+		{"mp4a.40.5", 64, 4.347891},
+		{"ac-3", 192, 4.509241},
+		{"aac", 32, 3.643341},
+	} {
+		if got := P1203Audio(c.codec, c.bitrate); math.Abs(got-c.want) > 1e-5 {
+			t.Errorf("%s %v kbps: %v, want %v", c.codec, c.bitrate, got, c.want)
+		}
+	}
+	if scores := P1203AudioPerSecond("aac", 128, 9.5); len(scores) != 10 || math.Abs(scores[9]-4.553814) > 1e-5 {
+		t.Errorf("audio scores %v", scores)
+	}
+}
+
+func TestP1203VideoPerSecond(t *testing.T) {
+	log := map[int]logging.SegPrintLogInformation{
+		1: {P1203Kbps: 300, RepWidth: 1920, RepHeight: 1080, RepFps: 30, SegmentDuration: 2},
+		2: {P1203Kbps: 5000, RepWidth: 1920, RepHeight: 1080, RepFps: 25, FrameRate: 30, SegmentDuration: 4},
+	}
+	scores := P1203VideoPerSecond(log)
+	if len(scores) != 6 || math.Abs(scores[1]-3.913604) > 1e-5 || math.Abs(scores[2]-4.408670) > 1e-5 || scores[5] != scores[2] {
+		t.Errorf("video scores %v", scores)
+	}
+}
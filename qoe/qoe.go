@@ -62,7 +62,7 @@ func CreateQoE(log *map[int]logging.SegPrintLogInformation, debugLog bool, initB
 		}
 		// create the P1203 value
 		if stopP1203 {
-			go createP1203(log, P1023Results, saveFilesBool, audioRate, audioCodec)
+			go createP1203(log, P1023Results, saveFilesBool, audioRate, audioCodec, debugLog)
 		}
 	}
 
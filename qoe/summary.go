@@ -0,0 +1,224 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package qoe
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+
+	glob "github.com/uccmisl/godash/global"
+	"github.com/uccmisl/godash/logging"
+)
+
+// SummarySchemaVersion : the version of the fields of summary.json
+const SummarySchemaVersion = 1
+
+// Summary : the QoE KPIs of a session, from the segment logs of its media types.
+// The stalls are those of the first media type, which drives the playout log
+type Summary struct {
+	SchemaVersion int    `json:"schema_version"`
+	Algorithm     string `json:"algorithm"`
+	// from the start of the stream to the arrival of the segment at which playback starts
+	StartupDelay float64 `json:"startup_delay_ms"`
+	// the media time of the segments of the first media type
+	ContentDuration float64 `json:"content_duration_ms"`
+	Stalls          int     `json:"stalls"`
+	StallDuration   float64 `json:"stall_duration_total_ms"`
+	AverageStall    float64 `json:"stall_duration_average_ms"`
+	// stalls per minute of content
+	StallFrequency float64 `json:"stall_frequency_per_min"`
+	// the stall duration over the content duration and the stall duration
+	RebufferingRatio float64        `json:"rebuffering_ratio"`
+	MediaTypes       []MediaSummary `json:"media_types"`
+	// the scores of the QoE models over the whole session, of the first video media type -
+	// P.1203 is only there if it was computed during the session
+	P1203  *float64 `json:"p1203,omitempty"`
+	Claye  float64  `json:"claye"`
+	Duanmu float64  `json:"duanmu"`
+	Yin    float64  `json:"yin"`
+	Yu     float64  `json:"yu"`
+}
+
+// MediaSummary : the bitrates, switches and bytes of a media type
+type MediaSummary struct {
+	MimeType string `json:"mime_type"`
+	Segments int    `json:"segments"`
+	// the mean bitrate of the segments, and weighted by their duration
+	AverageBitrate      float64 `json:"average_bitrate_bps"`
+	TimeWeightedBitrate float64 `json:"time_weighted_bitrate_bps"`
+	Switches            int     `json:"switches"`
+	SwitchesUp          int     `json:"switches_up"`
+	SwitchesDown        int     `json:"switches_down"`
+	// the mean and largest bitrate change of the switches
+	AverageSwitchMagnitude float64              `json:"switch_magnitude_average_bps"`
+	MaxSwitchMagnitude     float64              `json:"switch_magnitude_max_bps"`
+	Representations        []RepresentationTime `json:"representations"`
+	BytesDownloaded        int64                `json:"bytes_downloaded"`
+	BytesPlayed            int64                `json:"bytes_played"`
+}
+
+// RepresentationTime : the media time of a representation, in the order they were first used
+type RepresentationTime struct {
+	Index    int     `json:"rep_index"`
+	Bitrate  int     `json:"bitrate_bps"`
+	Width    int     `json:"width"`
+	Height   int     `json:"height"`
+	Segments int     `json:"segments"`
+	Time     float64 `json:"time_ms"`
+	Share    float64 `json:"share"`
+}
+
+// Summarise : the summary of a session, from the segment log of each media type - the logs are
+// indexed from segment 1, and maxRepRates are the highest bitrates of the media types for Claye
+func Summarise(logs []map[int]logging.SegPrintLogInformation, initBuffer int, maxRepRates []int) Summary {
+
+	s := Summary{SchemaVersion: SummarySchemaVersion, MediaTypes: []MediaSummary{}}
+	if len(logs) == 0 || len(logs[0]) == 0 {
+		return s
+	}
+
+	// the first media type drives playback
+	log := logs[0]
+	s.Algorithm = log[1].Adapt
+	// playback starts when the segment after the initial buffer arrives
+	start := initBuffer + 1
+	if start > len(log) {
+		start = len(log)
+	}
+	s.StartupDelay = float64(log[start].ArrivalTime)
+	for i := 1; i <= len(log); i++ {
+		s.ContentDuration += float64(log[i].SegmentDuration * glob.Conversion1000)
+		if log[i].StallTime < 0 {
+			s.Stalls++
+			s.StallDuration += float64(-log[i].StallTime)
+		}
+	}
+	if s.Stalls > 0 {
+		s.AverageStall = s.StallDuration / float64(s.Stalls)
+	}
+	if s.ContentDuration > 0 {
+		s.StallFrequency = float64(s.Stalls) / (s.ContentDuration / 60000)
+		s.RebufferingRatio = s.StallDuration / (s.ContentDuration + s.StallDuration)
+	}
+
+	qoeDone := false
+	for i, log := range logs {
+		s.MediaTypes = append(s.MediaTypes, summariseMedia(log))
+
+		// the QoE models score the video
+		if qoeDone || len(log) == 0 || log[1].MimeType == glob.RepRateCodecAudio {
+			continue
+		}
+		qoeDone = true
+		maxRepRate := 0
+		if i < len(maxRepRates) {
+			maxRepRate = maxRepRates[i]
+		}
+		if p1203 := log[len(log)].P1203; p1203 != 0 {
+			s.P1203 = &p1203
+		}
+		s.Claye = finalScore(func(c chan float64) { getClaye(log, c, maxRepRate, false) })
+		s.Duanmu = finalScore(func(c chan float64) { getDuanmu(log, c, initBuffer, false) })
+		s.Yin = finalScore(func(c chan float64) { getYin(log, c, initBuffer, false) })
+		s.Yu = finalScore(func(c chan float64) { getYu(log, c, false) })
+	}
+	return s
+}
+
+// finalScore : the score of a QoE model over the whole log, NaN is saved as 0 as JSON has no NaN
+func finalScore(model func(c chan float64)) float64 {
+	c := make(chan float64, 1)
+	model(c)
+	score := <-c
+	if math.IsNaN(score) || math.IsInf(score, 0) {
+		return 0
+	}
+	return score
+}
+
+// summariseMedia : the summary of the segment log of a media type
+func summariseMedia(log map[int]logging.SegPrintLogInformation) MediaSummary {
+
+	m := MediaSummary{Segments: len(log), Representations: []RepresentationTime{}}
+	if len(log) == 0 {
+		return m
+	}
+	m.MimeType = log[1].MimeType
+
+	var sumRate, sumWeightedRate, duration, sumMagnitude float64
+	representations := make(map[int]int)
+	for i := 1; i <= len(log); i++ {
+		segment := log[i]
+		segmentDuration := float64(segment.SegmentDuration * glob.Conversion1000)
+		sumRate += float64(segment.Bandwidth)
+		sumWeightedRate += float64(segment.Bandwidth) * segmentDuration
+		duration += segmentDuration
+		m.BytesDownloaded += int64(segment.SegSize)
+		if segment.Played {
+			m.BytesPlayed += int64(segment.SegSize)
+		}
+
+		if i > 1 && segment.Bandwidth != log[i-1].Bandwidth {
+			m.Switches++
+			if segment.Bandwidth > log[i-1].Bandwidth {
+				m.SwitchesUp++
+			} else {
+				m.SwitchesDown++
+			}
+			magnitude := math.Abs(float64(segment.Bandwidth - log[i-1].Bandwidth))
+			sumMagnitude += magnitude
+			m.MaxSwitchMagnitude = math.Max(m.MaxSwitchMagnitude, magnitude)
+		}
+
+		j, ok := representations[segment.RepIndex]
+		if !ok {
+			j = len(m.Representations)
+			representations[segment.RepIndex] = j
+			m.Representations = append(m.Representations, RepresentationTime{Index: segment.RepIndex, Bitrate: segment.Bandwidth,
+				Width: segment.RepWidth, Height: segment.RepHeight})
+		}
+		m.Representations[j].Segments++
+		m.Representations[j].Time += segmentDuration
+	}
+
+	m.AverageBitrate = sumRate / float64(len(log))
+	if duration > 0 {
+		m.TimeWeightedBitrate = sumWeightedRate / duration
+		for j := range m.Representations {
+			m.Representations[j].Share = m.Representations[j].Time / duration
+		}
+	}
+	if m.Switches > 0 {
+		m.AverageSwitchMagnitude = sumMagnitude / float64(m.Switches)
+	}
+	return m
+}
+
+// WriteSummary : save the summary of a session as JSON
+func WriteSummary(path string, s Summary) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
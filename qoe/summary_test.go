@@ -0,0 +1,107 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package qoe
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	glob "github.com/uccmisl/godash/global"
+	"github.com/uccmisl/godash/logging"
+)
+
+// segmentLog : a log of 2 second segments at the given bitrates and stall times
+func segmentLog(mimeType string, bitrates []int, stalls map[int]int) map[int]logging.SegPrintLogInformation {
+	log := make(map[int]logging.SegPrintLogInformation)
+	var segRates []float64
+	for i, bitrate := range bitrates {
+		segRates = append(segRates, float64(bitrate))
+		log[i+1] = logging.SegPrintLogInformation{
+			ArrivalTime:     (i + 1) * 1000,
+			StallTime:       stalls[i+1],
+			Bandwidth:       bitrate,
+			SegSize:         bitrate / 4,
+			SegmentDuration: 2,
+			Adapt:           "bba",
+			RepIndex:        bitrate / 1000000,
+			MimeType:        mimeType,
+			Played:          i < len(bitrates)-1,
+			SegmentRates:    append([]float64(nil), segRates...),
+			PlaybackTime:    (i + 1) * 2000,
+		}
+	}
+	return log
+}
+
+func TestSummarise(t *testing.T) {
+	video := segmentLog("video/mp4", []int{1000000, 1000000, 3000000, 2000000, 3000000}, map[int]int{4: -500, 5: -1500})
+	audio := segmentLog(glob.RepRateCodecAudio, []int{128000, 128000, 128000, 128000, 128000}, nil)
+	s := Summarise([]map[int]logging.SegPrintLogInformation{video, audio}, 2, []int{4000000, 128000})
+
+	if s.Algorithm != "bba" || s.StartupDelay != 3000 || s.ContentDuration != 10000 || s.Stalls != 2 || s.StallDuration != 2000 ||
+		s.AverageStall != 1000 || s.StallFrequency != 12 || s.RebufferingRatio != 2000.0/12000 {
+		t.Errorf("summary %+v", s)
+	}
+	if len(s.MediaTypes) != 2 {
+		t.Fatalf("media types %+v", s.MediaTypes)
+	}
+	m := s.MediaTypes[0]
+	if m.Segments != 5 || m.AverageBitrate != 2000000 || m.TimeWeightedBitrate != 2000000 || m.Switches != 3 || m.SwitchesUp != 2 ||
+		m.SwitchesDown != 1 || m.MaxSwitchMagnitude != 2000000 || m.AverageSwitchMagnitude != 4000000.0/3 ||
+		m.BytesDownloaded != 2500000 || m.BytesPlayed != 1750000 {
+		t.Errorf("video %+v", m)
+	}
+	if len(m.Representations) != 3 || m.Representations[0].Index != 1 || m.Representations[0].Time != 4000 ||
+		m.Representations[1].Index != 3 || m.Representations[1].Share != 0.4 {
+		t.Errorf("representations %+v", m.Representations)
+	}
+	if a := s.MediaTypes[1]; a.MimeType != glob.RepRateCodecAudio || a.Switches != 0 || a.AverageBitrate != 128000 {
+		t.Errorf("audio %+v", a)
+	}
+	if s.P1203 != nil || s.Claye == 0 {
+		t.Errorf("QoE %v %v", s.P1203, s.Claye)
+	}
+
+	path := filepath.Join(t.TempDir(), glob.SummaryFile)
+	if err := WriteSummary(path, s); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	if fields["schema_version"] != 1.0 || fields["rebuffering_ratio"] == nil || fields["p1203"] != nil {
+		t.Errorf("summary.json %s", data)
+	}
+}
+
+func TestSummariseEmpty(t *testing.T) {
+	if s := Summarise(nil, 2, nil); s.Stalls != 0 || len(s.MediaTypes) != 0 {
+		t.Errorf("summary %+v", s)
+	}
+}
@@ -0,0 +1,199 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package report
+
+import (
+	"fmt"
+	"html"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Point : a point of a series
+type Point struct {
+	X, Y float64
+}
+
+// Series : a line of a plot, a step series keeps each value until the next point
+type Series struct {
+	Name   string
+	Points []Point
+	Step   bool
+}
+
+// Plot : a plot of one or more series, with vertical markers such as the stalls
+type Plot struct {
+	Title   string
+	XLabel  string
+	YLabel  string
+	Series  []Series
+	Markers []float64
+	// the name of the markers in the legend
+	MarkerName string
+}
+
+// the size of a plot and of the margins around its axes, in pixels
+const (
+	plotWidth   = 860
+	plotHeight  = 300
+	marginLeft  = 70
+	marginRight = 20
+	marginTop   = 30
+	// the x axis label and the legend are below the plot
+	marginBottom = 70
+)
+
+// colors : the colors of the series, in order
+var colors = []string{"#1f77b4", "#ff7f0e", "#2ca02c", "#9467bd", "#8c564b", "#17becf", "#7f7f7f"}
+
+// markerColor : the color of the markers
+const markerColor = "#d62728"
+
+// bounds : the range of the points and markers of the plot, the y axis starts at 0
+func (p Plot) bounds() (xMin, xMax, yMax float64) {
+	xMin, xMax = math.Inf(1), math.Inf(-1)
+	for _, s := range p.Series {
+		for _, point := range s.Points {
+			xMin, xMax = math.Min(xMin, point.X), math.Max(xMax, point.X)
+			yMax = math.Max(yMax, point.Y)
+		}
+	}
+	for _, x := range p.Markers {
+		xMin, xMax = math.Min(xMin, x), math.Max(xMax, x)
+	}
+	if math.IsInf(xMin, 1) {
+		xMin, xMax = 0, 1
+	}
+	if xMax <= xMin {
+		xMax = xMin + 1
+	}
+	if yMax <= 0 {
+		yMax = 1
+	}
+	return xMin, xMax, yMax
+}
+
+// niceStep : a step of 1, 2 or 5 times a power of ten, for about count ticks over span
+func niceStep(span float64, count int) float64 {
+	raw := span / float64(count)
+	magnitude := math.Pow(10, math.Floor(math.Log10(raw)))
+	for _, m := range []float64{1, 2, 5} {
+		if raw <= m*magnitude {
+			return m * magnitude
+		}
+	}
+	return 10 * magnitude
+}
+
+// ticks : the ticks of an axis from min to max
+func ticks(min, max float64, count int) (float64, float64, []float64) {
+	step := niceStep(max-min, count)
+	min = math.Floor(min/step) * step
+	max = math.Ceil(max/step) * step
+	var values []float64
+	for i := 0; min+float64(i)*step <= max+step/2; i++ {
+		values = append(values, min+float64(i)*step)
+	}
+	return min, max, values
+}
+
+// formatTick : a tick with the decimals of the step of its axis
+func formatTick(v, step float64) string {
+	decimals := 0
+	if step < 1 {
+		decimals = int(math.Ceil(-math.Log10(step)))
+	}
+	return strconv.FormatFloat(v, 'f', decimals, 64)
+}
+
+// SVG : the plot as an SVG image
+func (p Plot) SVG() string {
+	xMin, xMax, yMax := p.bounds()
+	xMin, xMax, xTicks := ticks(xMin, xMax, 10)
+	_, yMax, yTicks := ticks(0, yMax, 5)
+	xStep, yStep := xTicks[1]-xTicks[0], yTicks[1]-yTicks[0]
+
+	width := float64(plotWidth - marginLeft - marginRight)
+	height := float64(plotHeight - marginTop - marginBottom)
+	x := func(v float64) float64 { return marginLeft + (v-xMin)/(xMax-xMin)*width }
+	y := func(v float64) float64 { return marginTop + height - v/yMax*height }
+
+	b := &strings.Builder{}
+	fmt.Fprintf(b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="11">`+"\n",
+		plotWidth, plotHeight, plotWidth, plotHeight)
+	fmt.Fprintf(b, `<text x="%d" y="18" font-size="14" font-weight="bold">%s</text>`+"\n", marginLeft, html.EscapeString(p.Title))
+
+	// the grid and the ticks of the axes
+	for _, v := range yTicks {
+		fmt.Fprintf(b, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#e0e0e0"/>`+"\n", x(xMin), y(v), x(xMax), y(v))
+		fmt.Fprintf(b, `<text x="%.1f" y="%.1f" text-anchor="end">%s</text>`+"\n", x(xMin)-6, y(v)+4, formatTick(v, yStep))
+	}
+	for _, v := range xTicks {
+		fmt.Fprintf(b, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#e0e0e0"/>`+"\n", x(v), y(0), x(v), y(yMax))
+		fmt.Fprintf(b, `<text x="%.1f" y="%.1f" text-anchor="middle">%s</text>`+"\n", x(v), y(0)+16, formatTick(v, xStep))
+	}
+	fmt.Fprintf(b, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="none" stroke="#606060"/>`+"\n", x(xMin), y(yMax), width, height)
+	fmt.Fprintf(b, `<text x="%.1f" y="%.1f" text-anchor="middle">%s</text>`+"\n", x(xMin)+width/2, y(0)+32, html.EscapeString(p.XLabel))
+	fmt.Fprintf(b, `<text transform="translate(16 %.1f) rotate(-90)" text-anchor="middle">%s</text>`+"\n", marginTop+height/2, html.EscapeString(p.YLabel))
+
+	for _, m := range p.Markers {
+		fmt.Fprintf(b, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="%s" stroke-dasharray="4 3"><title>%s %s</title></line>`+"\n",
+			x(m), y(0), x(m), y(yMax), markerColor, html.EscapeString(p.MarkerName), formatTick(m, xStep/100))
+	}
+
+	for i, s := range p.Series {
+		if len(s.Points) == 0 {
+			continue
+		}
+		path := &strings.Builder{}
+		fmt.Fprintf(path, "M%.1f %.1f", x(s.Points[0].X), y(s.Points[0].Y))
+		for _, point := range s.Points[1:] {
+			if s.Step {
+				fmt.Fprintf(path, " H%.1f V%.1f", x(point.X), y(point.Y))
+			} else {
+				fmt.Fprintf(path, " L%.1f %.1f", x(point.X), y(point.Y))
+			}
+		}
+		if s.Step {
+			// the last value holds to the end of the plot
+			fmt.Fprintf(path, " H%.1f", x(xMax))
+		}
+		fmt.Fprintf(b, `<path d="%s" fill="none" stroke="%s" stroke-width="1.5"><title>%s</title></path>`+"\n",
+			path.String(), colors[i%len(colors)], html.EscapeString(s.Name))
+	}
+
+	// the legend
+	lx := float64(marginLeft)
+	ly := float64(plotHeight - 14)
+	for i, s := range p.Series {
+		fmt.Fprintf(b, `<rect x="%.1f" y="%.1f" width="12" height="3" fill="%s"/><text x="%.1f" y="%.1f">%s</text>`+"\n",
+			lx, ly-4, colors[i%len(colors)], lx+16, ly, html.EscapeString(s.Name))
+		lx += 16 + 7*float64(len(s.Name)) + 20
+	}
+	if len(p.Markers) > 0 {
+		fmt.Fprintf(b, `<rect x="%.1f" y="%.1f" width="12" height="3" fill="%s"/><text x="%.1f" y="%.1f">%s</text>`+"\n",
+			lx, ly-4, markerColor, lx+16, ly, html.EscapeString(p.MarkerName))
+	}
+	b.WriteString("</svg>")
+	return b.String()
+}
@@ -0,0 +1,397 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package report
+
+import (
+	"bufio"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gonum.org/v1/gonum/stat"
+
+	glob "github.com/uccmisl/godash/global"
+	qlogreader "github.com/uccmisl/godash/qlog/reader"
+)
+
+// Report : the plots and summary table of a session
+type Report struct {
+	Title   string
+	Summary []Row
+	Plots   []Plot
+}
+
+// Row : a row of the summary table
+type Row struct {
+	Name  string
+	Value string
+}
+
+// qoeModels : the QoE columns of the segment log, in the order they are plotted
+var qoeModels = []string{glob.P1203Header, glob.ClaeHeader, glob.DuanmuHeader, glob.YinHeader, glob.YuHeader}
+
+// New : the report of a session, from its qlog-abr log and its segment log - the QoE
+// plots are left out when segmentLog is "" or the log has no QoE columns
+func New(l *qlogreader.Log, segmentLog string) (*Report, error) {
+
+	r := &Report{Title: "goDASH session " + filepath.Base(l.Path)}
+
+	var qoe map[string]map[string][]Point
+	var codecs []string
+	if segmentLog != "" {
+		var err error
+		codecs, qoe, err = ReadQoE(segmentLog)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	r.summarise(l, qoe, codecs)
+	r.plotSession(l)
+	for _, codec := range codecs {
+		var series []Series
+		for _, model := range qoeModels {
+			if points := qoe[codec][model]; len(points) > 0 {
+				series = append(series, Series{Name: model, Points: points})
+			}
+		}
+		r.Plots = append(r.Plots, Plot{
+			Title:  "QoE - " + codec,
+			XLabel: "segment",
+			YLabel: "QoE",
+			Series: series,
+		})
+	}
+	return r, nil
+}
+
+// mediaSeries : the time series of a media type, times in seconds and bitrates in kbps
+type mediaSeries struct {
+	bitrate    []Point
+	switches   []Point
+	buffer     []Point
+	throughput []Point
+	estimate   []Point
+}
+
+// plotSession : the plots of the bitrate, buffer level and throughput of each media type
+func (r *Report) plotSession(l *qlogreader.Log) {
+
+	var mediaTypes []string
+	series := make(map[string]*mediaSeries)
+	media := func(mediaType string) *mediaSeries {
+		if _, ok := series[mediaType]; !ok {
+			mediaTypes = append(mediaTypes, mediaType)
+			series[mediaType] = &mediaSeries{}
+		}
+		return series[mediaType]
+	}
+	var stalls []float64
+
+	for _, e := range l.Events {
+		t := e.Time.Seconds()
+		switch d := e.Details.(type) {
+		case qlogreader.Decision:
+			m := media(d.MediaType)
+			m.bitrate = append(m.bitrate, Point{t, float64(d.Bitrate)})
+			m.throughput = append(m.throughput, Point{t, d.Throughput / 1000})
+			if d.Estimate > 0 {
+				m.estimate = append(m.estimate, Point{t, d.Estimate / 1000})
+			}
+		case qlogreader.Switch:
+			m := media(d.MediaType)
+			if len(m.switches) == 0 && d.FromBitrate > 0 {
+				m.switches = append(m.switches, Point{0, float64(d.FromBitrate)})
+			}
+			m.switches = append(m.switches, Point{t, float64(d.ToBitrate)})
+		case qlogreader.BufferOccupancy:
+			m := media(d.MediaType)
+			m.buffer = append(m.buffer, Point{t, d.Playout.Seconds()})
+		case qlogreader.Playhead:
+			if e.Name == "rebuffer" {
+				stalls = append(stalls, t)
+			}
+		}
+	}
+
+	for _, mediaType := range mediaTypes {
+		m := series[mediaType]
+		// the decisions give the rate of every segment, the switches only its changes
+		bitrate := m.bitrate
+		if len(bitrate) == 0 {
+			bitrate = m.switches
+		}
+		name := mediaType
+		if name == "" {
+			name = "media"
+		}
+		if len(bitrate) > 0 {
+			r.Plots = append(r.Plots, Plot{
+				Title:      "Bitrate - " + name,
+				XLabel:     "time (s)",
+				YLabel:     "bitrate (kbps)",
+				Series:     []Series{{Name: "selected bitrate", Points: bitrate, Step: true}},
+				Markers:    stalls,
+				MarkerName: "stall",
+			})
+		}
+		if len(m.buffer) > 0 {
+			r.Plots = append(r.Plots, Plot{
+				Title:      "Buffer level - " + name,
+				XLabel:     "time (s)",
+				YLabel:     "buffer level (s)",
+				Series:     []Series{{Name: "buffer level", Points: m.buffer}},
+				Markers:    stalls,
+				MarkerName: "stall",
+			})
+		}
+		if len(m.throughput) > 0 {
+			plot := Plot{
+				Title:      "Throughput - " + name,
+				XLabel:     "time (s)",
+				YLabel:     "rate (kbps)",
+				Series:     []Series{{Name: "throughput", Points: m.throughput}},
+				Markers:    stalls,
+				MarkerName: "stall",
+			}
+			if len(m.estimate) > 0 {
+				plot.Series = append(plot.Series, Series{Name: "estimate", Points: m.estimate})
+			}
+			plot.Series = append(plot.Series, Series{Name: "selected bitrate", Points: m.bitrate, Step: true})
+			r.Plots = append(r.Plots, plot)
+		}
+	}
+}
+
+// summarise : the summary table of the session
+func (r *Report) summarise(l *qlogreader.Log, qoe map[string]map[string][]Point, codecs []string) {
+
+	s := qlogreader.Summarise(l)
+	add := func(name, value string) {
+		r.Summary = append(r.Summary, Row{Name: name, Value: value})
+	}
+
+	// the media types of the summary, then those that only have decisions
+	var mediaTypes []string
+	media := make(map[string]*qlogreader.MediaSummary)
+	for i := range s.MediaTypes {
+		m := &s.MediaTypes[i]
+		mediaTypes = append(mediaTypes, m.MediaType)
+		media[m.MediaType] = m
+	}
+	throughput := make(map[string][]float64)
+	bitrate := make(map[string][]float64)
+	for _, e := range l.Events {
+		switch d := e.Details.(type) {
+		case qlogreader.ManifestLoaded:
+			add("MPD", d.URL)
+		case qlogreader.Decision:
+			if len(throughput) == 0 {
+				add("algorithm", d.Algorithm)
+			}
+			if _, ok := throughput[d.MediaType]; !ok && media[d.MediaType] == nil {
+				mediaTypes = append(mediaTypes, d.MediaType)
+			}
+			throughput[d.MediaType] = append(throughput[d.MediaType], d.Throughput/1000)
+			bitrate[d.MediaType] = append(bitrate[d.MediaType], float64(d.Bitrate))
+		}
+	}
+	if l.GroupID != "" {
+		add("run", l.GroupID)
+	}
+	if l.Truncated {
+		add("log", "not closed, the summary is up to its last complete event")
+	}
+	add("startup delay", s.StartupDelay.Round(time.Millisecond).String())
+	add("playback time", s.PlaybackTime.Round(time.Millisecond).String())
+	add("stalls", fmt.Sprintf("%d (%s)", s.Stalls, s.StallTime.Round(time.Millisecond)))
+
+	for _, mediaType := range mediaTypes {
+		prefix := mediaType + " "
+		if m := media[mediaType]; m != nil {
+			add(prefix+"switches", fmt.Sprintf("%d (%d up, %d down)", m.Switches, m.SwitchesUp, m.SwitchesDown))
+			add(prefix+"average bitrate", kbps(m.AverageBitrate))
+		}
+		if values := bitrate[mediaType]; len(values) > 0 {
+			add(prefix+"mean segment bitrate", kbps(stat.Mean(values, nil)))
+		}
+		if values := throughput[mediaType]; len(values) > 0 {
+			sorted := append([]float64(nil), values...)
+			sort.Float64s(sorted)
+			add(prefix+"throughput mean", kbps(stat.Mean(values, nil)))
+			add(prefix+"throughput median", kbps(stat.Quantile(0.5, stat.Empirical, sorted, nil)))
+			add(prefix+"throughput 10th percentile", kbps(stat.Quantile(0.1, stat.Empirical, sorted, nil)))
+		}
+	}
+
+	// the QoE of the session is the value of the last segment
+	for _, codec := range codecs {
+		for _, model := range qoeModels {
+			if points := qoe[codec][model]; len(points) > 0 {
+				add(codec+" "+model, strconv.FormatFloat(points[len(points)-1].Y, 'f', 3, 64))
+			}
+		}
+	}
+}
+
+// Filename : the report of a qlog file, next to it with the extension .html
+func Filename(qlogPath string) string {
+	name := strings.TrimSuffix(qlogPath, qlogreader.GzipExtension)
+	return strings.TrimSuffix(name, filepath.Ext(name)) + ".html"
+}
+
+// kbps : a bitrate in kbps, "-" when it is not known
+func kbps(bitrate float64) string {
+	if bitrate < 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.0f kbps", bitrate)
+}
+
+// ReadQoE : the QoE of each segment of a segment log, by codec and by QoE model, in the
+// order the codecs appear in the log
+func ReadQoE(path string) (codecs []string, qoe map[string]map[string][]Point, err error) {
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	qoe = make(map[string]map[string][]Point)
+	codec := -1
+	var columns map[string]int
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		// the header line gives the columns
+		if fields[0] == glob.SegNum {
+			codec = -1
+			columns = make(map[string]int)
+			for i, field := range fields {
+				if field == glob.CodecHeader {
+					codec = i
+				}
+				for _, model := range qoeModels {
+					if field == model {
+						columns[model] = i
+					}
+				}
+			}
+			continue
+		}
+		segment, err := strconv.Atoi(fields[0])
+		if err != nil {
+			continue
+		}
+		name := ""
+		if codec >= 0 && codec < len(fields) {
+			name = fields[codec]
+		}
+		for _, model := range qoeModels {
+			i, ok := columns[model]
+			if !ok || i >= len(fields) {
+				continue
+			}
+			value, err := strconv.ParseFloat(fields[i], 64)
+			if err != nil {
+				continue
+			}
+			if _, ok := qoe[name]; !ok {
+				codecs = append(codecs, name)
+				qoe[name] = make(map[string][]Point)
+			}
+			qoe[name][model] = append(qoe[name][model], Point{float64(segment), value})
+		}
+	}
+	return codecs, qoe, scanner.Err()
+}
+
+// page : the HTML of a report, the plots are inline SVG
+var page = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 24px; color: #202020; }
+table { border-collapse: collapse; margin-bottom: 24px; }
+td { border: 1px solid #d0d0d0; padding: 4px 12px; }
+td:first-child { font-weight: bold; }
+figure { margin: 0 0 24px 0; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<h2>Summary</h2>
+<table>
+{{- range .Summary}}
+<tr><td>{{.Name}}</td><td>{{.Value}}</td></tr>
+{{- end}}
+</table>
+<h2>Plots</h2>
+{{- range .Plots}}
+<figure>{{.}}</figure>
+{{- end}}
+</body>
+</html>
+`))
+
+// Render : write the report as a self-contained HTML page
+func (r *Report) Render(w io.Writer) error {
+	plots := make([]template.HTML, len(r.Plots))
+	for i, p := range r.Plots {
+		plots[i] = template.HTML(p.SVG())
+	}
+	return page.Execute(w, struct {
+		Title   string
+		Summary []Row
+		Plots   []template.HTML
+	}{r.Title, r.Summary, plots})
+}
+
+// WriteFile : save the report to an HTML file
+func (r *Report) WriteFile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	if err := r.Render(w); err != nil {
+		f.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
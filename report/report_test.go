@@ -0,0 +1,125 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package report
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	glob "github.com/uccmisl/godash/global"
+	abrqlog "github.com/uccmisl/godash/qlog"
+	qlogreader "github.com/uccmisl/godash/qlog/reader"
+)
+
+func TestReport(t *testing.T) {
+	dir := t.TempDir()
+
+	qlogPath := filepath.Join(dir, "client_abr.qlog")
+	f, err := os.Create(qlogPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracer := abrqlog.NewStreamTracer(f, abrqlog.PerspectiveClient, "20260101-120000-abcd")
+	tracer.InitialiseStream(true)
+	playhead := abrqlog.NewPlayheadStatus()
+	tracer.PlayerInteraction(abrqlog.InteractionStatePlay, playhead, 1)
+	for i, bitrate := range []int64{1000, 2500, 1000} {
+		tracer.Decision(abrqlog.MediaTypeVideo, abrqlog.Decision{Algorithm: "bba", Segment: i + 2, Throughput: 3000000,
+			Estimate: 2800000, BufferLevel: 4 * time.Second, Index: i % 2, Bitrate: bitrate})
+		buffer := abrqlog.NewBufferStats()
+		buffer.PlayoutTime = time.Duration(i+1) * 2 * time.Second
+		tracer.UpdateBufferOccupancy(abrqlog.MediaTypeVideo, buffer)
+	}
+	tracer.Rebuffer(playhead)
+	tracer.EndStream(playhead)
+	tracer.Close()
+
+	segmentLog := filepath.Join(dir, glob.LogDownload)
+	log := glob.SegNum + " Arr_time " + glob.CodecHeader + " " + glob.P1203Header + " " + glob.YinHeader + "\n" +
+		"1 100 H264 4.5 2.0\n2 200 H264 4.2 1.5\n3 300 H264 3.9 -\n"
+	if err := os.WriteFile(segmentLog, []byte(log), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := qlogreader.ReadFile(qlogPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := New(l, segmentLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var titles []string
+	for _, p := range r.Plots {
+		titles = append(titles, p.Title)
+	}
+	if strings.Join(titles, ",") != "Bitrate - video,Buffer level - video,Throughput - video,QoE - H264" {
+		t.Errorf("plots %v", titles)
+	}
+	if p := r.Plots[2]; len(p.Series) != 3 || len(p.Markers) != 1 || p.Series[0].Points[0].Y != 3000 {
+		t.Errorf("throughput plot %#v", p)
+	}
+	if p := r.Plots[3]; len(p.Series) != 2 || len(p.Series[0].Points) != 3 || len(p.Series[1].Points) != 2 {
+		t.Errorf("QoE plot %#v", p)
+	}
+
+	rows := make(map[string]string)
+	for _, row := range r.Summary {
+		rows[row.Name] = row.Value
+	}
+	if rows["algorithm"] != "bba" || rows["run"] != "20260101-120000-abcd" || !strings.HasPrefix(rows["stalls"], "1 ") ||
+		rows["video throughput mean"] != "3000 kbps" || rows["video mean segment bitrate"] != "1500 kbps" || rows["H264 P.1203"] != "3.900" {
+		t.Errorf("summary %v", rows)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := r.Render(buf); err != nil {
+		t.Fatal(err)
+	}
+	page := buf.String()
+	if strings.Count(page, "<svg ") != 4 || !strings.Contains(page, "<td>video throughput mean</td><td>3000 kbps</td>") ||
+		!strings.Contains(page, "stroke-dasharray") {
+		t.Errorf("page %s", page)
+	}
+}
+
+func TestTicks(t *testing.T) {
+	min, max, values := ticks(0.3, 47, 5)
+	if min != 0 || max != 50 || len(values) != 6 || values[5] != 50 {
+		t.Errorf("ticks %v %v %v", min, max, values)
+	}
+}
+
+func TestFilename(t *testing.T) {
+	for path, want := range map[string]string{
+		"logs/client_abr_run_bba_stream.qlog":      "logs/client_abr_run_bba_stream.html",
+		"logs/client_abr_run_bba_stream.ndjson.gz": "logs/client_abr_run_bba_stream.html",
+	} {
+		if got := Filename(path); got != want {
+			t.Errorf("%s: %s", path, got)
+		}
+	}
+}